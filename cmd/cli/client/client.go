@@ -2,15 +2,29 @@ package client
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/piheta/seq.re/cmd/cli/models"
 )
 
+// maxRateLimitRetries is how often a chunked upload request is resent after the server asked to slow down
+const maxRateLimitRetries = 10
+
+// ErrUploadNotFound is returned for chunked uploads the server no longer knows, they expired or were finalized
+var ErrUploadNotFound = errors.New("upload not found")
+
 // Client handles API requests to the seqre server
 type Client struct {
 	BaseURL    string
@@ -81,11 +95,34 @@ func (c *Client) GetVersion() (*models.VersionResponse, error) {
 	return &versionResp, nil
 }
 
+// GetLanguages retrieves the languages pastes can be created with
+func (c *Client) GetLanguages() ([]models.Language, error) {
+	resp, err := http.Get(c.BaseURL + "/api/languages")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var languages []models.Language
+	if err := json.NewDecoder(resp.Body).Decode(&languages); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return languages, nil
+}
+
 // GetLink retrieves link information by short code
 func (c *Client) GetLink(short string) (*models.LinkResponse, error) {
-	resp, err := http.Get(c.BaseURL + "/api/links/" + short + "?cli=true")
+	resp, err := c.reveal("links", short)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to server: %w", err)
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -109,23 +146,56 @@ func (c *Client) GetLink(short string) (*models.LinkResponse, error) {
 	return &linkResp, nil
 }
 
+// GetLinkStats retrieves the click statistics of a link
+func (c *Client) GetLinkStats(short string, token string) (*models.LinkStatsResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/links/"+short+"/stats", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Deletion-Token", token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var stats models.LinkStatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &stats, nil
+}
+
 // CreateLink creates a shortened URL
 //
-//nolint:revive // encrypted and onetime flags are acceptable for control flow
-func (c *Client) CreateLink(url string, encrypted bool, onetime bool) (string, error) {
+//nolint:revive // encrypted, passwordProtected and preview flags are acceptable for control flow
+func (c *Client) CreateLink(url string, encrypted bool, passwordProtected bool, maxViews int, expiresIn string, alias string, preview bool) (*models.CreatedResponse, error) {
 	linkReq := models.LinkRequest{
-		URL:       url,
-		Encrypted: encrypted,
-		OneTime:   onetime,
+		URL:               url,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ExpiresIn:         expiresIn,
+		Alias:             alias,
+		Preview:           preview,
 	}
 	reqBody, err := json.Marshal(linkReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	resp, err := http.Post(c.BaseURL+"/api/links", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -133,33 +203,33 @@ func (c *Client) CreateLink(url string, encrypted bool, onetime bool) (string, e
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var shortURL string
-	if err := json.Unmarshal(body, &shortURL); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	var created models.CreatedResponse
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return shortURL, nil
+	return &created, nil
 }
 
 // CreateSecret creates a new secret and returns the full URL
-func (c *Client) CreateSecret(encryptedData string) (string, error) {
-	secretReq := models.SecretRequest{Data: encryptedData}
+func (c *Client) CreateSecret(encryptedData string, maxViews int, expiresIn string) (*models.CreatedResponse, error) {
+	secretReq := models.SecretRequest{Data: encryptedData, MaxViews: maxViews, ExpiresIn: expiresIn}
 	reqBody, err := json.Marshal(secretReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	resp, err := http.Post(c.BaseURL+"/api/secrets", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -167,28 +237,28 @@ func (c *Client) CreateSecret(encryptedData string) (string, error) {
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Server returns full URL as a string
-	var fullURL string
-	if err := json.Unmarshal(body, &fullURL); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	// Server returns the full URL (without key fragment) and expiry
+	var created models.CreatedResponse
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return fullURL, nil
+	return &created, nil
 }
 
-// GetSecret retrieves a secret by short code
-func (c *Client) GetSecret(short string) (string, error) {
-	resp, err := http.Get(c.BaseURL + "/s/" + short + "?cli=true")
+// GetSecret retrieves a secret by short code, using up one of its views
+func (c *Client) GetSecret(short string) (*models.SecretDataResponse, error) {
+	resp, err := c.reveal("secrets", short)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -196,59 +266,99 @@ func (c *Client) GetSecret(short string) (string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var secretReq models.SecretRequest
-	if err := json.Unmarshal(body, &secretReq); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	var secret models.SecretDataResponse
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return secretReq.Data, nil
+	return &secret, nil
 }
 
-// CreateImage uploads a raw image file
+// GetSecretStatus returns whether a secret was read, authorized by its deletion token
+func (c *Client) GetSecretStatus(short string, token string) (*models.SecretStatusResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/api/secrets/"+short+"/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Deletion-Token", token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var status models.SecretStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &status, nil
+}
+
+// CreateImage uploads a raw image file, the server removes its metadata unless keepMetadata is set
 //
-//nolint:revive // onetime flag is acceptable for control flow
-func (c *Client) CreateImage(imageData []byte, filename string, onetime bool) (string, error) {
+//nolint:revive // keepMetadata flag is acceptable for control flow
+func (c *Client) CreateImage(imageData []byte, filename string, keepMetadata bool, maxViews int, expiresIn string) (*models.CreatedResponse, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	// Add file field with actual filename
 	part, err := writer.CreateFormFile("file", filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %w", err)
+		return nil, fmt.Errorf("failed to create form file: %w", err)
 	}
 
 	if _, err := part.Write(imageData); err != nil {
-		return "", fmt.Errorf("failed to write image data: %w", err)
+		return nil, fmt.Errorf("failed to write image data: %w", err)
+	}
+
+	if keepMetadata {
+		if err := writer.WriteField("keep_metadata", "true"); err != nil {
+			return nil, fmt.Errorf("failed to write keep_metadata field: %w", err)
+		}
+	}
+
+	if maxViews > 0 {
+		if err := writer.WriteField("max_views", strconv.Itoa(maxViews)); err != nil {
+			return nil, fmt.Errorf("failed to write max_views field: %w", err)
+		}
 	}
 
-	// Add onetime flag if true
-	if onetime {
-		if err := writer.WriteField("onetime", "true"); err != nil {
-			return "", fmt.Errorf("failed to write onetime field: %w", err)
+	if expiresIn != "" {
+		if err := writer.WriteField("expires_in", expiresIn); err != nil {
+			return nil, fmt.Errorf("failed to write expires_in field: %w", err)
 		}
 	}
 
 	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("failed to close writer: %w", err)
+		return nil, fmt.Errorf("failed to close writer: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", c.BaseURL+"/api/images", body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -256,64 +366,75 @@ func (c *Client) CreateImage(imageData []byte, filename string, onetime bool) (s
 
 	if resp.StatusCode != http.StatusCreated {
 		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var imageURL string
-	if err := json.Unmarshal(respBody, &imageURL); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	var created models.CreatedResponse
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return imageURL, nil
+	return &created, nil
 }
 
 // CreateEncryptedImage uploads an encrypted image blob
 //
-//nolint:revive // onetime flag is acceptable for control flow
-func (c *Client) CreateEncryptedImage(encryptedData []byte, onetime bool) (string, error) {
+//nolint:revive // passwordProtected flag is acceptable for control flow
+func (c *Client) CreateEncryptedImage(encryptedData []byte, passwordProtected bool, maxViews int, expiresIn string) (*models.CreatedResponse, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
 	// Add file field
 	part, err := writer.CreateFormFile("file", "encrypted.bin")
 	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %w", err)
+		return nil, fmt.Errorf("failed to create form file: %w", err)
 	}
 
 	if _, err := part.Write(encryptedData); err != nil {
-		return "", fmt.Errorf("failed to write encrypted data: %w", err)
+		return nil, fmt.Errorf("failed to write encrypted data: %w", err)
 	}
 
 	// Add encrypted flag
 	if err := writer.WriteField("encrypted", "true"); err != nil {
-		return "", fmt.Errorf("failed to write encrypted field: %w", err)
+		return nil, fmt.Errorf("failed to write encrypted field: %w", err)
 	}
 
-	// Add onetime flag if true
-	if onetime {
-		if err := writer.WriteField("onetime", "true"); err != nil {
-			return "", fmt.Errorf("failed to write onetime field: %w", err)
+	if passwordProtected {
+		if err := writer.WriteField("password_protected", "true"); err != nil {
+			return nil, fmt.Errorf("failed to write password_protected field: %w", err)
+		}
+	}
+
+	if maxViews > 0 {
+		if err := writer.WriteField("max_views", strconv.Itoa(maxViews)); err != nil {
+			return nil, fmt.Errorf("failed to write max_views field: %w", err)
+		}
+	}
+
+	if expiresIn != "" {
+		if err := writer.WriteField("expires_in", expiresIn); err != nil {
+			return nil, fmt.Errorf("failed to write expires_in field: %w", err)
 		}
 	}
 
 	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("failed to close writer: %w", err)
+		return nil, fmt.Errorf("failed to close writer: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", c.BaseURL+"/api/images", body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -321,27 +442,27 @@ func (c *Client) CreateEncryptedImage(encryptedData []byte, onetime bool) (strin
 
 	if resp.StatusCode != http.StatusCreated {
 		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var imageURL string
-	if err := json.Unmarshal(respBody, &imageURL); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	var created models.CreatedResponse
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return imageURL, nil
+	return &created, nil
 }
 
 // GetImageRaw retrieves a raw (unencrypted) image by short code
 func (c *Client) GetImageRaw(short string) ([]byte, error) {
-	resp, err := http.Get(c.BaseURL + "/i/" + short + "?cli=true")
+	resp, err := c.reveal("images", short)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to server: %w", err)
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -362,10 +483,9 @@ func (c *Client) GetImageRaw(short string) ([]byte, error) {
 
 // GetImage retrieves an encrypted image by short code (returns base64 encoded data)
 func (c *Client) GetImage(short string) (string, error) {
-	short += "?cli=true"
-	resp, err := http.Get(c.BaseURL + "/i/" + short + "?cli=true")
+	resp, err := c.reveal("images", short)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return "", err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -392,22 +512,300 @@ func (c *Client) GetImage(short string) (string, error) {
 	return imageResp.Data, nil
 }
 
-// CreatePaste creates a new text paste
-func (c *Client) CreatePaste(content string, language string, encrypted bool, onetime bool) (string, error) {
-	pasteReq := models.PasteRequest{
-		Content:   content,
-		Language:  language,
-		Encrypted: encrypted,
-		OneTime:   onetime,
+// CreateFile uploads a file. For encrypted files data and filename are expected to be encrypted already.
+//
+//nolint:revive // encrypted and passwordProtected flags are acceptable for control flow
+func (c *Client) CreateFile(fileData []byte, filename string, encrypted bool, passwordProtected bool, maxViews int, expiresIn string) (*models.CreatedResponse, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	partName := filename
+	if encrypted {
+		partName = "encrypted.bin"
+	}
+
+	part, err := writer.CreateFormFile("file", partName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+
+	if _, err := part.Write(fileData); err != nil {
+		return nil, fmt.Errorf("failed to write file data: %w", err)
+	}
+
+	if err := writer.WriteField("filename", filename); err != nil {
+		return nil, fmt.Errorf("failed to write filename field: %w", err)
+	}
+
+	if encrypted {
+		if err := writer.WriteField("encrypted", "true"); err != nil {
+			return nil, fmt.Errorf("failed to write encrypted field: %w", err)
+		}
+	}
+
+	if passwordProtected {
+		if err := writer.WriteField("password_protected", "true"); err != nil {
+			return nil, fmt.Errorf("failed to write password_protected field: %w", err)
+		}
+	}
+
+	if maxViews > 0 {
+		if err := writer.WriteField("max_views", strconv.Itoa(maxViews)); err != nil {
+			return nil, fmt.Errorf("failed to write max_views field: %w", err)
+		}
+	}
+
+	if expiresIn != "" {
+		if err := writer.WriteField("expires_in", expiresIn); err != nil {
+			return nil, fmt.Errorf("failed to write expires_in field: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/api/files", body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var created models.CreatedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &created, nil
+}
+
+// CreateUpload starts a chunked upload
+func (c *Client) CreateUpload(uploadReq models.UploadRequest) (*models.UploadSessionResponse, error) {
+	reqBody, err := json.Marshal(uploadReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.doUploadRequest(func() (*http.Request, error) {
+		req, err := http.NewRequest("POST", c.BaseURL+"/api/uploads", bytes.NewReader(reqBody))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	return decodeUploadSession(resp, http.StatusCreated)
+}
+
+// GetUpload retrieves the progress of a chunked upload
+func (c *Client) GetUpload(id string) (*models.UploadSessionResponse, error) {
+	resp, err := c.doUploadRequest(func() (*http.Request, error) {
+		return http.NewRequest("GET", c.BaseURL+"/api/uploads/"+id, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	return decodeUploadSession(resp, http.StatusOK)
+}
+
+// UploadChunk sends one chunk of a chunked upload along with its checksum
+func (c *Client) UploadChunk(id string, index int, chunk []byte) (*models.UploadSessionResponse, error) {
+	sum := sha256.Sum256(chunk)
+
+	resp, err := c.doUploadRequest(func() (*http.Request, error) {
+		req, err := http.NewRequest("PUT", fmt.Sprintf("%s/api/uploads/%s/chunks/%d", c.BaseURL, id, index), bytes.NewReader(chunk))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("X-Chunk-SHA256", hex.EncodeToString(sum[:]))
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	return decodeUploadSession(resp, http.StatusOK)
+}
+
+// FinalizeUpload turns a completely sent chunked upload into an image or file
+func (c *Client) FinalizeUpload(id string) (*models.CreatedResponse, error) {
+	resp, err := c.doUploadRequest(func() (*http.Request, error) {
+		return http.NewRequest("POST", c.BaseURL+"/api/uploads/"+id+"/finalize", nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var created models.CreatedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &created, nil
+}
+
+// doUploadRequest sends a request of a chunked upload, waiting out rate limits since an upload makes many requests
+func (c *Client) doUploadRequest(newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			return resp, err
+		}
+		_ = resp.Body.Close()
+		time.Sleep(time.Second)
+	}
+}
+
+func decodeUploadSession(resp *http.Response, wantStatus int) (*models.UploadSessionResponse, error) {
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrUploadNotFound
+	}
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var session models.UploadSessionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &session, nil
+}
+
+// GetFileRaw retrieves an unencrypted file and the name it was uploaded with
+func (c *Client) GetFileRaw(short string) ([]byte, string, error) {
+	resp, err := c.reveal("files", short)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
+
+	fileData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var filename string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		filename = params["filename"]
+	}
+
+	return fileData, filename, nil
+}
+
+// GetFile retrieves an encrypted file by short code (returns base64 encoded data)
+func (c *Client) GetFile(short string) (*models.FileResponse, error) {
+	resp, err := c.reveal("files", short)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var fileResp models.FileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&fileResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &fileResp, nil
+}
+
+// CreatePaste creates a new text paste, fileName names its downloads and is only sent for unencrypted pastes
+//
+//nolint:revive // encrypted, passwordProtected and editable flags are acceptable for control flow
+func (c *Client) CreatePaste(content string, language string, fileName string, encrypted bool, passwordProtected bool, maxViews int, expiresIn string, editable bool) (*models.CreatedResponse, error) {
+	return c.createPaste(models.PasteRequest{
+		Content:           content,
+		Language:          language,
+		FileName:          fileName,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ExpiresIn:         expiresIn,
+		Editable:          editable,
+	})
+}
+
+// CreateBundle creates a paste of several named files. Encrypted bundles pass nil files and the encrypted JSON of
+// their files as encryptedFiles
+//
+//nolint:revive // passwordProtected and editable flags are acceptable for control flow
+func (c *Client) CreateBundle(files []models.PasteFile, encryptedFiles string, passwordProtected bool, maxViews int, expiresIn string, editable bool) (*models.CreatedResponse, error) {
+	return c.createPaste(models.PasteRequest{
+		Content:           encryptedFiles,
+		Files:             files,
+		Bundle:            true,
+		Encrypted:         encryptedFiles != "",
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ExpiresIn:         expiresIn,
+		Editable:          editable,
+	})
+}
+
+func (c *Client) createPaste(pasteReq models.PasteRequest) (*models.CreatedResponse, error) {
 	reqBody, err := json.Marshal(pasteReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	resp, err := http.Post(c.BaseURL+"/api/pastes", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -415,27 +813,42 @@ func (c *Client) CreatePaste(content string, language string, encrypted bool, on
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var pasteURL string
-	if err := json.Unmarshal(body, &pasteURL); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	var created models.CreatedResponse
+	if err := json.Unmarshal(body, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return pasteURL, nil
+	return &created, nil
 }
 
-// GetPasteRaw retrieves a raw (unencrypted) paste by short code
-func (c *Client) GetPasteRaw(short string) (string, error) {
-	resp, err := http.Get(c.BaseURL + "/p/" + short)
+// UpdatePaste adds a revision to an editable paste. Unencrypted bundles are updated with files, encrypted ones
+// with the encrypted JSON of their files and bundle set
+//
+//nolint:revive // bundle flag is acceptable for control flow
+func (c *Client) UpdatePaste(short string, token string, content string, files []models.PasteFile, bundle bool) (*models.RevisionResponse, error) {
+	reqBody, err := json.Marshal(models.UpdatePasteRequest{Content: content, Files: files, Bundle: bundle})
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.BaseURL+"/api/pastes/"+short, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Edit-Token", token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -443,23 +856,23 @@ func (c *Client) GetPasteRaw(short string) (string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	pasteData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	var revision models.RevisionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&revision); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return string(pasteData), nil
+	return &revision, nil
 }
 
-// GetPaste retrieves an encrypted paste by short code (returns base64 encoded data)
-func (c *Client) GetPaste(short string) (string, error) {
-	short += "?cli=true"
-	resp, err := http.Get(c.BaseURL + "/p/" + short)
+// GetPaste retrieves a paste by short code, revision is empty for the current revision. Unencrypted pastes come
+// as text in Data, encrypted pastes and bundles as JSON
+func (c *Client) GetPaste(short string, revision string) (*models.PasteResponse, error) {
+	resp, err := c.getPaste(short, revision)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %w", err)
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -467,19 +880,111 @@ func (c *Client) GetPaste(short string) (string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return &models.PasteResponse{Data: string(body)}, nil
 	}
 
-	// For encrypted pastes, server returns JSON with base64 data
 	var pasteResp models.PasteResponse
 	if err := json.Unmarshal(body, &pasteResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &pasteResp, nil
+}
+
+// Delete deletes a link, paste, image, file or secret using its deletion token.
+// resource is the API collection, e.g. "links" or "pastes".
+func (c *Client) Delete(resource string, short string, token string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.BaseURL+"/api/"+resource+"/"+short, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Deletion-Token", token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// getPaste fetches a revision of a paste, or reveals the current one. Only editable pastes have revisions and
+// they are never view limited, so revisions don't need a reveal token.
+func (c *Client) getPaste(short string, revision string) (*http.Response, error) {
+	if revision == "" {
+		return c.reveal("pastes", short)
+	}
+
+	resp, err := c.HTTPClient.Get(c.BaseURL + "/p/" + short + "?cli=true&rev=" + url.QueryEscape(revision))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	return resp, nil
+}
+
+// reveal uses up a view of a resource the way its interstitial page does, by fetching a reveal token and
+// posting it back. kind is the API collection of the resource, such as "pastes".
+func (c *Client) reveal(kind string, short string) (*http.Response, error) {
+	revealURL := c.BaseURL + "/api/" + kind + "/" + short + "/reveal"
+
+	// The server only hands out and accepts reveal tokens for requests naming it as their origin
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %w", err)
 	}
+	origin := base.Scheme + "://" + base.Host
 
-	return pasteResp.Data, nil
+	startReq, err := http.NewRequest(http.MethodGet, revealURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	startReq.Header.Set("Origin", origin)
+
+	startResp, err := c.HTTPClient.Do(startReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer func() {
+		_ = startResp.Body.Close()
+	}()
+
+	if startResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(startResp.Body)
+		return nil, fmt.Errorf("server returned status %d: %s", startResp.StatusCode, string(body))
+	}
+
+	var start models.RevealResponse
+	if err := json.NewDecoder(startResp.Body).Decode(&start); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, revealURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Reveal-Token", start.RevealToken)
+	req.Header.Set("Origin", origin)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	return resp, nil
 }
@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/atotto/clipboard"
+	"github.com/piheta/seq.re/cmd/cli/client"
+	"github.com/piheta/seq.re/cmd/cli/config"
+	"github.com/piheta/seq.re/cmd/cli/crypto"
+	"github.com/piheta/seq.re/cmd/cli/models"
+)
+
+// chunkedUploadThreshold is the size above which images and files are sent in resumable chunks
+const chunkedUploadThreshold = 8 << 20
+
+// useChunkedUpload reports whether a file is large enough to be sent in chunks
+func useChunkedUpload(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() > chunkedUploadThreshold
+}
+
+// uploadInChunks sends an image or file in chunks, picking up where an interrupted upload of the same file
+// with the same options left off. It returns the created resource and the key fragment of encrypted content.
+//
+//nolint:revive // encrypted, withPassword and keepMetadata flags are acceptable for control flow
+func uploadInChunks(apiClient *client.Client, kind string, path string, encrypted bool, withPassword bool, keepMetadata bool, maxViews int, expiresIn string) (*models.CreatedResponse, string, error) {
+	source, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+
+	want := models.PendingUpload{
+		Server:            apiClient.BaseURL,
+		Kind:              kind,
+		Source:            source,
+		Size:              info.Size(),
+		ModTime:           info.ModTime(),
+		Encrypted:         encrypted || withPassword,
+		PasswordProtected: withPassword,
+		MaxViews:          maxViews,
+		ExpiresIn:         expiresIn,
+		KeepMetadata:      keepMetadata,
+	}
+
+	pending, session, err := resumeChunkedUpload(apiClient, want)
+	if err != nil {
+		return nil, "", err
+	}
+	if pending == nil {
+		pending, session, err = startChunkedUpload(apiClient, want)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := sendMissingChunks(apiClient, pending, session); err != nil {
+		return nil, "", fmt.Errorf("%w (run the same command again to resume)", err)
+	}
+
+	created, err := apiClient.FinalizeUpload(pending.UploadID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to finalize upload: %w", err)
+	}
+	_ = config.ForgetPendingUpload(pending.UploadID)
+
+	return created, pending.KeyFragment, nil
+}
+
+// printUploadURL prints the URL of an uploaded image or file, with the key fragment of encrypted content
+func printUploadURL(url string, keyFragment string) {
+	fullURL := url
+	if keyFragment != "" {
+		fullURL = fmt.Sprintf("%s#%s", url, keyFragment)
+	}
+
+	_, _ = fmt.Fprint(os.Stdout, fullURL)
+
+	cfg, _ := config.Load()
+	if cfg.AutoCopyClipboard {
+		if err := clipboard.WriteAll(fullURL); err == nil {
+			_, _ = fmt.Fprint(os.Stdout, "     \033[90m\033[2m ✓ copied\033[0m")
+		}
+	}
+
+	_, _ = fmt.Fprintln(os.Stdout)
+}
+
+// resumeChunkedUpload finds an unfinished upload of the same file with the same options that the server still knows
+func resumeChunkedUpload(apiClient *client.Client, want models.PendingUpload) (*models.PendingUpload, *models.UploadSessionResponse, error) {
+	uploads, err := config.LoadPendingUploads()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := slices.IndexFunc(uploads, func(u models.PendingUpload) bool {
+		return u.Server == want.Server && u.Kind == want.Kind && u.Source == want.Source && u.Size == want.Size &&
+			u.ModTime.Equal(want.ModTime) && u.Encrypted == want.Encrypted && u.PasswordProtected == want.PasswordProtected &&
+			u.MaxViews == want.MaxViews && u.ExpiresIn == want.ExpiresIn && u.KeepMetadata == want.KeepMetadata
+	})
+	if index < 0 {
+		return nil, nil, nil
+	}
+	pending := uploads[index]
+
+	session, err := apiClient.GetUpload(pending.UploadID)
+	if errors.Is(err, client.ErrUploadNotFound) {
+		// The server dropped the upload, start over
+		_ = config.ForgetPendingUpload(pending.UploadID)
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resume upload: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Resuming upload, %d of %d chunks already sent\n", len(session.Received), session.Chunks)
+	return &pending, session, nil
+}
+
+// startChunkedUpload encrypts the content into a spool file if needed and starts a new upload on the server
+func startChunkedUpload(apiClient *client.Client, pending models.PendingUpload) (*models.PendingUpload, *models.UploadSessionResponse, error) {
+	uploadReq := models.UploadRequest{
+		Kind:              pending.Kind,
+		Size:              pending.Size,
+		Encrypted:         pending.Encrypted,
+		PasswordProtected: pending.PasswordProtected,
+		MaxViews:          pending.MaxViews,
+		ExpiresIn:         pending.ExpiresIn,
+		KeepMetadata:      pending.KeepMetadata,
+	}
+	if pending.Kind == "file" {
+		uploadReq.FileName = filepath.Base(pending.Source)
+	}
+
+	if pending.Encrypted {
+		stripMetadata := pending.Kind == "image" && !pending.KeepMetadata
+		spool, fileName, keyFragment, err := spoolEncrypted(pending.Source, uploadReq.FileName, stripMetadata, pending.PasswordProtected)
+		if err != nil {
+			return nil, nil, err
+		}
+		info, err := os.Stat(spool)
+		if err != nil {
+			_ = os.Remove(spool)
+			return nil, nil, fmt.Errorf("failed to read encrypted %s: %w", pending.Kind, err)
+		}
+
+		pending.Spool = spool
+		pending.KeyFragment = keyFragment
+		uploadReq.Size = info.Size()
+		uploadReq.FileName = fileName
+	}
+
+	session, err := apiClient.CreateUpload(uploadReq)
+	if err != nil {
+		if pending.Spool != "" {
+			_ = os.Remove(pending.Spool)
+		}
+		return nil, nil, fmt.Errorf("failed to start upload: %w", err)
+	}
+	pending.UploadID = session.ID
+
+	if err := config.RecordPendingUpload(pending); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: upload can't be resumed: %v\n", err)
+	}
+	return &pending, session, nil
+}
+
+// spoolEncrypted encrypts a file, and its name if given, writing the ciphertext to a spool file so that a
+// resumed upload sends the exact same bytes. The key fragment is empty for password protected content.
+//
+//nolint:revive // stripMetadata and withPassword flags are acceptable for control flow
+func spoolEncrypted(source string, fileName string, stripMetadata bool, withPassword bool) (spool string, encryptedName string, keyFragment string, err error) {
+	data, err := os.ReadFile(source) //nolint:gosec // User-provided path is intentional
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if stripMetadata {
+		data = stripImageMetadata(data)
+	}
+
+	var encrypt func([]byte) (string, error)
+	if withPassword {
+		password, err := readPassword(true)
+		if err != nil {
+			return "", "", "", err
+		}
+		encrypt = func(plaintext []byte) (string, error) {
+			return crypto.EncryptWithPassword(plaintext, password)
+		}
+	} else {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to generate encryption key: %w", err)
+		}
+		encrypt = func(plaintext []byte) (string, error) {
+			return crypto.Encrypt(plaintext, key)
+		}
+		keyFragment = crypto.EncodeKey(key)
+	}
+
+	encryptedDataB64, err := encrypt(data)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to encrypt file: %w", err)
+	}
+	encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedDataB64)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to decode encrypted data: %w", err)
+	}
+
+	if fileName != "" {
+		encryptedName, err = encrypt([]byte(fileName))
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to encrypt file name: %w", err)
+		}
+	}
+
+	f, err := config.CreateSpool()
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to create spool file: %w", err)
+	}
+	_, err = f.Write(encryptedBytes)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", "", "", fmt.Errorf("failed to write spool file: %w", err)
+	}
+
+	return f.Name(), encryptedName, keyFragment, nil
+}
+
+// sendMissingChunks sends the chunks the server has not received yet, showing progress on stderr
+func sendMissingChunks(apiClient *client.Client, pending *models.PendingUpload, session *models.UploadSessionResponse) error {
+	path := pending.Source
+	if pending.Spool != "" {
+		path = pending.Spool
+	}
+
+	f, err := os.Open(path) //nolint:gosec // User-provided path is intentional
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", pending.Kind, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	sent := len(session.Received)
+	buf := make([]byte, session.ChunkSize)
+	for index := range session.Chunks {
+		if slices.Contains(session.Received, index) {
+			continue
+		}
+
+		offset := int64(index) * session.ChunkSize
+		n, err := f.ReadAt(buf[:min(session.ChunkSize, session.Size-offset)], offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read chunk %d: %w", index, err)
+		}
+
+		if _, err := apiClient.UploadChunk(session.ID, index, buf[:n]); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr)
+			return fmt.Errorf("failed to upload chunk %d: %w", index, err)
+		}
+
+		sent++
+		_, _ = fmt.Fprintf(os.Stderr, "\rUploading %d%%", sent*100/session.Chunks)
+	}
+	_, _ = fmt.Fprint(os.Stderr, "\r\033[K")
+
+	return nil
+}
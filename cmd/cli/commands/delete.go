@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/piheta/seq.re/cmd/cli/client"
+	"github.com/piheta/seq.re/cmd/cli/config"
+	"github.com/piheta/seq.re/cmd/cli/models"
+)
+
+// Delete deletes a link, paste, image, file or secret. Without a token the one
+// recorded in the local history when the resource was created is used.
+func Delete(apiClient *client.Client, target string, token string) error {
+	// The key fragment never reaches the server, history entries are stored without it
+	target = strings.Split(target, "#")[0]
+
+	resource, short, err := parseResourceURL(target)
+	if err != nil {
+		return err
+	}
+
+	if token == "" {
+		var ok bool
+		token, ok = config.LookupDeletionToken(target)
+		if !ok {
+			return fmt.Errorf("no deletion token found for %s, pass it as: seqre delete <url> <token>", target)
+		}
+	}
+
+	if err := apiClient.Delete(resource, short, token); err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+
+	if err := config.ForgetURL(target); err != nil {
+		slog.Warn("Failed to update history", slog.String("error", err.Error()))
+	}
+
+	_, _ = fmt.Fprintf(os.Stdout, "Deleted %s\n", target)
+	return nil
+}
+
+// parseResourceURL maps a share URL to its API collection and short code
+func parseResourceURL(target string) (resource, short string, err error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return "links", parts[0], nil
+	case len(parts) == 2 && parts[0] == "p":
+		return "pastes", parts[1], nil
+	case len(parts) == 2 && parts[0] == "i":
+		return "images", parts[1], nil
+	case len(parts) == 2 && parts[0] == "s":
+		return "secrets", parts[1], nil
+	case len(parts) == 2 && parts[0] == "f":
+		return "files", parts[1], nil
+	default:
+		return "", "", errors.New("not a seqre link, paste, image, file or secret URL")
+	}
+}
+
+// recordDeletionToken keeps the deletion token so the resource can later be deleted by URL
+func recordDeletionToken(created *models.CreatedResponse) {
+	if err := config.RecordCreated(created); err != nil {
+		slog.Warn("Failed to save deletion token to history", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/piheta/seq.re/cmd/cli/client"
+	"github.com/piheta/seq.re/cmd/cli/crypto"
+)
+
+// FileUpload uploads any file under its base name. Encrypted files have their name encrypted too.
+//
+//nolint:revive // encrypted and withPassword flags are acceptable for control flow
+func FileUpload(apiClient *client.Client, filePath string, encrypted bool, withPassword bool, maxViews int, expiresIn string) error {
+	if useChunkedUpload(filePath) {
+		created, keyFragment, err := uploadInChunks(apiClient, "file", filePath, encrypted, withPassword, false, maxViews, expiresIn)
+		if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+		recordDeletionToken(created)
+		printUploadURL(created.URL, keyFragment)
+		return nil
+	}
+
+	fileData, err := os.ReadFile(filePath) //nolint:gosec // User-provided path is intentional
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	fileName := filepath.Base(filePath)
+
+	var fileURL string
+	var keyFragment string
+
+	if withPassword {
+		password, err := readPassword(true)
+		if err != nil {
+			return err
+		}
+
+		// The key is derived from the password, so the URL carries no fragment
+		encryptedDataB64, err := crypto.EncryptWithPassword(fileData, password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt file: %w", err)
+		}
+
+		encryptedName, err := crypto.EncryptWithPassword([]byte(fileName), password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt file name: %w", err)
+		}
+
+		encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedDataB64)
+		if err != nil {
+			return fmt.Errorf("failed to decode encrypted data: %w", err)
+		}
+
+		created, err := apiClient.CreateFile(encryptedBytes, encryptedName, true, true, maxViews, expiresIn)
+		if err != nil {
+			return fmt.Errorf("failed to upload encrypted file: %w", err)
+		}
+		recordDeletionToken(created)
+		fileURL = created.URL
+	} else if encrypted {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate encryption key: %w", err)
+		}
+
+		encryptedDataB64, err := crypto.Encrypt(fileData, key)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt file: %w", err)
+		}
+
+		encryptedName, err := crypto.Encrypt([]byte(fileName), key)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt file name: %w", err)
+		}
+
+		encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedDataB64)
+		if err != nil {
+			return fmt.Errorf("failed to decode encrypted data: %w", err)
+		}
+
+		created, err := apiClient.CreateFile(encryptedBytes, encryptedName, true, false, maxViews, expiresIn)
+		if err != nil {
+			return fmt.Errorf("failed to upload encrypted file: %w", err)
+		}
+		recordDeletionToken(created)
+		fileURL = created.URL
+
+		keyFragment = crypto.EncodeKey(key)
+	} else {
+		created, err := apiClient.CreateFile(fileData, fileName, false, false, maxViews, expiresIn)
+		if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+		recordDeletionToken(created)
+		fileURL = created.URL
+	}
+
+	printUploadURL(fileURL, keyFragment)
+
+	return nil
+}
+
+// FileGet downloads and optionally decrypts a file. It is saved under its original name in the
+// current directory unless output is given, "-" writes it to stdout.
+//
+//nolint:revive // withPassword flag is acceptable for control flow
+func FileGet(apiClient *client.Client, urlOrShort string, keyFragment string, withPassword bool, output string) error {
+	short := extractShortFromURL(urlOrShort)
+
+	if keyFragment == "" && strings.Contains(urlOrShort, "#") {
+		keyFragment = strings.SplitN(urlOrShort, "#", 2)[1]
+	}
+
+	var fileData []byte
+	var fileName string
+
+	if withPassword {
+		password, err := readPassword(false)
+		if err != nil {
+			return err
+		}
+
+		encrypted, err := apiClient.GetFile(short)
+		if err != nil {
+			return fmt.Errorf("failed to get file: %w", err)
+		}
+
+		fileData, err = crypto.DecryptWithPassword(encrypted.Data, password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt file: %w", err)
+		}
+
+		if encrypted.FileName != "" {
+			name, err := crypto.DecryptWithPassword(encrypted.FileName, password)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt file name: %w", err)
+			}
+			fileName = string(name)
+		}
+	} else if keyFragment != "" {
+		key, err := crypto.DecodeKey(keyFragment)
+		if err != nil {
+			return fmt.Errorf("failed to decode key: %w", err)
+		}
+
+		encrypted, err := apiClient.GetFile(short)
+		if err != nil {
+			return fmt.Errorf("failed to get file: %w", err)
+		}
+
+		fileData, err = crypto.Decrypt(encrypted.Data, key)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt file: %w", err)
+		}
+
+		if encrypted.FileName != "" {
+			name, err := crypto.Decrypt(encrypted.FileName, key)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt file name: %w", err)
+			}
+			fileName = string(name)
+		}
+	} else {
+		var err error
+		fileData, fileName, err = apiClient.GetFileRaw(short)
+		if err != nil {
+			return fmt.Errorf("failed to get file: %w", err)
+		}
+	}
+
+	if output == "-" {
+		_, _ = os.Stdout.Write(fileData)
+		return nil
+	}
+
+	if output == "" {
+		output = safeFileName(fileName)
+	}
+
+	// Never overwrite, the name may come from whoever uploaded the file
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) //nolint:gosec // Path is sanitized or user-provided
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists, pass --output <path> to save it elsewhere", output)
+		}
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if _, err := f.Write(fileData); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(os.Stdout, "Saved %s (%d bytes)\n", output, len(fileData))
+	return nil
+}
+
+// safeFileName reduces a downloaded file's name to a plain name in the current directory
+func safeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	return name
+}
@@ -5,26 +5,64 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/atotto/clipboard"
 	"github.com/piheta/seq.re/cmd/cli/client"
-	"github.com/piheta/seq.re/cmd/cli/config"
 	"github.com/piheta/seq.re/cmd/cli/crypto"
+	"github.com/piheta/seq.re/internal/imagemeta"
 )
 
-// ImageUpload uploads an image, optionally encrypting it and/or making it one-time
+// ImageUpload uploads an image, optionally encrypting it and/or making it one-time. EXIF, XMP and IPTC metadata
+// is removed unless keepMetadata is set, by the server for plain images and before encrypting for encrypted ones.
 //
-//nolint:revive // encrypted and onetime flags are acceptable for control flow
-func ImageUpload(apiClient *client.Client, imagePath string, encrypted bool, onetime bool) error {
+//nolint:revive // encrypted, withPassword and keepMetadata flags are acceptable for control flow
+func ImageUpload(apiClient *client.Client, imagePath string, encrypted bool, withPassword bool, keepMetadata bool, maxViews int, expiresIn string) error {
+	if useChunkedUpload(imagePath) {
+		created, keyFragment, err := uploadInChunks(apiClient, "image", imagePath, encrypted, withPassword, keepMetadata, maxViews, expiresIn)
+		if err != nil {
+			return fmt.Errorf("failed to upload image: %w", err)
+		}
+		recordDeletionToken(created)
+		printUploadURL(created.URL, keyFragment)
+		return nil
+	}
+
 	// Read image file
 	imageData, err := os.ReadFile(imagePath) //nolint:gosec // User-provided path is intentional
 	if err != nil {
 		return fmt.Errorf("failed to read image file: %w", err)
 	}
 
+	// The server can't see into encrypted images, so their metadata is removed here
+	if (encrypted || withPassword) && !keepMetadata {
+		imageData = stripImageMetadata(imageData)
+	}
+
 	var imageURL string
 	var keyFragment string
 
-	if encrypted {
+	if withPassword {
+		password, err := readPassword(true)
+		if err != nil {
+			return err
+		}
+
+		// The key is derived from the password, so the URL carries no fragment
+		encryptedDataB64, err := crypto.EncryptWithPassword(imageData, password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt image: %w", err)
+		}
+
+		encryptedBytes, err := base64.StdEncoding.DecodeString(encryptedDataB64)
+		if err != nil {
+			return fmt.Errorf("failed to decode encrypted data: %w", err)
+		}
+
+		created, err := apiClient.CreateEncryptedImage(encryptedBytes, true, maxViews, expiresIn)
+		if err != nil {
+			return fmt.Errorf("failed to upload encrypted image: %w", err)
+		}
+		recordDeletionToken(created)
+		imageURL = created.URL
+	} else if encrypted {
 		// Generate random AES-128 key
 		key, err := crypto.GenerateKey()
 		if err != nil {
@@ -44,48 +82,54 @@ func ImageUpload(apiClient *client.Client, imagePath string, encrypted bool, one
 		}
 
 		// Send encrypted bytes to server
-		imageURL, err = apiClient.CreateEncryptedImage(encryptedBytes, onetime)
+		created, err := apiClient.CreateEncryptedImage(encryptedBytes, false, maxViews, expiresIn)
 		if err != nil {
 			return fmt.Errorf("failed to upload encrypted image: %w", err)
 		}
+		recordDeletionToken(created)
+		imageURL = created.URL
 
 		// Encode key for URL fragment
 		keyFragment = crypto.EncodeKey(key)
 	} else {
 		// Send raw image data to server
-		imageURL, err = apiClient.CreateImage(imageData, imagePath, onetime)
+		created, err := apiClient.CreateImage(imageData, imagePath, keepMetadata, maxViews, expiresIn)
 		if err != nil {
 			return fmt.Errorf("failed to upload image: %w", err)
 		}
+		recordDeletionToken(created)
+		imageURL = created.URL
 	}
 
-	// Build final URL with fragment if encrypted
-	var fullURL string
-	if encrypted {
-		fullURL = fmt.Sprintf("%s#%s", imageURL, keyFragment)
-	} else {
-		fullURL = imageURL
-	}
-
-	_, _ = fmt.Fprint(os.Stdout, fullURL)
-
-	cfg, _ := config.Load()
-	if cfg.AutoCopyClipboard {
-		if err := clipboard.WriteAll(fullURL); err == nil {
-			_, _ = fmt.Fprint(os.Stdout, "     \033[90m\033[2m ✓ copied\033[0m")
-		}
-	}
-
-	_, _ = fmt.Fprintln(os.Stdout)
+	printUploadURL(imageURL, keyFragment)
 
 	return nil
 }
 
 // ImageGet retrieves and optionally decrypts an image
-func ImageGet(apiClient *client.Client, short string, keyFragment string) error {
+//
+//nolint:revive // withPassword flag is acceptable for control flow
+func ImageGet(apiClient *client.Client, short string, keyFragment string, withPassword bool) error {
 	encrypted := keyFragment != ""
 
-	if encrypted {
+	if withPassword {
+		password, err := readPassword(false)
+		if err != nil {
+			return err
+		}
+
+		encryptedData, err := apiClient.GetImage(short)
+		if err != nil {
+			return fmt.Errorf("failed to get image: %w", err)
+		}
+
+		plaintext, err := crypto.DecryptWithPassword(encryptedData, password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt image: %w", err)
+		}
+
+		_, _ = os.Stdout.Write(plaintext)
+	} else if encrypted {
 		// Decode key from fragment
 		key, err := crypto.DecodeKey(keyFragment)
 		if err != nil {
@@ -119,3 +163,13 @@ func ImageGet(apiClient *client.Client, short string, keyFragment string) error
 
 	return nil
 }
+
+// stripImageMetadata removes EXIF, XMP and IPTC metadata, images it can't parse are kept as they are
+func stripImageMetadata(imageData []byte) []byte {
+	stripped, err := imagemeta.StripBytes(imageData)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: could not remove image metadata: %v\n", err)
+		return imageData
+	}
+	return stripped
+}
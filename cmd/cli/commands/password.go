@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// readPassword asks for a password without echoing it. With confirm the password has
+// to be typed twice. When stdin is not a terminal the first line of stdin is used.
+func readPassword(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd()) //nolint:gosec // file descriptors fit in an int
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return checkPassword(strings.TrimRight(line, "\r\n"))
+	}
+
+	password, err := promptPassword(fd, "Password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		again, err := promptPassword(fd, "Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", errors.New("passwords do not match")
+		}
+	}
+
+	return checkPassword(password)
+}
+
+func promptPassword(fd int, prompt string) (string, error) {
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
+
+func checkPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	return password, nil
+}
@@ -1,34 +1,83 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/atotto/clipboard"
 	"github.com/piheta/seq.re/cmd/cli/client"
 	"github.com/piheta/seq.re/cmd/cli/config"
 	"github.com/piheta/seq.re/cmd/cli/crypto"
+	"github.com/piheta/seq.re/cmd/cli/models"
+	"github.com/piheta/seq.re/internal/languages"
 )
 
-// PasteCreate reads a file and creates a paste
-//nolint:revive // encrypted and onetime flags are acceptable for control flow
-func PasteCreate(apiClient *client.Client, filePath string, language string, encrypted bool, onetime bool) error {
-	// Read file content
-	content, err := os.ReadFile(filePath) // #nosec G304 -- User-provided file path is intentional
+// PasteCreate reads a file and creates a paste. Several files or a directory are uploaded as one bundle, encrypted
+// bundles encrypt the JSON of their files so the file names stay private too
+//
+//nolint:revive // encrypted, withPassword and editable flags are acceptable for control flow
+func PasteCreate(apiClient *client.Client, paths []string, language string, encrypted bool, withPassword bool, maxViews int, expiresIn string, editable bool) error {
+	content, files, err := readPaste(paths)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return err
 	}
 
-	// Auto-detect language from file extension if not provided
-	if language == "" {
-		language = detectLanguage(filePath)
+	switch {
+	case files != nil && language != "":
+		return errors.New("--language only applies to single files, bundles detect the language of every file")
+	case language != "":
+		if language, err = resolveLanguage(apiClient, language); err != nil {
+			return err
+		}
+	case files == nil:
+		// The server can't detect the language of encrypted content, so detect it before encrypting
+		language = detectLanguage(paths[0], content)
+	}
+
+	create := func(data string, encrypted bool, passwordProtected bool) (*models.CreatedResponse, error) {
+		switch {
+		case files == nil && encrypted:
+			return apiClient.CreatePaste(data, language, "", true, passwordProtected, maxViews, expiresIn, editable)
+		case files == nil:
+			// Downloads of the paste keep the name of the uploaded file
+			return apiClient.CreatePaste(data, language, filepath.Base(paths[0]), false, false, maxViews, expiresIn, editable)
+		case encrypted:
+			return apiClient.CreateBundle(nil, data, passwordProtected, maxViews, expiresIn, editable)
+		default:
+			return apiClient.CreateBundle(files, "", false, maxViews, expiresIn, editable)
+		}
 	}
 
 	var pasteURL string
 
-	if encrypted {
+	if withPassword {
+		password, err := readPassword(true)
+		if err != nil {
+			return err
+		}
+
+		// The key is derived from the password, so the URL carries no fragment
+		encryptedData, err := crypto.EncryptWithPassword(content, password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt content: %w", err)
+		}
+
+		created, err := create(encryptedData, true, true)
+		if err != nil {
+			return fmt.Errorf("failed to create paste: %w", err)
+		}
+		recordDeletionToken(created)
+		pasteURL = created.URL
+	} else if encrypted {
 		// Generate random AES-128 key
 		key, err := crypto.GenerateKey()
 		if err != nil {
@@ -42,22 +91,25 @@ func PasteCreate(apiClient *client.Client, filePath string, language string, enc
 		}
 
 		// Send encrypted data to server
-		pasteURL, err = apiClient.CreatePaste(encryptedData, language, true, onetime)
+		created, err := create(encryptedData, true, false)
 		if err != nil {
 			return fmt.Errorf("failed to create paste: %w", err)
 		}
+		recordDeletionToken(created)
 
 		// Encode key for URL fragment
 		keyFragment := crypto.EncodeKey(key)
 
 		// Append key fragment to server URL
-		pasteURL = fmt.Sprintf("%s#%s", pasteURL, keyFragment)
+		pasteURL = fmt.Sprintf("%s#%s", created.URL, keyFragment)
 	} else {
 		// Send plain text to server
-		pasteURL, err = apiClient.CreatePaste(string(content), language, false, onetime)
+		created, err := create(string(content), false, false)
 		if err != nil {
 			return fmt.Errorf("failed to create paste: %w", err)
 		}
+		recordDeletionToken(created)
+		pasteURL = created.URL
 	}
 
 	_, _ = fmt.Fprint(os.Stdout, pasteURL)
@@ -75,9 +127,12 @@ func PasteCreate(apiClient *client.Client, filePath string, language string, enc
 }
 
 // PasteGet retrieves a paste by URL or short code
-func PasteGet(apiClient *client.Client, urlOrShort string, keyFragment string) error {
+//
+//nolint:revive // withPassword flag is acceptable for control flow
+func PasteGet(apiClient *client.Client, urlOrShort string, keyFragment string, withPassword bool) error {
 	// Extract short code from URL if provided
 	short := extractShortFromURL(urlOrShort)
+	revision := extractRevision(urlOrShort)
 
 	// Check if URL contains key fragment
 	if keyFragment == "" && strings.Contains(urlOrShort, "#") {
@@ -87,35 +142,50 @@ func PasteGet(apiClient *client.Client, urlOrShort string, keyFragment string) e
 		}
 	}
 
-	var content string
-	var err error
+	pasteResp, err := apiClient.GetPaste(short, revision)
+	if err != nil {
+		return fmt.Errorf("failed to get paste: %w", err)
+	}
 
-	if keyFragment != "" {
-		// Encrypted paste
-		key, err := crypto.DecodeKey(keyFragment)
+	content := pasteResp.Data
+
+	if withPassword {
+		password, err := readPassword(false)
 		if err != nil {
-			return fmt.Errorf("failed to decode key: %w", err)
+			return err
+		}
+
+		plaintext, err := crypto.DecryptWithPassword(pasteResp.Data, password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt paste: %w", err)
 		}
 
-		// Get encrypted data from server
-		encryptedData, err := apiClient.GetPaste(short)
+		content = string(plaintext)
+	} else if keyFragment != "" {
+		// Encrypted paste
+		key, err := crypto.DecodeKey(keyFragment)
 		if err != nil {
-			return fmt.Errorf("failed to get paste: %w", err)
+			return fmt.Errorf("failed to decode key: %w", err)
 		}
 
 		// Decrypt the content
-		plaintext, err := crypto.Decrypt(encryptedData, key)
+		plaintext, err := crypto.Decrypt(pasteResp.Data, key)
 		if err != nil {
 			return fmt.Errorf("failed to decrypt paste: %w", err)
 		}
 
 		content = string(plaintext)
-	} else {
-		// Plain text paste
-		content, err = apiClient.GetPasteRaw(short)
-		if err != nil {
-			return fmt.Errorf("failed to get paste: %w", err)
+	}
+
+	// Bundles are printed file by file, encrypted ones decrypt to the JSON of their files
+	if pasteResp.Bundle {
+		files := pasteResp.Files
+		if files == nil {
+			if err := json.Unmarshal([]byte(content), &files); err != nil {
+				return fmt.Errorf("failed to read bundle: %w", err)
+			}
 		}
+		content = formatBundle(files)
 	}
 
 	_, _ = fmt.Fprint(os.Stdout, content)
@@ -132,58 +202,220 @@ func PasteGet(apiClient *client.Client, urlOrShort string, keyFragment string) e
 	return nil
 }
 
-// detectLanguage attempts to detect the language from file extension
-func detectLanguage(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	languageMap := map[string]string{
-		".go":         "go",
-		".py":         "python",
-		".js":         "javascript",
-		".ts":         "typescript",
-		".jsx":        "javascript",
-		".tsx":        "typescript",
-		".rs":         "rust",
-		".java":       "java",
-		".c":          "c",
-		".cpp":        "cpp",
-		".cc":         "cpp",
-		".cxx":        "cpp",
-		".h":          "c",
-		".hpp":        "cpp",
-		".rb":         "ruby",
-		".php":        "php",
-		".swift":      "swift",
-		".kt":         "kotlin",
-		".sh":         "bash",
-		".bash":       "bash",
-		".zsh":        "bash",
-		".sql":        "sql",
-		".json":       "json",
-		".yaml":       "yaml",
-		".yml":        "yaml",
-		".md":         "markdown",
-		".html":       "html",
-		".css":        "css",
-		".xml":        "xml",
-		".dockerfile": "dockerfile",
-		".txt":        "plain",
-	}
-
-	if lang, ok := languageMap[ext]; ok {
-		return lang
-	}
-
-	return "plain"
+// PasteUpdate replaces the content of an editable paste with a file, keeping the old content as a revision.
+// Bundles are replaced with several files or a directory. Encrypted pastes are encrypted with the key of the URL
+// or the password, so the URL keeps working. Without a token the one recorded in the local history when the
+// paste was created is used.
+//
+//nolint:revive // withPassword flag is acceptable for control flow
+func PasteUpdate(apiClient *client.Client, target string, paths []string, token string, withPassword bool) error {
+	pasteURL, keyFragment, _ := strings.Cut(target, "#")
+	short := extractShortFromURL(pasteURL)
+
+	if token == "" {
+		var ok bool
+		token, ok = config.LookupEditToken(pasteURL)
+		if !ok {
+			return fmt.Errorf("no edit token found for %s, pass it as: seqre paste update <url> <file> --token <token>", pasteURL)
+		}
+	}
+
+	content, files, err := readPaste(paths)
+	if err != nil {
+		return err
+	}
+
+	data := string(content)
+	if withPassword {
+		password, err := readPassword(false)
+		if err != nil {
+			return err
+		}
+
+		if data, err = crypto.EncryptWithPassword(content, password); err != nil {
+			return fmt.Errorf("failed to encrypt content: %w", err)
+		}
+	} else if keyFragment != "" {
+		key, err := crypto.DecodeKey(keyFragment)
+		if err != nil {
+			return fmt.Errorf("failed to decode key: %w", err)
+		}
+
+		if data, err = crypto.Encrypt(content, key); err != nil {
+			return fmt.Errorf("failed to encrypt content: %w", err)
+		}
+	}
+
+	// Bundles stay bundles, unencrypted ones send their files and encrypted ones the encrypted JSON of them
+	bundle := files != nil
+	if withPassword || keyFragment != "" {
+		files = nil
+	} else if bundle {
+		data = ""
+	}
+
+	revision, err := apiClient.UpdatePaste(short, token, data, files, bundle)
+	if err != nil {
+		return fmt.Errorf("failed to update paste: %w", err)
+	}
+
+	revisionURL := revision.URL
+	if keyFragment != "" {
+		revisionURL += "#" + keyFragment
+	}
+	_, _ = fmt.Fprintln(os.Stdout, revisionURL)
+
+	return nil
+}
+
+// readPaste reads the content of a paste. A single file is read as is, several files or a directory become the
+// files of a bundle, returned along with their JSON which is what encrypted bundles encrypt
+func readPaste(paths []string) ([]byte, []models.PasteFile, error) {
+	if len(paths) == 0 {
+		return nil, nil, errors.New("no file given")
+	}
+
+	if len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && !info.IsDir() {
+			content, err := os.ReadFile(paths[0]) // #nosec G304 -- User-provided file path is intentional
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read file: %w", err)
+			}
+			return content, nil, nil
+		}
+	}
+
+	var files []models.PasteFile
+	seen := make(map[string]bool)
+	add := func(name string, path string) error {
+		if seen[name] {
+			return fmt.Errorf("%s is given twice, bundles need unique file names", name)
+		}
+		seen[name] = true
+
+		content, err := os.ReadFile(path) // #nosec G304 -- User-provided file path is intentional
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "skipping binary file %s\n", path)
+			return nil
+		}
+
+		files = append(files, models.PasteFile{Name: name, Language: detectLanguage(path, content), Content: string(content)})
+		return nil
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		if !info.IsDir() {
+			if err := add(filepath.Base(path), path); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		// Files of a directory are named by their path inside it, hidden files and directories like .git are left out
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if file != path && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			return add(filepath.ToSlash(rel), file)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, nil, errors.New("no text files found")
+	}
+
+	content, err := json.Marshal(files)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	return content, files, nil
+}
+
+// formatBundle joins the files of a bundle for printing, each under a "==> name <==" line like head does
+func formatBundle(files []models.PasteFile) string {
+	var b strings.Builder
+	for i, file := range files {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("==> " + file.Name + " <==\n")
+		b.WriteString(file.Content)
+		if !strings.HasSuffix(file.Content, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// detectLanguage detects the language from the file name, shebang or editor modeline, "" when none matches
+func detectLanguage(filePath string, content []byte) string {
+	if language := languages.FromFileName(filepath.ToSlash(filePath)); language != "" {
+		return language
+	}
+	return languages.Detect(string(content))
+}
+
+// resolveLanguage looks up a --language ID or alias in the languages of the server
+func resolveLanguage(apiClient *client.Client, language string) (string, error) {
+	available, err := apiClient.GetLanguages()
+	if err != nil {
+		// Servers without GET /api/languages validate the language themselves
+		return language, nil //nolint:nilerr // the server has the final say
+	}
+
+	for _, l := range available {
+		if strings.EqualFold(l.ID, language) || slices.ContainsFunc(l.Aliases, func(alias string) bool { return strings.EqualFold(alias, language) }) {
+			return l.ID, nil
+		}
+	}
+	return "", fmt.Errorf("unknown language %q, see seqre paste languages", language)
+}
+
+// PasteLanguages lists the languages of the server with the aliases and file extensions that map to them
+func PasteLanguages(apiClient *client.Client) error {
+	available, err := apiClient.GetLanguages()
+	if err != nil {
+		return fmt.Errorf("failed to get languages: %w", err)
+	}
+
+	for _, l := range available {
+		_, _ = fmt.Fprintf(os.Stdout, "%-12s %-12s %s\n", l.ID, l.Name, strings.Join(append(l.Aliases, l.Extensions...), " "))
+	}
+	return nil
 }
 
 // extractShortFromURL extracts the short code from a URL or returns the input if it's already a short code
 func extractShortFromURL(urlOrShort string) string {
-	// Remove fragment if present
+	// Remove fragment and query if present
 	urlOrShort = strings.Split(urlOrShort, "#")[0]
+	urlOrShort = strings.Split(urlOrShort, "?")[0]
 
-	// If it's already a 6-character short code, return it
-	if len(urlOrShort) == 6 && !strings.Contains(urlOrShort, "/") {
+	// If it's already a short code, return it
+	if !strings.Contains(urlOrShort, "/") {
 		return urlOrShort
 	}
 
@@ -195,3 +427,13 @@ func extractShortFromURL(urlOrShort string) string {
 
 	return urlOrShort
 }
+
+// extractRevision returns the revision a paste URL like http://localhost:8080/p/abc123?rev=2 points at, empty for
+// the current revision
+func extractRevision(urlOrShort string) string {
+	u, err := url.Parse(strings.Split(urlOrShort, "#")[0])
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("rev")
+}
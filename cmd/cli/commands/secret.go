@@ -3,6 +3,8 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/piheta/seq.re/cmd/cli/client"
@@ -11,7 +13,7 @@ import (
 )
 
 // SecretCreate encrypts and creates a secret, returning a URL with fragment
-func SecretCreate(apiClient *client.Client, secret string) error {
+func SecretCreate(apiClient *client.Client, secret string, maxViews int, expiresIn string) error {
 	// Generate random AES-128 key
 	key, err := crypto.GenerateKey()
 	if err != nil {
@@ -25,16 +27,17 @@ func SecretCreate(apiClient *client.Client, secret string) error {
 	}
 
 	// Send encrypted data to server - returns full URL without fragment
-	secretURL, err := apiClient.CreateSecret(encryptedData)
+	created, err := apiClient.CreateSecret(encryptedData, maxViews, expiresIn)
 	if err != nil {
 		return fmt.Errorf("failed to create secret: %w", err)
 	}
+	recordDeletionToken(created)
 
 	// Encode key for URL fragment
 	keyFragment := crypto.EncodeKey(key)
 
 	// Append key fragment to server URL
-	fullURL := fmt.Sprintf("%s#%s", secretURL, keyFragment)
+	fullURL := fmt.Sprintf("%s#%s", created.URL, keyFragment)
 	_, _ = fmt.Fprint(os.Stdout, fullURL)
 
 	cfg, _ := config.Load()
@@ -58,13 +61,13 @@ func SecretGet(apiClient *client.Client, short string, keyFragment string) error
 	}
 
 	// Get encrypted data from server
-	encryptedData, err := apiClient.GetSecret(short)
+	secret, err := apiClient.GetSecret(short)
 	if err != nil {
 		return fmt.Errorf("failed to get secret: %w", err)
 	}
 
 	// Decrypt the secret
-	plaintext, err := crypto.Decrypt(encryptedData, key)
+	plaintext, err := crypto.Decrypt(secret.Data, key)
 	if err != nil {
 		return fmt.Errorf("failed to decrypt secret: %w", err)
 	}
@@ -78,7 +81,51 @@ func SecretGet(apiClient *client.Client, short string, keyFragment string) error
 		}
 	}
 
+	if secret.ViewsLeft > 0 {
+		_, _ = fmt.Fprintf(os.Stdout, "     \033[90m\033[2m %d more view(s) left\033[0m", secret.ViewsLeft)
+	}
+
 	_, _ = fmt.Fprintln(os.Stdout)
 
 	return nil
 }
+
+// SecretStatus shows whether a secret was read. Without a token the one recorded
+// when the secret was created on this machine is used.
+func SecretStatus(apiClient *client.Client, target string, token string) error {
+	target = strings.Split(target, "#")[0]
+
+	resource, short, err := parseResourceURL(target)
+	if err != nil {
+		return err
+	}
+	if resource != "secrets" {
+		return fmt.Errorf("%s is not a secret URL", target)
+	}
+
+	if token == "" {
+		var ok bool
+		token, ok = config.LookupDeletionToken(target)
+		if !ok {
+			return fmt.Errorf("no deletion token found for %s, pass it as: seqre secret status <url> <token>", target)
+		}
+	}
+
+	status, err := apiClient.GetSecretStatus(short, token)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(os.Stdout, "Status: %s\n", status.Status)
+	if status.Status == "pending" {
+		_, _ = fmt.Fprintf(os.Stdout, "Views left: %d\n", status.ViewsLeft)
+	}
+	if status.ConsumedAt != nil {
+		_, _ = fmt.Fprintf(os.Stdout, "Consumed: %s\n", status.ConsumedAt.Format(time.RFC3339))
+	}
+	for _, read := range status.Reads {
+		_, _ = fmt.Fprintf(os.Stdout, "  read %s from network %s\n", read.At.Format(time.RFC3339), read.NetworkHash[:12])
+	}
+
+	return nil
+}
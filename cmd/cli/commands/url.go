@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,18 +12,35 @@ import (
 	"github.com/piheta/seq.re/cmd/cli/client"
 	"github.com/piheta/seq.re/cmd/cli/config"
 	"github.com/piheta/seq.re/cmd/cli/crypto"
+	"github.com/piheta/seq.re/internal/qrcode"
 )
 
 // URLShorten creates a shortened URL
 //
-//nolint:revive // encrypted and onetime flags are acceptable for control flow
-func URLShorten(apiClient *client.Client, url string, encrypted bool, onetime bool) error {
+//nolint:revive // encrypted, withPassword, preview and qr flags are acceptable for control flow
+func URLShorten(apiClient *client.Client, url string, encrypted bool, withPassword bool, maxViews int, expiresIn string, alias string, preview bool, qr bool) error {
 	normalizedURL := normalizeURL(url)
 
 	var shortURL string
-	var err error
+	if withPassword {
+		password, err := readPassword(true)
+		if err != nil {
+			return err
+		}
+
+		// The key is derived from the password, so the URL carries no fragment
+		encryptedURL, err := crypto.EncryptWithPassword([]byte(normalizedURL), password)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt URL: %w", err)
+		}
 
-	if encrypted {
+		created, err := apiClient.CreateLink(encryptedURL, true, true, maxViews, expiresIn, alias, preview)
+		if err != nil {
+			return fmt.Errorf("failed to shorten URL: %w", err)
+		}
+		recordDeletionToken(created)
+		shortURL = created.URL
+	} else if encrypted {
 		// Generate random AES-128 key
 		key, err := crypto.GenerateKey()
 		if err != nil {
@@ -35,22 +54,25 @@ func URLShorten(apiClient *client.Client, url string, encrypted bool, onetime bo
 		}
 
 		// Send encrypted URL to server
-		shortURL, err = apiClient.CreateLink(encryptedURL, true, onetime)
+		created, err := apiClient.CreateLink(encryptedURL, true, false, maxViews, expiresIn, alias, preview)
 		if err != nil {
 			return fmt.Errorf("failed to shorten URL: %w", err)
 		}
+		recordDeletionToken(created)
 
 		// Encode key for URL fragment
 		keyFragment := crypto.EncodeKey(key)
 
 		// Append key fragment to server URL
-		shortURL = fmt.Sprintf("%s#%s", shortURL, keyFragment)
+		shortURL = fmt.Sprintf("%s#%s", created.URL, keyFragment)
 	} else {
 		// Send plain URL to server
-		shortURL, err = apiClient.CreateLink(normalizedURL, false, onetime)
+		created, err := apiClient.CreateLink(normalizedURL, false, false, maxViews, expiresIn, alias, preview)
 		if err != nil {
 			return fmt.Errorf("failed to shorten URL: %w", err)
 		}
+		recordDeletionToken(created)
+		shortURL = created.URL
 	}
 
 	_, _ = fmt.Fprint(os.Stdout, shortURL)
@@ -64,6 +86,15 @@ func URLShorten(apiClient *client.Client, url string, encrypted bool, onetime bo
 
 	_, _ = fmt.Fprintln(os.Stdout)
 
+	// Encoded here rather than fetched from /qr, the server never sees the key fragment of encrypted links
+	if qr {
+		code, err := qrcode.Encode(shortURL)
+		if err != nil {
+			return fmt.Errorf("failed to generate QR code: %w", err)
+		}
+		_, _ = fmt.Fprint(os.Stdout, "\n"+code.Terminal())
+	}
+
 	return nil
 }
 
@@ -76,8 +107,20 @@ func URLExpand(apiClient *client.Client, short string, keyFragment string) error
 
 	url := linkResp.URL
 
-	// If key fragment is provided, decrypt the URL
-	if keyFragment != "" {
+	if linkResp.PasswordProtected {
+		password, err := readPassword(false)
+		if err != nil {
+			return err
+		}
+
+		plaintext, err := crypto.DecryptWithPassword(url, password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt URL: %w", err)
+		}
+
+		url = string(plaintext)
+	} else if keyFragment != "" {
+		// If key fragment is provided, decrypt the URL
 		key, err := crypto.DecodeKey(keyFragment)
 		if err != nil {
 			return fmt.Errorf("failed to decode key: %w", err)
@@ -93,11 +136,75 @@ func URLExpand(apiClient *client.Client, short string, keyFragment string) error
 	}
 
 	_, _ = fmt.Fprintf(os.Stdout, "URL: %s\n", url)
-	_, _ = fmt.Fprintf(os.Stdout, "Expires: %s\n", linkResp.ExpiresAt.Format(time.RFC3339))
+	if linkResp.ExpiresAt.IsZero() {
+		_, _ = fmt.Fprint(os.Stdout, "Expires: never\n")
+	} else {
+		_, _ = fmt.Fprintf(os.Stdout, "Expires: %s\n", linkResp.ExpiresAt.Format(time.RFC3339))
+	}
+	if linkResp.Preview {
+		_, _ = fmt.Fprint(os.Stdout, "Browsers see a preview page before being redirected\n")
+	}
 
 	return nil
 }
 
+// URLStats shows the click statistics of a shortened URL. Without a token the
+// deletion token recorded in the local history is used.
+func URLStats(apiClient *client.Client, target string, token string) error {
+	target = strings.Split(target, "#")[0]
+
+	resource, short, err := parseResourceURL(target)
+	if err != nil {
+		return err
+	}
+	if resource != "links" {
+		return fmt.Errorf("%s is not a shortened URL", target)
+	}
+
+	if token == "" {
+		var ok bool
+		token, ok = config.LookupDeletionToken(target)
+		if !ok {
+			return fmt.Errorf("no deletion token found for %s, pass it as: seqre url stats <url> <token>", target)
+		}
+	}
+
+	stats, err := apiClient.GetLinkStats(short, token)
+	if err != nil {
+		return fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(os.Stdout, "Clicks: %d\n", stats.Clicks)
+	_, _ = fmt.Fprintf(os.Stdout, "Unique visitors: %d\n", stats.UniqueVisitors)
+	if stats.LastClickAt != nil {
+		_, _ = fmt.Fprintf(os.Stdout, "Last click: %s\n", stats.LastClickAt.Format(time.RFC3339))
+	}
+	printCounts("Referrers", stats.Referrers)
+	printCounts("User agents", stats.UserAgents)
+
+	return nil
+}
+
+// printCounts prints a counter map sorted by count, highest first
+func printCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(keys, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+
+	_, _ = fmt.Fprintf(os.Stdout, "%s:\n", title)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(os.Stdout, "  %-30s %d\n", k, counts[k])
+	}
+}
+
 // normalizeURL ensures URL has a protocol scheme
 func normalizeURL(input string) string {
 	if !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://") {
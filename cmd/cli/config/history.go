@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/piheta/seq.re/cmd/cli/models"
+	"gopkg.in/yaml.v3"
+)
+
+// GetHistoryPath returns the path of the deletion token history file
+func GetHistoryPath() string {
+	configPath := GetPath()
+	if configPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), "history")
+}
+
+// LoadHistory reads the deletion token history from disk
+func LoadHistory() ([]models.HistoryEntry, error) {
+	historyPath := GetHistoryPath()
+	if historyPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(historyPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var entries []models.HistoryEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return entries, nil
+}
+
+// SaveHistory writes the deletion token history to disk, dropping expired entries
+func SaveHistory(entries []models.HistoryEntry) error {
+	historyPath := GetHistoryPath()
+	if historyPath == "" {
+		return errors.New("could not determine history path")
+	}
+
+	entries = slices.DeleteFunc(entries, func(e models.HistoryEntry) bool {
+		return e.ExpiresAt != nil && e.ExpiresAt.Before(time.Now())
+	})
+
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0o750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	if err := os.WriteFile(historyPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// RecordCreated remembers the deletion and edit tokens of a newly created resource
+func RecordCreated(created *models.CreatedResponse) error {
+	if created.DeletionToken == "" {
+		return nil
+	}
+
+	entries, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, models.HistoryEntry{
+		URL:           created.URL,
+		DeletionToken: created.DeletionToken,
+		EditToken:     created.EditToken,
+		CreatedAt:     time.Now(),
+		ExpiresAt:     created.ExpiresAt,
+	})
+	return SaveHistory(entries)
+}
+
+// LookupDeletionToken returns the stored deletion token for a URL
+func LookupDeletionToken(url string) (string, bool) {
+	entries, err := LoadHistory()
+	if err != nil {
+		return "", false
+	}
+
+	for _, e := range entries {
+		if e.URL == url {
+			return e.DeletionToken, true
+		}
+	}
+	return "", false
+}
+
+// LookupEditToken returns the stored edit token for the URL of an editable paste
+func LookupEditToken(url string) (string, bool) {
+	entries, err := LoadHistory()
+	if err != nil {
+		return "", false
+	}
+
+	for _, e := range entries {
+		if e.URL == url && e.EditToken != "" {
+			return e.EditToken, true
+		}
+	}
+	return "", false
+}
+
+// ForgetURL removes a URL from the deletion token history
+func ForgetURL(url string) error {
+	entries, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+
+	return SaveHistory(slices.DeleteFunc(entries, func(e models.HistoryEntry) bool {
+		return e.URL == url
+	}))
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/piheta/seq.re/cmd/cli/models"
+	"gopkg.in/yaml.v3"
+)
+
+// GetUploadsPath returns the path of the file that tracks unfinished chunked uploads
+func GetUploadsPath() string {
+	configPath := GetPath()
+	if configPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), "uploads")
+}
+
+// CreateSpool creates a private file next to the config that holds encrypted content until its upload is finalized
+func CreateSpool() (*os.File, error) {
+	uploadsPath := GetUploadsPath()
+	if uploadsPath == "" {
+		return nil, errors.New("could not determine pending uploads path")
+	}
+
+	dir := filepath.Dir(uploadsPath)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return os.CreateTemp(dir, "upload-*.bin")
+}
+
+// LoadPendingUploads reads the unfinished chunked uploads from disk
+func LoadPendingUploads() ([]models.PendingUpload, error) {
+	uploadsPath := GetUploadsPath()
+	if uploadsPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(uploadsPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read pending uploads: %w", err)
+	}
+
+	var uploads []models.PendingUpload
+	if err := yaml.Unmarshal(data, &uploads); err != nil {
+		return nil, fmt.Errorf("failed to parse pending uploads: %w", err)
+	}
+	return uploads, nil
+}
+
+// SavePendingUploads writes the unfinished chunked uploads to disk
+func SavePendingUploads(uploads []models.PendingUpload) error {
+	uploadsPath := GetUploadsPath()
+	if uploadsPath == "" {
+		return errors.New("could not determine pending uploads path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(uploadsPath), 0o750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(uploads)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending uploads: %w", err)
+	}
+
+	if err := os.WriteFile(uploadsPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write pending uploads: %w", err)
+	}
+	return nil
+}
+
+// RecordPendingUpload remembers a chunked upload until it is finalized
+func RecordPendingUpload(upload models.PendingUpload) error {
+	uploads, err := LoadPendingUploads()
+	if err != nil {
+		return err
+	}
+	return SavePendingUploads(append(uploads, upload))
+}
+
+// ForgetPendingUpload drops a chunked upload and its spooled content
+func ForgetPendingUpload(uploadID string) error {
+	uploads, err := LoadPendingUploads()
+	if err != nil {
+		return err
+	}
+
+	return SavePendingUploads(slices.DeleteFunc(uploads, func(u models.PendingUpload) bool {
+		if u.UploadID != uploadID {
+			return false
+		}
+		if u.Spool != "" {
+			_ = os.Remove(u.Spool)
+		}
+		return true
+	}))
+}
@@ -3,13 +3,27 @@ package crypto // nolint
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/pbkdf2"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 )
 
+// Password mode derives the key with PBKDF2-SHA256. Salt and iteration count are
+// stored in front of the ciphertext: salt (16 bytes) | iterations (uint32, big endian) | nonce | ciphertext
+const (
+	passwordSaltSize   = 16
+	passwordIterations = 600000
+	passwordHeaderSize = passwordSaltSize + 4
+
+	// Upper bound for iteration counts read from ciphertext, so a crafted share can't stall the CLI
+	maxPasswordIterations = 10_000_000
+)
+
 // GenerateKey generates a random AES-128 key (16 bytes)
 func GenerateKey() ([]byte, error) {
 	key := make([]byte, 16) // AES-128
@@ -84,3 +98,66 @@ func DecodeKey(encodedKey string) ([]byte, error) {
 	}
 	return key, nil
 }
+
+// DeriveKey derives an AES-128 key from a password with PBKDF2-SHA256
+func DeriveKey(password string, salt []byte, iterations int) ([]byte, error) {
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, 16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+// EncryptWithPassword encrypts plaintext with a key derived from password and
+// prepends the KDF salt and iteration count. The result is base64 encoded.
+func EncryptWithPassword(plaintext []byte, password string) (string, error) {
+	header := make([]byte, passwordHeaderSize)
+	if _, err := io.ReadFull(rand.Reader, header[:passwordSaltSize]); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	binary.BigEndian.PutUint32(header[passwordSaltSize:], passwordIterations)
+
+	key, err := DeriveKey(password, header[:passwordSaltSize], passwordIterations)
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, err := Encrypt(plaintext, key)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(append(header, ciphertext...)), nil
+}
+
+// DecryptWithPassword decrypts ciphertext produced by EncryptWithPassword
+func DecryptWithPassword(ciphertextB64 string, password string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertextB64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	if len(data) < passwordHeaderSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	iterations := int(binary.BigEndian.Uint32(data[passwordSaltSize:passwordHeaderSize]))
+	if iterations < 1 || iterations > maxPasswordIterations {
+		return nil, fmt.Errorf("unsupported iteration count %d", iterations)
+	}
+	key, err := DeriveKey(password, data[:passwordSaltSize], iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := Decrypt(base64.StdEncoding.EncodeToString(data[passwordHeaderSize:]), key)
+	if err != nil {
+		return nil, fmt.Errorf("wrong password or corrupted data: %w", err)
+	}
+	return plaintext, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/piheta/seq.re/cmd/cli/client"
 	"github.com/piheta/seq.re/cmd/cli/commands"
@@ -48,11 +50,22 @@ func main() {
 	switch command {
 	case "url":
 		if len(os.Args) < 3 {
-			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre url <URL> [--encrypted] [--onetime]\n")
+			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre url <URL> [--encrypted] [--password] [--onetime|--views <n>] [--expires <duration>] [--alias <name>] [--preview] [--qr]\n")
 			_, _ = fmt.Fprint(os.Stdout, "       seqre url get <short> [key]\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre url stats <url> [token]\n")
 			os.Exit(1)
 		}
-		if os.Args[2] == "get" {
+		if os.Args[2] == "stats" {
+			if len(os.Args) < 4 {
+				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre url stats <url> [token]\n")
+				os.Exit(1)
+			}
+			token := ""
+			if len(os.Args) >= 5 {
+				token = os.Args[4]
+			}
+			err = commands.URLStats(apiClient, os.Args[3], token)
+		} else if os.Args[2] == "get" {
 			if len(os.Args) < 4 {
 				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre url get <short> [key]\n")
 				os.Exit(1)
@@ -66,21 +79,47 @@ func main() {
 		} else {
 			url := os.Args[2]
 			encrypted := false
-			onetime := false
+			withPassword := false
+			maxViews := 0
+			expiresIn := ""
+			alias := ""
+			preview := false
+			qr := false
 
 			// Parse flags
 			for i := 3; i < len(os.Args); i++ {
 				switch os.Args[i] {
 				case "--encrypted":
 					encrypted = true
+				case "--password":
+					withPassword = true
 				case "--onetime":
-					onetime = true
+					maxViews = 1
+				case "--views":
+					if i+1 < len(os.Args) {
+						maxViews = parseViews(os.Args[i+1])
+						i++
+					}
+				case "--expires":
+					if i+1 < len(os.Args) {
+						expiresIn = os.Args[i+1]
+						i++
+					}
+				case "--alias":
+					if i+1 < len(os.Args) {
+						alias = os.Args[i+1]
+						i++
+					}
+				case "--preview":
+					preview = true
+				case "--qr":
+					qr = true
 				default:
 					// Ignore unknown flags
 				}
 			}
 
-			err = commands.URLShorten(apiClient, url, encrypted, onetime)
+			err = commands.URLShorten(apiClient, url, encrypted, withPassword, maxViews, expiresIn, alias, preview, qr)
 		}
 
 	case "ip":
@@ -88,11 +127,22 @@ func main() {
 
 	case "secret":
 		if len(os.Args) < 3 {
-			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre secret <text>\n")
+			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre secret <text> [--views <n>] [--expires <duration>]\n")
 			_, _ = fmt.Fprint(os.Stdout, "       seqre secret get <short> <key>\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre secret status <url> [token]\n")
 			os.Exit(1)
 		}
-		if os.Args[2] == "get" {
+		if os.Args[2] == "status" {
+			if len(os.Args) < 4 {
+				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre secret status <url> [token]\n")
+				os.Exit(1)
+			}
+			token := ""
+			if len(os.Args) >= 5 {
+				token = os.Args[4]
+			}
+			err = commands.SecretStatus(apiClient, os.Args[3], token)
+		} else if os.Args[2] == "get" {
 			if len(os.Args) < 5 {
 				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre secret get <short> <key>\n")
 				os.Exit(1)
@@ -101,80 +151,193 @@ func main() {
 		} else {
 			// Join all args from index 2 onwards to support multi-word secrets
 			secretText := ""
+			expiresIn := ""
+			maxViews := 0
 			for i := 2; i < len(os.Args); i++ {
-				if i > 2 {
+				if os.Args[i] == "--expires" && i+1 < len(os.Args) {
+					expiresIn = os.Args[i+1]
+					i++
+					continue
+				}
+				if os.Args[i] == "--views" && i+1 < len(os.Args) {
+					maxViews = parseViews(os.Args[i+1])
+					i++
+					continue
+				}
+				if secretText != "" {
 					secretText += " "
 				}
 				secretText += os.Args[i]
 			}
-			err = commands.SecretCreate(apiClient, secretText)
+			err = commands.SecretCreate(apiClient, secretText, maxViews, expiresIn)
 		}
 
 	case "img":
 		if len(os.Args) < 3 {
-			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre img <file> [--encrypted] [--onetime]\n")
-			_, _ = fmt.Fprint(os.Stdout, "       seqre img get <short> [key]\n")
+			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre img <file> [--encrypted] [--password] [--onetime|--views <n>] [--expires <duration>] [--keep-metadata]\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre img get <short> [key] [--password]\n")
 			os.Exit(1)
 		}
 		if os.Args[2] == "get" {
 			if len(os.Args) < 4 {
-				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre img get <short> [key]\n")
+				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre img get <short> [key] [--password]\n")
 				os.Exit(1)
 			}
 			// Optional key for encrypted images
-			keyFragment := ""
-			if len(os.Args) >= 5 {
-				keyFragment = os.Args[4]
-			}
-			err = commands.ImageGet(apiClient, os.Args[3], keyFragment)
+			keyFragment, withPassword := parseGetArgs(os.Args[4:])
+			err = commands.ImageGet(apiClient, os.Args[3], keyFragment, withPassword)
 		} else {
 			// Upload image
 			imagePath := os.Args[2]
 			encrypted := false
-			onetime := false
+			withPassword := false
+			keepMetadata := false
+			maxViews := 0
+			expiresIn := ""
 
 			// Parse flags
 			for i := 3; i < len(os.Args); i++ {
 				switch os.Args[i] {
 				case "--encrypted":
 					encrypted = true
+				case "--password":
+					withPassword = true
+				case "--keep-metadata":
+					keepMetadata = true
 				case "--onetime":
-					onetime = true
+					maxViews = 1
+				case "--views":
+					if i+1 < len(os.Args) {
+						maxViews = parseViews(os.Args[i+1])
+						i++
+					}
+				case "--expires":
+					if i+1 < len(os.Args) {
+						expiresIn = os.Args[i+1]
+						i++
+					}
 				default:
 					// Ignore unknown flags
 				}
 			}
 
-			err = commands.ImageUpload(apiClient, imagePath, encrypted, onetime)
+			err = commands.ImageUpload(apiClient, imagePath, encrypted, withPassword, keepMetadata, maxViews, expiresIn)
 		}
 
-	case "paste":
+	case "file":
 		if len(os.Args) < 3 {
-			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre paste <file> [--language <lang>] [--encrypted] [--onetime]\n")
-			_, _ = fmt.Fprint(os.Stdout, "       seqre paste get <url|short> [key]\n")
+			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre file <file> [--encrypted] [--password] [--onetime|--views <n>] [--expires <duration>]\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre file get <url|short> [key] [--password] [--output <path>]\n")
 			os.Exit(1)
 		}
 		if os.Args[2] == "get" {
 			if len(os.Args) < 4 {
-				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre paste get <url|short> [key]\n")
+				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre file get <url|short> [key] [--password] [--output <path>]\n")
 				os.Exit(1)
 			}
-			// Optional key for encrypted pastes
-			keyFragment := ""
-			if len(os.Args) >= 5 {
-				keyFragment = os.Args[4]
+			// --output takes a value, the remaining arguments are the optional key and --password
+			output := ""
+			var rest []string
+			for i := 4; i < len(os.Args); i++ {
+				if os.Args[i] == "--output" && i+1 < len(os.Args) {
+					output = os.Args[i+1]
+					i++
+					continue
+				}
+				rest = append(rest, os.Args[i])
 			}
-			err = commands.PasteGet(apiClient, os.Args[3], keyFragment)
+			keyFragment, withPassword := parseGetArgs(rest)
+			err = commands.FileGet(apiClient, os.Args[3], keyFragment, withPassword, output)
 		} else {
-			// Upload paste
+			// Upload file
 			filePath := os.Args[2]
-			language := ""
 			encrypted := false
-			onetime := false
+			withPassword := false
+			maxViews := 0
+			expiresIn := ""
 
 			// Parse flags
 			for i := 3; i < len(os.Args); i++ {
 				switch os.Args[i] {
+				case "--encrypted":
+					encrypted = true
+				case "--password":
+					withPassword = true
+				case "--onetime":
+					maxViews = 1
+				case "--views":
+					if i+1 < len(os.Args) {
+						maxViews = parseViews(os.Args[i+1])
+						i++
+					}
+				case "--expires":
+					if i+1 < len(os.Args) {
+						expiresIn = os.Args[i+1]
+						i++
+					}
+				default:
+					// Ignore unknown flags
+				}
+			}
+
+			err = commands.FileUpload(apiClient, filePath, encrypted, withPassword, maxViews, expiresIn)
+		}
+
+	case "paste":
+		if len(os.Args) < 3 {
+			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre paste <file|dir>... [--language <lang>] [--encrypted] [--password] [--onetime|--views <n>] [--expires <duration>] [--editable]\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre paste get <url|short> [key] [--password]\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre paste update <url> <file|dir>... [--token <token>] [--password]\n")
+			_, _ = fmt.Fprint(os.Stdout, "       seqre paste languages\n")
+			os.Exit(1)
+		}
+		if os.Args[2] == "languages" {
+			err = commands.PasteLanguages(apiClient)
+		} else if os.Args[2] == "update" {
+			if len(os.Args) < 5 {
+				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre paste update <url> <file|dir>... [--token <token>] [--password]\n")
+				os.Exit(1)
+			}
+			var paths []string
+			token := ""
+			withPassword := false
+			for i := 4; i < len(os.Args); i++ {
+				switch os.Args[i] {
+				case "--token":
+					if i+1 < len(os.Args) {
+						token = os.Args[i+1]
+						i++
+					}
+				case "--password":
+					withPassword = true
+				default:
+					paths = append(paths, os.Args[i])
+				}
+			}
+			err = commands.PasteUpdate(apiClient, os.Args[3], paths, token, withPassword)
+		} else if os.Args[2] == "get" {
+			if len(os.Args) < 4 {
+				_, _ = fmt.Fprint(os.Stdout, "Usage: seqre paste get <url|short> [key] [--password]\n")
+				os.Exit(1)
+			}
+			// Optional key for encrypted pastes
+			keyFragment, withPassword := parseGetArgs(os.Args[4:])
+			err = commands.PasteGet(apiClient, os.Args[3], keyFragment, withPassword)
+		} else {
+			// Upload paste, several files or a directory become a bundle
+			var paths []string
+			language := ""
+			encrypted := false
+			withPassword := false
+			maxViews := 0
+			expiresIn := ""
+			editable := false
+
+			// Parse flags
+			for i := 2; i < len(os.Args); i++ {
+				switch os.Args[i] {
+				case "--editable":
+					editable = true
 				case "--language":
 					if i+1 < len(os.Args) {
 						language = os.Args[i+1]
@@ -182,16 +345,42 @@ func main() {
 					}
 				case "--encrypted":
 					encrypted = true
+				case "--password":
+					withPassword = true
 				case "--onetime":
-					onetime = true
+					maxViews = 1
+				case "--views":
+					if i+1 < len(os.Args) {
+						maxViews = parseViews(os.Args[i+1])
+						i++
+					}
+				case "--expires":
+					if i+1 < len(os.Args) {
+						expiresIn = os.Args[i+1]
+						i++
+					}
 				default:
-					// Ignore unknown flags
+					// Ignore unknown flags, everything else is a file
+					if !strings.HasPrefix(os.Args[i], "--") {
+						paths = append(paths, os.Args[i])
+					}
 				}
 			}
 
-			err = commands.PasteCreate(apiClient, filePath, language, encrypted, onetime)
+			err = commands.PasteCreate(apiClient, paths, language, encrypted, withPassword, maxViews, expiresIn, editable)
 		}
 
+	case "delete":
+		if len(os.Args) < 3 {
+			_, _ = fmt.Fprint(os.Stdout, "Usage: seqre delete <url> [token]\n")
+			os.Exit(1)
+		}
+		token := ""
+		if len(os.Args) >= 4 {
+			token = os.Args[3]
+		}
+		err = commands.Delete(apiClient, os.Args[2], token)
+
 	default:
 		slog.Error("Unknown command", slog.String("command", command))
 		os.Exit(1)
@@ -203,6 +392,28 @@ func main() {
 	}
 }
 
+// parseGetArgs splits the arguments after "get <short>" into an optional key and the --password flag
+func parseGetArgs(args []string) (keyFragment string, withPassword bool) {
+	for _, arg := range args {
+		if arg == "--password" {
+			withPassword = true
+		} else if keyFragment == "" {
+			keyFragment = arg
+		}
+	}
+	return keyFragment, withPassword
+}
+
+// parseViews reads the value of --views, exiting unless it is a positive number
+func parseViews(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		slog.Error("Invalid --views, expected a positive number", slog.String("value", value))
+		os.Exit(1)
+	}
+	return n
+}
+
 func handleConfigCommand() error {
 	if len(os.Args) < 3 {
 		return errors.New("usage: seqre config <set|get|clipboard> [args]")
@@ -240,17 +451,33 @@ func handleConfigCommand() error {
 func printUsage() {
 	_, _ = fmt.Fprint(os.Stdout, "Usage: seqre <command> [args]\n")
 	_, _ = fmt.Fprint(os.Stdout, "Commands:\n")
-	_, _ = fmt.Fprint(os.Stdout, "  ip                                                              Get your IP address\n")
-	_, _ = fmt.Fprint(os.Stdout, "  url <URL> [--encrypted] [--onetime]                             Create a shortened URL\n")
-	_, _ = fmt.Fprint(os.Stdout, "  url get <short> [key]                                           Expand a shortened URL\n")
-	_, _ = fmt.Fprint(os.Stdout, "  secret <text>                                                   Create an encrypted secret\n")
-	_, _ = fmt.Fprint(os.Stdout, "  secret get <short> <key>                                        Retrieve and decrypt a secret\n")
-	_, _ = fmt.Fprint(os.Stdout, "  img <file> [--encrypted] [--onetime]                            Upload an image\n")
-	_, _ = fmt.Fprint(os.Stdout, "  img get <short> [key]                                           Download an image\n")
-	_, _ = fmt.Fprint(os.Stdout, "  paste <file> [--language <lang>] [--encrypted] [--onetime]      Upload a paste\n")
-	_, _ = fmt.Fprint(os.Stdout, "  paste get <url|short> [key]                                     Retrieve a paste\n")
-	_, _ = fmt.Fprint(os.Stdout, "  config set <server>                                             Set the server URL\n")
-	_, _ = fmt.Fprint(os.Stdout, "  config get                                                      Get the server URL\n")
-	_, _ = fmt.Fprint(os.Stdout, "  config clipboard <on|off>                                       Enable/disable auto-copy to clipboard\n")
-	_, _ = fmt.Fprint(os.Stdout, "  version                                                         Show version information\n")
+	_, _ = fmt.Fprint(os.Stdout, "  ip                                                                                                   Get your IP address\n")
+	_, _ = fmt.Fprint(os.Stdout, "  url <URL> [--encrypted|--password] [--onetime|--views <n>] [--expires <d>] [--alias <name>] [--qr]   Create a shortened URL\n")
+	_, _ = fmt.Fprint(os.Stdout, "  url get <short> [key]                                                                                Expand a shortened URL\n")
+	_, _ = fmt.Fprint(os.Stdout, "  url stats <url> [token]                                                                              Show click statistics of a shortened URL\n")
+	_, _ = fmt.Fprint(os.Stdout, "  secret <text> [--views <n>] [--expires <d>]                                                          Create an encrypted secret\n")
+	_, _ = fmt.Fprint(os.Stdout, "  secret get <short> <key>                                                                             Retrieve and decrypt a secret\n")
+	_, _ = fmt.Fprint(os.Stdout, "  secret status <url> [token]                                                                          Show whether a secret was read\n")
+	_, _ = fmt.Fprint(os.Stdout, "  img <file> [--encrypted|--password] [--onetime|--views <n>] [--expires <d>] [--keep-metadata]        Upload an image\n")
+	_, _ = fmt.Fprint(os.Stdout, "  img get <short> [key] [--password]                                                                   Download an image\n")
+	_, _ = fmt.Fprint(os.Stdout, "  file <file> [--encrypted|--password] [--onetime|--views <n>] [--expires <d>]                         Upload a file\n")
+	_, _ = fmt.Fprint(os.Stdout, "  file get <url|short> [key] [--password] [--output <path>]                                            Download a file\n")
+	_, _ = fmt.Fprint(os.Stdout, "  paste <file>... [--language <lang>] [--encrypted|--password] [--onetime|--views <n>] [--expires <d>] Upload a paste\n")
+	_, _ = fmt.Fprint(os.Stdout, "  paste get <url|short> [key] [--password]                                                             Retrieve a paste\n")
+	_, _ = fmt.Fprint(os.Stdout, "  paste update <url> <file>... [--token <token>] [--password]                                          Add a revision to a paste created with --editable\n")
+	_, _ = fmt.Fprint(os.Stdout, "  paste languages                                                                                      List the languages of pastes\n")
+	_, _ = fmt.Fprint(os.Stdout, "  delete <url> [token]                                                                                 Delete a link, paste, image, file or secret\n")
+	_, _ = fmt.Fprint(os.Stdout, "  config set <server>                                                                                  Set the server URL\n")
+	_, _ = fmt.Fprint(os.Stdout, "  config get                                                                                           Get the server URL\n")
+	_, _ = fmt.Fprint(os.Stdout, "  config clipboard <on|off>                                                                            Enable/disable auto-copy to clipboard\n")
+	_, _ = fmt.Fprint(os.Stdout, "  version                                                                                              Show version information\n")
+	_, _ = fmt.Fprint(os.Stdout, "\nDurations for --expires: 1h, 1d, 7d, 30d or never (subject to server policy)\n")
+	_, _ = fmt.Fprint(os.Stdout, "--password derives the key from a prompted password instead of putting it in the URL\n")
+	_, _ = fmt.Fprint(os.Stdout, "--views deletes the content after n views, --onetime is short for --views 1 (secrets default to 1)\n")
+	_, _ = fmt.Fprint(os.Stdout, "paste <file> --editable keeps an edit token for paste update, earlier revisions stay readable with ?rev=N\n")
+	_, _ = fmt.Fprint(os.Stdout, "paste with several files or a directory uploads them as one bundle, encryption covers the file names too\n")
+	_, _ = fmt.Fprint(os.Stdout, "paste detects the language from the file name, shebang or editor modeline unless --language is given\n")
+	_, _ = fmt.Fprint(os.Stdout, "url --qr prints a QR code of the link, encrypted links include their key\n")
+	_, _ = fmt.Fprint(os.Stdout, "url --preview shows browsers the destination before redirecting, any link can be previewed as <url>+\n")
+	_, _ = fmt.Fprint(os.Stdout, "Image uploads lose their EXIF, XMP and IPTC metadata (GPS position, camera, timestamps) unless --keep-metadata is given\n")
 }
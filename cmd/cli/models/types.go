@@ -10,15 +10,47 @@ type IPResponse struct {
 
 // LinkRequest represents a request to create a shortened URL
 type LinkRequest struct {
-	URL       string `json:"url"`
-	Encrypted bool   `json:"encrypted"`
-	OneTime   bool   `json:"onetime"`
+	URL               string `json:"url"`
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"password_protected"`
+	MaxViews          int    `json:"max_views,omitempty"`
+	ExpiresIn         string `json:"expires_in,omitempty"`
+	Alias             string `json:"alias,omitempty"`
+	Preview           bool   `json:"preview,omitempty"`
 }
 
 // LinkResponse represents link information
 type LinkResponse struct {
-	URL       string    `json:"url"`
-	ExpiresAt time.Time `json:"expires_at"`
+	URL               string    `json:"url"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	PasswordProtected bool      `json:"password_protected"`
+	Preview           bool      `json:"preview"`
+}
+
+// CreatedResponse represents the response from creating a link, secret, image or paste
+type CreatedResponse struct {
+	URL           string     `json:"url"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	DeletionToken string     `json:"deletion_token"`
+	EditToken     string     `json:"edit_token,omitempty"`
+}
+
+// LinkStatsResponse represents the click statistics of a link
+type LinkStatsResponse struct {
+	Clicks         int            `json:"clicks"`
+	UniqueVisitors int            `json:"unique_visitors"`
+	Referrers      map[string]int `json:"referrers"`
+	UserAgents     map[string]int `json:"user_agents"`
+	LastClickAt    *time.Time     `json:"last_click_at,omitempty"`
+}
+
+// HistoryEntry represents a created resource whose deletion and edit tokens are kept locally
+type HistoryEntry struct {
+	URL           string     `yaml:"url"`
+	DeletionToken string     `yaml:"deletion_token"`
+	EditToken     string     `yaml:"edit_token,omitempty"`
+	CreatedAt     time.Time  `yaml:"created_at"`
+	ExpiresAt     *time.Time `yaml:"expires_at,omitempty"`
 }
 
 // VersionResponse represents version information
@@ -30,7 +62,29 @@ type VersionResponse struct {
 
 // SecretRequest represents a request to create a secret
 type SecretRequest struct {
-	Data string `json:"data"`
+	Data      string `json:"data"`
+	MaxViews  int    `json:"max_views,omitempty"`
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// SecretDataResponse represents a revealed secret
+type SecretDataResponse struct {
+	Data      string `json:"data"`
+	ViewsLeft int    `json:"views_left"`
+}
+
+// SecretStatusResponse represents whether a secret was read, and by which networks
+type SecretStatusResponse struct {
+	Status     string               `json:"status"`
+	ViewsLeft  int                  `json:"views_left"`
+	ConsumedAt *time.Time           `json:"consumed_at,omitempty"`
+	Reads      []SecretReadResponse `json:"reads"`
+}
+
+// SecretReadResponse represents a single read of a secret
+type SecretReadResponse struct {
+	At          time.Time `json:"at"`
+	NetworkHash string    `json:"network_hash"`
 }
 
 // SecretResponse represents the response from creating a secret
@@ -38,6 +92,13 @@ type SecretResponse struct {
 	Short string `json:"short"`
 }
 
+// FileResponse represents an encrypted file, its name is encrypted with the same key
+type FileResponse struct {
+	Data              string `json:"data"`
+	FileName          string `json:"filename"`
+	PasswordProtected bool   `json:"password_protected"`
+}
+
 // Config represents the CLI configuration
 type Config struct {
 	Server            string `yaml:"server"`
@@ -46,13 +107,94 @@ type Config struct {
 
 // PasteRequest represents a request to create a paste
 type PasteRequest struct {
-	Content   string `json:"content"`
-	Language  string `json:"language,omitempty"`
-	Encrypted bool   `json:"encrypted"`
-	OneTime   bool   `json:"onetime"`
+	Content           string      `json:"content"`
+	Language          string      `json:"language,omitempty"`
+	FileName          string      `json:"filename,omitempty"`
+	Encrypted         bool        `json:"encrypted"`
+	PasswordProtected bool        `json:"password_protected"`
+	MaxViews          int         `json:"max_views,omitempty"`
+	ExpiresIn         string      `json:"expires_in,omitempty"`
+	Editable          bool        `json:"editable,omitempty"`
+	Files             []PasteFile `json:"files,omitempty"`
+	Bundle            bool        `json:"bundle,omitempty"`
+}
+
+// Language represents an entry of the language registry of the server
+type Language struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Aliases    []string `json:"aliases"`
+	Extensions []string `json:"extensions"`
+	FileNames  []string `json:"filenames"`
+}
+
+// PasteFile represents a named file of a paste bundle
+type PasteFile struct {
+	Name     string `json:"name"`
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+}
+
+// UpdatePasteRequest represents a request to add a revision to a paste
+type UpdatePasteRequest struct {
+	Content string      `json:"content,omitempty"`
+	Files   []PasteFile `json:"files,omitempty"`
+	Bundle  bool        `json:"bundle,omitempty"`
+}
+
+// RevisionResponse represents the response from updating a paste
+type RevisionResponse struct {
+	URL      string `json:"url"`
+	Revision int    `json:"revision"`
 }
 
 // PasteResponse represents the response from getting a paste
 type PasteResponse struct {
-	Data string `json:"data"`
+	Data   string      `json:"data"`
+	Files  []PasteFile `json:"files"`
+	Bundle bool        `json:"bundle"`
+}
+
+// RevealResponse represents the reveal token handed out before a view is used up
+type RevealResponse struct {
+	RevealToken string `json:"reveal_token"`
+}
+
+// UploadRequest represents a request to start a chunked upload of an image or file
+type UploadRequest struct {
+	Kind              string `json:"kind"`
+	Size              int64  `json:"size"`
+	FileName          string `json:"filename,omitempty"`
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"password_protected"`
+	MaxViews          int    `json:"max_views,omitempty"`
+	ExpiresIn         string `json:"expires_in,omitempty"`
+	KeepMetadata      bool   `json:"keep_metadata,omitempty"`
+}
+
+// UploadSessionResponse represents the progress of a chunked upload
+type UploadSessionResponse struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	ChunkSize int64     `json:"chunk_size"`
+	Chunks    int       `json:"chunks"`
+	Received  []int     `json:"received"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PendingUpload represents a chunked upload that has not been finalized yet and can be resumed
+type PendingUpload struct {
+	Server            string    `yaml:"server"`
+	Kind              string    `yaml:"kind"`
+	Source            string    `yaml:"source"` // Absolute path of the uploaded file
+	Size              int64     `yaml:"size"`
+	ModTime           time.Time `yaml:"mod_time"`
+	Encrypted         bool      `yaml:"encrypted"`
+	PasswordProtected bool      `yaml:"password_protected"`
+	MaxViews          int       `yaml:"max_views,omitempty"`
+	ExpiresIn         string    `yaml:"expires_in,omitempty"`
+	KeepMetadata      bool      `yaml:"keep_metadata,omitempty"`
+	UploadID          string    `yaml:"upload_id"`
+	Spool             string    `yaml:"spool,omitempty"`        // Encrypted content being sent, the source is sent as is when empty
+	KeyFragment       string    `yaml:"key_fragment,omitempty"` // Key of the encrypted content, needed for the URL once finalized
 }
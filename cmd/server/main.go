@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,12 +12,19 @@ import (
 
 	mw "github.com/piheta/apicore/middleware"
 	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/domains"
+	"github.com/piheta/seq.re/internal/features/admin"
+	"github.com/piheta/seq.re/internal/features/file"
 	"github.com/piheta/seq.re/internal/features/img"
 	"github.com/piheta/seq.re/internal/features/ip"
 	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/oembed"
 	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/plain"
+	"github.com/piheta/seq.re/internal/features/qr"
 	"github.com/piheta/seq.re/internal/features/secret"
 	"github.com/piheta/seq.re/internal/features/seqre"
+	"github.com/piheta/seq.re/internal/features/upload"
 	"github.com/piheta/seq.re/internal/features/web"
 	"github.com/piheta/seq.re/internal/metrics"
 	localmw "github.com/piheta/seq.re/internal/middleware"
@@ -36,9 +44,14 @@ var (
 func init() {
 	shared.InitValidator()
 	config.InitEnv()
+	shared.InitRevealKey()
 	if err := config.ConnectDB(config.GetDataPath() + "/badger"); err != nil {
 		log.Fatal(err)
 	}
+	// Records of older versions are moved before cleanup workers look for files without a record
+	if err := shared.MigrateKeys(config.DB); err != nil {
+		log.Fatal(err)
+	}
 
 	go func() {
 		c := make(chan os.Signal, 1)
@@ -60,34 +73,71 @@ func main() {
 	secretRepo := secret.NewSecretRepo(config.DB)
 	imageRepo := img.NewImageRepo(config.DB)
 	pasteRepo := paste.NewPasteRepo(config.DB)
+	fileRepo := file.NewFileRepo(config.DB)
+	uploadRepo := upload.NewUploadRepo(config.DB)
 
 	templateService := shared.NewTemplateService(version, config.Config.ContactEmail)
 
+	var resolver *net.Resolver
+	if config.Config.ResolveHosts {
+		resolver = net.DefaultResolver
+	}
+
 	ipService := ip.NewIPService()
-	linkService := link.NewLinkService(linkRepo)
 	secretService := secret.NewSecretService(secretRepo)
 	imageService := img.NewImageService(imageRepo, config.GetDataPath()+"/imgs")
 	pasteService := paste.NewPasteService(pasteRepo)
+	fileService := file.NewFileService(fileRepo, config.GetDataPath()+"/files")
+	uploadService := upload.NewUploadService(uploadRepo)
 
 	imageService.StartCleanupWorker(1 * time.Hour)
+	fileService.StartCleanupWorker(1 * time.Hour)
+
+	domainPolicy, err := domains.Load(config.GetDataPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+	domainPolicy.StartWatcher(10 * time.Second)
+	linkService := link.NewLinkService(linkRepo, resolver, domainPolicy)
 
 	// Register Prometheus collectors
 	linkCollector := metrics.NewLinkCollector(linkRepo)
 	imageCollector := metrics.NewImageCollector(imageRepo)
 	pasteCollector := metrics.NewPasteCollector(pasteRepo)
 	secretCollector := metrics.NewSecretCollector(secretRepo)
+	fileCollector := metrics.NewFileCollector(fileRepo)
 	prometheus.MustRegister(linkCollector)
 	prometheus.MustRegister(imageCollector)
 	prometheus.MustRegister(pasteCollector)
 	prometheus.MustRegister(secretCollector)
+	prometheus.MustRegister(fileCollector)
 
 	ipHandler := ip.NewIPHandler(ipService)
 	linkHandler := link.NewLinkHandler(linkService, templateService)
 	secretHandler := secret.NewSecretHandler(secretService, templateService)
 	imageHandler := img.NewImageHandler(imageService, templateService)
 	pasteHandler := paste.NewPasteHandler(pasteService, templateService)
+	fileHandler := file.NewFileHandler(fileService, templateService)
+	uploadHandler := upload.NewUploadHandler(uploadService, map[string]upload.Target{
+		upload.KindImage: {Dir: imageService.UploadDir(), Store: imageHandler.StoreUpload},
+		upload.KindFile:  {Dir: fileService.UploadDir(), Store: fileHandler.StoreUpload},
+	})
+	cards := map[string]shared.CardFunc{
+		"":  linkService.Card,
+		"i": imageService.Card,
+		"p": pasteService.Card,
+		"f": fileService.Card,
+		"s": secretService.Card,
+	}
+	oembedHandler := oembed.NewOEmbedHandler(cards)
+	qrHandler := qr.NewQRHandler(cards)
+	plainHandler := plain.NewPlainHandler(fileService.UploadDir(),
+		plain.Target{MaxSize: paste.MaxContentSize, Store: pasteHandler.StoreUpload},
+		plain.Target{MaxSize: config.Config.MaxUploadSize, Store: fileHandler.StoreUpload},
+	)
 	seqreHandler := seqre.NewSeqreHandler(version, commit, date)
 	webHandler := web.NewWebHandler(templateService, version)
+	adminHandler := admin.NewAdminHandler(domainPolicy, config.Config.AdminToken)
 
 	// Static files
 	fs := http.FileServer(http.Dir("web/static"))
@@ -97,6 +147,7 @@ func main() {
 	mux.Handle("GET /", mw.Public(webHandler.ServeIndex))
 	mux.Handle("GET /tab/url", mw.Public(webHandler.ServeURLTab))
 	mux.Handle("GET /tab/image", mw.Public(webHandler.ServeImageTab))
+	mux.Handle("GET /tab/file", mw.Public(webHandler.ServeFileTab))
 	mux.Handle("GET /tab/secret", mw.Public(webHandler.ServeSecretTab))
 	mux.Handle("GET /tab/code", mw.Public(webHandler.ServeCodeTab))
 	mux.Handle("GET /tab/ip", mw.Public(webHandler.ServeIPTab))
@@ -105,22 +156,73 @@ func main() {
 	// API routes
 	mux.Handle("GET /api/ip", mw.Public(ipHandler.GetPublicIP))
 	mux.Handle("GET /api/version", mw.Public(seqreHandler.GetVersion))
+	mux.Handle("GET /api/languages", mw.Public(pasteHandler.ListLanguages))
 
 	mux.Handle("POST /api/links", localmw.RateLimit(2, 5, mw.Public(linkHandler.CreateLink)))
 	mux.Handle("GET /api/links/{short}", localmw.RateLimit(2, 5, mw.Public(linkHandler.GetLinkByShort)))
 	mux.Handle("POST /api/links/{short}/onetime", localmw.RateLimit(2, 5, mw.Public(linkHandler.RevealOneTimeLink)))
+	mux.Handle("GET /api/links/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(linkHandler.StartLinkReveal)))
+	mux.Handle("POST /api/links/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(linkHandler.RevealLink)))
+	mux.Handle("DELETE /api/links/{short}", localmw.RateLimit(2, 5, mw.Public(linkHandler.DeleteLink)))
+	mux.Handle("GET /api/links/{short}/stats", localmw.RateLimit(2, 5, mw.Public(linkHandler.GetLinkStats)))
 
 	mux.Handle("POST /api/secrets", localmw.RateLimit(2, 5, mw.Public(secretHandler.CreateSecret)))
 	mux.Handle("GET /s/{short}", localmw.RateLimit(2, 5, mw.Public(secretHandler.GetSecretByShort)))
 	mux.Handle("POST /api/secrets/{short}/onetime", localmw.RateLimit(2, 5, mw.Public(secretHandler.RevealOneTimeSecret)))
-
-	mux.Handle("POST /api/images", localmw.RateLimit(2, 5, mw.Public(imageHandler.CreateImage)))
-	mux.Handle("GET /i/{short}", localmw.RateLimit(2, 5, mw.Public(imageHandler.GetImageByShort)))
-	mux.Handle("POST /api/images/{short}/onetime", localmw.RateLimit(2, 5, mw.Public(imageHandler.RevealOneTimeImage)))
+	mux.Handle("GET /api/secrets/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(secretHandler.StartSecretReveal)))
+	mux.Handle("POST /api/secrets/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(secretHandler.RevealSecret)))
+	mux.Handle("DELETE /api/secrets/{short}", localmw.RateLimit(2, 5, mw.Public(secretHandler.DeleteSecret)))
+	mux.Handle("GET /api/secrets/{short}/status", localmw.RateLimit(2, 5, mw.Public(secretHandler.GetSecretStatus)))
+
+	mux.Handle("POST /api/images", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(imageHandler.CreateImage))))
+	mux.Handle("GET /i/{short}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(imageHandler.GetImageByShort))))
+	mux.Handle("GET /i/{short}/{size}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(imageHandler.GetImageByShort))))
+	mux.Handle("POST /api/images/{short}/onetime", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(imageHandler.RevealOneTimeImage))))
+	mux.Handle("GET /api/images/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(imageHandler.StartImageReveal)))
+	mux.Handle("POST /api/images/{short}/reveal", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(imageHandler.RevealImage))))
+	mux.Handle("DELETE /api/images/{short}", localmw.RateLimit(2, 5, mw.Public(imageHandler.DeleteImage)))
+
+	mux.Handle("POST /api/files", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(fileHandler.CreateFile))))
+	mux.Handle("GET /f/{short}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(fileHandler.GetFileByShort))))
+	mux.Handle("POST /api/files/{short}/onetime", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(fileHandler.RevealOneTimeFile))))
+	mux.Handle("GET /api/files/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(fileHandler.StartFileReveal)))
+	mux.Handle("POST /api/files/{short}/reveal", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(fileHandler.RevealFile))))
+	mux.Handle("DELETE /api/files/{short}", localmw.RateLimit(2, 5, mw.Public(fileHandler.DeleteFile)))
+
+	mux.Handle("POST /api/uploads", localmw.RateLimit(2, 5, mw.Public(uploadHandler.CreateUpload)))
+	mux.Handle("GET /api/uploads/{id}", localmw.RateLimit(2, 5, mw.Public(uploadHandler.GetUpload)))
+	mux.Handle("PUT /api/uploads/{id}/chunks/{index}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(uploadHandler.UploadChunk))))
+	mux.Handle("POST /api/uploads/{id}/finalize", localmw.RateLimit(2, 5, mw.Public(uploadHandler.FinalizeUpload)))
+	mux.Handle("DELETE /api/uploads/{id}", localmw.RateLimit(2, 5, mw.Public(uploadHandler.AbortUpload)))
 
 	mux.Handle("POST /api/pastes", localmw.RateLimit(2, 5, mw.Public(pasteHandler.CreatePaste)))
 	mux.Handle("GET /p/{short}", localmw.RateLimit(2, 5, mw.Public(pasteHandler.GetPasteByShort)))
+	mux.Handle("GET /p/{short}/raw", localmw.RateLimit(2, 5, mw.Public(pasteHandler.GetPasteRaw)))
+	mux.Handle("GET /p/{short}/download", localmw.RateLimit(2, 5, mw.Public(pasteHandler.DownloadPaste)))
+	mux.Handle("GET /p/{short}/raw/{name...}", localmw.RateLimit(2, 5, mw.Public(pasteHandler.GetPasteFile)))
 	mux.Handle("POST /api/pastes/{short}/onetime", localmw.RateLimit(2, 5, mw.Public(pasteHandler.RevealOneTimePaste)))
+	mux.Handle("GET /api/pastes/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(pasteHandler.StartPasteReveal)))
+	mux.Handle("POST /api/pastes/{short}/reveal", localmw.RateLimit(2, 5, mw.Public(pasteHandler.RevealPaste)))
+	mux.Handle("PUT /api/pastes/{short}", localmw.RateLimit(2, 5, mw.Public(pasteHandler.UpdatePaste)))
+	mux.Handle("POST /api/pastes/{short}/fork", localmw.RateLimit(2, 5, mw.Public(pasteHandler.ForkPaste)))
+	mux.Handle("DELETE /api/pastes/{short}", localmw.RateLimit(2, 5, mw.Public(pasteHandler.DeletePaste)))
+
+	// Plain uploads for curl: --data-binary @file host/, -T file host/, -T - host/p and -T file host/f/
+	mux.Handle("POST /{$}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(plainHandler.Upload))))
+	mux.Handle("PUT /{$}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(plainHandler.Upload))))
+	mux.Handle("PUT /{name}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(plainHandler.Upload))))
+	mux.Handle("POST /p", localmw.RateLimit(2, 5, mw.Public(plainHandler.UploadPaste)))
+	mux.Handle("PUT /p", localmw.RateLimit(2, 5, mw.Public(plainHandler.UploadPaste)))
+	mux.Handle("PUT /p/{name}", localmw.RateLimit(2, 5, mw.Public(plainHandler.UploadPaste)))
+	mux.Handle("POST /f", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(plainHandler.UploadFile))))
+	mux.Handle("PUT /f", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(plainHandler.UploadFile))))
+	mux.Handle("PUT /f/{name}", localmw.Timeout(config.Config.TransferTimeout, localmw.RateLimit(2, 5, mw.Public(plainHandler.UploadFile))))
+
+	mux.Handle("GET /oembed", localmw.RateLimit(2, 5, mw.Public(oembedHandler.GetOEmbed)))
+	mux.Handle("GET /qr/{short}", localmw.RateLimit(2, 5, mw.Public(qrHandler.GetQRCode)))
+	mux.Handle("GET /qr/{kind}/{short}", localmw.RateLimit(2, 5, mw.Public(qrHandler.GetResourceQRCode)))
+
+	mux.Handle("POST /api/admin/domains", localmw.RateLimit(2, 5, mw.Public(adminHandler.AddDomainEntry)))
 
 	mux.Handle("GET /{short}", localmw.RateLimit(2, 5, mw.Public(linkHandler.RedirectByShort)))
 
@@ -128,7 +230,7 @@ func main() {
 
 	server := &http.Server{
 		Addr:         ":8080",
-		Handler:      mw.SecurityHeaders(mw.RequestLogger(localmw.NewPrometheusMiddleware()(mux))),
+		Handler:      localmw.WithResponseController(mw.SecurityHeaders(mw.RequestLogger(localmw.NewPrometheusMiddleware()(mux)))),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NeverExpires is the expiry duration of resources that are kept until deleted.
+const NeverExpires time.Duration = 0
+
+// ParseExpiry parses an expiry value such as "30m", "1h", "7d", "2w" or "never".
+func ParseExpiry(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "never" {
+		return NeverExpires, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.ParseInt(number, 10, 64)
+			if err != nil || n <= 0 || n > math.MaxInt64/int64(unit) {
+				return 0, fmt.Errorf("invalid expiry: %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid expiry: %q", value)
+	}
+	return d, nil
+}
+
+// FormatExpiry renders an expiry duration in the same notation ParseExpiry accepts.
+func FormatExpiry(d time.Duration) string {
+	switch {
+	case d == NeverExpires:
+		return "never"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
+
+func expiryFromEnv(key, fallback string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+
+	d, err := ParseExpiry(value)
+	if err != nil {
+		slog.With("key", key).With("value", value).Warn("Invalid expiry in environment, using default")
+		d, _ = ParseExpiry(fallback)
+	}
+	return d
+}
+
+func expiryListFromEnv(key string) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var durations []time.Duration
+	var errs []error
+	for item := range strings.SplitSeq(value, ",") {
+		d, err := ParseExpiry(item)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		durations = append(durations, d)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		slog.With("key", key).With("error", err).Warn("Ignoring invalid expiry values in environment")
+	}
+	return durations
+}
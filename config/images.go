@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Bounds for the longest side of image variants in pixels.
+const (
+	MinImageSize = 16
+	MaxImageSize = 4096
+)
+
+// ParseImageSizes parses image variants such as "thumb=320,preview=1280" into the longest side of each variant.
+// Variant names end up in file names, so they are limited to lowercase letters and digits.
+func ParseImageSizes(value string) (map[string]int, error) {
+	sizes := make(map[string]int)
+	for entry := range strings.SplitSeq(value, ",") {
+		name, size, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || !isVariantName(name) {
+			return nil, fmt.Errorf("invalid image size: %q", entry)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n < MinImageSize || n > MaxImageSize {
+			return nil, fmt.Errorf("invalid image size: %q", entry)
+		}
+		sizes[name] = n
+	}
+	return sizes, nil
+}
+
+func isVariantName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func imageSizesFromEnv(key, fallback string) map[string]int {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+
+	sizes, err := ParseImageSizes(value)
+	if err != nil {
+		slog.With("key", key).With("value", value).Warn("Invalid image sizes in environment, using default")
+		sizes, _ = ParseImageSizes(fallback)
+	}
+	return sizes
+}
@@ -4,18 +4,36 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/lmittmann/tint"
 )
 
 type config struct {
-	RedirectHost    string
-	RedirectPort    string
-	BehindProxy     bool
-	DataPath        string
-	DBEncryptionKey string
-	ContactEmail    string
+	RedirectHost      string
+	RedirectPort      string
+	BehindProxy       bool
+	DataPath          string
+	DBEncryptionKey   string
+	ContactEmail      string
+	ExpiryDefault     time.Duration
+	ExpiryMin         time.Duration
+	ExpiryMax         time.Duration
+	ExpiryAllowed     []time.Duration
+	LinkShortLength   int
+	PasteShortLength  int
+	ImageShortLength  int
+	SecretShortLength int
+	FileShortLength   int
+	SecretReceiptTTL  time.Duration
+	MaxUploadSize     int64
+	MaxUploadSessions int
+	TransferTimeout   time.Duration
+	ImageSizes        map[string]int
+	AdminToken        string
+	RevealSecret      string
+	ResolveHosts      bool
 }
 
 var Config config
@@ -28,12 +46,29 @@ func InitEnv() {
 	}
 
 	Config = config{
-		RedirectHost:    os.Getenv("REDIRECT_HOST"),          // http://localhost
-		RedirectPort:    os.Getenv("REDIRECT_PORT"),          // :8080
-		BehindProxy:     os.Getenv("BEHIND_PROXY") == "true", // Required in order to determine sender ip
-		DataPath:        os.Getenv("DATA_PATH"),
-		DBEncryptionKey: os.Getenv("DB_ENCRYPTION_KEY"),
-		ContactEmail:    os.Getenv("CONTACT_EMAIL"),
+		RedirectHost:      os.Getenv("REDIRECT_HOST"),          // http://localhost
+		RedirectPort:      os.Getenv("REDIRECT_PORT"),          // :8080
+		BehindProxy:       os.Getenv("BEHIND_PROXY") == "true", // Required in order to determine sender ip
+		DataPath:          os.Getenv("DATA_PATH"),
+		DBEncryptionKey:   os.Getenv("DB_ENCRYPTION_KEY"),
+		ContactEmail:      os.Getenv("CONTACT_EMAIL"),
+		ExpiryDefault:     expiryFromEnv("EXPIRY_DEFAULT", "7d"),
+		ExpiryMin:         expiryFromEnv("EXPIRY_MIN", "5m"),
+		ExpiryMax:         expiryFromEnv("EXPIRY_MAX", "30d"),  // "never" removes the upper bound
+		ExpiryAllowed:     expiryListFromEnv("EXPIRY_ALLOWED"), // Optional fixed set, e.g. 1h,1d,7d,never
+		LinkShortLength:   shortLengthFromEnv("LINK_SHORT_LENGTH"),
+		PasteShortLength:  shortLengthFromEnv("PASTE_SHORT_LENGTH"),
+		ImageShortLength:  shortLengthFromEnv("IMAGE_SHORT_LENGTH"),
+		SecretShortLength: shortLengthFromEnv("SECRET_SHORT_LENGTH"),
+		FileShortLength:   shortLengthFromEnv("FILE_SHORT_LENGTH"),
+		SecretReceiptTTL:  expiryFromEnv("SECRET_RECEIPT_TTL", "7d"), // How long read receipts outlive their secret
+		MaxUploadSize:     sizeFromEnv("MAX_UPLOAD_SIZE", "100MB"),
+		MaxUploadSessions: countFromEnv("MAX_UPLOAD_SESSIONS", 5),     // Unfinished chunked uploads per client IP, 0 disables the limit
+		TransferTimeout:   durationFromEnv("TRANSFER_TIMEOUT", "10m"), // Read and write timeout of upload and download routes
+		ImageSizes:        imageSizesFromEnv("IMAGE_SIZES", "thumb=320,preview=1280"),
+		AdminToken:        os.Getenv("ADMIN_TOKEN"),             // Enables the admin API, unset disables it
+		RevealSecret:      os.Getenv("REVEAL_SECRET"),           // Signs reveal tokens, unset uses a key per process
+		ResolveHosts:      os.Getenv("RESOLVE_HOSTS") == "true", // Reject link hosts that resolve to internal addresses
 	}
 
 	if !dotEnvLoaded {
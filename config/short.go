@@ -0,0 +1,29 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Bounds for short code lengths. Codes start at the configured length and
+// grow towards MaxShortLength when the keyspace gets crowded.
+const (
+	MinShortLength     = 4
+	MaxShortLength     = 16
+	DefaultShortLength = 6
+)
+
+func shortLengthFromEnv(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return DefaultShortLength
+	}
+
+	length, err := strconv.Atoi(value)
+	if err != nil || length < MinShortLength || length > MaxShortLength {
+		slog.With("key", key).With("value", value).Warn("Invalid short code length in environment, using default")
+		return DefaultShortLength
+	}
+	return length
+}
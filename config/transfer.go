@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSize parses a byte size such as "512KB", "100MB" or "2GB", plain numbers are bytes.
+func ParseSize(value string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(value))
+
+	unit := int64(1)
+	for _, suffix := range []struct {
+		name string
+		size int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if n, ok := strings.CutSuffix(number, suffix.name); ok {
+			number, unit = strings.TrimSpace(n), suffix.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("invalid size: %q", value)
+	}
+	return n * unit, nil
+}
+
+func sizeFromEnv(key, fallback string) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+
+	size, err := ParseSize(value)
+	if err != nil {
+		slog.With("key", key).With("value", value).Warn("Invalid size in environment, using default")
+		size, _ = ParseSize(fallback)
+	}
+	return size
+}
+
+func countFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		slog.With("key", key).With("value", value).Warn("Invalid count in environment, using default")
+		return fallback
+	}
+	return n
+}
+
+func durationFromEnv(key, fallback string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		slog.With("key", key).With("value", value).Warn("Invalid duration in environment, using default")
+		d, _ = time.ParseDuration(fallback)
+	}
+	return d
+}
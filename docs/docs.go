@@ -15,72 +15,2329 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/ip": {
+        "/": {
+            "post": {
+                "description": "Creates a paste from text of up to 1MB and a file from larger or binary bodies, as sent by ` + "`" + `curl --data-binary @file https://seq.re/` + "`" + ` or ` + "`" + `curl -T file https://seq.re/` + "`" + `. Options come from query parameters or headers. Answers the URL in plain text, followed by the expiry and a command that deletes it, the deletion token is also sent in X-Deletion-Token",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "plain"
+                ],
+                "summary": "Upload a raw body",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Expiry such as 1h, 1d, 30d or never, or the X-Expires header",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of views before deletion, or the X-Views header",
+                        "name": "views",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Shorthand for views=1, or the X-Onetime header",
+                        "name": "onetime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of pastes, detected when empty, or the X-Language header",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of downloads, or the X-Filename header",
+                        "name": "filename",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "URL, expiry and deletion command",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Empty body or invalid options"
+                    },
+                    "413": {
+                        "description": "Body larger than the configured maximum upload size"
+                    }
+                }
+            }
+        },
+        "/api/admin/domains": {
+            "post": {
+                "description": "Appends an exact host (example.com), a domain with its subdomains (*.example.com) or a /regex/ to blocklist.txt or allowlist.txt in the data directory. The entry applies right away, links to blocked domains stop redirecting. Requires the ADMIN_TOKEN as a bearer token, the route doesn't exist when no token is configured",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Add a domain policy entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cADMIN_TOKEN\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "List (block or allow) and entry",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/admin.DomainEntryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/admin.DomainEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid list or entry"
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token"
+                    },
+                    "404": {
+                        "description": "Admin routes are disabled"
+                    }
+                }
+            }
+        },
+        "/api/files": {
+            "post": {
+                "description": "Uploads any file (raw or encrypted blob), keeping its name and content type for the download",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "file"
+                ],
+                "summary": "Upload a file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "File to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name, overrides the name of the uploaded part. Encrypted with the content for encrypted files",
+                        "name": "filename",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether the file is encrypted",
+                        "name": "encrypted",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether the encryption key is derived from a password",
+                        "name": "password_protected",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Shorthand for max_views 1",
+                        "name": "onetime",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of downloads before the file is deleted",
+                        "name": "max_views",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expiry such as 1h, 1d, 30d or never (server default when empty)",
+                        "name": "expires_in",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "File URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or expiry"
+                    },
+                    "413": {
+                        "description": "File larger than the configured maximum upload size"
+                    },
+                    "500": {
+                        "description": "Internal server error"
+                    }
+                }
+            }
+        },
+        "/api/files/{short}": {
+            "delete": {
+                "description": "Deletes the file using the deletion token returned when it was created",
+                "tags": [
+                    "file"
+                ],
+                "summary": "Delete a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "File deleted"
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/files/{short}/onetime": {
+            "post": {
+                "description": "Uses up one view of the file and returns it as an attachment (or encrypted data as JSON if encrypted)",
+                "tags": [
+                    "file"
+                ],
+                "summary": "Reveal view limited file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token of the interstitial page",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/files/{short}/reveal": {
             "get": {
-                "description": "Returns the public IP address of the client making the request",
+                "description": "Returns a reveal token for POST /api/files/{short}/reveal without using up a download, link previews only ever get this far",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "file"
+                ],
+                "summary": "Start revealing a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/shared.RevealResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "A request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "post": {
+                "description": "Uses up a download of a view limited file and returns it as an attachment (or encrypted data as JSON if encrypted). Requires the token from GET /api/files/{short}/reveal",
+                "tags": [
+                    "file"
+                ],
+                "summary": "Reveal a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/images": {
+            "post": {
+                "description": "Uploads an image file (raw or encrypted blob)",
                 "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "image"
+                ],
+                "summary": "Upload an image",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Image file to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether the file is encrypted",
+                        "name": "encrypted",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Whether the encryption key is derived from a password",
+                        "name": "password_protected",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Shorthand for max_views 1",
+                        "name": "onetime",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of views before the image is deleted",
+                        "name": "max_views",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expiry such as 1h, 1d, 30d or never (server default when empty)",
+                        "name": "expires_in",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Keep the EXIF, XMP and IPTC metadata of unencrypted images, which is removed by default",
+                        "name": "keep_metadata",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Image URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or expiry, or a JPEG, PNG or WebP image that can't be parsed to remove its metadata"
+                    },
+                    "413": {
+                        "description": "Image larger than the configured maximum upload size"
+                    },
+                    "500": {
+                        "description": "Internal server error"
+                    }
+                }
+            }
+        },
+        "/api/images/{short}": {
+            "delete": {
+                "description": "Deletes the image using the deletion token returned when it was created",
+                "tags": [
+                    "image"
+                ],
+                "summary": "Delete a image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Image deleted"
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/images/{short}/onetime": {
+            "post": {
+                "description": "Consumes the one-time image and returns the raw image (one-time use only)",
+                "tags": [
+                    "image"
+                ],
+                "summary": "Reveal one-time image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token of the interstitial page",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/images/{short}/reveal": {
+            "get": {
+                "description": "Returns a reveal token for POST /api/images/{short}/reveal without using up a view, link previews only ever get this far",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "image"
+                ],
+                "summary": "Start revealing an image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/shared.RevealResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "A request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "post": {
+                "description": "Uses up a view of a view limited image and returns the raw image (or encrypted data as JSON if encrypted). Requires the token from GET /api/images/{short}/reveal",
+                "tags": [
+                    "image"
+                ],
+                "summary": "Reveal an image",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/ip": {
+            "get": {
+                "description": "Returns the public IP of the client making the request",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ip"
+                ],
+                "summary": "Get client public IP",
+                "responses": {
+                    "200": {
+                        "description": "IP retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/ip.IP"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/languages": {
+            "get": {
+                "description": "Returns the language registry: IDs for the language of pastes and files, along with the aliases, file extensions and file names that map to them. Unencrypted pastes created without a language get one detected from their file name, shebang or editor modeline.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "paste"
+                ],
+                "summary": "List paste languages",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/languages.Language"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/links": {
+            "post": {
+                "description": "Creates a new shortened URL from the provided original URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "link"
+                ],
+                "summary": "Create a shortened URL",
+                "parameters": [
+                    {
+                        "description": "Link request with URL to shorten",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/link.LinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Shortened URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, URL format, expiry or alias"
+                    },
+                    "403": {
+                        "description": "Destination domain is blocked on this server"
+                    },
+                    "409": {
+                        "description": "Alias is already taken"
+                    },
+                    "500": {
+                        "description": "Internal server error"
+                    }
+                }
+            }
+        },
+        "/api/links/{short}": {
+            "get": {
+                "description": "Returns the original URL and expiry time associated with the given short code",
+                "tags": [
+                    "link"
+                ],
+                "summary": "Get link information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link information",
+                        "schema": {
+                            "$ref": "#/definitions/link.LinkResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "View limited link, use /api/links/{short}/reveal, or a blocked destination"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the link using the deletion token returned when it was created",
+                "tags": [
+                    "link"
+                ],
+                "summary": "Delete a link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Link deleted"
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/links/{short}/onetime": {
+            "post": {
+                "description": "Consumes the one-time link and returns the URL for redirect (one-time use only)",
+                "tags": [
+                    "link"
+                ],
+                "summary": "Reveal one-time link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token of the interstitial page",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link content HTML partial",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin, a link preview bot or a blocked destination"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/links/{short}/reveal": {
+            "get": {
+                "description": "Returns a reveal token for POST /api/links/{short}/reveal without using up a view, link previews only ever get this far",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "link"
+                ],
+                "summary": "Start revealing a link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/shared.RevealResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "A request without this server's origin, a link preview bot or a blocked destination"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "post": {
+                "description": "Uses up a view of a view limited link and returns the original URL and expiry time. Requires the token from GET /api/links/{short}/reveal",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "link"
+                ],
+                "summary": "Reveal a link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link information",
+                        "schema": {
+                            "$ref": "#/definitions/link.LinkResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin, a link preview bot or a blocked destination"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/links/{short}/stats": {
+            "get": {
+                "description": "Returns total clicks, unique visitors, referrer hosts and user agent families. Requires the link's deletion token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "link"
+                ],
+                "summary": "Get link click statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code or alias",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click statistics",
+                        "schema": {
+                            "$ref": "#/definitions/link.LinkStatsResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/pastes": {
+            "post": {
+                "description": "Creates a text paste (code, logs, plain text), or a bundle of named files. Unencrypted bundles are sent as files, encrypted bundles as content holding the encrypted JSON of their files with bundle set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Create a paste",
+                "parameters": [
+                    {
+                        "description": "Paste content and options",
+                        "name": "paste",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/paste.CreatePasteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Paste URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or expiry"
+                    },
+                    "500": {
+                        "description": "Internal server error"
+                    }
+                }
+            }
+        },
+        "/api/pastes/{short}": {
+            "put": {
+                "description": "Replaces the content of a paste created with editable set, keeping the previous content as a revision. Encrypted pastes are updated with content encrypted by the same key, unencrypted bundles with files.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Update a paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Edit token",
+                        "name": "X-Edit-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "New content",
+                        "name": "paste",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/paste.UpdatePasteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "URL of the new revision",
+                        "schema": {
+                            "$ref": "#/definitions/paste.RevisionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, or content for an unencrypted bundle"
+                    },
+                    "403": {
+                        "description": "Invalid edit token, or a paste that isn't editable"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "409": {
+                        "description": "Revision limit reached"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes the paste using the deletion token returned when it was created",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Delete a paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Paste deleted"
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/pastes/{short}/fork": {
+            "post": {
+                "description": "Creates a new paste with the content, language and encryption of a revision of an existing paste. Forks of encrypted pastes are decrypted with the original key. View limited pastes can't be forked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Fork a paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to fork, the current one by default",
+                        "name": "rev",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Options of the fork",
+                        "name": "paste",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/paste.ForkPasteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Fork URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, revision or expiry"
+                    },
+                    "403": {
+                        "description": "View limited paste"
+                    },
+                    "404": {
+                        "description": "Paste or revision not found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/pastes/{short}/onetime": {
+            "post": {
+                "description": "Consumes the one-time paste and returns the content (one-time use only)",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Reveal one-time paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token of the interstitial page",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paste content HTML partial",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/pastes/{short}/reveal": {
+            "get": {
+                "description": "Returns a reveal token for POST /api/pastes/{short}/reveal without using up a view, link previews only ever get this far",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Start revealing a paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/shared.RevealResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "A request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "post": {
+                "description": "Uses up a view of a view limited paste and returns the content as text/plain (or encrypted data as JSON if encrypted). Requires the token from GET /api/pastes/{short}/reveal",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Reveal a paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paste content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/secrets": {
+            "post": {
+                "description": "Creates a new shortened URL from the provided original URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Create a shortened URL",
+                "parameters": [
+                    {
+                        "description": "Secret request with URL to shorten",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/secret.SecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Secret URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or expiry"
+                    },
+                    "500": {
+                        "description": "Internal server error"
+                    }
+                }
+            }
+        },
+        "/api/secrets/{short}": {
+            "delete": {
+                "description": "Deletes the secret using the deletion token returned when it was created",
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Delete a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Secret deleted"
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/secrets/{short}/onetime": {
+            "post": {
+                "description": "Consumes the one-time secret and returns the content (one-time use only)",
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Reveal one-time secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token of the interstitial page",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Secret content HTML partial",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/secrets/{short}/reveal": {
+            "get": {
+                "description": "Returns a reveal token for POST /api/secrets/{short}/reveal without using up a view, link previews only ever get this far",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Start revealing a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/shared.RevealResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "A request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            },
+            "post": {
+                "description": "Uses up a view of the secret and returns its encrypted data. Requires the token from GET /api/secrets/{short}/reveal",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Reveal a secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reveal token",
+                        "name": "X-Reveal-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/secret.SecretResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/secrets/{short}/status": {
+            "get": {
+                "description": "Returns pending, consumed or expired along with the time and network hash of every read. Requires the secret's deletion token and works until its receipt expires.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Get secret read status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deletion token",
+                        "name": "X-Deletion-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Secret status",
+                        "schema": {
+                            "$ref": "#/definitions/secret.SecretStatusResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid deletion token"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/api/uploads": {
+            "post": {
+                "description": "Starts an upload of an image or file that is sent in numbered chunks, so an interrupted transfer can be resumed. Takes the same options as the multipart upload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Start a chunked upload",
+                "parameters": [
+                    {
+                        "description": "Upload size and options of the finished image or file",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/upload.UploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Upload ID and chunk layout",
+                        "schema": {
+                            "$ref": "#/definitions/upload.SessionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request, chunk size or expiry"
+                    },
+                    "413": {
+                        "description": "Upload larger than the configured maximum upload size"
+                    },
+                    "429": {
+                        "description": "Too many unfinished uploads from this IP"
+                    }
+                }
+            }
+        },
+        "/api/uploads/{id}": {
+            "get": {
+                "description": "Lists the chunks received so far, clients resume by sending the missing ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Get chunked upload progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chunk layout and received chunks",
+                        "schema": {
+                            "$ref": "#/definitions/upload.SessionResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Discards the upload and the chunks received so far",
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Abort a chunked upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Upload discarded"
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned"
+                    }
+                }
+            }
+        },
+        "/api/uploads/{id}/chunks/{index}": {
+            "put": {
+                "description": "Stores chunk number index (from 0) of the upload. Every chunk but the last has the chunk size of the upload",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Upload a chunk",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Chunk index",
+                        "name": "index",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Hex encoded SHA-256 of the chunk",
+                        "name": "X-Chunk-SHA256",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chunk layout and received chunks",
+                        "schema": {
+                            "$ref": "#/definitions/upload.SessionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chunk index, size or checksum"
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned"
+                    }
+                }
+            }
+        },
+        "/api/uploads/{id}/finalize": {
+            "post": {
+                "description": "Assembles the chunks into the image or file, responding like the multipart upload of its kind",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "upload"
+                ],
+                "summary": "Finalize a chunked upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Image or file URL and expiry time",
+                        "schema": {
+                            "$ref": "#/definitions/shared.CreatedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned"
+                    },
+                    "409": {
+                        "description": "Chunks are missing"
+                    }
+                }
+            }
+        },
+        "/api/version": {
+            "get": {
+                "description": "Returns the version of the seqre server",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "seqre"
+                ],
+                "summary": "Get Seqre server version",
+                "responses": {
+                    "200": {
+                        "description": "Server version",
+                        "schema": {
+                            "$ref": "#/definitions/seqre.VersionResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/f": {
+            "put": {
+                "description": "Creates a file from the body, text or not, as sent by ` + "`" + `curl -T build.tar.gz https://seq.re/f/` + "`" + `. Takes the options of POST /",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "plain"
+                ],
+                "summary": "Upload a raw body as a file",
+                "responses": {
+                    "201": {
+                        "description": "URL, expiry and deletion command",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Empty body or invalid options"
+                    },
+                    "413": {
+                        "description": "Body larger than the configured maximum upload size"
+                    }
+                }
+            }
+        },
+        "/f/{short}": {
+            "get": {
+                "description": "Returns the file as an attachment with its original name (or encrypted data as JSON if encrypted). Unencrypted files support Range and conditional requests",
+                "tags": [
+                    "file"
+                ],
+                "summary": "Get file by short code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "View limited file requested with cli=true, use /api/files/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/i/{short}": {
+            "get": {
+                "description": "Returns the raw image file for the given short code (or encrypted data as JSON if encrypted). Raw images support Range and conditional requests.\nA size such as thumb or preview returns the image scaled down to fit that size, view limited and encrypted images are always returned whole",
+                "tags": [
+                    "image"
+                ],
+                "summary": "Get image by short code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image size, configured with IMAGE_SIZES",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the image itself to clients accepting text/html, which otherwise get a page showing it",
+                        "name": "raw",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown image size"
+                    },
+                    "403": {
+                        "description": "View limited image requested with cli=true, use /api/images/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/i/{short}/{size}": {
+            "get": {
+                "description": "Returns the raw image file for the given short code (or encrypted data as JSON if encrypted). Raw images support Range and conditional requests.\nA size such as thumb or preview returns the image scaled down to fit that size, view limited and encrypted images are always returned whole",
+                "tags": [
+                    "image"
+                ],
+                "summary": "Get image by short code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image size, configured with IMAGE_SIZES",
+                        "name": "size",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image size, configured with IMAGE_SIZES",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the image itself to clients accepting text/html, which otherwise get a page showing it",
+                        "name": "raw",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Image file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown image size"
+                    },
+                    "403": {
+                        "description": "View limited image requested with cli=true, use /api/images/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/oembed": {
+            "get": {
+                "description": "Describes an image, paste, file, secret or link URL of this server for link previews. Encrypted and view limited content gets a card that reveals nothing, and never uses up a view",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oembed"
+                ],
+                "summary": "Get oEmbed metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "URL of the shared resource",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format, only json is supported",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/oembed.OEmbedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "URL does not point to a resource of this server"
+                    },
+                    "501": {
+                        "description": "Format not supported"
+                    }
+                }
+            }
+        },
+        "/p": {
+            "put": {
+                "description": "Creates a paste from a text body of up to 1MB, as sent by ` + "`" + `cat log | curl -T - https://seq.re/p` + "`" + `. Takes the options of POST /",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "plain"
+                ],
+                "summary": "Upload a raw body as a paste",
+                "responses": {
+                    "201": {
+                        "description": "URL, expiry and deletion command",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Empty or binary body, or invalid options"
+                    },
+                    "413": {
+                        "description": "Body larger than 1MB"
+                    }
+                }
+            }
+        },
+        "/p/{short}": {
+            "get": {
+                "description": "Returns the paste content as text/plain (or as JSON for encrypted pastes and bundles)",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Get paste by short code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to show, the current one by default",
+                        "name": "rev",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to compare against, shows the changes since then (unencrypted pastes only)",
+                        "name": "diff",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paste content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid revision, or a diff of an encrypted paste"
+                    },
+                    "403": {
+                        "description": "View limited paste requested with cli=true, use /api/pastes/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Paste or revision not found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/p/{short}/download": {
+            "get": {
+                "description": "Returns an unencrypted paste as an attachment under the file name chosen at creation, or the short code with the extension of its language. Bundles are downloaded as a zip archive of their files.",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Download a paste",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to download, the current one by default",
+                        "name": "rev",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paste content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Invalid revision, or an encrypted paste"
+                    },
+                    "403": {
+                        "description": "View limited paste, use /api/pastes/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Paste or revision not found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/p/{short}/raw": {
+            "get": {
+                "description": "Returns an unencrypted paste as text/plain under its file name, with an ETag for conditional requests. Bundles are returned file by file, each under a \"==\u003e name \u003c==\" line.",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Get a paste as plain text",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to show, the current one by default",
+                        "name": "rev",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paste content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Invalid revision, or an encrypted paste"
+                    },
+                    "403": {
+                        "description": "View limited paste, use /api/pastes/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Paste or revision not found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/p/{short}/raw/{name}": {
+            "get": {
+                "description": "Returns a file of an unencrypted bundle as text/plain, with an ETag for conditional requests. Encrypted bundles keep their file names encrypted and have no raw URLs per file.",
+                "tags": [
+                    "paste"
+                ],
+                "summary": "Get a file of a paste bundle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name, a relative path like dir/main.go",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision to show, the current one by default",
+                        "name": "rev",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "File content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Invalid revision, or an encrypted paste"
+                    },
+                    "403": {
+                        "description": "View limited bundle, use /api/pastes/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Paste, revision or file not found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
+                    }
+                }
+            }
+        },
+        "/qr/{kind}/{short}": {
+            "get": {
+                "description": "Renders the URL of an image (i), paste (p), file (f) or secret (s) as a PNG or SVG QR code. Encrypted resources, secrets included, are refused since their key lives in the URL fragment",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "qr"
+                ],
+                "summary": "Get the QR code of a shared resource",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Route prefix: i, p, f or s",
+                        "name": "kind",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "png (default) or svg",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Encrypted resource or unknown format"
+                    },
+                    "404": {
+                        "description": "Resource not found"
+                    }
+                }
+            }
+        },
+        "/qr/{short}": {
+            "get": {
+                "description": "Renders the short URL of a link as a PNG or SVG QR code. Encrypted links are refused, their key lives in the URL fragment the server never sees, so their codes are generated by the web UI or ` + "`" + `seqre url --qr` + "`" + `",
                 "produces": [
-                    "application/json"
+                    "image/png",
+                    "image/svg+xml"
                 ],
                 "tags": [
-                    "address"
+                    "qr"
+                ],
+                "summary": "Get the QR code of a link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "png (default) or svg",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "QR code",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Encrypted resource or unknown format"
+                    },
+                    "404": {
+                        "description": "Resource not found"
+                    }
+                }
+            }
+        },
+        "/s/{short}": {
+            "get": {
+                "description": "Shows the one-time view page where users can reveal the secret",
+                "tags": [
+                    "secret"
+                ],
+                "summary": "Get secret information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "short",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get client public IP",
                 "responses": {
                     "200": {
-                        "description": "IP address retrieved successfully",
+                        "description": "One-time view page",
                         "schema": {
-                            "$ref": "#/definitions/address.Address"
+                            "type": "string"
                         }
+                    },
+                    "403": {
+                        "description": "Requested with cli=true, use /api/secrets/{short}/reveal"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity"
                     }
                 }
             }
         },
-        "/api/link": {
-            "post": {
-                "description": "Creates a new shortened URL from the provided original URL",
+        "/{name}": {
+            "put": {
+                "description": "Creates a paste from text of up to 1MB and a file from larger or binary bodies, as sent by ` + "`" + `curl --data-binary @file https://seq.re/` + "`" + ` or ` + "`" + `curl -T file https://seq.re/` + "`" + `. Options come from query parameters or headers. Answers the URL in plain text, followed by the expiry and a command that deletes it, the deletion token is also sent in X-Deletion-Token",
                 "consumes": [
-                    "application/json"
+                    "application/octet-stream"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "link"
+                    "plain"
                 ],
-                "summary": "Create a shortened URL",
+                "summary": "Upload a raw body",
                 "parameters": [
                     {
-                        "description": "Link request with URL to shorten",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/link.LinkRequest"
-                        }
+                        "type": "string",
+                        "description": "File name, appended by curl -T",
+                        "name": "name",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Expiry such as 1h, 1d, 30d or never, or the X-Expires header",
+                        "name": "expires",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of views before deletion, or the X-Views header",
+                        "name": "views",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Shorthand for views=1, or the X-Onetime header",
+                        "name": "onetime",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language of pastes, detected when empty, or the X-Language header",
+                        "name": "language",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of downloads, or the X-Filename header",
+                        "name": "filename",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Shortened URL",
+                        "description": "URL, expiry and deletion command",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid request or URL format"
+                        "description": "Empty body or invalid options"
                     },
-                    "500": {
-                        "description": "Internal server error"
+                    "413": {
+                        "description": "Body larger than the configured maximum upload size"
                     }
                 }
             }
         },
         "/{short}": {
             "get": {
-                "description": "Redirects to the original URL associated with the given short code",
+                "description": "Redirects to the original URL associated with the given short code. ` + "`" + `/{short}+` + "`" + ` or ` + "`" + `?preview=1` + "`" + ` show the destination, creation and expiry time and warnings about risky destinations instead, as do links created with preview",
                 "tags": [
                     "link"
                 ],
@@ -88,16 +2345,28 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short code",
+                        "description": "Short code, followed by + for the preview page",
                         "name": "short",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "1 for the preview page",
+                        "name": "preview",
+                        "in": "query"
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "Preview page"
+                    },
                     "301": {
                         "description": "Redirect to original URL"
                     },
+                    "403": {
+                        "description": "View limited link requested with cli=true, use /api/links/{short}/reveal, or a blocked destination"
+                    },
                     "404": {
                         "description": "Short code not found"
                     }
@@ -106,7 +2375,38 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "address.Address": {
+        "admin.DomainEntryRequest": {
+            "type": "object",
+            "required": [
+                "entry",
+                "list"
+            ],
+            "properties": {
+                "entry": {
+                    "description": "example.com, *.example.com or /regex/",
+                    "type": "string"
+                },
+                "list": {
+                    "type": "string",
+                    "enum": [
+                        "block",
+                        "allow"
+                    ]
+                }
+            }
+        },
+        "admin.DomainEntryResponse": {
+            "type": "object",
+            "properties": {
+                "entry": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "string"
+                }
+            }
+        },
+        "ip.IP": {
             "type": "object",
             "properties": {
                 "ip": {
@@ -114,16 +2414,451 @@ const docTemplate = `{
                 }
             }
         },
+        "languages.Language": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "extensions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "filenames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
         "link.LinkRequest": {
             "type": "object",
             "required": [
                 "url"
             ],
             "properties": {
+                "alias": {
+                    "description": "Optional vanity code, e.g. \"deploy-guide\"",
+                    "type": "string"
+                },
+                "encrypted": {
+                    "type": "boolean"
+                },
+                "expires_in": {
+                    "description": "e.g. \"1h\", \"7d\", \"never\"; server default when empty",
+                    "type": "string"
+                },
+                "max_views": {
+                    "description": "Link is deleted after this many views",
+                    "type": "integer"
+                },
+                "onetime": {
+                    "description": "Shorthand for max_views 1",
+                    "type": "boolean"
+                },
+                "password_protected": {
+                    "description": "Key derived from a passphrase instead of carried in the fragment",
+                    "type": "boolean"
+                },
+                "preview": {
+                    "description": "Always show the destination on a preview page instead of redirecting",
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "link.LinkResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "preview": {
+                    "description": "Browsers are shown a preview page instead of being redirected",
+                    "type": "boolean"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "views_left": {
+                    "description": "Only set for view limited links",
+                    "type": "integer"
+                }
+            }
+        },
+        "link.LinkStatsResponse": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "last_click_at": {
+                    "type": "string"
+                },
+                "referrers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "unique_visitors": {
+                    "type": "integer"
+                },
+                "user_agents": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "oembed.OEmbedResponse": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer"
+                },
+                "provider_name": {
+                    "type": "string"
+                },
+                "provider_url": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "paste.CreatePasteRequest": {
+            "type": "object",
+            "properties": {
+                "bundle": {
+                    "description": "Encrypted content is the JSON of the files of a bundle",
+                    "type": "boolean"
+                },
+                "content": {
+                    "description": "1MB max",
+                    "type": "string",
+                    "maxLength": 1048576
+                },
+                "editable": {
+                    "description": "Hand out an edit token for PUT /api/pastes/{short}",
+                    "type": "boolean"
+                },
+                "encrypted": {
+                    "type": "boolean"
+                },
+                "expires_in": {
+                    "description": "e.g. \"1h\", \"7d\", \"never\"; server default when empty",
+                    "type": "string"
+                },
+                "filename": {
+                    "description": "Name of downloads, unencrypted single pastes only",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "files": {
+                    "description": "Creates a bundle of named files instead",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "$ref": "#/definitions/paste.PasteFile"
+                    }
+                },
+                "language": {
+                    "description": "ID or alias from GET /api/languages, detected when empty",
+                    "type": "string"
+                },
+                "max_views": {
+                    "description": "Paste is deleted after this many views",
+                    "type": "integer"
+                },
+                "onetime": {
+                    "description": "Shorthand for max_views 1",
+                    "type": "boolean"
+                },
+                "password_protected": {
+                    "description": "Key derived from a passphrase instead of carried in the fragment",
+                    "type": "boolean"
+                }
+            }
+        },
+        "paste.ForkPasteRequest": {
+            "type": "object",
+            "properties": {
+                "editable": {
+                    "type": "boolean"
+                },
+                "expires_in": {
+                    "description": "Server default when empty, forks don't inherit the expiry",
+                    "type": "string"
+                }
+            }
+        },
+        "paste.PasteFile": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "maxLength": 1048576
+                },
+                "language": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "paste.RevisionResponse": {
+            "type": "object",
+            "properties": {
+                "revision": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "paste.UpdatePasteRequest": {
+            "type": "object",
+            "properties": {
+                "bundle": {
+                    "description": "Encrypted content is the JSON of the files of a bundle",
+                    "type": "boolean"
+                },
+                "content": {
+                    "description": "1MB max",
+                    "type": "string",
+                    "maxLength": 1048576
+                },
+                "files": {
+                    "description": "Unencrypted bundles are updated with files",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "$ref": "#/definitions/paste.PasteFile"
+                    }
+                }
+            }
+        },
+        "secret.SecretReadResponse": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "network_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "secret.SecretRequest": {
+            "type": "object",
+            "required": [
+                "data"
+            ],
+            "properties": {
+                "data": {
+                    "type": "string",
+                    "minLength": 44
+                },
+                "expires_in": {
+                    "description": "e.g. \"1h\", \"7d\", \"never\"; server default when empty",
+                    "type": "string"
+                },
+                "max_views": {
+                    "description": "Views before the secret is deleted, 1 when empty",
+                    "type": "integer"
+                }
+            }
+        },
+        "secret.SecretResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "string"
+                },
+                "views_left": {
+                    "type": "integer"
+                }
+            }
+        },
+        "secret.SecretStatusResponse": {
+            "type": "object",
+            "properties": {
+                "consumed_at": {
+                    "type": "string"
+                },
+                "reads": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/secret.SecretReadResponse"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "views_left": {
+                    "type": "integer"
+                }
+            }
+        },
+        "seqre.VersionResponse": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "shared.CreatedResponse": {
+            "type": "object",
+            "properties": {
+                "deletion_token": {
+                    "type": "string"
+                },
+                "edit_token": {
+                    "description": "Only set for editable pastes",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
                 "url": {
                     "type": "string"
                 }
             }
+        },
+        "shared.RevealResponse": {
+            "type": "object",
+            "properties": {
+                "reveal_token": {
+                    "type": "string"
+                },
+                "views_left": {
+                    "description": "Only set for view limited resources",
+                    "type": "integer"
+                }
+            }
+        },
+        "upload.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "chunk_size": {
+                    "type": "integer"
+                },
+                "chunks": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "received": {
+                    "description": "Indexes of the chunks stored so far",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "upload.UploadRequest": {
+            "type": "object",
+            "required": [
+                "kind",
+                "size"
+            ],
+            "properties": {
+                "chunk_size": {
+                    "description": "Server default when empty",
+                    "type": "integer"
+                },
+                "content_type": {
+                    "description": "Detected from the content when empty",
+                    "type": "string"
+                },
+                "encrypted": {
+                    "type": "boolean"
+                },
+                "expires_in": {
+                    "description": "e.g. \"1h\", \"7d\", \"never\"; server default when empty",
+                    "type": "string"
+                },
+                "filename": {
+                    "description": "Encrypted with the content for encrypted files",
+                    "type": "string"
+                },
+                "keep_metadata": {
+                    "type": "boolean"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "image",
+                        "file"
+                    ]
+                },
+                "max_views": {
+                    "type": "integer"
+                },
+                "onetime": {
+                    "type": "boolean"
+                },
+                "password_protected": {
+                    "type": "boolean"
+                },
+                "size": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,279 @@
+// Package domains decides which hosts links may point to. Block and allow lists are plain text files in the data
+// directory, one entry per line, and are reloaded when they change so a phishing domain can be blocked without a
+// restart.
+package domains
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// List names a list file.
+type List string
+
+const (
+	Block List = "block"
+	Allow List = "allow"
+)
+
+// files maps the lists to their file names in the data directory
+var files = map[List]string{
+	Block: "blocklist.txt",
+	Allow: "allowlist.txt",
+}
+
+// ErrInvalidEntry is returned for entries that are neither a host, a *.suffix nor a /regex/.
+var ErrInvalidEntry = errors.New("invalid domain entry")
+
+// BlockedError reports why a host was rejected.
+type BlockedError struct {
+	Host  string
+	Entry string // Blocklist entry that matched, empty when the host is missing from the allowlist
+}
+
+func (e *BlockedError) Error() string {
+	if e.Entry == "" {
+		return e.Host + " is not on the allowlist"
+	}
+	return fmt.Sprintf("%s is blocked by %q", e.Host, e.Entry)
+}
+
+// entry is a parsed list line: an exact host, a domain with all its subdomains (*.example.com) or a regular
+// expression matched against the whole host (/^ex.*\.com$/).
+type entry struct {
+	raw    string
+	host   string
+	suffix string
+	re     *regexp.Regexp
+}
+
+func (e entry) matches(host string) bool {
+	switch {
+	case e.re != nil:
+		return e.re.MatchString(host)
+	case e.suffix != "":
+		return host == e.suffix || strings.HasSuffix(host, "."+e.suffix)
+	default:
+		return host == e.host
+	}
+}
+
+// Policy holds the parsed lists. A host is rejected when it matches the blocklist, or when the allowlist has
+// entries and the host matches none of them.
+type Policy struct {
+	dir      string
+	mu       sync.RWMutex
+	lists    map[List][]entry
+	modTimes map[List]time.Time
+}
+
+// Load reads the lists from dir, missing files are empty lists.
+func Load(dir string) (*Policy, error) {
+	p := &Policy{dir: dir, lists: map[List][]entry{}, modTimes: map[List]time.Time{}}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Check returns a *BlockedError when links to host are not allowed.
+func (p *Policy) Check(host string) error {
+	host, err := shared.NormalizeHost(host)
+	if err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, e := range p.lists[Block] {
+		if e.matches(host) {
+			return &BlockedError{Host: host, Entry: e.raw}
+		}
+	}
+	if allow := p.lists[Allow]; len(allow) > 0 {
+		for _, e := range allow {
+			if e.matches(host) {
+				return nil
+			}
+		}
+		return &BlockedError{Host: host}
+	}
+	return nil
+}
+
+// Add appends an entry to a list file and applies it right away.
+func (p *Policy) Add(list List, raw string) error {
+	name, ok := files[list]
+	if !ok {
+		return fmt.Errorf("unknown list %q", list)
+	}
+	if _, err := parseEntry(raw); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(p.dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, strings.TrimSpace(raw)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return p.reload()
+}
+
+// Reload reads both list files again.
+func (p *Policy) Reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reload()
+}
+
+func (p *Policy) reload() error {
+	for list, name := range files {
+		path := filepath.Join(p.dir, name)
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			p.lists[list], p.modTimes[list] = nil, time.Time{}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		entries, err := readList(path)
+		if err != nil {
+			return err
+		}
+		p.lists[list], p.modTimes[list] = entries, info.ModTime()
+	}
+	return nil
+}
+
+// changed reports whether a list file was created, modified or removed since it was last read.
+func (p *Policy) changed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for list, name := range files {
+		var modTime time.Time
+		if info, err := os.Stat(filepath.Join(p.dir, name)); err == nil {
+			modTime = info.ModTime()
+		}
+		if !modTime.Equal(p.modTimes[list]) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartWatcher reloads the lists whenever one of the files changes, checking every interval.
+func (p *Policy) StartWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		slog.With("interval", interval).With("dir", p.dir).Info("Domain policy watcher started")
+
+		for range ticker.C {
+			if !p.changed() {
+				continue
+			}
+			if err := p.Reload(); err != nil {
+				slog.With("error", err).Error("failed to reload domain policy")
+				continue
+			}
+			slog.Info("Domain policy reloaded")
+		}
+	}()
+}
+
+// readList parses a list file. Blank lines and # comments are skipped, so are invalid entries, which are logged
+// rather than failing the whole list.
+func readList(path string) ([]entry, error) {
+	f, err := os.Open(path) // #nosec G304 -- List file in the data directory
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var entries []entry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		// Comments start a line or follow an entry after whitespace
+		if i := strings.Index(text, "#"); i == 0 || i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		e, err := parseEntry(text)
+		if err != nil {
+			slog.With("error", err).With("file", path).With("line", line).Warn("skipping invalid domain entry")
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func parseEntry(raw string) (entry, error) {
+	raw = strings.TrimSpace(raw)
+	e := entry{raw: raw}
+
+	// Entries are stored one per line, a line break would turn one entry into several
+	if strings.ContainsAny(raw, "\r\n") {
+		return entry{}, fmt.Errorf("%w: %q", ErrInvalidEntry, raw)
+	}
+
+	switch {
+	case len(raw) > 2 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/"):
+		re, err := regexp.Compile(raw[1 : len(raw)-1])
+		if err != nil {
+			return entry{}, fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+		}
+		e.re = re
+	case strings.HasPrefix(raw, "*."):
+		suffix, err := shared.NormalizeHost(raw[2:])
+		if err != nil || !validHost(suffix) {
+			return entry{}, fmt.Errorf("%w: %q", ErrInvalidEntry, raw)
+		}
+		e.suffix = suffix
+	default:
+		host, err := shared.NormalizeHost(raw)
+		if err != nil || !validHost(host) {
+			return entry{}, fmt.Errorf("%w: %q", ErrInvalidEntry, raw)
+		}
+		e.host = host
+	}
+	return e, nil
+}
+
+// validHost accepts host names and IP addresses, without schemes, ports or paths.
+func validHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	return host != "" && !strings.ContainsAny(host, "/:@*?# \t")
+}
@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/apicore/response"
+	"github.com/piheta/seq.re/internal/domains"
+	s "github.com/piheta/seq.re/internal/shared"
+)
+
+type AdminHandler struct {
+	policy *domains.Policy
+	token  string // Empty disables the admin routes
+}
+
+func NewAdminHandler(policy *domains.Policy, token string) *AdminHandler {
+	return &AdminHandler{policy: policy, token: token}
+}
+
+// AddDomainEntry adds an entry to the domain block or allow list
+// @Summary Add a domain policy entry
+// @Description Appends an exact host (example.com), a domain with its subdomains (*.example.com) or a /regex/ to blocklist.txt or allowlist.txt in the data directory. The entry applies right away, links to blocked domains stop redirecting. Requires the ADMIN_TOKEN as a bearer token, the route doesn't exist when no token is configured
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Param request body DomainEntryRequest true "List (block or allow) and entry"
+// @Success 201 {object} DomainEntryResponse
+// @Failure 400 "Invalid list or entry"
+// @Failure 401 "Missing or invalid admin token"
+// @Failure 404 "Admin routes are disabled"
+// @Router /api/admin/domains [post]
+func (h *AdminHandler) AddDomainEntry(w http.ResponseWriter, r *http.Request) error {
+	if err := h.authorize(r); err != nil {
+		return err
+	}
+
+	var req DomainEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierr.NewError(400, "validation", "Invalid JSON body")
+	}
+	if err := s.Validate.Struct(&req); err != nil {
+		return apierr.NewError(400, "validation", "list must be block or allow and entry is required")
+	}
+
+	entry := strings.TrimSpace(req.Entry)
+	if err := h.policy.Add(domains.List(req.List), entry); err != nil {
+		if errors.Is(err, domains.ErrInvalidEntry) {
+			return apierr.NewError(400, "validation", "Entries are a host (example.com), a domain with its subdomains (*.example.com) or a /regex/")
+		}
+		return err
+	}
+
+	return response.JSON(w, 201, DomainEntryResponse{List: req.List, Entry: entry})
+}
+
+// authorize checks the bearer token in constant time. Without a configured token the admin routes don't exist.
+func (h *AdminHandler) authorize(r *http.Request) error {
+	if h.token == "" {
+		return apierr.NewError(404, "not_found", "Not found")
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		return apierr.NewError(401, "unauthorized", "Missing or invalid admin token")
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package admin
+
+type DomainEntryRequest struct {
+	List  string `json:"list" validate:"required,oneof=block allow"`
+	Entry string `json:"entry" validate:"required"` // example.com, *.example.com or /regex/
+}
+
+type DomainEntryResponse struct {
+	List  string `json:"list"`
+	Entry string `json:"entry"`
+}
@@ -0,0 +1,343 @@
+package file
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/apicore/response"
+	"github.com/piheta/seq.re/config"
+	s "github.com/piheta/seq.re/internal/shared"
+)
+
+// maxFileNameLength caps stored file names, encrypted names are base64 and somewhat longer than the plain name.
+const maxFileNameLength = 1024
+
+type FileHandler struct {
+	fileService     *FileService
+	templateService *s.TemplateService
+}
+
+func NewFileHandler(fileService *FileService, templateService *s.TemplateService) *FileHandler {
+	return &FileHandler{
+		fileService:     fileService,
+		templateService: templateService,
+	}
+}
+
+// CreateFile uploads a file
+// @Summary Upload a file
+// @Description Uploads any file (raw or encrypted blob), keeping its name and content type for the download
+// @Tags file
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "File to upload"
+// @Param filename formData string false "File name, overrides the name of the uploaded part. Encrypted with the content for encrypted files"
+// @Param encrypted formData bool false "Whether the file is encrypted"
+// @Param password_protected formData bool false "Whether the encryption key is derived from a password"
+// @Param onetime formData bool false "Shorthand for max_views 1"
+// @Param max_views formData int false "Number of downloads before the file is deleted"
+// @Param expires_in formData string false "Expiry such as 1h, 1d, 30d or never (server default when empty)"
+// @Success 201 {object} shared.CreatedResponse "File URL and expiry time"
+// @Failure 400 "Invalid request or expiry"
+// @Failure 413 "File larger than the configured maximum upload size"
+// @Failure 500 "Internal server error"
+// @Router /api/files [post]
+func (h *FileHandler) CreateFile(w http.ResponseWriter, r *http.Request) error {
+	upload, err := s.ReceiveUpload(w, r, h.fileService.UploadDir(), config.Config.MaxUploadSize)
+	if err != nil {
+		return err
+	}
+	// Nothing is left to remove once the service has moved the upload into place
+	defer upload.Remove()
+
+	return h.StoreUpload(w, r, upload)
+}
+
+// StoreUpload stores a completely received upload as a file, reading its options from the upload fields.
+// Multipart and chunked uploads both end up here.
+func (h *FileHandler) StoreUpload(w http.ResponseWriter, r *http.Request, upload *s.Upload) error {
+	encrypted := upload.Value("encrypted") == "true"
+	passwordProtected := upload.Value("password_protected") == "true"
+	onetime := upload.Value("onetime") == "true"
+
+	var maxViews int
+	if value := upload.Value("max_views"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return apierr.NewError(400, "validation", "Invalid max_views")
+		}
+		maxViews = n
+	}
+
+	if passwordProtected && !encrypted {
+		return apierr.NewError(400, "validation", "Password protection requires an encrypted file")
+	}
+
+	expiresIn, err := s.ParseExpiresIn(upload.Value("expires_in"))
+	if err != nil {
+		return err
+	}
+
+	maxViews, err = s.ViewLimit(maxViews, onetime)
+	if err != nil {
+		return err
+	}
+
+	fileName := upload.Value("filename")
+	if fileName == "" && !encrypted {
+		fileName = upload.FileName
+	}
+	if len(fileName) > maxFileNameLength {
+		return apierr.NewError(400, "validation", "File name too long")
+	}
+
+	// Names and types of encrypted files are only known to the recipient
+	contentType := "application/octet-stream"
+	if !encrypted {
+		fileName = s.SanitizeFileName(fileName)
+		contentType = detectContentType(upload.ContentType, upload.Sniffed)
+	}
+
+	file, err := h.fileService.StoreFile(upload.Path, upload.Size, fileName, contentType, encrypted, passwordProtected, maxViews, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	fileURL := fmt.Sprintf("%s%s/f/%s", config.Config.RedirectHost, config.Config.RedirectPort, file.Short)
+
+	// Check if this is an HTMX request (wants HTML response)
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := map[string]string{
+			"URL":           fileURL,
+			"ButtonID":      "file",
+			"Expires":       s.DescribeExpiry(file.ExpiresAt),
+			"DeletionToken": file.DeletionToken,
+		}
+		if warning := s.ViewsWarning(maxViews); warning != "" {
+			data["Warning"] = warning
+		}
+		return h.templateService.RenderResult(w, data)
+	}
+
+	created := s.NewCreatedResponse(fileURL, file.ExpiresAt, file.DeletionToken)
+	if upload.Plain {
+		return s.WritePlainCreated(w, created, s.PublicURL("/api/files/"+file.Short))
+	}
+	return response.JSON(w, 201, created)
+}
+
+// GetFileByShort serves the file as a download
+// @Summary Get file by short code
+// @Description Returns the file as an attachment with its original name (or encrypted data as JSON if encrypted). Unencrypted files support Range and conditional requests
+// @Tags file
+// @Param short path string true "Short code"
+// @Success 200 {file} binary "File"
+// @Failure 403 "View limited file requested with cli=true, use /api/files/{short}/reveal"
+// @Failure 404
+// @Failure 422
+// @Router /f/{short} [get]
+func (h *FileHandler) GetFileByShort(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.FileShortLength) {
+		return s.MapError(w, r, apierr.NewError(422, "validation", "Invalid file code"), h.templateService)
+	}
+
+	fileCheck, err := h.fileService.CheckFileExists(short)
+	if err != nil {
+		return s.MapError(w, r, apierr.NewError(404, "not_found", "File not found"), h.templateService)
+	}
+
+	if fileCheck.MaxViews > 0 {
+		if r.URL.Query().Get("cli") == "true" {
+			return s.RevealRequired("files", short)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := map[string]any{
+			"ID":                short,
+			"Type":              "file",
+			"PasswordProtected": fileCheck.PasswordProtected,
+			"ViewsLeft":         fileCheck.ViewsLeft,
+			"RevealToken":       s.NewRevealToken("files", short),
+			"Card":              fileCard(fileCheck),
+		}
+		return h.templateService.RenderOnetime(w, data)
+	}
+
+	// Encrypted files are decrypted in the browser, which fetches the data from the JSON API
+	if fileCheck.Encrypted && r.URL.Query().Get("cli") != "true" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := map[string]any{
+			"ID":                short,
+			"PasswordProtected": fileCheck.PasswordProtected,
+			"Card":              fileCard(fileCheck),
+		}
+		return h.templateService.RenderFileDecrypt(w, data)
+	}
+
+	file, f, err := h.fileService.GetFile(short)
+	if err != nil {
+		return s.MapError(w, r, apierr.NewError(404, "not_found", "File not found"), h.templateService)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return serveFile(w, r, file, f)
+}
+
+// RevealOneTimeFile consumes a view of the file and returns its data.
+// @Summary Reveal view limited file
+// @Description Uses up one view of the file and returns it as an attachment (or encrypted data as JSON if encrypted)
+// @Tags file
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token of the interstitial page"
+// @Success 200 {file} binary "File"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/files/{short}/onetime [post]
+func (h *FileHandler) RevealOneTimeFile(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.FileShortLength) {
+		return h.templateService.RenderError(w, "Invalid file code")
+	}
+
+	if err := s.CheckReveal(r, "files", short); err != nil {
+		return s.MapError(w, r, err, h.templateService)
+	}
+
+	file, f, err := h.fileService.GetFile(short)
+	if err != nil {
+		return h.templateService.RenderError(w, "This one-time link has already been viewed or does not exist.")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return serveFile(w, r, file, f)
+}
+
+// StartFileReveal hands out a reveal token without using up a download.
+// @Summary Start revealing a file
+// @Description Returns a reveal token for POST /api/files/{short}/reveal without using up a download, link previews only ever get this far
+// @Tags file
+// @Produce json
+// @Param short path string true "Short code"
+// @Success 200 {object} shared.RevealResponse
+// @Failure 403 "A request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/files/{short}/reveal [get]
+func (h *FileHandler) StartFileReveal(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.FileShortLength) {
+		return apierr.NewError(422, "validation", "Invalid file code")
+	}
+
+	file, err := h.fileService.CheckFileExists(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "File not found")
+	}
+
+	return s.StartReveal(w, r, "files", short, file.MaxViews, file.ViewsLeft)
+}
+
+// RevealFile uses up a download of the file and returns it.
+// @Summary Reveal a file
+// @Description Uses up a download of a view limited file and returns it as an attachment (or encrypted data as JSON if encrypted). Requires the token from GET /api/files/{short}/reveal
+// @Tags file
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token"
+// @Success 200 {file} binary "File"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/files/{short}/reveal [post]
+func (h *FileHandler) RevealFile(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.FileShortLength) {
+		return apierr.NewError(422, "validation", "Invalid file code")
+	}
+
+	if err := s.CheckReveal(r, "files", short); err != nil {
+		return err
+	}
+
+	file, f, err := h.fileService.GetFile(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "File not found")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return serveFile(w, r, file, f)
+}
+
+// DeleteFile deletes a file before it expires.
+// @Summary Delete a file
+// @Description Deletes the file using the deletion token returned when it was created
+// @Tags file
+// @Param short path string true "Short code"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 204 "File deleted"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/files/{short} [delete]
+func (h *FileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.FileShortLength) {
+		return apierr.NewError(422, "validation", "Invalid file code")
+	}
+
+	if err := h.fileService.RevokeFile(short, s.DeletionToken(r)); err != nil {
+		return s.TokenError(err, "File not found")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// serveFile streams an opened file, encrypted files as base64 JSON and others as an attachment under their
+// original name, with Range and conditional request support.
+func serveFile(w http.ResponseWriter, r *http.Request, file *File, f *os.File) error {
+	viewsLeft := s.RemainingViews(file.MaxViews, file.ViewsLeft)
+	if file.Encrypted {
+		return s.StreamDataJSON(w, 200, f, FileResponse{
+			FileName:          file.FileName,
+			ContentType:       file.ContentType,
+			PasswordProtected: file.PasswordProtected,
+			ViewsLeft:         viewsLeft,
+		})
+	}
+
+	if viewsLeft != nil {
+		w.Header().Set(s.ViewsLeftHeader, strconv.Itoa(*viewsLeft))
+	}
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", s.ContentDisposition("attachment", file.FileName))
+	http.ServeContent(w, r, "", file.CreatedAt, f)
+	return nil
+}
+
+// detectContentType keeps the type declared by the client, falling back to the sniffed one when there is none.
+func detectContentType(declared, sniffed string) string {
+	if declared != "" && declared != "application/octet-stream" {
+		if _, _, err := mime.ParseMediaType(declared); err == nil {
+			return declared
+		}
+	}
+	return sniffed
+}
@@ -0,0 +1,27 @@
+package file
+
+import "time"
+
+type File struct {
+	Short             string
+	FilePath          string
+	FileName          string // Original name, encrypted along with the content for encrypted files
+	ContentType       string
+	Size              int64
+	Encrypted         bool
+	PasswordProtected bool
+	MaxViews          int // Views allowed in total, 0 for unlimited
+	ViewsLeft         int // Decremented on every view, the file is deleted when it reaches 0
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	DeletionTokenHash string
+	DeletionToken     string `json:"-"` // Returned to the creator once, never stored
+}
+
+type FileResponse struct {
+	Data              string `json:"data,omitempty"` // Streamed separately by StreamDataJSON
+	FileName          string `json:"filename"`
+	ContentType       string `json:"content_type"`
+	PasswordProtected bool   `json:"password_protected,omitempty"`
+	ViewsLeft         *int   `json:"views_left,omitempty"` // Only set for view limited files
+}
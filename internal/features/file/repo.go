@@ -0,0 +1,131 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+type FileRepo struct {
+	db *badger.DB
+}
+
+func NewFileRepo(db *badger.DB) *FileRepo {
+	return &FileRepo{db: db}
+}
+
+// Create stores the file under its short code, failing with shared.ErrShortTaken if the code is in use.
+func (r *FileRepo) Create(file *File) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.FilePrefix, file.Short)
+		if err := shared.EnsureUnused(txn, key); err != nil {
+			return err
+		}
+
+		data, _ := json.Marshal(file)
+		entry := badger.NewEntry(key, data)
+		if !file.ExpiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(file.ExpiresAt))
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+func (r *FileRepo) GetByShort(short string) (*File, error) {
+	var file File
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(shared.Key(shared.FilePrefix, short))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &file)
+		})
+	})
+
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	return &file, err
+}
+
+// ConsumeView uses up one view of the file in a single transaction, so concurrent readers can't exceed
+// its view limit. The file is deleted with its last view, otherwise it is rewritten with the remaining TTL.
+func (r *FileRepo) ConsumeView(short string) (*File, error) {
+	var file File
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.FilePrefix, short)
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &file)
+		}); err != nil {
+			return err
+		}
+
+		file.ViewsLeft--
+		if file.ViewsLeft <= 0 {
+			file.ViewsLeft = 0
+			return txn.Delete(key)
+		}
+
+		data, _ := json.Marshal(&file)
+		entry := badger.NewEntry(key, data)
+		entry.ExpiresAt = item.ExpiresAt()
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+func (r *FileRepo) Delete(short string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		err := txn.Delete(shared.Key(shared.FilePrefix, short))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *FileRepo) CountFiles() (encrypted, unencrypted int, err error) {
+	return encrypted, unencrypted, r.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = true
+		opts.Prefix = []byte(shared.FilePrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+
+			_ = item.Value(func(val []byte) error {
+				var file File
+				if err := json.Unmarshal(val, &file); err != nil || file.FilePath == "" {
+					return err
+				}
+
+				if file.Encrypted {
+					encrypted++
+				} else {
+					unencrypted++
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
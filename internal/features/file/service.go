@@ -0,0 +1,170 @@
+package file
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+type FileService struct {
+	fileRepo  *FileRepo
+	uploadDir string
+}
+
+func NewFileService(fileRepo *FileRepo, uploadDir string) *FileService {
+	if err := os.MkdirAll(uploadDir, 0750); err != nil {
+		slog.Error("Failed to create upload directory", "error", err)
+	}
+	return &FileService{
+		fileRepo:  fileRepo,
+		uploadDir: uploadDir,
+	}
+}
+
+// CreateFile stores a file held in memory, uploads are streamed to disk and stored with StoreFile.
+//
+//nolint:revive // encrypted and passwordProtected flags are acceptable for control flow
+func (s *FileService) CreateFile(fileData []byte, fileName string, contentType string, encrypted bool, passwordProtected bool, maxViews int, expiresIn time.Duration) (*File, error) {
+	tempPath, err := shared.WriteTempUpload(s.uploadDir, fileData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return s.StoreFile(tempPath, int64(len(fileData)), fileName, contentType, encrypted, passwordProtected, maxViews, expiresIn)
+}
+
+// StoreFile moves a completely received upload at tempPath into place, it is removed when storing fails.
+//
+//nolint:revive // encrypted and passwordProtected flags are acceptable for control flow
+func (s *FileService) StoreFile(tempPath string, size int64, fileName string, contentType string, encrypted bool, passwordProtected bool, maxViews int, expiresIn time.Duration) (*File, error) {
+	token, tokenHash := shared.NewDeletionToken()
+
+	file := File{
+		FileName:          fileName,
+		ContentType:       contentType,
+		Size:              size,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ViewsLeft:         maxViews,
+		CreatedAt:         time.Now(),
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+		DeletionTokenHash: tokenHash,
+		DeletionToken:     token,
+	}
+
+	// Files are stored under their short code, the original name only lives in the record
+	err := shared.AllocateShort(config.Config.FileShortLength, func(short string) error {
+		file.Short = short
+		file.FilePath = filepath.Join(s.uploadDir, short+".bin")
+		return s.fileRepo.Create(&file)
+	})
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return nil, err
+	}
+
+	if err := os.Rename(tempPath, file.FilePath); err != nil {
+		_ = s.fileRepo.Delete(file.Short)
+		_ = os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	return &file, nil
+}
+
+// GetFile opens the stored file, claiming a view when it is view limited. The caller closes the file.
+func (s *FileService) GetFile(short string) (*File, *os.File, error) {
+	file, err := s.fileRepo.GetByShort(short)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if file.MaxViews > 0 {
+		// Claim a view atomically, only requests within the view limit get to read the file
+		file, err = s.fileRepo.ConsumeView(short)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Once open, the file stays readable after the last view removes it
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	if file.MaxViews > 0 && file.ViewsLeft == 0 {
+		if err := os.Remove(file.FilePath); err != nil {
+			slog.With("error", err).With("path", file.FilePath).Error("failed to delete view limited file from disk")
+		}
+	}
+
+	return file, f, nil
+}
+
+func (s *FileService) DeleteFile(short string, filePath string) error {
+	if err := s.fileRepo.Delete(short); err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		slog.With("error", err).With("path", filePath).Error("failed to delete file from disk")
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFile deletes the file if the deletion token matches the one handed out on creation.
+func (s *FileService) RevokeFile(short, token string) error {
+	file, err := s.fileRepo.GetByShort(short)
+	if err != nil {
+		return err
+	}
+
+	if err := shared.CheckDeletionToken(token, file.DeletionTokenHash); err != nil {
+		return err
+	}
+
+	return s.DeleteFile(short, file.FilePath)
+}
+
+// UploadDir is where uploads are received before they are stored.
+func (s *FileService) UploadDir() string {
+	return s.uploadDir
+}
+
+func (s *FileService) CheckFileExists(short string) (*File, error) {
+	return s.fileRepo.GetByShort(short)
+}
+
+// Card describes the file for link previews without using up a download.
+func (s *FileService) Card(short string) (*shared.Card, error) {
+	file, err := s.fileRepo.GetByShort(short)
+	if err != nil {
+		return nil, err
+	}
+	return fileCard(file), nil
+}
+
+// fileCard shows the name of unencrypted files, encrypted and view limited files get a card that reveals nothing.
+func fileCard(file *File) *shared.Card {
+	fileURL := shared.PublicURL("/f/" + file.Short)
+	if file.Encrypted || file.MaxViews > 0 {
+		card := shared.ProtectedCard(fileURL, file.Encrypted)
+		return &card
+	}
+
+	return &shared.Card{
+		Type:        "link",
+		Title:       file.FileName,
+		Description: fmt.Sprintf("%s, %d bytes", file.ContentType, file.Size),
+		URL:         fileURL,
+	}
+}
@@ -1,10 +1,15 @@
 package img
 
 import (
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/shared"
 )
 
 func (s *ImageService) StartCleanupWorker(interval time.Duration) {
@@ -29,19 +34,19 @@ func (s *ImageService) cleanupOrphanedFiles() {
 
 	deletedCount := 0
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() || shared.UploadInProgress(entry) {
 			continue
 		}
 
+		// Images are stored as {short}{ext} and their variants as {short}.{size}{ext}
 		filename := entry.Name()
-		ext := filepath.Ext(filename)
-		if ext == "" {
+		short, _, ok := strings.Cut(filename, ".")
+		if !ok {
 			continue
 		}
-		short := filename[:len(filename)-len(ext)]
 
-		_, err := s.imageRepo.GetByShort(short)
-		if err != nil {
+		// Only a missing record makes a file an orphan, a failed lookup mustn't delete images
+		if _, err := s.imageRepo.GetByShort(short); errors.Is(err, badger.ErrKeyNotFound) {
 			filePath := filepath.Join(s.uploadDir, filename)
 			if err := os.Remove(filePath); err != nil {
 				slog.With("error", err).With("file", filename).Warn("failed to delete orphaned file")
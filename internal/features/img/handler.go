@@ -1,13 +1,16 @@
 package img
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/piheta/apicore/apierr"
 	"github.com/piheta/apicore/response"
 	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/imagemeta"
 	s "github.com/piheta/seq.re/internal/shared"
 )
 
@@ -31,41 +34,76 @@ func NewImageHandler(imageService *ImageService, templateService *s.TemplateServ
 // @Produce json
 // @Param file formData file true "Image file to upload"
 // @Param encrypted formData bool false "Whether the file is encrypted"
-// @Success 201 {string} string "Image URL"
-// @Failure 400 "Invalid request"
+// @Param password_protected formData bool false "Whether the encryption key is derived from a password"
+// @Param onetime formData bool false "Shorthand for max_views 1"
+// @Param max_views formData int false "Number of views before the image is deleted"
+// @Param expires_in formData string false "Expiry such as 1h, 1d, 30d or never (server default when empty)"
+// @Param keep_metadata formData bool false "Keep the EXIF, XMP and IPTC metadata of unencrypted images, which is removed by default"
+// @Success 201 {object} shared.CreatedResponse "Image URL and expiry time"
+// @Failure 400 "Invalid request or expiry, or a JPEG, PNG or WebP image that can't be parsed to remove its metadata"
+// @Failure 413 "Image larger than the configured maximum upload size"
 // @Failure 500 "Internal server error"
 // @Router /api/images [post]
 func (h *ImageHandler) CreateImage(w http.ResponseWriter, r *http.Request) error {
-	// Parse multipart form (32MB max)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
-		return apierr.NewError(400, "invalid_request", "Failed to parse multipart form")
+	upload, err := s.ReceiveUpload(w, r, h.imageService.UploadDir(), config.Config.MaxUploadSize)
+	if err != nil {
+		return err
 	}
+	// Nothing is left to remove once the service has moved the upload into place
+	defer upload.Remove()
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		return apierr.NewError(400, "invalid_request", "No file provided")
+	return h.StoreUpload(w, r, upload)
+}
+
+// StoreUpload stores a completely received upload as an image, reading its options from the upload fields.
+// Multipart and chunked uploads both end up here.
+func (h *ImageHandler) StoreUpload(w http.ResponseWriter, r *http.Request, upload *s.Upload) error {
+	encrypted := upload.Value("encrypted") == "true"
+	passwordProtected := upload.Value("password_protected") == "true"
+	onetime := upload.Value("onetime") == "true"
+
+	var maxViews int
+	if value := upload.Value("max_views"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return apierr.NewError(400, "validation", "Invalid max_views")
+		}
+		maxViews = n
 	}
-	defer func() {
-		_ = file.Close()
-	}()
 
-	fileData, err := io.ReadAll(file)
+	if passwordProtected && !encrypted {
+		return apierr.NewError(400, "validation", "Password protection requires an encrypted image")
+	}
+
+	expiresIn, err := s.ParseExpiresIn(upload.Value("expires_in"))
 	if err != nil {
-		return apierr.NewError(500, "read_error", "Failed to read file")
+		return err
 	}
 
-	encrypted := r.FormValue("encrypted") == "true"
-	onetime := r.FormValue("onetime") == "true"
+	maxViews, err = s.ViewLimit(maxViews, onetime)
+	if err != nil {
+		return err
+	}
 
-	contentType := http.DetectContentType(fileData)
+	contentType := upload.Sniffed
 	if contentType == "application/octet-stream" {
-		headerType := header.Header.Get("Content-Type")
-		if headerType != "" && headerType != "application/octet-stream" {
-			contentType = headerType
+		if upload.ContentType != "" && upload.ContentType != "application/octet-stream" {
+			contentType = upload.ContentType
+		}
+	}
+
+	// Encrypted images can only be stripped by the client, the server never sees their content
+	if !encrypted && upload.Value("keep_metadata") != "true" {
+		err := h.imageService.StripMetadata(upload.Path)
+		if errors.Is(err, imagemeta.ErrMalformed) {
+			return apierr.NewError(400, "malformed_image", "Image could not be parsed to remove its metadata, upload it with keep_metadata to store it as is")
+		}
+		if err != nil {
+			return err
 		}
 	}
 
-	image, err := h.imageService.CreateImage(fileData, contentType, encrypted, onetime)
+	image, err := h.imageService.StoreImage(upload.Path, contentType, encrypted, passwordProtected, maxViews, expiresIn)
 	if err != nil {
 		return err
 	}
@@ -76,31 +114,40 @@ func (h *ImageHandler) CreateImage(w http.ResponseWriter, r *http.Request) error
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		data := map[string]string{
-			"URL":      imageURL,
-			"ButtonID": "image",
+			"URL":           imageURL,
+			"ButtonID":      "image",
+			"Expires":       s.DescribeExpiry(image.ExpiresAt),
+			"DeletionToken": image.DeletionToken,
 		}
-		if onetime {
-			data["Warning"] = "This link will self-destruct after being viewed once."
+		if warning := s.ViewsWarning(maxViews); warning != "" {
+			data["Warning"] = warning
 		}
 		return h.templateService.RenderResult(w, data)
 	}
 
-	return response.JSON(w, 201, imageURL)
+	return response.JSON(w, 201, s.NewCreatedResponse(imageURL, image.ExpiresAt, image.DeletionToken))
 }
 
 // GetImageByShort retrieves and serves the image file
 // @Summary Get image by short code
-// @Description Returns the raw image file for the given short code (or encrypted data as JSON if encrypted)
+// @Description Returns the raw image file for the given short code (or encrypted data as JSON if encrypted). Raw images support Range and conditional requests.
+// @Description A size such as thumb or preview returns the image scaled down to fit that size, view limited and encrypted images are always returned whole
 // @Tags image
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
+// @Param size path string false "Image size, configured with IMAGE_SIZES"
+// @Param size query string false "Image size, configured with IMAGE_SIZES"
+// @Param raw query bool false "Return the image itself to clients accepting text/html, which otherwise get a page showing it"
 // @Success 200 {file} binary "Image file"
+// @Failure 400 "Unknown image size"
+// @Failure 403 "View limited image requested with cli=true, use /api/images/{short}/reveal"
 // @Failure 404
 // @Failure 422
 // @Router /i/{short} [get]
+// @Router /i/{short}/{size} [get]
 func (h *ImageHandler) GetImageByShort(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !s.IsValidShort(short, config.Config.ImageShortLength) {
 		return s.MapError(w, r, apierr.NewError(422, "validation", "Invalid image code"), h.templateService)
 	}
 
@@ -109,68 +156,211 @@ func (h *ImageHandler) GetImageByShort(w http.ResponseWriter, r *http.Request) e
 		return s.MapError(w, r, apierr.NewError(404, "not_found", "Image not found"), h.templateService)
 	}
 
-	if imageCheck.OneTime && r.URL.Query().Get("cli") != "true" {
+	size := r.PathValue("size")
+	if size == "" {
+		size = r.URL.Query().Get("size")
+	}
+	if size != "" {
+		variant, variantFile, err := h.imageService.GetVariant(short, size)
+		switch {
+		case err == nil:
+			defer func() {
+				_ = variantFile.Close()
+			}()
+			return serveImage(w, r, variant, variantFile)
+		case errors.Is(err, ErrUnknownSize):
+			return s.MapError(w, r, apierr.NewError(400, "validation", "Unknown image size"), h.templateService)
+		case !errors.Is(err, ErrNoVariants):
+			return s.MapError(w, r, apierr.NewError(404, "not_found", "Image not found"), h.templateService)
+		}
+	}
+
+	if imageCheck.MaxViews > 0 {
+		if r.URL.Query().Get("cli") == "true" {
+			return s.RevealRequired("images", short)
+		}
+
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		data := map[string]string{
-			"ID":   short,
-			"Type": "image",
+		data := map[string]any{
+			"ID":                short,
+			"Type":              "image",
+			"PasswordProtected": imageCheck.PasswordProtected,
+			"ViewsLeft":         imageCheck.ViewsLeft,
+			"RevealToken":       s.NewRevealToken("images", short),
+			"Card":              s.ProtectedCard(s.PublicURL("/i/"+short), imageCheck.Encrypted),
 		}
 		return h.templateService.RenderOnetime(w, data)
 	}
 
-	image, imageData, err := h.imageService.GetImage(short)
-	if err != nil {
-		return s.MapError(w, r, apierr.NewError(404, "not_found", "Image not found"), h.templateService)
+	// Encrypted images are decrypted in the browser, which fetches the data from the JSON API
+	if imageCheck.Encrypted && r.URL.Query().Get("cli") != "true" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := map[string]any{
+			"ID":                short,
+			"ContentType":       imageCheck.ContentType,
+			"PasswordProtected": imageCheck.PasswordProtected,
+			"Card":              s.ProtectedCard(s.PublicURL("/i/"+short), true),
+		}
+		return h.templateService.RenderImageDecrypt(w, data)
 	}
 
-	if image.Encrypted {
-		if r.URL.Query().Get("cli") != "true" {
-			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			data := map[string]string{
-				"Data":        string(imageData),
-				"ContentType": image.ContentType,
-			}
-			return h.templateService.RenderImageDecrypt(w, data)
+	// Pages opening the image get a landing page carrying its card, image tags and ?raw=true get the image itself
+	w.Header().Add("Vary", "Accept")
+	if s.WantsHTML(r) && r.URL.Query().Get("cli") != "true" && r.URL.Query().Get("raw") != "true" {
+		card, err := h.imageService.Card(short)
+		if err != nil {
+			return s.MapError(w, r, apierr.NewError(404, "not_found", "Image not found"), h.templateService)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data := map[string]any{
+			"ID":   short,
+			"Card": card,
 		}
-		return response.JSON(w, 200, ImageResponse{Data: string(imageData)})
+		return h.templateService.RenderImageViewer(w, data)
 	}
 
-	w.Header().Set("Content-Type", image.ContentType)
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(imageData)
-	return nil
+	image, imageFile, err := h.imageService.GetImage(short)
+	if err != nil {
+		return s.MapError(w, r, apierr.NewError(404, "not_found", "Image not found"), h.templateService)
+	}
+	defer func() {
+		_ = imageFile.Close()
+	}()
+
+	return serveImage(w, r, image, imageFile)
 }
 
 // RevealOneTimeImage consumes the one-time image and returns the raw image data.
 // @Summary Reveal one-time image
 // @Description Consumes the one-time image and returns the raw image (one-time use only)
 // @Tags image
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token of the interstitial page"
 // @Success 200 {file} binary "Image file"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
 // @Failure 404
 // @Failure 422
 // @Router /api/images/{short}/onetime [post]
 func (h *ImageHandler) RevealOneTimeImage(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !s.IsValidShort(short, config.Config.ImageShortLength) {
 		return h.templateService.RenderError(w, "Invalid image code")
 	}
 
-	image, imageData, err := h.imageService.GetImage(short)
+	if err := s.CheckReveal(r, "images", short); err != nil {
+		return s.MapError(w, r, err, h.templateService)
+	}
+
+	image, imageFile, err := h.imageService.GetImage(short)
 	if err != nil {
 		return h.templateService.RenderError(w, "This one-time link has already been viewed or does not exist.")
 	}
+	defer func() {
+		_ = imageFile.Close()
+	}()
+
+	return serveImage(w, r, image, imageFile)
+}
+
+// StartImageReveal hands out a reveal token without using up a view.
+// @Summary Start revealing an image
+// @Description Returns a reveal token for POST /api/images/{short}/reveal without using up a view, link previews only ever get this far
+// @Tags image
+// @Produce json
+// @Param short path string true "Short code"
+// @Success 200 {object} shared.RevealResponse
+// @Failure 403 "A request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/images/{short}/reveal [get]
+func (h *ImageHandler) StartImageReveal(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.ImageShortLength) {
+		return apierr.NewError(422, "validation", "Invalid image code")
+	}
+
+	image, err := h.imageService.CheckImageExists(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Image not found")
+	}
+
+	return s.StartReveal(w, r, "images", short, image.MaxViews, image.ViewsLeft)
+}
+
+// RevealImage uses up a view of the image and returns it.
+// @Summary Reveal an image
+// @Description Uses up a view of a view limited image and returns the raw image (or encrypted data as JSON if encrypted). Requires the token from GET /api/images/{short}/reveal
+// @Tags image
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token"
+// @Success 200 {file} binary "Image file"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/images/{short}/reveal [post]
+func (h *ImageHandler) RevealImage(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.ImageShortLength) {
+		return apierr.NewError(422, "validation", "Invalid image code")
+	}
 
+	if err := s.CheckReveal(r, "images", short); err != nil {
+		return err
+	}
+
+	image, imageFile, err := h.imageService.GetImage(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Image not found")
+	}
+	defer func() {
+		_ = imageFile.Close()
+	}()
+
+	return serveImage(w, r, image, imageFile)
+}
+
+// DeleteImage deletes a image before it expires.
+// @Summary Delete a image
+// @Description Deletes the image using the deletion token returned when it was created
+// @Tags image
+// @Param short path string true "Short code"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 204 "Image deleted"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/images/{short} [delete]
+func (h *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.ImageShortLength) {
+		return apierr.NewError(422, "validation", "Invalid image code")
+	}
+
+	if err := h.imageService.RevokeImage(short, s.DeletionToken(r)); err != nil {
+		return s.TokenError(err, "Image not found")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// serveImage streams an opened image, encrypted images as base64 JSON and others as they are,
+// with Range and conditional request support.
+func serveImage(w http.ResponseWriter, r *http.Request, image *Image, imageFile *os.File) error {
+	viewsLeft := s.RemainingViews(image.MaxViews, image.ViewsLeft)
 	if image.Encrypted {
-		w.Header().Set("Content-Type", "application/json")
-		return response.JSON(w, 200, ImageResponse{
-			Data: string(imageData), // already base64 encoded
-		})
+		return s.StreamDataJSON(w, 200, imageFile, ImageResponse{PasswordProtected: image.PasswordProtected, ViewsLeft: viewsLeft})
 	}
 
+	if viewsLeft != nil {
+		w.Header().Set(s.ViewsLeftHeader, strconv.Itoa(*viewsLeft))
+	}
 	w.Header().Set("Content-Type", image.ContentType)
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(imageData)
+	http.ServeContent(w, r, "", image.CreatedAt, imageFile)
 	return nil
 }
@@ -3,15 +3,21 @@ package img
 import "time"
 
 type Image struct {
-	Short       string
-	FilePath    string
-	ContentType string
-	Encrypted   bool
-	OneTime     bool
-	CreatedAt   time.Time
-	ExpiresAt   time.Time
+	Short             string
+	FilePath          string
+	ContentType       string
+	Encrypted         bool
+	PasswordProtected bool
+	MaxViews          int // Views allowed in total, 0 for unlimited
+	ViewsLeft         int // Decremented on every view, the image is deleted when it reaches 0
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	DeletionTokenHash string
+	DeletionToken     string `json:"-"` // Returned to the creator once, never stored
 }
 
 type ImageResponse struct {
-	Data string `json:"data"`
+	Data              string `json:"data,omitempty"` // Streamed separately by StreamDataJSON
+	PasswordProtected bool   `json:"password_protected,omitempty"`
+	ViewsLeft         *int   `json:"views_left,omitempty"` // Only set for view limited images
 }
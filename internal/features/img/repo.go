@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/shared"
 )
 
 type ImageRepo struct {
@@ -16,10 +17,19 @@ func NewImageRepo(db *badger.DB) *ImageRepo {
 	return &ImageRepo{db: db}
 }
 
+// Create stores the image under its short code, failing with shared.ErrShortTaken if the code is in use.
 func (r *ImageRepo) Create(image *Image) error {
-	return r.db.Update(func(txn *badger.Txn) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.ImagePrefix, image.Short)
+		if err := shared.EnsureUnused(txn, key); err != nil {
+			return err
+		}
+
 		data, _ := json.Marshal(image)
-		entry := badger.NewEntry([]byte(image.Short), data).WithTTL(time.Until(image.ExpiresAt))
+		entry := badger.NewEntry(key, data)
+		if !image.ExpiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(image.ExpiresAt))
+		}
 		return txn.SetEntry(entry)
 	})
 }
@@ -28,7 +38,7 @@ func (r *ImageRepo) GetByShort(short string) (*Image, error) {
 	var image Image
 
 	err := r.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(short))
+		item, err := txn.Get(shared.Key(shared.ImagePrefix, short))
 		if err != nil {
 			return err
 		}
@@ -44,9 +54,46 @@ func (r *ImageRepo) GetByShort(short string) (*Image, error) {
 
 	return &image, err
 }
+
+// ConsumeView uses up one view of the image in a single transaction, so concurrent readers can't exceed
+// its view limit. The image is deleted with its last view, otherwise it is rewritten with the remaining TTL.
+func (r *ImageRepo) ConsumeView(short string) (*Image, error) {
+	var image Image
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.ImagePrefix, short)
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &image)
+		}); err != nil {
+			return err
+		}
+
+		image.ViewsLeft--
+		if image.ViewsLeft <= 0 {
+			image.ViewsLeft = 0
+			return txn.Delete(key)
+		}
+
+		data, _ := json.Marshal(&image)
+		entry := badger.NewEntry(key, data)
+		entry.ExpiresAt = item.ExpiresAt()
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
 func (r *ImageRepo) Delete(short string) error {
 	return r.db.Update(func(txn *badger.Txn) error {
-		err := txn.Delete([]byte(short))
+		err := txn.Delete(shared.Key(shared.ImagePrefix, short))
 		if errors.Is(err, badger.ErrKeyNotFound) {
 			return nil
 		}
@@ -58,16 +105,13 @@ func (r *ImageRepo) CountImages() (encrypted, unencrypted int, err error) {
 	return encrypted, unencrypted, r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = true
+		opts.Prefix = []byte(shared.ImagePrefix)
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 
-			if len(item.Key()) != 6 {
-				continue
-			}
-
 			_ = item.Value(func(val []byte) error {
 				var image Image
 				if err := json.Unmarshal(val, &image); err != nil || image.FilePath == "" {
@@ -1,20 +1,23 @@
 package img
 
 import (
-	"encoding/base64"
-	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/imagemeta"
 	"github.com/piheta/seq.re/internal/shared"
+	"golang.org/x/sync/singleflight"
 )
 
 type ImageService struct {
 	imageRepo *ImageRepo
 	uploadDir string
+	variants  singleflight.Group // Coalesces concurrent first requests of the same variant
+	decodes   chan struct{}      // Limits how many images are decoded for variants at once
 }
 
 func NewImageService(imageRepo *ImageRepo, uploadDir string) *ImageService {
@@ -24,68 +27,125 @@ func NewImageService(imageRepo *ImageRepo, uploadDir string) *ImageService {
 	return &ImageService{
 		imageRepo: imageRepo,
 		uploadDir: uploadDir,
+		decodes:   make(chan struct{}, maxConcurrentDecodes),
 	}
 }
 
-func (s *ImageService) CreateImage(fileData []byte, contentType string, encrypted bool, onetime bool) (*Image, error) {
-	short := shared.CreateShort()
+// CreateImage stores an image held in memory, uploads are streamed to disk and stored with StoreImage.
+func (s *ImageService) CreateImage(fileData []byte, contentType string, encrypted bool, passwordProtected bool, maxViews int, expiresIn time.Duration) (*Image, error) {
+	tempPath, err := shared.WriteTempUpload(s.uploadDir, fileData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	return s.StoreImage(tempPath, contentType, encrypted, passwordProtected, maxViews, expiresIn)
+}
 
+// StoreImage moves a completely received upload at tempPath into place, it is removed when storing fails.
+func (s *ImageService) StoreImage(tempPath string, contentType string, encrypted bool, passwordProtected bool, maxViews int, expiresIn time.Duration) (*Image, error) {
 	ext := s.getFileExtension(contentType, encrypted)
-	filename := fmt.Sprintf("%s%s", short, ext)
-	filePath := filepath.Join(s.uploadDir, filename)
 
-	if err := os.WriteFile(filePath, fileData, 0600); err != nil {
-		return nil, fmt.Errorf("failed to write file: %w", err)
-	}
+	token, tokenHash := shared.NewDeletionToken()
 
 	image := Image{
-		Short:       short,
-		FilePath:    filePath,
-		ContentType: contentType,
-		Encrypted:   encrypted,
-		OneTime:     onetime,
-		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(7 * 24 * time.Hour),
+		ContentType:       contentType,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ViewsLeft:         maxViews,
+		CreatedAt:         time.Now(),
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+		DeletionTokenHash: tokenHash,
+		DeletionToken:     token,
 	}
 
-	err := s.imageRepo.Create(&image)
+	// The file is named after the short code, so it is only written once the code is claimed
+	err := shared.AllocateShort(config.Config.ImageShortLength, func(short string) error {
+		image.Short = short
+		image.FilePath = filepath.Join(s.uploadDir, short+ext)
+		return s.imageRepo.Create(&image)
+	})
 	if err != nil {
-		_ = os.Remove(filePath)
+		_ = os.Remove(tempPath)
 		return nil, err
 	}
 
+	if err := os.Rename(tempPath, image.FilePath); err != nil {
+		_ = s.imageRepo.Delete(image.Short)
+		_ = os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
 	return &image, nil
 }
 
-func (s *ImageService) GetImage(short string) (*Image, []byte, error) {
+// StripMetadata removes EXIF, XMP and IPTC metadata from the received upload at tempPath. JPEG, PNG and WebP images
+// that can't be parsed fail with imagemeta.ErrMalformed rather than being stored as received, browsers still show
+// many broken files and their metadata would be kept.
+func (s *ImageService) StripMetadata(tempPath string) error {
+	src, err := os.Open(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	tmp, err := shared.CreateTempUpload(s.uploadDir)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	err = imagemeta.Strip(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to strip metadata: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), tempPath); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// GetImage opens the stored image, claiming a view when it is view limited. The caller closes the file.
+func (s *ImageService) GetImage(short string) (*Image, *os.File, error) {
 	image, err := s.imageRepo.GetByShort(short)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	fileData, err := os.ReadFile(image.FilePath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	if image.MaxViews > 0 {
+		// Claim a view atomically, only requests within the view limit get to read the file
+		image, err = s.imageRepo.ConsumeView(short)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	if image.Encrypted {
-		fileData = []byte(base64.StdEncoding.EncodeToString(fileData))
+	// Once open, the file stays readable after the last view removes it
+	f, err := os.Open(image.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	if image.OneTime {
-		if err := s.DeleteImage(short, image.FilePath); err != nil {
-			slog.With("error", err).With("short", short).Error("failed to delete onetime image after retrieval")
-			return nil, nil, errors.New("failed to delete image")
+	if image.MaxViews > 0 && image.ViewsLeft == 0 {
+		if err := os.Remove(image.FilePath); err != nil {
+			slog.With("error", err).With("path", image.FilePath).Error("failed to delete view limited image from disk")
 		}
 	}
 
-	return image, fileData, nil
+	return image, f, nil
 }
 
 func (s *ImageService) DeleteImage(short string, filePath string) error {
 	if err := s.imageRepo.Delete(short); err != nil {
 		return err
 	}
+	s.removeVariants(short)
 
 	if err := os.Remove(filePath); err != nil {
 		slog.With("error", err).With("path", filePath).Error("failed to delete file from disk")
@@ -95,6 +155,25 @@ func (s *ImageService) DeleteImage(short string, filePath string) error {
 	return nil
 }
 
+// RevokeImage deletes the image if the deletion token matches the one handed out on creation.
+func (s *ImageService) RevokeImage(short, token string) error {
+	image, err := s.imageRepo.GetByShort(short)
+	if err != nil {
+		return err
+	}
+
+	if err := shared.CheckDeletionToken(token, image.DeletionTokenHash); err != nil {
+		return err
+	}
+
+	return s.DeleteImage(short, image.FilePath)
+}
+
+// UploadDir is where uploads are received before they are stored.
+func (s *ImageService) UploadDir() string {
+	return s.uploadDir
+}
+
 func (s *ImageService) CheckImageExists(short string) (*Image, error) {
 	return s.imageRepo.GetByShort(short)
 }
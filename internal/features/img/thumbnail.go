@@ -0,0 +1,315 @@
+package img
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Registers the GIF decoder, thumbnails show the first frame
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/imagemeta"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+var (
+	// ErrUnknownSize is returned for image variants that are not configured.
+	ErrUnknownSize = errors.New("unknown image size")
+	// ErrNoVariants is returned for view limited and encrypted images, which only ever serve their original.
+	ErrNoVariants = errors.New("image has no variants")
+)
+
+// maxThumbnailPixels caps the images that are decoded for thumbnails, larger ones are served as they are.
+const maxThumbnailPixels = 40_000_000
+
+// maxConcurrentDecodes caps the images decoded for variants at the same time, a decoded image takes up to
+// 4 bytes per pixel.
+const maxConcurrentDecodes = 2
+
+// thumbnailQuality is the JPEG quality of thumbnails of JPEG images.
+const thumbnailQuality = 85
+
+// GetVariant opens the image scaled down to the configured size, creating the variant on first use.
+// Variants are cached next to the original as {short}.{size}{ext}. Images that already fit, and formats that
+// can't be decoded, are served as the original. The returned image has the content type of the opened file.
+func (s *ImageService) GetVariant(short string, size string) (*Image, *os.File, error) {
+	maxSide, ok := config.Config.ImageSizes[size]
+	if !ok {
+		return nil, nil, ErrUnknownSize
+	}
+
+	original, err := s.imageRepo.GetByShort(short)
+	if err != nil {
+		return nil, nil, err
+	}
+	if original.Encrypted || original.MaxViews > 0 {
+		return nil, nil, ErrNoVariants
+	}
+
+	if variant := s.cachedVariant(original, size); variant != nil {
+		if f, err := os.Open(variant.FilePath); err == nil {
+			return variant, f, nil
+		}
+	}
+
+	// Concurrent first requests wait for a single creation instead of each decoding the original
+	created, err, _ := s.variants.Do(short+"/"+size, func() (any, error) {
+		if variant := s.cachedVariant(original, size); variant != nil {
+			return variant, nil
+		}
+		return s.createVariant(original, size, maxSide)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	variant := *created.(*Image)
+
+	f, err := os.Open(variant.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return &variant, f, nil
+}
+
+// cachedVariant returns the stored variant of an image, nil when it wasn't created yet.
+func (s *ImageService) cachedVariant(original *Image, size string) *Image {
+	for _, format := range variantFormats {
+		variant := *original
+		variant.FilePath = s.variantPath(original.Short, size, format.ext)
+		variant.ContentType = format.contentType
+		if _, err := os.Stat(variant.FilePath); err == nil {
+			return &variant
+		}
+	}
+	return nil
+}
+
+// Card describes the image for link previews without using up a view. Unencrypted images are previewed by
+// their largest variant, encrypted and view limited images get a card that reveals nothing.
+func (s *ImageService) Card(short string) (*shared.Card, error) {
+	stored, err := s.imageRepo.GetByShort(short)
+	if err != nil {
+		return nil, err
+	}
+
+	imageURL := shared.PublicURL("/i/" + short)
+	if stored.Encrypted || stored.MaxViews > 0 {
+		card := shared.ProtectedCard(imageURL, stored.Encrypted)
+		return &card, nil
+	}
+
+	card := shared.Card{Type: "link", Title: "Image", URL: imageURL, Image: imageURL}
+
+	var f *os.File
+	if size := previewSize(); size != "" {
+		if _, f, err = s.GetVariant(short, size); err == nil {
+			card.Image = shared.PublicURL("/i/" + short + "/" + size)
+		}
+	}
+	if f == nil {
+		if f, err = os.Open(stored.FilePath); err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	// Images the server can't decode are still linked, oEmbed only calls them photos with known dimensions
+	if cfg, _, err := image.DecodeConfig(f); err == nil {
+		card.Type, card.Width, card.Height = "photo", cfg.Width, cfg.Height
+	}
+	return &card, nil
+}
+
+// previewSize returns the configured size used for link previews, the preview size or else the largest one.
+func previewSize() string {
+	if _, ok := config.Config.ImageSizes["preview"]; ok {
+		return "preview"
+	}
+
+	var largest string
+	for size, maxSide := range config.Config.ImageSizes {
+		if largest == "" || maxSide > config.Config.ImageSizes[largest] || (maxSide == config.Config.ImageSizes[largest] && size < largest) {
+			largest = size
+		}
+	}
+	return largest
+}
+
+// variantFormat is how a variant is encoded, JPEG images keep their format and everything else becomes a PNG.
+type variantFormat struct {
+	ext         string
+	contentType string
+	encode      func(w io.Writer, img image.Image) error
+}
+
+var variantFormats = []variantFormat{
+	{".jpg", "image/jpeg", func(w io.Writer, img image.Image) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: thumbnailQuality})
+	}},
+	{".png", "image/png", png.Encode},
+}
+
+func (s *ImageService) variantPath(short string, size string, ext string) string {
+	return filepath.Join(s.uploadDir, short+"."+size+ext)
+}
+
+// createVariant scales the original down and stores it, it returns the original when there is nothing to scale.
+func (s *ImageService) createVariant(original *Image, size string, maxSide int) (*Image, error) {
+	src, err := os.Open(original.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	cfg, format, err := image.DecodeConfig(src)
+	if err != nil || max(cfg.Width, cfg.Height) <= maxSide || cfg.Width*cfg.Height > maxThumbnailPixels {
+		return original, nil
+	}
+
+	orientation := uint16(1)
+	if format == "jpeg" {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		orientation = imagemeta.Orientation(src)
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	s.decodes <- struct{}{}
+	defer func() {
+		<-s.decodes
+	}()
+
+	decoded, _, err := image.Decode(src)
+	if err != nil {
+		return original, nil
+	}
+
+	out := variantFormats[1]
+	if format == "jpeg" {
+		out = variantFormats[0]
+	}
+
+	tmp, err := shared.CreateTempUpload(s.uploadDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	err = out.encode(tmp, orient(scaleDown(decoded, maxSide), orientation))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	variant := *original
+	variant.FilePath = s.variantPath(original.Short, size, out.ext)
+	variant.ContentType = out.contentType
+	if err := os.Rename(tmp.Name(), variant.FilePath); err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+	return &variant, nil
+}
+
+// removeVariants deletes the cached variants of an image.
+func (s *ImageService) removeVariants(short string) {
+	variants, _ := filepath.Glob(filepath.Join(s.uploadDir, short+".*.*"))
+	for _, variant := range variants {
+		_ = os.Remove(variant)
+	}
+}
+
+// scaleDown shrinks img so its longest side is maxSide, averaging the pixels each target pixel covers.
+func scaleDown(img image.Image, maxSide int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := maxSide, max(1, srcH*maxSide/srcW)
+	if srcH > srcW {
+		dstW, dstH = max(1, srcW*maxSide/srcH), maxSide
+	}
+
+	// Premultiplied RGBA averages correctly across transparent pixels
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for dy := range dstH {
+		y0, y1 := dy*srcH/dstH, max((dy+1)*srcH/dstH, dy*srcH/dstH+1)
+		for dx := range dstW {
+			x0, x1 := dx*srcW/dstW, max((dx+1)*srcW/dstW, dx*srcW/dstW+1)
+
+			var r, g, b, a, n int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+					a += int(row[i+3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation, variants carry no EXIF data so they are stored upright.
+func orient(img *image.RGBA, orientation uint16) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		// Orientations 5 to 8 are rotated by 90°
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Flipped
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90° counterclockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], img.Pix[img.PixOffset(x, y):img.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -1,14 +1,19 @@
 package link
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/piheta/apicore/apierr"
 	"github.com/piheta/apicore/response"
 	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/domains"
 	s "github.com/piheta/seq.re/internal/shared"
 )
 
@@ -28,16 +33,21 @@ func NewLinkHandler(linkService *LinkService, templateService *s.TemplateService
 // For browser requests, serves an HTML page that handles client-side decryption.
 // For non-browser requests (CLI, curl), performs server-side redirect.
 // @Summary Redirect to original URL
-// @Description Redirects to the original URL associated with the given short code
+// @Description Redirects to the original URL associated with the given short code. `/{short}+` or `?preview=1` show the destination, creation and expiry time and warnings about risky destinations instead, as do links created with preview
 // @Tags link
-// @Param short path string true "Short code"
+// @Param short path string true "Short code, followed by + for the preview page"
+// @Param preview query string false "1 for the preview page"
+// @Success 200 "Preview page"
 // @Success 301 "Redirect to original URL"
+// @Failure 403 "View limited link requested with cli=true, use /api/links/{short}/reveal, or a blocked destination"
 // @Failure 404 "Short code not found"
 // @Router /{short} [get]
 func (h *LinkHandler) RedirectByShort(w http.ResponseWriter, r *http.Request) error {
-	short := r.PathValue("short")
+	// Short codes and aliases never contain a +, so /{short}+ can't clash with one
+	short, preview := strings.CutSuffix(r.PathValue("short"), "+")
+	preview = preview || r.URL.Query().Get("preview") == "1"
 
-	if len(short) != 6 {
+	if !s.IsValidLinkCode(short) {
 		return s.MapError(w, r, apierr.NewError(422, "validation", "Invalid link code"), h.templateService)
 	}
 
@@ -45,12 +55,22 @@ func (h *LinkHandler) RedirectByShort(w http.ResponseWriter, r *http.Request) er
 	if err != nil {
 		return s.MapError(w, r, apierr.NewError(404, "url", "Link not found"), h.templateService)
 	}
+	if err := h.checkDestination(r.Context(), link); err != nil {
+		return s.MapError(w, r, err, h.templateService)
+	}
+
+	if link.MaxViews > 0 {
+		if r.URL.Query().Get("cli") == "true" {
+			return s.RevealRequired("links", short)
+		}
 
-	if link.OneTime && r.URL.Query().Get("cli") != "true" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		data := map[string]string{
-			"ID":   short,
-			"Type": "url",
+		data := map[string]any{
+			"ID":          short,
+			"Type":        "url",
+			"ViewsLeft":   link.ViewsLeft,
+			"RevealToken": s.NewRevealToken("links", short),
+			"Card":        linkCard(link),
 		}
 		return h.templateService.RenderOnetime(w, data)
 	}
@@ -60,15 +80,54 @@ func (h *LinkHandler) RedirectByShort(w http.ResponseWriter, r *http.Request) er
 		return s.MapError(w, r, apierr.NewError(404, "url", "Link not found"), h.templateService)
 	}
 
+	// Link previews aren't clicks, neither is checking the destination before following it
+	if !s.IsUnfurlBot(r) && !preview {
+		if err := h.linkService.RecordClick(link, s.GetIP(r), r.Referer(), r.UserAgent()); err != nil {
+			slog.With("error", err).With("short", short).Warn("failed to record click")
+		}
+	}
+
+	if r.URL.Query().Get("cli") != "true" && (preview || link.Preview) {
+		return h.renderPreview(w, link)
+	}
+
 	if r.URL.Query().Get("cli") != "true" && link.Encrypted {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		data := LinkResponse{URL: link.URL}
+		data := map[string]any{
+			"URL":               link.URL,
+			"PasswordProtected": link.PasswordProtected,
+			"Card":              linkCard(link),
+		}
 		return h.templateService.RenderRedirect(w, data)
 	}
 
 	return response.Redirect(w, r, link.URL)
 }
 
+// renderPreview shows where a link leads without going there. Encrypted destinations are only known to the
+// browser, which decrypts and shows them on the redirect page instead, unchecked for risks.
+func (h *LinkHandler) renderPreview(w http.ResponseWriter, link *Link) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := map[string]any{
+		"URL":     link.URL,
+		"Created": link.CreatedAt.UTC().Format("2006-01-02 15:04 MST"),
+		"Expires": s.DescribeExpiry(link.ExpiresAt),
+		"Card":    linkCard(link),
+	}
+
+	if link.Encrypted {
+		data["PasswordProtected"] = link.PasswordProtected
+		data["Preview"] = true
+		return h.templateService.RenderRedirect(w, data)
+	}
+
+	if u, err := url.Parse(link.URL); err == nil {
+		data["Host"] = u.Hostname()
+	}
+	data["Warnings"] = previewWarnings(link.URL)
+	return h.templateService.RenderLinkPreview(w, data)
+}
+
 // CreateLink creates a new shortened URL.
 // @Summary Create a shortened URL
 // @Description Creates a new shortened URL from the provided original URL
@@ -76,8 +135,10 @@ func (h *LinkHandler) RedirectByShort(w http.ResponseWriter, r *http.Request) er
 // @Accept json
 // @Produce json
 // @Param request body LinkRequest true "Link request with URL to shorten"
-// @Success 201 {string} string "Shortened URL"
-// @Failure 400 "Invalid request or URL format"
+// @Success 201 {object} shared.CreatedResponse "Shortened URL and expiry time"
+// @Failure 400 "Invalid request, URL format, expiry or alias"
+// @Failure 403 "Destination domain is blocked on this server"
+// @Failure 409 "Alias is already taken"
 // @Failure 500 "Internal server error"
 // @Router /api/links [post]
 func (h *LinkHandler) CreateLink(w http.ResponseWriter, r *http.Request) error {
@@ -88,6 +149,10 @@ func (h *LinkHandler) CreateLink(w http.ResponseWriter, r *http.Request) error {
 
 	// Skip URL validation for encrypted links (client encrypts before sending)
 	// For encrypted links, only verify that URL field is not empty
+	if linkReq.PasswordProtected && !linkReq.Encrypted {
+		return apierr.NewError(400, "validation", "Password protection requires an encrypted URL")
+	}
+
 	if linkReq.Encrypted {
 		if linkReq.URL == "" {
 			return apierr.NewError(400, "validation", "URL is required")
@@ -99,9 +164,37 @@ func (h *LinkHandler) CreateLink(w http.ResponseWriter, r *http.Request) error {
 		if err := s.Validate.Struct(&linkReq); err != nil {
 			return err
 		}
+		if err := h.checkDomain(linkReq.URL); err != nil {
+			return err
+		}
+		if err := h.linkService.CheckNewDestination(r.Context(), linkReq.URL); err != nil {
+			return err
+		}
+	}
+
+	expiresIn, err := s.ParseExpiresIn(linkReq.ExpiresIn)
+	if err != nil {
+		return err
 	}
 
-	link, err := h.linkService.CreateLink(linkReq.URL, linkReq.Encrypted, linkReq.OneTime)
+	maxViews, err := s.ViewLimit(linkReq.MaxViews, linkReq.OneTime)
+	if err != nil {
+		return err
+	}
+
+	var link *Link
+	if linkReq.Alias != "" {
+		if err := s.CheckAlias(linkReq.Alias); err != nil {
+			return err
+		}
+
+		link, err = h.linkService.CreateLinkWithAlias(linkReq.Alias, linkReq.URL, linkReq.Encrypted, linkReq.PasswordProtected, maxViews, expiresIn, linkReq.Preview)
+		if errors.Is(err, s.ErrShortTaken) {
+			return apierr.NewError(409, "conflict", "Alias is already taken")
+		}
+	} else {
+		link, err = h.linkService.CreateLink(linkReq.URL, linkReq.Encrypted, linkReq.PasswordProtected, maxViews, expiresIn, linkReq.Preview)
+	}
 	if err != nil {
 		return err
 	}
@@ -112,42 +205,153 @@ func (h *LinkHandler) CreateLink(w http.ResponseWriter, r *http.Request) error {
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		data := map[string]string{
-			"URL":      shortURL,
-			"ButtonID": "url",
+			"URL":           shortURL,
+			"ButtonID":      "url",
+			"Expires":       s.DescribeExpiry(link.ExpiresAt),
+			"DeletionToken": link.DeletionToken,
 		}
-		if linkReq.OneTime {
-			data["Warning"] = "This link will self-destruct after being viewed once."
+		if warning := s.ViewsWarning(maxViews); warning != "" {
+			data["Warning"] = warning
 		}
 		return h.templateService.RenderResult(w, data)
 	}
 
-	return response.JSON(w, 201, shortURL)
+	return response.JSON(w, 201, s.NewCreatedResponse(shortURL, link.ExpiresAt, link.DeletionToken))
 }
 
 // GetLinkByShort retrieves link information by short code.
 // @Summary Get link information
 // @Description Returns the original URL and expiry time associated with the given short code
 // @Tags link
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
 // @Success 200 {object} LinkResponse "Link information"
+// @Failure 403 "View limited link, use /api/links/{short}/reveal, or a blocked destination"
 // @Failure 404
 // @Failure 422
 // @Router /api/links/{short} [get]
 func (h *LinkHandler) GetLinkByShort(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !s.IsValidLinkCode(short) {
 		return s.MapError(w, r, apierr.NewError(422, "validation", "Invalid link code"), h.templateService)
 	}
 
-	link, err := h.linkService.GetLinkByShort(short)
+	link, err := h.linkService.CheckLinkExists(short)
 	if err != nil {
 		return s.MapError(w, r, apierr.NewError(404, "url", "Link not found"), h.templateService)
 	}
+	if err := h.checkDestination(r.Context(), link); err != nil {
+		return s.MapError(w, r, err, h.templateService)
+	}
+	if link.MaxViews > 0 {
+		return s.RevealRequired("links", short)
+	}
+
+	return writeLink(w, link)
+}
+
+// StartLinkReveal hands out a reveal token without using up a view.
+// @Summary Start revealing a link
+// @Description Returns a reveal token for POST /api/links/{short}/reveal without using up a view, link previews only ever get this far
+// @Tags link
+// @Produce json
+// @Param short path string true "Short code"
+// @Success 200 {object} shared.RevealResponse
+// @Failure 403 "A request without this server's origin, a link preview bot or a blocked destination"
+// @Failure 404
+// @Failure 422
+// @Router /api/links/{short}/reveal [get]
+func (h *LinkHandler) StartLinkReveal(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidLinkCode(short) {
+		return apierr.NewError(422, "validation", "Invalid link code")
+	}
+
+	link, err := h.linkService.CheckLinkExists(short)
+	if err != nil {
+		return apierr.NewError(404, "url", "Link not found")
+	}
+	if err := h.checkDestination(r.Context(), link); err != nil {
+		return err
+	}
 
+	return s.StartReveal(w, r, "links", short, link.MaxViews, link.ViewsLeft)
+}
+
+// RevealLink uses up a view of the link and returns its information.
+// @Summary Reveal a link
+// @Description Uses up a view of a view limited link and returns the original URL and expiry time. Requires the token from GET /api/links/{short}/reveal
+// @Tags link
+// @Produce json
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token"
+// @Success 200 {object} LinkResponse "Link information"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin, a link preview bot or a blocked destination"
+// @Failure 404
+// @Failure 422
+// @Router /api/links/{short}/reveal [post]
+func (h *LinkHandler) RevealLink(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidLinkCode(short) {
+		return apierr.NewError(422, "validation", "Invalid link code")
+	}
+
+	if err := s.CheckReveal(r, "links", short); err != nil {
+		return err
+	}
+
+	// Reveal tokens handed out before the domain was blocked mustn't use up a view
+	if link, err := h.linkService.CheckLinkExists(short); err == nil {
+		if err := h.checkDestination(r.Context(), link); err != nil {
+			return err
+		}
+	}
+
+	link, err := h.linkService.GetLinkByShort(short)
+	if err != nil {
+		return apierr.NewError(404, "url", "Link not found")
+	}
+
+	return writeLink(w, link)
+}
+
+// checkDestination applies the domain policy to a stored link and checks where its host resolves to now.
+// Encrypted destinations are unknown to the server.
+func (h *LinkHandler) checkDestination(ctx context.Context, link *Link) error {
+	if link.Encrypted {
+		return nil
+	}
+	if err := h.checkDomain(link.URL); err != nil {
+		return err
+	}
+	return h.linkService.CheckDestinationAddress(ctx, link.URL)
+}
+
+// checkDomain rejects destinations the domain policy doesn't allow.
+func (h *LinkHandler) checkDomain(rawURL string) error {
+	var blocked *domains.BlockedError
+	err := h.linkService.CheckDomain(rawURL)
+	switch {
+	case errors.As(err, &blocked) && blocked.Entry == "":
+		return apierr.NewError(http.StatusForbidden, "blocked_domain", fmt.Sprintf("Only links to allowed domains can be shortened on this server, %s is not one of them", blocked.Host))
+	case errors.As(err, &blocked):
+		return apierr.NewError(http.StatusForbidden, "blocked_domain", fmt.Sprintf("Links to %s are blocked on this server", blocked.Host))
+	case err != nil:
+		return apierr.NewError(http.StatusBadRequest, "validation", "invalid URL")
+	}
+	return nil
+}
+
+// writeLink writes the information of a link for API clients.
+func writeLink(w http.ResponseWriter, link *Link) error {
 	linkResp := LinkResponse{
-		link.URL,
-		link.ExpiresAt,
+		URL:               link.URL,
+		ExpiresAt:         link.ExpiresAt,
+		PasswordProtected: link.PasswordProtected,
+		ViewsLeft:         s.RemainingViews(link.MaxViews, link.ViewsLeft),
+		Preview:           link.Preview,
 	}
 
 	return response.JSON(w, 200, linkResp)
@@ -157,18 +361,30 @@ func (h *LinkHandler) GetLinkByShort(w http.ResponseWriter, r *http.Request) err
 // @Summary Reveal one-time link
 // @Description Consumes the one-time link and returns the URL for redirect (one-time use only)
 // @Tags link
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token of the interstitial page"
 // @Success 200 {string} string "Link content HTML partial"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin, a link preview bot or a blocked destination"
 // @Failure 404
 // @Failure 422
 // @Router /api/links/{short}/onetime [post]
 func (h *LinkHandler) RevealOneTimeLink(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !s.IsValidLinkCode(short) {
 		return h.templateService.RenderError(w, "Invalid link code")
 	}
 
+	if err := s.CheckReveal(r, "links", short); err != nil {
+		return s.MapError(w, r, err, h.templateService)
+	}
+
+	if link, err := h.linkService.CheckLinkExists(short); err == nil {
+		if err := h.checkDestination(r.Context(), link); err != nil {
+			return s.MapError(w, r, err, h.templateService)
+		}
+	}
+
 	link, err := h.linkService.GetLinkByShort(short)
 	if err != nil {
 		return h.templateService.RenderError(w, "This one-time link has already been viewed or does not exist.")
@@ -176,9 +392,64 @@ func (h *LinkHandler) RevealOneTimeLink(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	data := map[string]any{
-		"Type": "url",
-		"Data": link.URL,
+		"Type":              "url",
+		"Data":              link.URL,
+		"PasswordProtected": link.PasswordProtected,
+		"ViewsLeft":         link.ViewsLeft,
 	}
 
 	return h.templateService.RenderOnetimeReveal(w, data)
 }
+
+// DeleteLink deletes a link before it expires.
+// @Summary Delete a link
+// @Description Deletes the link using the deletion token returned when it was created
+// @Tags link
+// @Param short path string true "Short code"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 204 "Link deleted"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/links/{short} [delete]
+func (h *LinkHandler) DeleteLink(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidLinkCode(short) {
+		return apierr.NewError(422, "validation", "Invalid link code")
+	}
+
+	if err := h.linkService.RevokeLink(short, s.DeletionToken(r)); err != nil {
+		return s.TokenError(err, "Link not found")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// GetLinkStats returns the click analytics of a link.
+// @Summary Get link click statistics
+// @Description Returns total clicks, unique visitors, referrer hosts and user agent families. Requires the link's deletion token.
+// @Tags link
+// @Produce json
+// @Param short path string true "Short code or alias"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 200 {object} LinkStatsResponse "Click statistics"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/links/{short}/stats [get]
+func (h *LinkHandler) GetLinkStats(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidLinkCode(short) {
+		return apierr.NewError(422, "validation", "Invalid link code")
+	}
+
+	stats, err := h.linkService.GetStats(short, s.DeletionToken(r))
+	if err != nil {
+		return s.TokenError(err, "Link not found")
+	}
+
+	return response.JSON(w, 200, stats)
+}
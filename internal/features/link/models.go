@@ -3,14 +3,22 @@ package link
 import "time"
 
 type LinkRequest struct {
-	URL       string `json:"url" validate:"required,notprivateip"`
-	Encrypted bool   `json:"encrypted"`
-	OneTime   bool   `json:"onetime"`
+	URL               string `json:"url" validate:"required,notprivateip"`
+	Encrypted         bool   `json:"encrypted"`
+	PasswordProtected bool   `json:"password_protected"`   // Key derived from a passphrase instead of carried in the fragment
+	OneTime           bool   `json:"onetime"`              // Shorthand for max_views 1
+	MaxViews          int    `json:"max_views,omitempty"`  // Link is deleted after this many views
+	ExpiresIn         string `json:"expires_in,omitempty"` // e.g. "1h", "7d", "never"; server default when empty
+	Alias             string `json:"alias,omitempty"`      // Optional vanity code, e.g. "deploy-guide"
+	Preview           bool   `json:"preview"`              // Always show the destination on a preview page instead of redirecting
 }
 
 type LinkResponse struct {
-	URL       string    `json:"url"`
-	ExpiresAt time.Time `json:"expires_at"`
+	URL               string    `json:"url"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	PasswordProtected bool      `json:"password_protected,omitempty"`
+	ViewsLeft         *int      `json:"views_left,omitempty"` // Only set for view limited links
+	Preview           bool      `json:"preview,omitempty"`    // Browsers are shown a preview page instead of being redirected
 }
 
 type RedirectRequest struct {
@@ -18,10 +26,40 @@ type RedirectRequest struct {
 }
 
 type Link struct {
-	Short     string
-	URL       string
-	Encrypted bool
-	OneTime   bool
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	Short             string
+	URL               string
+	Encrypted         bool
+	PasswordProtected bool
+	MaxViews          int  // Views allowed in total, 0 for unlimited
+	ViewsLeft         int  // Decremented on every view, the link is deleted when it reaches 0
+	Preview           bool // Browsers always get the preview page, as if they had asked for /{short}+
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	DeletionTokenHash string
+	DeletionToken     string `json:"-"` // Returned to the creator once, never stored
+}
+
+// LinkStats holds the click counters of a link. Visitors are only counted
+// through salted hashes, no IP addresses are stored.
+type LinkStats struct {
+	Clicks         int
+	UniqueVisitors int
+	Referrers      map[string]int // Referrer host, "direct" when none was sent
+	UserAgents     map[string]int // Coarse family such as "firefox", "curl" or "bot"
+	LastClickAt    time.Time
+}
+
+type LinkStatsResponse struct {
+	Clicks         int            `json:"clicks"`
+	UniqueVisitors int            `json:"unique_visitors"`
+	Referrers      map[string]int `json:"referrers"`
+	UserAgents     map[string]int `json:"user_agents"`
+	LastClickAt    *time.Time     `json:"last_click_at,omitempty"`
+}
+
+// Click is a single anonymised visit of a link.
+type Click struct {
+	VisitorID string
+	Referrer  string
+	UserAgent string
 }
@@ -0,0 +1,77 @@
+package link
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// Hosts of other shorteners, a link to one of them hides where it finally leads
+var shortenerHosts = map[string]bool{
+	"bit.ly":      true,
+	"buff.ly":     true,
+	"cutt.ly":     true,
+	"goo.gl":      true,
+	"is.gd":       true,
+	"ow.ly":       true,
+	"rb.gy":       true,
+	"shorturl.at": true,
+	"t.co":        true,
+	"t.ly":        true,
+	"tiny.cc":     true,
+	"tinyurl.com": true,
+	"v.gd":        true,
+}
+
+// Extensions of files that run when opened
+var executableExtensions = map[string]bool{
+	".apk": true, ".app": true, ".bat": true, ".cmd": true, ".dmg": true, ".exe": true, ".hta": true, ".jar": true,
+	".lnk": true, ".msi": true, ".pkg": true, ".ps1": true, ".scr": true, ".vbs": true,
+}
+
+// previewWarnings lists what looks risky about a destination: schemes other than https, hosts that hide or
+// imitate the real destination, unusual ports and executable downloads.
+func previewWarnings(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return []string{"The destination is not a valid URL."}
+	}
+
+	var warnings []string
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	case "https":
+	case "http":
+		warnings = append(warnings, "The connection to the destination is not encrypted (http), anything sent to it can be read on the way.")
+	default:
+		warnings = append(warnings, fmt.Sprintf("The destination opens a %s: URL rather than a web page.", scheme))
+	}
+
+	// Unicode hosts and a trailing dot open the same site as their ASCII form without it, look at that one
+	host := strings.ToLower(u.Hostname())
+	if normalized, err := shared.NormalizeHost(host); err == nil {
+		host = normalized
+	}
+	if u.User != nil {
+		warnings = append(warnings, fmt.Sprintf("The URL starts with a user name (%s@), the site it actually opens is %s.", u.User.Username(), host))
+	}
+	if net.ParseIP(host) != nil {
+		warnings = append(warnings, "The destination is an IP address instead of a domain name.")
+	}
+	if strings.HasPrefix(host, "xn--") || strings.Contains(host, ".xn--") {
+		warnings = append(warnings, "The domain contains international characters (xn--) that can imitate a familiar one.")
+	}
+	if shortenerHosts[strings.TrimPrefix(host, "www.")] {
+		warnings = append(warnings, fmt.Sprintf("The destination is another link shortener (%s), which hides where it finally leads.", host))
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		warnings = append(warnings, fmt.Sprintf("The destination uses the unusual port %s.", port))
+	}
+	if ext := strings.ToLower(path.Ext(u.Path)); executableExtensions[ext] {
+		warnings = append(warnings, fmt.Sprintf("The destination downloads a %s file, which can run programs on your device.", ext))
+	}
+	return warnings
+}
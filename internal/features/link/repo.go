@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/shared"
 	"time"
 )
 
@@ -15,10 +16,19 @@ func NewLinkRepo(db *badger.DB) *LinkRepo {
 	return &LinkRepo{db: db}
 }
 
+// Create stores the link under its short code, failing with shared.ErrShortTaken if the code is in use.
 func (r *LinkRepo) Create(link *Link) error {
-	return r.db.Update(func(txn *badger.Txn) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.LinkPrefix, link.Short)
+		if err := shared.EnsureUnused(txn, key); err != nil {
+			return err
+		}
+
 		data, _ := json.Marshal(link)
-		entry := badger.NewEntry([]byte(link.Short), data).WithTTL(time.Until(link.ExpiresAt))
+		entry := badger.NewEntry(key, data)
+		if !link.ExpiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(link.ExpiresAt))
+		}
 		return txn.SetEntry(entry)
 	})
 }
@@ -27,7 +37,7 @@ func (r *LinkRepo) GetByShort(short string) (*Link, error) {
 	var link Link
 
 	err := r.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(short))
+		item, err := txn.Get(shared.Key(shared.LinkPrefix, short))
 		if err != nil {
 			return err
 		}
@@ -44,30 +54,136 @@ func (r *LinkRepo) GetByShort(short string) (*Link, error) {
 	return &link, err
 }
 
+// ConsumeView uses up one view of the link in a single transaction, so concurrent readers can't exceed
+// its view limit. The link is deleted with its last view, otherwise it is rewritten with the remaining TTL.
+func (r *LinkRepo) ConsumeView(short string) (*Link, error) {
+	var link Link
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.LinkPrefix, short)
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &link)
+		}); err != nil {
+			return err
+		}
+
+		link.ViewsLeft--
+		if link.ViewsLeft <= 0 {
+			link.ViewsLeft = 0
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+			return txn.Delete(shared.Key(shared.LinkStatsPrefix, short))
+		}
+
+		data, _ := json.Marshal(&link)
+		entry := badger.NewEntry(key, data)
+		entry.ExpiresAt = item.ExpiresAt()
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
 func (r *LinkRepo) Delete(short string) error {
 	return r.db.Update(func(txn *badger.Txn) error {
-		err := txn.Delete([]byte(short))
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			return nil
+		for _, key := range [][]byte{shared.Key(shared.LinkPrefix, short), shared.Key(shared.LinkStatsPrefix, short)} {
+			if err := txn.Delete(key); err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RecordClick adds a click to the link's counters. The first click of a visitor
+// within the visitor id's lifetime counts as a unique visit.
+func (r *LinkRepo) RecordClick(short string, click Click, expiresAt time.Time) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		stats, err := getStats(txn, short)
+		if err != nil {
+			return err
+		}
+
+		stats.add(click)
+		stats.LastClickAt = time.Now()
+
+		visitorKey := shared.Key(shared.LinkVisitorPrefix, short+":"+click.VisitorID)
+		_, err = txn.Get(visitorKey)
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+			stats.UniqueVisitors++
+			// Visitor ids come from a daily salt, they are worthless after two days
+			if err := txn.SetEntry(badger.NewEntry(visitorKey, nil).WithTTL(48 * time.Hour)); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
 		}
+
+		data, _ := json.Marshal(stats)
+		entry := badger.NewEntry(shared.Key(shared.LinkStatsPrefix, short), data)
+		if !expiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(expiresAt))
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+// GetStats returns the click counters of a link. Links without clicks have empty stats.
+func (r *LinkRepo) GetStats(short string) (*LinkStats, error) {
+	var stats *LinkStats
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		var err error
+		stats, err = getStats(txn, short)
 		return err
 	})
+
+	return stats, err
+}
+
+// DailySalt returns today's salt for hashing visitors.
+func (r *LinkRepo) DailySalt() ([]byte, error) {
+	return shared.DailySalt(r.db, time.Now())
+}
+
+func getStats(txn *badger.Txn, short string) (*LinkStats, error) {
+	var stats LinkStats
+
+	item, err := txn.Get(shared.Key(shared.LinkStatsPrefix, short))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return &stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &stats)
+	})
+	return &stats, err
 }
 
 func (r *LinkRepo) CountLinks() (encrypted, unencrypted int, err error) {
 	return encrypted, unencrypted, r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = true
+		opts.Prefix = []byte(shared.LinkPrefix)
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 
-			if len(item.Key()) != 6 {
-				continue
-			}
-
 			_ = item.Value(func(val []byte) error {
 				var link Link
 				if err := json.Unmarshal(val, &link); err != nil || link.URL == "" {
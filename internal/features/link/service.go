@@ -1,37 +1,70 @@
 package link
 
 import (
-	"errors"
-	"log/slog"
+	"context"
+	"net"
+	"net/url"
 	"time"
 
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/domains"
 	"github.com/piheta/seq.re/internal/shared"
 )
 
 type LinkService struct {
 	linkRepo *LinkRepo
+	resolver *net.Resolver   // Looks up destination hosts, nil skips the lookups
+	policy   *domains.Policy // Block and allow lists of destinations, nil allows every host
 }
 
-func NewLinkService(linkRepo *LinkRepo) *LinkService {
-	return &LinkService{linkRepo: linkRepo}
+// NewLinkService creates the link service. With a resolver, destinations whose host resolves to an internal
+// address are rejected, it is enabled with RESOLVE_HOSTS. With a policy, destinations it doesn't allow are rejected.
+func NewLinkService(linkRepo *LinkRepo, resolver *net.Resolver, policy *domains.Policy) *LinkService {
+	return &LinkService{linkRepo: linkRepo, resolver: resolver, policy: policy}
 }
 
-func (s *LinkService) CreateLink(url string, encrypted, onetime bool) (*Link, error) {
-	link := Link{
-		Short:     shared.CreateShort(),
-		URL:       url,
-		Encrypted: encrypted,
-		OneTime:   onetime,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
-	}
+func (s *LinkService) CreateLink(url string, encrypted, passwordProtected bool, maxViews int, expiresIn time.Duration, preview bool) (*Link, error) {
+	link := newLink(url, encrypted, passwordProtected, maxViews, expiresIn, preview)
 
-	err := s.linkRepo.Create(&link)
+	err := shared.AllocateShort(config.Config.LinkShortLength, func(short string) error {
+		link.Short = short
+		return s.linkRepo.Create(link)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &link, nil
+	return link, nil
+}
+
+// CreateLinkWithAlias stores the link under a caller chosen alias. It fails with
+// shared.ErrShortTaken if the alias is already in use.
+func (s *LinkService) CreateLinkWithAlias(alias, url string, encrypted, passwordProtected bool, maxViews int, expiresIn time.Duration, preview bool) (*Link, error) {
+	link := newLink(url, encrypted, passwordProtected, maxViews, expiresIn, preview)
+	link.Short = alias
+
+	if err := s.linkRepo.Create(link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func newLink(url string, encrypted, passwordProtected bool, maxViews int, expiresIn time.Duration, preview bool) *Link {
+	token, tokenHash := shared.NewDeletionToken()
+
+	return &Link{
+		URL:               url,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ViewsLeft:         maxViews,
+		Preview:           preview,
+		CreatedAt:         time.Now(),
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+		DeletionTokenHash: tokenHash,
+		DeletionToken:     token,
+	}
 }
 
 func (s *LinkService) GetLinkByShort(short string) (*Link, error) {
@@ -40,11 +73,9 @@ func (s *LinkService) GetLinkByShort(short string) (*Link, error) {
 		return nil, err
 	}
 
-	if link.OneTime {
-		if err := s.DeleteLink(short); err != nil {
-			slog.With("error", err).With("short", short).Error("failed to delete onetime paste after retrieval")
-			return nil, errors.New("failed to delete paste")
-		}
+	if link.MaxViews > 0 {
+		// Count the view atomically so concurrent requests can't follow it past the view limit
+		return s.linkRepo.ConsumeView(short)
 	}
 
 	return link, nil
@@ -54,6 +85,102 @@ func (s *LinkService) DeleteLink(short string) error {
 	return s.linkRepo.Delete(short)
 }
 
+// RevokeLink deletes the link if the deletion token matches the one handed out on creation.
+func (s *LinkService) RevokeLink(short, token string) error {
+	link, err := s.linkRepo.GetByShort(short)
+	if err != nil {
+		return err
+	}
+
+	if err := shared.CheckDeletionToken(token, link.DeletionTokenHash); err != nil {
+		return err
+	}
+
+	return s.linkRepo.Delete(short)
+}
+
+// CheckNewDestination rejects the destination of a new link if its host doesn't resolve or resolves to an
+// internal address.
+func (s *LinkService) CheckNewDestination(ctx context.Context, url string) error {
+	return shared.CheckResolvedURL(ctx, s.resolver, url)
+}
+
+// CheckDomain returns a *domains.BlockedError when the domain policy doesn't allow the host of a destination. It
+// runs when links are created and again when they are opened, so blocking a domain also stops existing links to it.
+func (s *LinkService) CheckDomain(rawURL string) error {
+	if s.policy == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return s.policy.Check(u.Hostname())
+}
+
+// CheckDestinationAddress rejects the destination of a stored link if its host resolves to an internal address by now.
+func (s *LinkService) CheckDestinationAddress(ctx context.Context, url string) error {
+	return shared.CheckDestinationAddress(ctx, s.resolver, url)
+}
+
 func (s *LinkService) CheckLinkExists(short string) (*Link, error) {
 	return s.linkRepo.GetByShort(short)
 }
+
+// RecordClick counts a visit of the link. The IP and user agent are only used to derive
+// an anonymous visitor id with today's salt and are never stored.
+func (s *LinkService) RecordClick(link *Link, ip, referrer, userAgent string) error {
+	salt, err := s.linkRepo.DailySalt()
+	if err != nil {
+		return err
+	}
+
+	click := Click{
+		VisitorID: shared.HashVisitor(salt, link.Short, ip, userAgent),
+		Referrer:  referrerHost(referrer),
+		UserAgent: userAgentFamily(userAgent),
+	}
+	return s.linkRepo.RecordClick(link.Short, click, link.ExpiresAt)
+}
+
+// GetStats returns the click counters of a link to the holder of its deletion token.
+func (s *LinkService) GetStats(short, token string) (*LinkStatsResponse, error) {
+	link, err := s.linkRepo.GetByShort(short)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := shared.CheckDeletionToken(token, link.DeletionTokenHash); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.linkRepo.GetStats(short)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := stats.response()
+	return &resp, nil
+}
+
+// Card describes the link for link previews without using up a view.
+func (s *LinkService) Card(short string) (*shared.Card, error) {
+	link, err := s.linkRepo.GetByShort(short)
+	if err != nil {
+		return nil, err
+	}
+	return linkCard(link), nil
+}
+
+// linkCard names the destination of plain links, which redirect unfurl bots there anyway. Encrypted and view
+// limited links get a card that reveals nothing.
+func linkCard(link *Link) *shared.Card {
+	linkURL := shared.PublicURL("/" + link.Short)
+	if link.Encrypted || link.MaxViews > 0 {
+		card := shared.ProtectedCard(linkURL, link.Encrypted)
+		return &card
+	}
+
+	return &shared.Card{Type: "link", Title: "Short link", Description: link.URL, URL: linkURL}
+}
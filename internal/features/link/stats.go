@@ -0,0 +1,90 @@
+package link
+
+import (
+	"net/url"
+	"strings"
+)
+
+// maxReferrers bounds the size of a stats record, further hosts are counted as "other".
+const maxReferrers = 50
+
+// referrerHost reduces a Referer header to its host so paths and query strings aren't stored.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return "direct"
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return "other"
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// userAgentFamily maps a User-Agent header to a coarse client family.
+// Order matters, most browsers include the tokens of the ones they imitate.
+func userAgentFamily(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	families := []struct {
+		token  string
+		family string
+	}{
+		{"bot", "bot"},
+		{"crawler", "bot"},
+		{"spider", "bot"},
+		{"curl/", "curl"},
+		{"wget/", "wget"},
+		{"go-http-client", "go"},
+		{"python", "python"},
+		{"edg/", "edge"},
+		{"opr/", "opera"},
+		{"firefox/", "firefox"},
+		{"chrome/", "chrome"},
+		{"safari/", "safari"},
+	}
+
+	for _, f := range families {
+		if strings.Contains(ua, f.token) {
+			return f.family
+		}
+	}
+	return "other"
+}
+
+func (stats *LinkStats) add(click Click) {
+	if stats.Referrers == nil {
+		stats.Referrers = make(map[string]int)
+	}
+	if stats.UserAgents == nil {
+		stats.UserAgents = make(map[string]int)
+	}
+
+	referrer := click.Referrer
+	if _, seen := stats.Referrers[referrer]; !seen && len(stats.Referrers) >= maxReferrers {
+		referrer = "other"
+	}
+
+	stats.Clicks++
+	stats.Referrers[referrer]++
+	stats.UserAgents[click.UserAgent]++
+}
+
+func (stats *LinkStats) response() LinkStatsResponse {
+	resp := LinkStatsResponse{
+		Clicks:         stats.Clicks,
+		UniqueVisitors: stats.UniqueVisitors,
+		Referrers:      stats.Referrers,
+		UserAgents:     stats.UserAgents,
+	}
+	if resp.Referrers == nil {
+		resp.Referrers = map[string]int{}
+	}
+	if resp.UserAgents == nil {
+		resp.UserAgents = map[string]int{}
+	}
+	if !stats.LastClickAt.IsZero() {
+		resp.LastClickAt = &stats.LastClickAt
+	}
+	return resp
+}
@@ -0,0 +1,93 @@
+package oembed
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/apicore/response"
+	s "github.com/piheta/seq.re/internal/shared"
+)
+
+// CardFunc looks up the card of a resource by its short code without using up a view.
+type CardFunc = s.CardFunc
+
+type OEmbedHandler struct {
+	cards map[string]CardFunc // By route prefix such as "p" or "i", "" for links
+}
+
+func NewOEmbedHandler(cards map[string]CardFunc) *OEmbedHandler {
+	return &OEmbedHandler{cards: cards}
+}
+
+// GetOEmbed describes a shared resource for link previews.
+// @Summary Get oEmbed metadata
+// @Description Describes an image, paste, file, secret or link URL of this server for link previews. Encrypted and view limited content gets a card that reveals nothing, and never uses up a view
+// @Tags oembed
+// @Produce json
+// @Param url query string true "URL of the shared resource"
+// @Param format query string false "Response format, only json is supported"
+// @Success 200 {object} OEmbedResponse
+// @Failure 404 "URL does not point to a resource of this server"
+// @Failure 501 "Format not supported"
+// @Router /oembed [get]
+func (h *OEmbedHandler) GetOEmbed(w http.ResponseWriter, r *http.Request) error {
+	if format := r.URL.Query().Get("format"); format != "" && format != "json" {
+		return apierr.NewError(501, "not_implemented", "Only the json format is supported")
+	}
+
+	prefix, short, ok := parseResourceURL(r.URL.Query().Get("url"))
+	if !ok {
+		return apierr.NewError(404, "not_found", "URL does not point to a resource of this server")
+	}
+
+	lookup, ok := h.cards[prefix]
+	if !ok {
+		return apierr.NewError(404, "not_found", "URL does not point to a resource of this server")
+	}
+
+	card, err := lookup(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Resource not found")
+	}
+
+	resp := OEmbedResponse{
+		Type:         card.Type,
+		Version:      "1.0",
+		Title:        card.Title,
+		ProviderName: "seq.re",
+		ProviderURL:  s.PublicURL("/"),
+	}
+	if card.Type == "photo" {
+		resp.URL = card.Image
+		resp.Width = card.Width
+		resp.Height = card.Height
+	}
+
+	return response.JSON(w, 200, resp)
+}
+
+// parseResourceURL splits a URL of this server such as https://seq.re/p/abc into its route prefix and short code.
+// Links have no prefix.
+func parseResourceURL(rawURL string) (prefix string, short string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+
+	server, err := url.Parse(s.PublicURL("/"))
+	if err != nil || !strings.EqualFold(u.Host, server.Host) {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch len(segments) {
+	case 1:
+		return "", segments[0], segments[0] != ""
+	case 2:
+		return segments[0], segments[1], segments[1] != ""
+	default:
+		return "", "", false
+	}
+}
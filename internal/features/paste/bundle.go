@@ -0,0 +1,81 @@
+package paste
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode"
+
+	"github.com/piheta/apicore/apierr"
+)
+
+// maxBundleSize caps all files of a bundle together, the same as the content of a single paste.
+const maxBundleSize = MaxContentSize
+
+// ErrBundleMismatch is returned when updating a bundle with a single file or another paste with a bundle.
+var ErrBundleMismatch = errors.New("bundles can only be updated with bundles")
+
+// validateFiles checks the files of an unencrypted bundle. Names must be unique relative paths, so every file has
+// a raw URL of its own, and all files together must fit the size limit of a single paste.
+func validateFiles(files []PasteFile) error {
+	if len(files) == 0 {
+		return apierr.NewError(400, "validation", "Bundles need at least one file")
+	}
+
+	seen := make(map[string]bool, len(files))
+	size := 0
+	for _, file := range files {
+		if !validFileName(file.Name) {
+			return apierr.NewError(400, "validation", fmt.Sprintf("Invalid file name %q, use a relative path like dir/main.go", file.Name))
+		}
+		if seen[file.Name] {
+			return apierr.NewError(400, "validation", fmt.Sprintf("Duplicate file name %q", file.Name))
+		}
+		seen[file.Name] = true
+
+		size += len(file.Content)
+		if size > maxBundleSize {
+			return apierr.NewError(400, "validation", "Bundles can hold at most 1MB of content")
+		}
+	}
+	return nil
+}
+
+// validFileName accepts slash separated relative paths without "." or ".." elements.
+func validFileName(name string) bool {
+	return fs.ValidPath(name) && name != "." && !strings.ContainsRune(name, '\\') &&
+		!strings.ContainsFunc(name, unicode.IsControl)
+}
+
+// Text returns the content of the revision. Bundles are joined file by file, each under a "==> name <==" line,
+// so revisions of a bundle can be compared like any other paste.
+func (r *Revision) Text() string {
+	if r.Files == nil {
+		return r.Content
+	}
+
+	var b strings.Builder
+	for i, file := range r.Files {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("==> " + file.Name + " <==\n")
+		b.WriteString(file.Content)
+		if !strings.HasSuffix(file.Content, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// viewerData returns what the viewer decrypts and renders: the content of a paste, or the JSON of the files of an
+// unencrypted bundle. Encrypted bundles already are encrypted JSON.
+func viewerData(content string, files []PasteFile) string {
+	if files == nil {
+		return content
+	}
+	data, _ := json.Marshal(files)
+	return string(data)
+}
@@ -0,0 +1,145 @@
+package paste
+
+import (
+	"slices"
+	"strings"
+)
+
+// DiffOp marks whether a line of a diff was kept, added or removed.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = " "
+	DiffInsert DiffOp = "+"
+	DiffDelete DiffOp = "-"
+)
+
+// DiffLine is a single line of a diff between two revisions.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// maxDiffEdits caps how many edits Diff searches for, revisions that differ more are shown as replaced entirely.
+const maxDiffEdits = 1000
+
+// Diff compares two revisions line by line and returns the shortest edit script, found with Myers' algorithm.
+func Diff(from, to string) []DiffLine {
+	a, b := splitLines(from), splitLines(to)
+
+	// Unchanged lines around the edits are common and cheap to strip before searching
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, max(len(a), len(b)))
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{DiffEqual, line})
+	}
+	lines = append(lines, shortestEdit(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{DiffEqual, line})
+	}
+	return lines
+}
+
+// shortestEdit runs the greedy forward search of Myers' algorithm and backtracks through the furthest reaching
+// paths of every step. v is indexed by diagonal k = x - y, trace keeps its diagonals -d..d after step d.
+func shortestEdit(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+				return backtrack(a, b, trace)
+			}
+		}
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+	}
+
+	lines := make([]DiffLine, 0, n+m)
+	for _, line := range a {
+		lines = append(lines, DiffLine{DiffDelete, line})
+	}
+	for _, line := range b {
+		lines = append(lines, DiffLine{DiffInsert, line})
+	}
+	return lines
+}
+
+func backtrack(a, b []string, trace [][]int) []DiffLine {
+	furthest := func(d, k int) int {
+		return trace[d][k+d]
+	}
+
+	var reversed []DiffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		k := x - y
+
+		insert := k == -d || (k != d && furthest(d-1, k-1) < furthest(d-1, k+1))
+		prevK := k - 1
+		if insert {
+			prevK = k + 1
+		}
+		prevX := furthest(d-1, prevK)
+		prevY := prevX - prevK
+
+		// Follow the diagonal back to the edit that started it
+		midX := prevX + 1
+		if insert {
+			midX = prevX
+		}
+		for x > midX {
+			x--
+			y--
+			reversed = append(reversed, DiffLine{DiffEqual, a[x]})
+		}
+		if insert {
+			reversed = append(reversed, DiffLine{DiffInsert, b[prevY]})
+		} else {
+			reversed = append(reversed, DiffLine{DiffDelete, a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, DiffLine{DiffEqual, a[x]})
+	}
+
+	slices.Reverse(reversed)
+	return reversed
+}
+
+// splitLines splits text into lines, a trailing newline doesn't start another line.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
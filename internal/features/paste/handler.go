@@ -1,13 +1,22 @@
 package paste
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"unicode/utf8"
 
+	"github.com/dgraph-io/badger/v4"
 	"github.com/piheta/apicore/apierr"
 	"github.com/piheta/apicore/response"
 	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/languages"
 	"github.com/piheta/seq.re/internal/shared"
 )
 
@@ -25,13 +34,13 @@ func NewPasteHandler(pasteService *PasteService, templateService *shared.Templat
 
 // CreatePaste creates a new text paste
 // @Summary Create a paste
-// @Description Creates a text paste (code, logs, plain text)
+// @Description Creates a text paste (code, logs, plain text), or a bundle of named files. Unencrypted bundles are sent as files, encrypted bundles as content holding the encrypted JSON of their files with bundle set.
 // @Tags paste
 // @Accept json
 // @Produce json
 // @Param paste body CreatePasteRequest true "Paste content and options"
-// @Success 201 {string} string "Paste URL"
-// @Failure 400 "Invalid request"
+// @Success 201 {object} shared.CreatedResponse "Paste URL and expiry time"
+// @Failure 400 "Invalid request or expiry"
 // @Failure 500 "Internal server error"
 // @Router /api/pastes [post]
 func (h *PasteHandler) CreatePaste(w http.ResponseWriter, r *http.Request) error {
@@ -44,7 +53,51 @@ func (h *PasteHandler) CreatePaste(w http.ResponseWriter, r *http.Request) error
 		return apierr.NewError(400, "validation", err.Error())
 	}
 
-	paste, err := h.pasteService.CreatePaste(req.Content, req.Language, req.Encrypted, req.OneTime)
+	// Aliases like "py" are stored as the language they stand for, the validator already rejected unknown ones
+	req.Language = normalizeLanguage(req.Language)
+	for i := range req.Files {
+		req.Files[i].Language = normalizeLanguage(req.Files[i].Language)
+	}
+
+	if req.PasswordProtected && !req.Encrypted {
+		return apierr.NewError(400, "validation", "Password protection requires encrypted content")
+	}
+
+	// Names of encrypted pastes would be stored in plain text, bundles name every file on their own
+	fileName := req.FileName
+	if fileName != "" {
+		if req.Encrypted || req.Bundle || req.Files != nil {
+			return apierr.NewError(400, "validation", "Only unencrypted single file pastes can have a file name")
+		}
+		fileName = shared.SanitizeFileName(fileName)
+	}
+
+	expiresIn, err := shared.ParseExpiresIn(req.ExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	maxViews, err := shared.ViewLimit(req.MaxViews, req.OneTime)
+	if err != nil {
+		return err
+	}
+
+	if req.Editable && maxViews > 0 {
+		return apierr.NewError(400, "validation", "Editable pastes can't be view limited")
+	}
+
+	var paste *Paste
+	switch {
+	case req.Bundle || req.Files != nil:
+		if err := validateBundle(req); err != nil {
+			return err
+		}
+		paste, err = h.pasteService.CreateBundle(req.Files, req.Content, req.Encrypted, req.PasswordProtected, maxViews, expiresIn, req.Editable)
+	case req.Editable:
+		paste, err = h.pasteService.CreateEditablePaste(req.Content, req.Language, fileName, req.Encrypted, req.PasswordProtected, expiresIn)
+	default:
+		paste, err = h.pasteService.CreatePaste(req.Content, req.Language, fileName, req.Encrypted, req.PasswordProtected, maxViews, expiresIn)
+	}
 	if err != nil {
 		return err
 	}
@@ -55,31 +108,99 @@ func (h *PasteHandler) CreatePaste(w http.ResponseWriter, r *http.Request) error
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		data := map[string]string{
-			"URL":      pasteURL,
-			"ButtonID": "code",
+			"URL":           pasteURL,
+			"ButtonID":      "code",
+			"Expires":       shared.DescribeExpiry(paste.ExpiresAt),
+			"DeletionToken": paste.DeletionToken,
 		}
-		if req.OneTime {
-			data["Warning"] = "This link will self-destruct after being viewed once."
+		if warning := shared.ViewsWarning(maxViews); warning != "" {
+			data["Warning"] = warning
 		}
 		return h.templateService.RenderResult(w, data)
 	}
 
-	return response.JSON(w, 201, pasteURL)
+	return writeCreated(w, paste)
+}
+
+// StoreUpload creates a paste from a plain upload, the body of `curl --data-binary @file` or `curl -T file`. Its
+// options are read from the upload fields, the language is detected like for any paste without one.
+func (h *PasteHandler) StoreUpload(w http.ResponseWriter, r *http.Request, upload *shared.Upload) error {
+	if upload.Size > MaxContentSize {
+		return apierr.NewError(413, "too_large", "Pastes can hold at most 1MB, upload larger content as a file")
+	}
+	content, err := os.ReadFile(upload.Path)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		return apierr.NewError(400, "validation", "Pastes must be text, upload binary content as a file")
+	}
+
+	language := upload.Value("language")
+	if language != "" {
+		id, ok := languages.Normalize(language)
+		if !ok {
+			return apierr.NewError(400, "validation", fmt.Sprintf("Unknown language %q, see /api/languages", language))
+		}
+		language = id
+	}
+
+	fileName := upload.Value("filename")
+	if fileName == "" {
+		fileName = upload.FileName
+	}
+	if len(fileName) > 255 {
+		return apierr.NewError(400, "validation", "File name too long")
+	}
+	if fileName != "" {
+		fileName = shared.SanitizeFileName(fileName)
+	}
+
+	var maxViews int
+	if value := upload.Value("max_views"); value != "" {
+		if maxViews, err = strconv.Atoi(value); err != nil {
+			return apierr.NewError(400, "validation", "Invalid max_views")
+		}
+	}
+	maxViews, err = shared.ViewLimit(maxViews, upload.Value("onetime") == "true")
+	if err != nil {
+		return err
+	}
+
+	expiresIn, err := shared.ParseExpiresIn(upload.Value("expires_in"))
+	if err != nil {
+		return err
+	}
+
+	paste, err := h.pasteService.CreatePaste(string(content), language, fileName, false, false, maxViews, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	if upload.Plain {
+		created := shared.NewCreatedResponse(shared.PublicURL("/p/"+paste.Short), paste.ExpiresAt, paste.DeletionToken)
+		return shared.WritePlainCreated(w, created, shared.PublicURL("/api/pastes/"+paste.Short))
+	}
+	return writeCreated(w, paste)
 }
 
 // GetPasteByShort retrieves and serves the paste content
 // @Summary Get paste by short code
-// @Description Returns the paste content as text/plain (or encrypted data as JSON if encrypted)
+// @Description Returns the paste content as text/plain (or as JSON for encrypted pastes and bundles)
 // @Tags paste
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
+// @Param rev query int false "Revision to show, the current one by default"
+// @Param diff query int false "Revision to compare against, shows the changes since then (unencrypted pastes only)"
 // @Success 200 {string} string "Paste content"
-// @Failure 404
+// @Failure 400 "Invalid revision, or a diff of an encrypted paste"
+// @Failure 403 "View limited paste requested with cli=true, use /api/pastes/{short}/reveal"
+// @Failure 404 "Paste or revision not found"
 // @Failure 422
 // @Router /p/{short} [get]
 func (h *PasteHandler) GetPasteByShort(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
 		return shared.MapError(w, r, apierr.NewError(422, "validation", "Invalid paste code"), h.templateService)
 	}
 
@@ -88,11 +209,19 @@ func (h *PasteHandler) GetPasteByShort(w http.ResponseWriter, r *http.Request) e
 		return shared.MapError(w, r, apierr.NewError(404, "not_found", "Paste not found"), h.templateService)
 	}
 
-	if paste.OneTime && r.URL.Query().Get("cli") != "true" {
+	if paste.MaxViews > 0 {
+		if r.URL.Query().Get("cli") == "true" {
+			return shared.RevealRequired("pastes", short)
+		}
+
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		data := map[string]string{
-			"ID":   short,
-			"Type": "code",
+		data := map[string]any{
+			"ID":                short,
+			"Type":              "code",
+			"PasswordProtected": paste.PasswordProtected,
+			"ViewsLeft":         paste.ViewsLeft,
+			"RevealToken":       shared.NewRevealToken("pastes", short),
+			"Card":              pasteCard(paste),
 		}
 		return h.templateService.RenderOnetime(w, data)
 	}
@@ -102,45 +231,229 @@ func (h *PasteHandler) GetPasteByShort(w http.ResponseWriter, r *http.Request) e
 		return shared.MapError(w, r, apierr.NewError(404, "not_found", "Paste not found"), h.templateService)
 	}
 
+	revision, err := revisionParam(r, "rev", paste.CurrentRevision())
+	if err != nil {
+		return shared.MapError(w, r, err, h.templateService)
+	}
+	shown, err := h.pasteService.GetRevision(paste, revision)
+	if err != nil {
+		return shared.MapError(w, r, revisionError(err), h.templateService)
+	}
+
+	var diff []DiffLine
+	diffFrom, err := revisionParam(r, "diff", 0)
+	if err != nil {
+		return shared.MapError(w, r, err, h.templateService)
+	}
+	if diffFrom > 0 {
+		if paste.Encrypted {
+			return shared.MapError(w, r, apierr.NewError(400, "validation", "Encrypted pastes can't be compared on the server"), h.templateService)
+		}
+		from, err := h.pasteService.GetRevision(paste, diffFrom)
+		if err != nil {
+			return shared.MapError(w, r, revisionError(err), h.templateService)
+		}
+		diff = Diff(from.Text(), shown.Text())
+	}
+
 	if r.URL.Query().Get("cli") != "true" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		data := map[string]any{
-			"Type":      "code",
-			"Data":      paste.Content,
-			"Encrypted": paste.Encrypted,
+			"Type":              "code",
+			"Short":             short,
+			"Data":              viewerData(shown.Content, shown.Files),
+			"Bundle":            paste.Bundle,
+			"Encrypted":         paste.Encrypted,
+			"PasswordProtected": paste.PasswordProtected,
 			"Metadata": map[string]string{
 				"Language": paste.Language,
 			},
+			"Revision":     revision,
+			"Revisions":    paste.CurrentRevision(),
+			"PrevRevision": revision - 1,
+			"NextRevision": 0,
+			"Diff":         diff,
+			"ForkedFrom":   paste.ForkedFrom,
+			"Card":         pasteCard(paste),
+		}
+		if revision < paste.CurrentRevision() {
+			data["NextRevision"] = revision + 1
 		}
 		return h.templateService.RenderContentViewer(w, data)
 	}
 
-	if paste.Encrypted {
-		return response.JSON(w, 200, PasteResponse{Data: paste.Content})
+	if diffFrom > 0 {
+		return writeDiff(w, diff)
 	}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(paste.Content))
+	revised := *paste
+	revised.Content, revised.Files = shown.Content, shown.Files
+	return writePaste(w, &revised)
+}
+
+// GetPasteRaw serves the paste as plain text, for curl, wget and raw links.
+// @Summary Get a paste as plain text
+// @Description Returns an unencrypted paste as text/plain under its file name, with an ETag for conditional requests. Bundles are returned file by file, each under a "==> name <==" line.
+// @Tags paste
+// @Param short path string true "Short code"
+// @Param rev query int false "Revision to show, the current one by default"
+// @Success 200 {string} string "Paste content"
+// @Success 304 "Not modified"
+// @Failure 400 "Invalid revision, or an encrypted paste"
+// @Failure 403 "View limited paste, use /api/pastes/{short}/reveal"
+// @Failure 404 "Paste or revision not found"
+// @Failure 422
+// @Router /p/{short}/raw [get]
+func (h *PasteHandler) GetPasteRaw(w http.ResponseWriter, r *http.Request) error {
+	paste, revision, err := h.rawRevision(r)
+	if err != nil {
+		return err
+	}
+
+	serveText(w, r, "text/plain; charset=utf-8", "inline", downloadName(paste), revision.CreatedAt, []byte(revision.Text()))
 	return nil
 }
 
+// DownloadPaste serves the paste as an attachment.
+// @Summary Download a paste
+// @Description Returns an unencrypted paste as an attachment under the file name chosen at creation, or the short code with the extension of its language. Bundles are downloaded as a zip archive of their files.
+// @Tags paste
+// @Param short path string true "Short code"
+// @Param rev query int false "Revision to download, the current one by default"
+// @Success 200 {file} file "Paste content"
+// @Success 304 "Not modified"
+// @Failure 400 "Invalid revision, or an encrypted paste"
+// @Failure 403 "View limited paste, use /api/pastes/{short}/reveal"
+// @Failure 404 "Paste or revision not found"
+// @Failure 422
+// @Router /p/{short}/download [get]
+func (h *PasteHandler) DownloadPaste(w http.ResponseWriter, r *http.Request) error {
+	paste, revision, err := h.rawRevision(r)
+	if err != nil {
+		return err
+	}
+
+	if revision.Files == nil {
+		serveText(w, r, "text/plain; charset=utf-8", "attachment", downloadName(paste), revision.CreatedAt, []byte(revision.Content))
+		return nil
+	}
+
+	archive, err := zipFiles(revision.Files, revision.CreatedAt)
+	if err != nil {
+		return err
+	}
+	serveText(w, r, "application/zip", "attachment", downloadName(paste), revision.CreatedAt, archive)
+	return nil
+}
+
+// GetPasteFile serves a single file of an unencrypted bundle.
+// @Summary Get a file of a paste bundle
+// @Description Returns a file of an unencrypted bundle as text/plain, with an ETag for conditional requests. Encrypted bundles keep their file names encrypted and have no raw URLs per file.
+// @Tags paste
+// @Param short path string true "Short code"
+// @Param name path string true "File name, a relative path like dir/main.go"
+// @Param rev query int false "Revision to show, the current one by default"
+// @Success 200 {string} string "File content"
+// @Success 304 "Not modified"
+// @Failure 400 "Invalid revision, or an encrypted paste"
+// @Failure 403 "View limited bundle, use /api/pastes/{short}/reveal"
+// @Failure 404 "Paste, revision or file not found"
+// @Failure 422
+// @Router /p/{short}/raw/{name} [get]
+func (h *PasteHandler) GetPasteFile(w http.ResponseWriter, r *http.Request) error {
+	_, revision, err := h.rawRevision(r)
+	if err != nil {
+		return err
+	}
+
+	name := r.PathValue("name")
+	for _, file := range revision.Files {
+		if file.Name == name {
+			serveText(w, r, "text/plain; charset=utf-8", "inline", path.Base(file.Name), revision.CreatedAt, []byte(file.Content))
+			return nil
+		}
+	}
+	return apierr.NewError(404, "not_found", "File not found")
+}
+
+// StartPasteReveal hands out a reveal token without using up a view.
+// @Summary Start revealing a paste
+// @Description Returns a reveal token for POST /api/pastes/{short}/reveal without using up a view, link previews only ever get this far
+// @Tags paste
+// @Produce json
+// @Param short path string true "Short code"
+// @Success 200 {object} shared.RevealResponse
+// @Failure 403 "A request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/pastes/{short}/reveal [get]
+func (h *PasteHandler) StartPasteReveal(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
+		return apierr.NewError(422, "validation", "Invalid paste code")
+	}
+
+	paste, err := h.pasteService.CheckPasteExists(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Paste not found")
+	}
+
+	return shared.StartReveal(w, r, "pastes", short, paste.MaxViews, paste.ViewsLeft)
+}
+
+// RevealPaste uses up a view of the paste and returns its content.
+// @Summary Reveal a paste
+// @Description Uses up a view of a view limited paste and returns the content as text/plain (or encrypted data as JSON if encrypted). Requires the token from GET /api/pastes/{short}/reveal
+// @Tags paste
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token"
+// @Success 200 {string} string "Paste content"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/pastes/{short}/reveal [post]
+func (h *PasteHandler) RevealPaste(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
+		return apierr.NewError(422, "validation", "Invalid paste code")
+	}
+
+	if err := shared.CheckReveal(r, "pastes", short); err != nil {
+		return err
+	}
+
+	paste, err := h.pasteService.GetPaste(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Paste not found")
+	}
+
+	return writePaste(w, paste)
+}
+
 // RevealOneTimePaste consumes the one-time paste and returns the content.
 // @Summary Reveal one-time paste
 // @Description Consumes the one-time paste and returns the content (one-time use only)
 // @Tags paste
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token of the interstitial page"
 // @Success 200 {string} string "Paste content HTML partial"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
 // @Failure 404
 // @Failure 422
 // @Router /api/pastes/{short}/onetime [post]
 func (h *PasteHandler) RevealOneTimePaste(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
 		return h.templateService.RenderError(w, "Invalid paste code")
 	}
 
+	if err := shared.CheckReveal(r, "pastes", short); err != nil {
+		return shared.MapError(w, r, err, h.templateService)
+	}
+
 	paste, err := h.pasteService.GetPaste(short)
 	if err != nil {
 		return h.templateService.RenderError(w, "This one-time link has already been viewed or does not exist.")
@@ -148,8 +461,11 @@ func (h *PasteHandler) RevealOneTimePaste(w http.ResponseWriter, r *http.Request
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	data := map[string]any{
-		"Type": "code",
-		"Data": paste.Content,
+		"Type":              "code",
+		"Data":              viewerData(paste.Content, paste.Files),
+		"Bundle":            paste.Bundle,
+		"PasswordProtected": paste.PasswordProtected,
+		"ViewsLeft":         paste.ViewsLeft,
 		"Metadata": map[string]string{
 			"Language": paste.Language,
 		},
@@ -157,3 +473,271 @@ func (h *PasteHandler) RevealOneTimePaste(w http.ResponseWriter, r *http.Request
 
 	return h.templateService.RenderOnetimeReveal(w, data)
 }
+
+// UpdatePaste appends a revision to an editable paste.
+// @Summary Update a paste
+// @Description Replaces the content of a paste created with editable set, keeping the previous content as a revision. Encrypted pastes are updated with content encrypted by the same key, unencrypted bundles with files.
+// @Tags paste
+// @Accept json
+// @Produce json
+// @Param short path string true "Short code"
+// @Param X-Edit-Token header string true "Edit token"
+// @Param paste body UpdatePasteRequest true "New content"
+// @Success 200 {object} RevisionResponse "URL of the new revision"
+// @Failure 400 "Invalid request, or content for an unencrypted bundle"
+// @Failure 403 "Invalid edit token, or a paste that isn't editable"
+// @Failure 404
+// @Failure 409 "Revision limit reached"
+// @Failure 422
+// @Router /api/pastes/{short} [put]
+func (h *PasteHandler) UpdatePaste(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
+		return apierr.NewError(422, "validation", "Invalid paste code")
+	}
+
+	var req UpdatePasteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierr.NewError(400, "invalid_request", "Failed to parse request body")
+	}
+
+	if err := shared.Validate.Struct(req); err != nil {
+		return apierr.NewError(400, "validation", err.Error())
+	}
+
+	if req.Files != nil {
+		if req.Content != "" {
+			return apierr.NewError(400, "validation", "Send either content or files")
+		}
+		if err := validateFiles(req.Files); err != nil {
+			return err
+		}
+		for i := range req.Files {
+			req.Files[i].Language = normalizeLanguage(req.Files[i].Language)
+		}
+	}
+
+	paste, err := h.pasteService.UpdatePaste(short, r.Header.Get(EditTokenHeader), req.Content, req.Files, req.Bundle || req.Files != nil)
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return apierr.NewError(404, "not_found", "Paste not found")
+	case errors.Is(err, ErrInvalidEditToken):
+		return apierr.NewError(403, "forbidden", "Invalid edit token")
+	case errors.Is(err, ErrBundleMismatch):
+		return apierr.NewError(400, "validation", "Bundles can only be updated with bundles, unencrypted ones with files")
+	case errors.Is(err, ErrRevisionLimit):
+		return apierr.NewError(409, "conflict", fmt.Sprintf("Pastes can have at most %d revisions, fork it to keep editing", maxRevisions))
+	case err != nil:
+		return err
+	}
+
+	return response.JSON(w, 200, RevisionResponse{
+		URL:      fmt.Sprintf("%s?rev=%d", shared.PublicURL("/p/"+short), paste.Revision),
+		Revision: paste.Revision,
+	})
+}
+
+// ForkPaste creates a new paste seeded from an existing one.
+// @Summary Fork a paste
+// @Description Creates a new paste with the content, language and encryption of a revision of an existing paste. Forks of encrypted pastes are decrypted with the original key. View limited pastes can't be forked.
+// @Tags paste
+// @Accept json
+// @Produce json
+// @Param short path string true "Short code"
+// @Param rev query int false "Revision to fork, the current one by default"
+// @Param paste body ForkPasteRequest false "Options of the fork"
+// @Success 201 {object} shared.CreatedResponse "Fork URL and expiry time"
+// @Failure 400 "Invalid request, revision or expiry"
+// @Failure 403 "View limited paste"
+// @Failure 404 "Paste or revision not found"
+// @Failure 422
+// @Router /api/pastes/{short}/fork [post]
+func (h *PasteHandler) ForkPaste(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
+		return apierr.NewError(422, "validation", "Invalid paste code")
+	}
+
+	// The body is optional, a bare POST forks with the default expiry
+	var req ForkPasteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return apierr.NewError(400, "invalid_request", "Failed to parse request body")
+	}
+
+	expiresIn, err := shared.ParseExpiresIn(req.ExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	revision, err := revisionParam(r, "rev", 0)
+	if err != nil {
+		return err
+	}
+
+	paste, err := h.pasteService.ForkPaste(short, revision, req.Editable, expiresIn)
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return apierr.NewError(404, "not_found", "Paste not found")
+	case errors.Is(err, ErrViewLimited):
+		return apierr.NewError(403, "forbidden", "View limited pastes can't be forked")
+	case err != nil:
+		return revisionError(err)
+	}
+
+	return writeCreated(w, paste)
+}
+
+// DeletePaste deletes a paste before it expires.
+// @Summary Delete a paste
+// @Description Deletes the paste using the deletion token returned when it was created
+// @Tags paste
+// @Param short path string true "Short code"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 204 "Paste deleted"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/pastes/{short} [delete]
+func (h *PasteHandler) DeletePaste(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
+		return apierr.NewError(422, "validation", "Invalid paste code")
+	}
+
+	if err := h.pasteService.RevokePaste(short, shared.DeletionToken(r)); err != nil {
+		return shared.TokenError(err, "Paste not found")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// rawRevision looks up the revision of an unencrypted paste that raw and download requests ask for. Those requests
+// come from curl and bots alike, so view limited pastes are never served and have to be revealed instead.
+func (h *PasteHandler) rawRevision(r *http.Request) (*Paste, *Revision, error) {
+	short := r.PathValue("short")
+
+	if !shared.IsValidShort(short, config.Config.PasteShortLength) {
+		return nil, nil, apierr.NewError(422, "validation", "Invalid paste code")
+	}
+
+	paste, err := h.pasteService.CheckPasteExists(short)
+	if err != nil {
+		return nil, nil, apierr.NewError(404, "not_found", "Paste not found")
+	}
+	if paste.MaxViews > 0 {
+		return nil, nil, shared.RevealRequired("pastes", short)
+	}
+	if paste.Encrypted {
+		return nil, nil, apierr.NewError(400, "invalid_request", "Encrypted pastes can only be decrypted in the browser or with the CLI")
+	}
+
+	number, err := revisionParam(r, "rev", paste.CurrentRevision())
+	if err != nil {
+		return nil, nil, err
+	}
+	revision, err := h.pasteService.GetRevision(paste, number)
+	if err != nil {
+		return nil, nil, revisionError(err)
+	}
+	return paste, revision, nil
+}
+
+// writePaste writes the paste for API clients, encrypted pastes and bundles as JSON and others as plain text.
+func writePaste(w http.ResponseWriter, paste *Paste) error {
+	viewsLeft := shared.RemainingViews(paste.MaxViews, paste.ViewsLeft)
+	if paste.Encrypted || paste.Bundle {
+		return response.JSON(w, 200, PasteResponse{
+			Data:              paste.Content,
+			Files:             paste.Files,
+			Bundle:            paste.Bundle,
+			PasswordProtected: paste.PasswordProtected,
+			ViewsLeft:         viewsLeft,
+		})
+	}
+
+	if viewsLeft != nil {
+		w.Header().Set(shared.ViewsLeftHeader, strconv.Itoa(*viewsLeft))
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(paste.Content))
+	return nil
+}
+
+// ListLanguages lists the languages of pastes
+// @Summary List paste languages
+// @Description Returns the language registry: IDs for the language of pastes and files, along with the aliases, file extensions and file names that map to them. Unencrypted pastes created without a language get one detected from their file name, shebang or editor modeline.
+// @Tags paste
+// @Produce json
+// @Success 200 {array} languages.Language
+// @Router /api/languages [get]
+func (h *PasteHandler) ListLanguages(w http.ResponseWriter, _ *http.Request) error {
+	return response.JSON(w, 200, languages.All())
+}
+
+// normalizeLanguage returns the ID of a language or alias, "" stays "" to have the language detected.
+func normalizeLanguage(language string) string {
+	if id, ok := languages.Normalize(language); ok {
+		return id
+	}
+	return language
+}
+
+// validateBundle checks a request for a bundle. Unencrypted bundles are sent as files, encrypted ones as content
+// holding the encrypted JSON of their files, which keeps their names encrypted too.
+func validateBundle(req CreatePasteRequest) error {
+	switch {
+	case req.Language != "":
+		return apierr.NewError(400, "validation", "Bundles set the language of every file")
+	case req.Encrypted && req.Files != nil:
+		return apierr.NewError(400, "validation", "Encrypted bundles are sent as content, the encrypted JSON of their files")
+	case req.Encrypted:
+		return nil
+	case req.Content != "":
+		return apierr.NewError(400, "validation", "Unencrypted bundles are sent as files")
+	}
+	return validateFiles(req.Files)
+}
+
+// writeCreated answers a created paste or fork, the edit token is only set for editable pastes.
+func writeCreated(w http.ResponseWriter, paste *Paste) error {
+	created := shared.NewCreatedResponse(shared.PublicURL("/p/"+paste.Short), paste.ExpiresAt, paste.DeletionToken)
+	created.EditToken = paste.EditToken
+	return response.JSON(w, 201, created)
+}
+
+// writeDiff writes a diff for API clients as text, every line prefixed with +, - or a space.
+func writeDiff(w http.ResponseWriter, diff []DiffLine) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	for _, line := range diff {
+		_, _ = io.WriteString(w, string(line.Op)+line.Text+"\n")
+	}
+	return nil
+}
+
+// revisionParam reads a revision number from the query, fallback when it isn't set.
+func revisionParam(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, apierr.NewError(400, "validation", "Invalid revision")
+	}
+	return n, nil
+}
+
+// revisionError maps errors from looking up a revision to API errors.
+func revisionError(err error) error {
+	if errors.Is(err, ErrUnknownRevision) {
+		return apierr.NewError(404, "not_found", "Revision not found")
+	}
+	return err
+}
@@ -2,23 +2,89 @@ package paste
 
 import "time"
 
+// EditTokenHeader carries the edit token on PUT /api/pastes/{short}.
+const EditTokenHeader = "X-Edit-Token"
+
+// MaxContentSize caps the content of a paste, larger plain uploads are stored as files instead.
+const MaxContentSize = 1 << 20
+
 type Paste struct {
-	Short     string
+	Short             string
+	Content           string
+	Language          string      // Optional: an ID of the languages registry, detected from the content when empty
+	FileName          string      // Name of downloads, derived from the short code and language when empty
+	Files             []PasteFile // Files of an unencrypted bundle, Content is empty
+	Bundle            bool        // Encrypted bundles keep their files as encrypted JSON in Content
+	Encrypted         bool
+	PasswordProtected bool
+	MaxViews          int    // Views allowed in total, 0 for unlimited
+	ViewsLeft         int    // Decremented on every view, the paste is deleted when it reaches 0
+	Revision          int    // Number of the current revision, 0 for pastes stored before revisions
+	ForkedFrom        string // Short code of the paste this one was forked from
+	CreatedAt         time.Time
+	UpdatedAt         time.Time // When the current revision was saved, zero for the first revision
+	ExpiresAt         time.Time
+	DeletionTokenHash string
+	DeletionToken     string `json:"-"` // Returned to the creator once, never stored
+	EditTokenHash     string // Empty for pastes that can't be edited
+	EditToken         string `json:"-"` // Returned to the creator once, never stored
+}
+
+// CurrentRevision returns the number of the current revision, revisions are numbered from 1.
+func (p *Paste) CurrentRevision() int {
+	return max(p.Revision, 1)
+}
+
+// Revision is an earlier version of an editable paste, kept until the paste expires or is deleted.
+type Revision struct {
+	Number    int
 	Content   string
-	Language  string // Optional: "go", "python", "json", "markdown", etc.
-	Encrypted bool
-	OneTime   bool
+	Files     []PasteFile
 	CreatedAt time.Time
-	ExpiresAt time.Time
+}
+
+// PasteFile is a named file of a paste bundle. Names are relative paths like "cmd/main.go".
+type PasteFile struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Language string `json:"language,omitempty" validate:"omitempty,language"`
+	Content  string `json:"content" validate:"max=1048576"`
 }
 
 type PasteResponse struct {
-	Data string `json:"data"`
+	Data              string      `json:"data,omitempty"`
+	Files             []PasteFile `json:"files,omitempty"`  // Files of an unencrypted bundle
+	Bundle            bool        `json:"bundle,omitempty"` // Data of an encrypted bundle decrypts to the JSON of its files
+	PasswordProtected bool        `json:"password_protected,omitempty"`
+	ViewsLeft         *int        `json:"views_left,omitempty"` // Only set for view limited pastes
 }
 
 type CreatePasteRequest struct {
-	Content   string `json:"content" validate:"required,max=1048576"` // 1MB max
-	Language  string `json:"language,omitempty" validate:"omitempty,oneof='' javascript python go java rust cpp c csharp typescript php ruby swift kotlin html css sql bash json yaml markdown"`
-	Encrypted bool   `json:"encrypted"`
-	OneTime   bool   `json:"onetime"`
+	Content           string      `json:"content" validate:"required_without=Files,max=1048576"` // 1MB max
+	Files             []PasteFile `json:"files,omitempty" validate:"omitempty,max=100,dive"`     // Creates a bundle of named files instead
+	Bundle            bool        `json:"bundle"`                                                // Encrypted content is the JSON of the files of a bundle
+	Language          string      `json:"language,omitempty" validate:"omitempty,language"`      // ID or alias from GET /api/languages, detected when empty
+	FileName          string      `json:"filename,omitempty" validate:"max=255"`                 // Name of downloads, unencrypted single pastes only
+	Encrypted         bool        `json:"encrypted"`
+	PasswordProtected bool        `json:"password_protected"`   // Key derived from a passphrase instead of carried in the fragment
+	OneTime           bool        `json:"onetime"`              // Shorthand for max_views 1
+	MaxViews          int         `json:"max_views,omitempty"`  // Paste is deleted after this many views
+	ExpiresIn         string      `json:"expires_in,omitempty"` // e.g. "1h", "7d", "never"; server default when empty
+	Editable          bool        `json:"editable"`             // Hand out an edit token for PUT /api/pastes/{short}
+}
+
+type UpdatePasteRequest struct {
+	Content string      `json:"content" validate:"required_without=Files,max=1048576"` // 1MB max
+	Files   []PasteFile `json:"files,omitempty" validate:"omitempty,max=100,dive"`     // Unencrypted bundles are updated with files
+	Bundle  bool        `json:"bundle"`                                                // Encrypted content is the JSON of the files of a bundle
+}
+
+type ForkPasteRequest struct {
+	Editable  bool   `json:"editable"`
+	ExpiresIn string `json:"expires_in,omitempty"` // Server default when empty, forks don't inherit the expiry
+}
+
+// RevisionResponse is returned when a paste is updated, URL points at the new revision.
+type RevisionResponse struct {
+	URL      string `json:"url"`
+	Revision int    `json:"revision"`
 }
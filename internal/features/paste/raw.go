@@ -0,0 +1,62 @@
+package paste
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/piheta/seq.re/internal/languages"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// downloadName returns the file name chosen at creation, or the short code with the extension of the language.
+// Bundles download as a zip archive of their files.
+func downloadName(paste *Paste) string {
+	switch {
+	case paste.Bundle:
+		return paste.Short + ".zip"
+	case paste.FileName != "":
+		return paste.FileName
+	}
+
+	if ext := languages.Extension(paste.Language); ext != "" {
+		return paste.Short + ext
+	}
+	return paste.Short + ".txt"
+}
+
+// serveText writes content under the file name with an ETag of the content, so clients can revalidate instead of
+// downloading a paste again. disposition is "inline" for raw views and "attachment" for downloads.
+func serveText(w http.ResponseWriter, r *http.Request, contentType, disposition, name string, modTime time.Time, content []byte) {
+	sum := sha256.Sum256(content)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", shared.ContentDisposition(disposition, name))
+	w.Header().Set("ETag", `"`+base64.RawURLEncoding.EncodeToString(sum[:18])+`"`)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Pastes are user content served from this origin, never let browsers sniff them into HTML
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(content))
+}
+
+// zipFiles packs the files of a bundle into a zip archive, dated like the revision they belong to.
+func zipFiles(files []PasteFile, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: modTime})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write([]byte(file.Content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
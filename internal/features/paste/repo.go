@@ -3,9 +3,11 @@ package paste
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/shared"
 )
 
 type PasteRepo struct {
@@ -16,10 +18,19 @@ func NewPasteRepo(db *badger.DB) *PasteRepo {
 	return &PasteRepo{db: db}
 }
 
+// Create stores the paste under its short code, failing with shared.ErrShortTaken if the code is in use.
 func (r *PasteRepo) Create(paste *Paste) error {
-	return r.db.Update(func(txn *badger.Txn) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.PastePrefix, paste.Short)
+		if err := shared.EnsureUnused(txn, key); err != nil {
+			return err
+		}
+
 		data, _ := json.Marshal(paste)
-		entry := badger.NewEntry([]byte(paste.Short), data).WithTTL(time.Until(paste.ExpiresAt))
+		entry := badger.NewEntry(key, data)
+		if !paste.ExpiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(paste.ExpiresAt))
+		}
 		return txn.SetEntry(entry)
 	})
 }
@@ -28,7 +39,7 @@ func (r *PasteRepo) GetByShort(short string) (*Paste, error) {
 	var paste Paste
 
 	err := r.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(short))
+		item, err := txn.Get(shared.Key(shared.PastePrefix, short))
 		if err != nil {
 			return err
 		}
@@ -44,33 +55,154 @@ func (r *PasteRepo) GetByShort(short string) (*Paste, error) {
 
 	return &paste, err
 }
+
+// ConsumeView uses up one view of the paste in a single transaction, so concurrent readers can't exceed
+// its view limit. The paste is deleted with its last view, otherwise it is rewritten with the remaining TTL.
+func (r *PasteRepo) ConsumeView(short string) (*Paste, error) {
+	var paste Paste
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.PastePrefix, short)
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &paste)
+		}); err != nil {
+			return err
+		}
+
+		paste.ViewsLeft--
+		if paste.ViewsLeft <= 0 {
+			paste.ViewsLeft = 0
+			return txn.Delete(key)
+		}
+
+		data, _ := json.Marshal(&paste)
+		entry := badger.NewEntry(key, data)
+		entry.ExpiresAt = item.ExpiresAt()
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &paste, nil
+}
+
+// AddRevision replaces the content or files of the paste in a single transaction and keeps the previous content as a
+// revision that expires with the paste. check vets the stored paste before anything is written.
+func (r *PasteRepo) AddRevision(short string, content string, files []PasteFile, check func(*Paste) error) (*Paste, error) {
+	var paste Paste
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.PastePrefix, short)
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &paste)
+		}); err != nil {
+			return err
+		}
+
+		if err := check(&paste); err != nil {
+			return err
+		}
+
+		previous := Revision{Number: paste.CurrentRevision(), Content: paste.Content, Files: paste.Files, CreatedAt: paste.CreatedAt}
+		if !paste.UpdatedAt.IsZero() {
+			previous.CreatedAt = paste.UpdatedAt
+		}
+		revisionData, _ := json.Marshal(&previous)
+		revisionEntry := badger.NewEntry(revisionKey(short, previous.Number), revisionData)
+		revisionEntry.ExpiresAt = item.ExpiresAt()
+		if err := txn.SetEntry(revisionEntry); err != nil {
+			return err
+		}
+
+		paste.Content, paste.Files = content, files
+		paste.Revision = previous.Number + 1
+		paste.UpdatedAt = time.Now()
+
+		data, _ := json.Marshal(&paste)
+		entry := badger.NewEntry(key, data)
+		entry.ExpiresAt = item.ExpiresAt()
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &paste, nil
+}
+
+// GetRevision returns an earlier revision of the paste, the current one is the paste itself.
+func (r *PasteRepo) GetRevision(short string, number int) (*Revision, error) {
+	var revision Revision
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(revisionKey(short, number))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &revision)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// Delete removes the paste along with its earlier revisions.
 func (r *PasteRepo) Delete(short string) error {
 	return r.db.Update(func(txn *badger.Txn) error {
-		err := txn.Delete([]byte(short))
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			return nil
+		keys := [][]byte{shared.Key(shared.PastePrefix, short)}
+
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = shared.Key(shared.PasteRevisionPrefix, short+":")
+		it := txn.NewIterator(opts)
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
 		}
-		return err
+		it.Close()
+
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+		}
+		return nil
 	})
 }
 
+func revisionKey(short string, number int) []byte {
+	return shared.Key(shared.PasteRevisionPrefix, short+":"+strconv.Itoa(number))
+}
+
 func (r *PasteRepo) CountPastes() (encrypted, unencrypted int, err error) {
 	return encrypted, unencrypted, r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = true
+		opts.Prefix = []byte(shared.PastePrefix)
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 
-			if len(item.Key()) != 6 {
-				continue
-			}
-
 			_ = item.Value(func(val []byte) error {
 				var paste Paste
-				if err := json.Unmarshal(val, &paste); err != nil || paste.Content == "" {
+				if err := json.Unmarshal(val, &paste); err != nil || (paste.Content == "" && paste.Files == nil) {
 					return err
 				}
 
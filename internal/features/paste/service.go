@@ -2,12 +2,31 @@ package paste
 
 import (
 	"errors"
-	"log/slog"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/languages"
 	"github.com/piheta/seq.re/internal/shared"
 )
 
+// maxRevisions caps how often a paste can be edited, every revision is stored until the paste expires.
+const maxRevisions = 100
+
+var (
+	// ErrInvalidEditToken is returned when updating a paste without its edit token, or one that isn't editable.
+	ErrInvalidEditToken = errors.New("invalid edit token")
+	// ErrRevisionLimit is returned when a paste already has maxRevisions revisions.
+	ErrRevisionLimit = errors.New("revision limit reached")
+	// ErrUnknownRevision is returned for revision numbers the paste never had.
+	ErrUnknownRevision = errors.New("unknown revision")
+	// ErrViewLimited is returned when forking a view limited paste.
+	ErrViewLimited = errors.New("paste is view limited")
+)
+
 type PasteService struct {
 	pasteRepo *PasteRepo
 }
@@ -18,23 +37,138 @@ func NewPasteService(pasteRepo *PasteRepo) *PasteService {
 	}
 }
 
-func (s *PasteService) CreatePaste(content string, language string, encrypted bool, onetime bool) (*Paste, error) {
+func (s *PasteService) CreatePaste(content string, language string, fileName string, encrypted bool, passwordProtected bool, maxViews int, expiresIn time.Duration) (*Paste, error) {
 	paste := Paste{
-		Short:     shared.CreateShort(),
-		Content:   content,
-		Language:  language,
-		Encrypted: encrypted,
-		OneTime:   onetime,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+		Content:           content,
+		Language:          language,
+		FileName:          fileName,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ViewsLeft:         maxViews,
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
 	}
 
-	err := s.pasteRepo.Create(&paste)
+	return s.create(&paste)
+}
+
+// CreateEditablePaste creates a paste along with an edit token that allows appending revisions. Editable pastes
+// can't be view limited, every revision would otherwise need views of its own.
+func (s *PasteService) CreateEditablePaste(content string, language string, fileName string, encrypted bool, passwordProtected bool, expiresIn time.Duration) (*Paste, error) {
+	paste := Paste{
+		Content:           content,
+		Language:          language,
+		FileName:          fileName,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+	}
+	paste.EditToken, paste.EditTokenHash = shared.NewDeletionToken()
+
+	return s.create(&paste)
+}
+
+// CreateBundle creates a paste of several named files. Unencrypted bundles are stored as files, encrypted ones as
+// content holding the encrypted JSON of their files, so the server never learns the file names.
+func (s *PasteService) CreateBundle(files []PasteFile, content string, encrypted bool, passwordProtected bool, maxViews int, expiresIn time.Duration, editable bool) (*Paste, error) {
+	paste := Paste{
+		Bundle:            true,
+		Encrypted:         encrypted,
+		PasswordProtected: passwordProtected,
+		MaxViews:          maxViews,
+		ViewsLeft:         maxViews,
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+	}
+	if encrypted {
+		paste.Content = content
+	} else {
+		paste.Files = files
+	}
+	if editable {
+		paste.EditToken, paste.EditTokenHash = shared.NewDeletionToken()
+	}
+
+	return s.create(&paste)
+}
+
+// ForkPaste creates a new paste seeded from a revision of an existing one, 0 for its current revision. The fork
+// keeps the language, file name and encryption of the original, encrypted forks are read with the original key.
+func (s *PasteService) ForkPaste(short string, revision int, editable bool, expiresIn time.Duration) (*Paste, error) {
+	original, err := s.pasteRepo.GetByShort(short)
 	if err != nil {
 		return nil, err
 	}
+	if original.MaxViews > 0 {
+		// Reading a view limited paste without using up a view would defeat the limit
+		return nil, ErrViewLimited
+	}
 
-	return &paste, nil
+	if revision == 0 {
+		revision = original.CurrentRevision()
+	}
+	forked, err := s.GetRevision(original, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	paste := Paste{
+		Content:           forked.Content,
+		Files:             forked.Files,
+		Bundle:            original.Bundle,
+		Language:          original.Language,
+		FileName:          original.FileName,
+		Encrypted:         original.Encrypted,
+		PasswordProtected: original.PasswordProtected,
+		ForkedFrom:        short,
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+	}
+	if editable {
+		paste.EditToken, paste.EditTokenHash = shared.NewDeletionToken()
+	}
+
+	return s.create(&paste)
+}
+
+func (s *PasteService) create(paste *Paste) (*Paste, error) {
+	paste.CreatedAt = time.Now()
+	paste.Language = detectLanguage(paste.Language, paste.FileName, paste.Content, paste.Encrypted)
+	paste.Files = detectFileLanguages(paste.Files)
+	paste.DeletionToken, paste.DeletionTokenHash = shared.NewDeletionToken()
+
+	err := shared.AllocateShort(config.Config.PasteShortLength, func(short string) error {
+		paste.Short = short
+		return s.pasteRepo.Create(paste)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paste, nil
+}
+
+// detectLanguage detects the language of unencrypted content created without one by its file name, shebang or
+// editor modeline. Encrypted content can only be detected by clients.
+func detectLanguage(language, fileName, content string, encrypted bool) string {
+	if language != "" || encrypted {
+		return language
+	}
+	if id := languages.FromFileName(fileName); id != "" {
+		return id
+	}
+	return languages.Detect(content)
+}
+
+// detectFileLanguages returns a copy of the files of a bundle, with the language detected for files without one.
+func detectFileLanguages(files []PasteFile) []PasteFile {
+	if files == nil {
+		return nil
+	}
+
+	files = slices.Clone(files)
+	for i := range files {
+		files[i].Language = detectLanguage(files[i].Language, files[i].Name, files[i].Content, false)
+	}
+	return files
 }
 
 func (s *PasteService) GetPaste(short string) (*Paste, error) {
@@ -43,20 +177,113 @@ func (s *PasteService) GetPaste(short string) (*Paste, error) {
 		return nil, err
 	}
 
-	if paste.OneTime {
-		if err := s.DeletePaste(short); err != nil {
-			slog.With("error", err).With("short", short).Error("failed to delete onetime paste after retrieval")
-			return nil, errors.New("failed to delete paste")
-		}
+	if paste.MaxViews > 0 {
+		// Count the view atomically so concurrent requests can't read past the view limit
+		return s.pasteRepo.ConsumeView(short)
 	}
 
 	return paste, nil
 }
 
+// UpdatePaste appends a revision with the new content if the edit token matches the one handed out on creation.
+// Bundles stay bundles: unencrypted ones are updated with files, encrypted ones with the encrypted JSON of their
+// files as content and bundle set.
+func (s *PasteService) UpdatePaste(short, token, content string, files []PasteFile, bundle bool) (*Paste, error) {
+	return s.pasteRepo.AddRevision(short, content, detectFileLanguages(files), func(paste *Paste) error {
+		if err := shared.CheckDeletionToken(token, paste.EditTokenHash); err != nil {
+			return ErrInvalidEditToken
+		}
+		if paste.Bundle != bundle || (paste.Bundle && !paste.Encrypted) != (files != nil) {
+			return ErrBundleMismatch
+		}
+		if paste.CurrentRevision() >= maxRevisions {
+			return ErrRevisionLimit
+		}
+		return nil
+	})
+}
+
+// GetRevision returns a revision of the paste without using up a view.
+func (s *PasteService) GetRevision(paste *Paste, number int) (*Revision, error) {
+	switch {
+	case number == paste.CurrentRevision():
+		current := &Revision{Number: number, Content: paste.Content, Files: paste.Files, CreatedAt: paste.CreatedAt}
+		if !paste.UpdatedAt.IsZero() {
+			current.CreatedAt = paste.UpdatedAt
+		}
+		return current, nil
+	case number < 1 || number > paste.CurrentRevision():
+		return nil, ErrUnknownRevision
+	}
+
+	revision, err := s.pasteRepo.GetRevision(paste.Short, number)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrUnknownRevision
+	}
+	return revision, err
+}
+
 func (s *PasteService) DeletePaste(short string) error {
 	return s.pasteRepo.Delete(short)
 }
 
+// RevokePaste deletes the paste if the deletion token matches the one handed out on creation.
+func (s *PasteService) RevokePaste(short, token string) error {
+	paste, err := s.pasteRepo.GetByShort(short)
+	if err != nil {
+		return err
+	}
+
+	if err := shared.CheckDeletionToken(token, paste.DeletionTokenHash); err != nil {
+		return err
+	}
+
+	return s.pasteRepo.Delete(short)
+}
+
 func (s *PasteService) CheckPasteExists(short string) (*Paste, error) {
 	return s.pasteRepo.GetByShort(short)
 }
+
+// Card describes the paste for link previews without using up a view.
+func (s *PasteService) Card(short string) (*shared.Card, error) {
+	paste, err := s.pasteRepo.GetByShort(short)
+	if err != nil {
+		return nil, err
+	}
+	return pasteCard(paste), nil
+}
+
+// pasteCard shows the language and first lines of unencrypted pastes, or the file names of bundles. Encrypted and view limited pastes get a
+// card that reveals nothing.
+func pasteCard(paste *Paste) *shared.Card {
+	pasteURL := shared.PublicURL("/p/" + paste.Short)
+	if paste.Encrypted || paste.MaxViews > 0 {
+		card := shared.ProtectedCard(pasteURL, paste.Encrypted)
+		return &card
+	}
+
+	if paste.Bundle {
+		names := make([]string, len(paste.Files))
+		for i, file := range paste.Files {
+			names[i] = file.Name
+		}
+		return &shared.Card{
+			Type:        "link",
+			Title:       fmt.Sprintf("Paste (%d files)", len(paste.Files)),
+			Description: shared.Excerpt(strings.Join(names, "\n")),
+			URL:         pasteURL,
+		}
+	}
+
+	title := "Paste"
+	if paste.Language != "" {
+		title = fmt.Sprintf("Paste (%s)", paste.Language)
+	}
+	return &shared.Card{
+		Type:        "link",
+		Title:       title,
+		Description: shared.Excerpt(paste.Content),
+		URL:         pasteURL,
+	}
+}
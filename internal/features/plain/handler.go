@@ -0,0 +1,103 @@
+package plain
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"unicode/utf8"
+
+	s "github.com/piheta/seq.re/internal/shared"
+)
+
+// Target stores plain uploads of one kind, the way it stores multipart and chunked uploads.
+type Target struct {
+	MaxSize int64
+	Store   func(w http.ResponseWriter, r *http.Request, upload *s.Upload) error
+}
+
+// PlainHandler creates pastes and files from raw request bodies, for hosts that have curl but not the CLI.
+type PlainHandler struct {
+	dir   string // Bodies are received here, it must be the upload directory of the file target
+	paste Target
+	file  Target
+}
+
+func NewPlainHandler(dir string, paste, file Target) *PlainHandler {
+	return &PlainHandler{dir: dir, paste: paste, file: file}
+}
+
+// Upload creates a paste from a text body and a file from anything else
+// @Summary Upload a raw body
+// @Description Creates a paste from text of up to 1MB and a file from larger or binary bodies, as sent by `curl --data-binary @file https://seq.re/` or `curl -T file https://seq.re/`. Options come from query parameters or headers. Answers the URL in plain text, followed by the expiry and a command that deletes it, the deletion token is also sent in X-Deletion-Token
+// @Tags plain
+// @Accept octet-stream
+// @Produce plain
+// @Param name path string false "File name, appended by curl -T"
+// @Param expires query string false "Expiry such as 1h, 1d, 30d or never, or the X-Expires header"
+// @Param views query int false "Number of views before deletion, or the X-Views header"
+// @Param onetime query bool false "Shorthand for views=1, or the X-Onetime header"
+// @Param language query string false "Language of pastes, detected when empty, or the X-Language header"
+// @Param filename query string false "Name of downloads, or the X-Filename header"
+// @Success 201 {string} string "URL, expiry and deletion command"
+// @Failure 400 "Empty body or invalid options"
+// @Failure 413 "Body larger than the configured maximum upload size"
+// @Router / [post]
+// @Router /{name} [put]
+func (h *PlainHandler) Upload(w http.ResponseWriter, r *http.Request) error {
+	upload, err := s.ReceiveBody(w, r, h.dir, h.file.MaxSize)
+	if err != nil {
+		return err
+	}
+	defer upload.Remove()
+
+	if upload.Size <= h.paste.MaxSize && isText(upload.Path) {
+		return h.paste.Store(w, r, upload)
+	}
+	return h.file.Store(w, r, upload)
+}
+
+// UploadPaste creates a paste from the body
+// @Summary Upload a raw body as a paste
+// @Description Creates a paste from a text body of up to 1MB, as sent by `cat log | curl -T - https://seq.re/p`. Takes the options of POST /
+// @Tags plain
+// @Accept plain
+// @Produce plain
+// @Success 201 {string} string "URL, expiry and deletion command"
+// @Failure 400 "Empty or binary body, or invalid options"
+// @Failure 413 "Body larger than 1MB"
+// @Router /p [put]
+func (h *PlainHandler) UploadPaste(w http.ResponseWriter, r *http.Request) error {
+	upload, err := s.ReceiveBody(w, r, h.dir, h.paste.MaxSize)
+	if err != nil {
+		return err
+	}
+	defer upload.Remove()
+
+	return h.paste.Store(w, r, upload)
+}
+
+// UploadFile creates a file from the body
+// @Summary Upload a raw body as a file
+// @Description Creates a file from the body, text or not, as sent by `curl -T build.tar.gz https://seq.re/f/`. Takes the options of POST /
+// @Tags plain
+// @Accept octet-stream
+// @Produce plain
+// @Success 201 {string} string "URL, expiry and deletion command"
+// @Failure 400 "Empty body or invalid options"
+// @Failure 413 "Body larger than the configured maximum upload size"
+// @Router /f [put]
+func (h *PlainHandler) UploadFile(w http.ResponseWriter, r *http.Request) error {
+	upload, err := s.ReceiveBody(w, r, h.dir, h.file.MaxSize)
+	if err != nil {
+		return err
+	}
+	defer upload.Remove()
+
+	return h.file.Store(w, r, upload)
+}
+
+// isText reports whether the received body reads as UTF-8 text, the same test the CLI applies before pasting files.
+func isText(path string) bool {
+	content, err := os.ReadFile(path) // #nosec G304 -- Temporary file created by ReceiveBody
+	return err == nil && utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
@@ -0,0 +1,97 @@
+package qr
+
+import (
+	"net/http"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/seq.re/internal/qrcode"
+	s "github.com/piheta/seq.re/internal/shared"
+)
+
+// pngScale is the size of a module in PNG codes, large enough to print.
+const pngScale = 8
+
+type QRHandler struct {
+	cards map[string]s.CardFunc // By route prefix such as "p" or "i", "" for links
+}
+
+func NewQRHandler(cards map[string]s.CardFunc) *QRHandler {
+	return &QRHandler{cards: cards}
+}
+
+// GetQRCode renders the URL of a link as a QR code
+// @Summary Get the QR code of a link
+// @Description Renders the short URL of a link as a PNG or SVG QR code. Encrypted links are refused, their key lives in the URL fragment the server never sees, so their codes are generated by the web UI or `seqre url --qr`
+// @Tags qr
+// @Produce png
+// @Produce image/svg+xml
+// @Param short path string true "Short code"
+// @Param format query string false "png (default) or svg"
+// @Success 200 {file} binary "QR code"
+// @Failure 400 "Encrypted resource or unknown format"
+// @Failure 404 "Resource not found"
+// @Router /qr/{short} [get]
+func (h *QRHandler) GetQRCode(w http.ResponseWriter, r *http.Request) error {
+	return h.render(w, r, "", r.PathValue("short"))
+}
+
+// GetResourceQRCode renders the URL of an image, paste, file or secret as a QR code
+// @Summary Get the QR code of a shared resource
+// @Description Renders the URL of an image (i), paste (p), file (f) or secret (s) as a PNG or SVG QR code. Encrypted resources, secrets included, are refused since their key lives in the URL fragment
+// @Tags qr
+// @Produce png
+// @Produce image/svg+xml
+// @Param kind path string true "Route prefix: i, p, f or s"
+// @Param short path string true "Short code"
+// @Param format query string false "png (default) or svg"
+// @Success 200 {file} binary "QR code"
+// @Failure 400 "Encrypted resource or unknown format"
+// @Failure 404 "Resource not found"
+// @Router /qr/{kind}/{short} [get]
+func (h *QRHandler) GetResourceQRCode(w http.ResponseWriter, r *http.Request) error {
+	kind := r.PathValue("kind")
+	if kind == "" {
+		return apierr.NewError(404, "not_found", "Resource not found")
+	}
+	return h.render(w, r, kind, r.PathValue("short"))
+}
+
+func (h *QRHandler) render(w http.ResponseWriter, r *http.Request, kind, short string) error {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "png" && format != "svg" {
+		return apierr.NewError(400, "invalid_request", "Format must be png or svg")
+	}
+
+	lookup, ok := h.cards[kind]
+	if !ok {
+		return apierr.NewError(404, "not_found", "Resource not found")
+	}
+	card, err := lookup(short)
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Resource not found")
+	}
+	// Without the key in the fragment the code would only lead to ciphertext
+	if card.Encrypted {
+		return apierr.NewError(400, "encrypted", "QR codes of encrypted content are generated in the browser or the CLI, the server never sees the key")
+	}
+
+	code, err := qrcode.Encode(card.URL)
+	if err != nil {
+		return apierr.NewError(500, "internal_error", "Failed to generate QR code")
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		_, err = w.Write(code.SVG())
+		return err
+	}
+
+	image, err := code.PNG(pngScale)
+	if err != nil {
+		return apierr.NewError(500, "internal_error", "Failed to generate QR code")
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, err = w.Write(image)
+	return err
+}
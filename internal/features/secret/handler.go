@@ -30,8 +30,8 @@ func NewSecretHandler(secretService *SecretService, templateService *s.TemplateS
 // @Accept json
 // @Produce json
 // @Param request body SecretRequest true "Secret request with URL to shorten"
-// @Success 201 {string} string "Shortened URL"
-// @Failure 400 "Invalid request or URL format"
+// @Success 201 {object} shared.CreatedResponse "Secret URL and expiry time"
+// @Failure 400 "Invalid request or expiry"
 // @Failure 500 "Internal server error"
 // @Router /api/secrets [post]
 func (h *SecretHandler) CreateSecret(w http.ResponseWriter, r *http.Request) error {
@@ -44,7 +44,18 @@ func (h *SecretHandler) CreateSecret(w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	secret, err := h.secretService.CreateSecret(secretReq.Data)
+	expiresIn, err := s.ParseExpiresIn(secretReq.ExpiresIn)
+	if err != nil {
+		return err
+	}
+
+	// Secrets are always view limited, a single view unless asked otherwise
+	maxViews, err := s.ViewLimit(secretReq.MaxViews, true)
+	if err != nil {
+		return err
+	}
+
+	secret, err := h.secretService.CreateSecret(secretReq.Data, maxViews, expiresIn)
 	if err != nil {
 		return err
 	}
@@ -55,54 +66,112 @@ func (h *SecretHandler) CreateSecret(w http.ResponseWriter, r *http.Request) err
 	if r.Header.Get("HX-Request") == "true" {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		data := map[string]string{
-			"URL":      secretURL,
-			"ButtonID": "secret",
-			"Warning":  "This link will self-destruct after being viewed once.",
+			"URL":           secretURL,
+			"ButtonID":      "secret",
+			"Warning":       s.ViewsWarning(secret.MaxViews),
+			"Expires":       s.DescribeExpiry(secret.ExpiresAt),
+			"DeletionToken": secret.DeletionToken,
 		}
 		return h.templateService.RenderResult(w, data)
 	}
 
-	return response.JSON(w, 201, secretURL)
+	return response.JSON(w, 201, s.NewCreatedResponse(secretURL, secret.ExpiresAt, secret.DeletionToken))
 }
 
 // GetSecretByShort shows the one-time view page for the secret.
 // @Summary Get secret information
 // @Description Shows the one-time view page where users can reveal the secret
 // @Tags secret
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
 // @Success 200 {string} string "One-time view page"
+// @Failure 403 "Requested with cli=true, use /api/secrets/{short}/reveal"
 // @Failure 404
 // @Failure 422
 // @Router /s/{short} [get]
 func (h *SecretHandler) GetSecretByShort(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !s.IsValidShort(short, config.Config.SecretShortLength) {
 		return s.MapError(w, r, apierr.NewError(422, "validation", "Invalid secret code"), h.templateService)
 	}
 
 	// Check if secret exists without consuming it
-	exists, err := h.secretService.CheckSecretExists(short)
-	if err != nil || !exists {
+	secret, err := h.secretService.CheckSecretExists(short)
+	if err != nil {
 		return s.MapError(w, r, apierr.NewError(404, "not_found", "Secret not found or already viewed"), h.templateService)
 	}
 
-	if r.URL.Query().Get("cli") != "true" {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		data := map[string]string{
-			"ID":   short,
-			"Type": "secret",
-		}
-		return h.templateService.RenderOnetime(w, data)
+	// Secrets are always view limited, API clients reveal them with POST /api/secrets/{short}/reveal
+	if r.URL.Query().Get("cli") == "true" {
+		return s.RevealRequired("secrets", short)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := map[string]any{
+		"ID":          short,
+		"Type":        "secret",
+		"ViewsLeft":   secret.ViewsLeft,
+		"RevealToken": s.NewRevealToken("secrets", short),
+		"Card":        secretCard(short),
+	}
+	return h.templateService.RenderOnetime(w, data)
+}
+
+// StartSecretReveal hands out a reveal token without using up a view.
+// @Summary Start revealing a secret
+// @Description Returns a reveal token for POST /api/secrets/{short}/reveal without using up a view, link previews only ever get this far
+// @Tags secret
+// @Produce json
+// @Param short path string true "Short code"
+// @Success 200 {object} shared.RevealResponse
+// @Failure 403 "A request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/secrets/{short}/reveal [get]
+func (h *SecretHandler) StartSecretReveal(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.SecretShortLength) {
+		return apierr.NewError(422, "validation", "Invalid secret code")
 	}
 
-	// For API clients, immediately reveal the secret (backward compatibility)
-	secret, err := h.secretService.GetSecret(short)
+	secret, err := h.secretService.CheckSecretExists(short)
 	if err != nil {
-		return s.MapError(w, r, apierr.NewError(404, "not_found", "Secret not found or already viewed"), h.templateService)
+		return apierr.NewError(404, "not_found", "Secret not found or already viewed")
+	}
+
+	return s.StartReveal(w, r, "secrets", short, secret.MaxViews, secret.ViewsLeft)
+}
+
+// RevealSecret uses up a view of the secret and returns it.
+// @Summary Reveal a secret
+// @Description Uses up a view of the secret and returns its encrypted data. Requires the token from GET /api/secrets/{short}/reveal
+// @Tags secret
+// @Produce json
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token"
+// @Success 200 {object} SecretResponse
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
+// @Failure 404
+// @Failure 422
+// @Router /api/secrets/{short}/reveal [post]
+func (h *SecretHandler) RevealSecret(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.SecretShortLength) {
+		return apierr.NewError(422, "validation", "Invalid secret code")
+	}
+
+	if err := s.CheckReveal(r, "secrets", short); err != nil {
+		return err
 	}
 
-	secretResp := SecretResponse{Data: secret.Data}
+	secret, err := h.secretService.GetSecret(short, s.GetIP(r))
+	if err != nil {
+		return apierr.NewError(404, "not_found", "Secret not found or already viewed")
+	}
+
+	secretResp := SecretResponse{Data: secret.Data, ViewsLeft: secret.ViewsLeft}
 	return response.JSON(w, 200, secretResp)
 }
 
@@ -110,27 +179,87 @@ func (h *SecretHandler) GetSecretByShort(w http.ResponseWriter, r *http.Request)
 // @Summary Reveal one-time secret
 // @Description Consumes the one-time secret and returns the content (one-time use only)
 // @Tags secret
-// @Param short path string true "Short code (6 characters)"
+// @Param short path string true "Short code"
+// @Param X-Reveal-Token header string true "Reveal token of the interstitial page"
 // @Success 200 {string} string "Secret content HTML partial"
+// @Failure 403 "Missing or expired reveal token, a request without this server's origin or a link preview bot"
 // @Failure 404
 // @Failure 422
 // @Router /api/secrets/{short}/onetime [post]
 func (h *SecretHandler) RevealOneTimeSecret(w http.ResponseWriter, r *http.Request) error {
 	short := r.PathValue("short")
 
-	if len(short) != 6 {
+	if !s.IsValidShort(short, config.Config.SecretShortLength) {
 		return h.templateService.RenderError(w, "Invalid secret code")
 	}
 
-	secret, err := h.secretService.GetSecret(short)
+	if err := s.CheckReveal(r, "secrets", short); err != nil {
+		return s.MapError(w, r, err, h.templateService)
+	}
+
+	secret, err := h.secretService.GetSecret(short, s.GetIP(r))
 	if err != nil {
 		return h.templateService.RenderError(w, "This one-time link has already been viewed or does not exist.")
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	data := map[string]any{
-		"Type": "secret",
-		"Data": secret.Data,
+		"Type":      "secret",
+		"Data":      secret.Data,
+		"ViewsLeft": secret.ViewsLeft,
 	}
 	return h.templateService.RenderOnetimeReveal(w, data)
 }
+
+// GetSecretStatus reports whether a secret was read.
+// @Summary Get secret read status
+// @Description Returns pending, consumed or expired along with the time and network hash of every read. Requires the secret's deletion token and works until its receipt expires.
+// @Tags secret
+// @Produce json
+// @Param short path string true "Short code"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 200 {object} SecretStatusResponse "Secret status"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/secrets/{short}/status [get]
+func (h *SecretHandler) GetSecretStatus(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.SecretShortLength) {
+		return apierr.NewError(422, "validation", "Invalid secret code")
+	}
+
+	status, err := h.secretService.GetStatus(short, s.DeletionToken(r))
+	if err != nil {
+		return s.TokenError(err, "Secret not found")
+	}
+
+	return response.JSON(w, 200, status)
+}
+
+// DeleteSecret deletes a secret before it expires.
+// @Summary Delete a secret
+// @Description Deletes the secret using the deletion token returned when it was created
+// @Tags secret
+// @Param short path string true "Short code"
+// @Param X-Deletion-Token header string true "Deletion token"
+// @Success 204 "Secret deleted"
+// @Failure 403 "Invalid deletion token"
+// @Failure 404
+// @Failure 422
+// @Router /api/secrets/{short} [delete]
+func (h *SecretHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) error {
+	short := r.PathValue("short")
+
+	if !s.IsValidShort(short, config.Config.SecretShortLength) {
+		return apierr.NewError(422, "validation", "Invalid secret code")
+	}
+
+	if err := h.secretService.RevokeSecret(short, s.DeletionToken(r)); err != nil {
+		return s.TokenError(err, "Secret not found or already viewed")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -3,16 +3,55 @@ package secret
 import "time"
 
 type SecretRequest struct {
-	Data string `json:"data" validate:"required,base64,min=44"`
+	Data      string `json:"data" validate:"required,base64,min=44"`
+	MaxViews  int    `json:"max_views,omitempty"`  // Views before the secret is deleted, 1 when empty
+	ExpiresIn string `json:"expires_in,omitempty"` // e.g. "1h", "7d", "never"; server default when empty
 }
 
 type SecretResponse struct {
-	Data string `json:"data"`
+	Data      string `json:"data"`
+	ViewsLeft int    `json:"views_left"`
 }
 
 type Secret struct {
-	Short     string
-	Data      string
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	Short             string
+	Data              string
+	MaxViews          int // Views allowed in total, secrets are never unlimited
+	ViewsLeft         int // Decremented on every view, the secret is deleted when it reaches 0
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	DeletionTokenHash string
+	DeletionToken     string `json:"-"` // Returned to the creator once, never stored
+}
+
+// SecretReceipt records who read a secret and when, and is kept for a while after the secret
+// is gone so its creator can check on it. Readers are only stored as a salted hash of their network.
+type SecretReceipt struct {
+	DeletionTokenHash string
+	Reads             []SecretRead
+	ConsumedAt        time.Time // Time of the last allowed view, zero while views are left
+}
+
+type SecretRead struct {
+	At          time.Time
+	NetworkHash string // Hash of the reader's /24 or /48 network, comparable within a UTC day
+}
+
+// Secret statuses reported to the creator.
+const (
+	SecretPending  = "pending"
+	SecretConsumed = "consumed"
+	SecretExpired  = "expired"
+)
+
+type SecretStatusResponse struct {
+	Status     string               `json:"status"`
+	ViewsLeft  int                  `json:"views_left"`
+	ConsumedAt *time.Time           `json:"consumed_at,omitempty"`
+	Reads      []SecretReadResponse `json:"reads"`
+}
+
+type SecretReadResponse struct {
+	At          time.Time `json:"at"`
+	NetworkHash string    `json:"network_hash"`
 }
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/shared"
 )
 
 type SecretRepo struct {
@@ -16,11 +18,28 @@ func NewSecretRepo(db *badger.DB) *SecretRepo {
 	return &SecretRepo{db: db}
 }
 
+// Create stores the secret and its empty read receipt under its short code, failing with
+// shared.ErrShortTaken if the code is in use. Codes stay taken while an old receipt is kept.
 func (r *SecretRepo) Create(secret *Secret) error {
-	return r.db.Update(func(txn *badger.Txn) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.SecretPrefix, secret.Short)
+		for _, k := range [][]byte{key, shared.Key(shared.SecretReceiptPrefix, secret.Short)} {
+			if err := shared.EnsureUnused(txn, k); err != nil {
+				return err
+			}
+		}
+
 		data, _ := json.Marshal(secret)
-		entry := badger.NewEntry([]byte(secret.Short), data).WithTTL(time.Until(secret.ExpiresAt))
-		return txn.SetEntry(entry)
+		entry := badger.NewEntry(key, data)
+		if !secret.ExpiresAt.IsZero() {
+			entry = entry.WithTTL(time.Until(secret.ExpiresAt))
+		}
+		if err := txn.SetEntry(entry); err != nil {
+			return err
+		}
+
+		receipt := SecretReceipt{DeletionTokenHash: secret.DeletionTokenHash}
+		return setReceipt(txn, secret.Short, &receipt, receiptExpiresAt(secret.ExpiresAt))
 	})
 }
 
@@ -28,7 +47,7 @@ func (r *SecretRepo) GetByShort(short string) (*Secret, error) {
 	var secret Secret
 
 	err := r.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(short))
+		item, err := txn.Get(shared.Key(shared.SecretPrefix, short))
 		if err != nil {
 			return err
 		}
@@ -44,30 +63,139 @@ func (r *SecretRepo) GetByShort(short string) (*Secret, error) {
 
 	return &secret, err
 }
+
+// ConsumeView uses up one view of the secret in a single transaction, so concurrent readers can't exceed
+// its view limit. The secret is deleted with its last view, otherwise it is rewritten with the remaining TTL.
+// The read is added to the secret's receipt, which is kept for SecretReceiptTTL once the secret is consumed.
+func (r *SecretRepo) ConsumeView(short string, read SecretRead) (*Secret, error) {
+	var secret Secret
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		key := shared.Key(shared.SecretPrefix, short)
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &secret)
+		}); err != nil {
+			return err
+		}
+
+		secret.ViewsLeft--
+		if err := recordRead(txn, &secret, read); err != nil {
+			return err
+		}
+
+		if secret.ViewsLeft <= 0 {
+			secret.ViewsLeft = 0
+			return txn.Delete(key)
+		}
+
+		data, _ := json.Marshal(&secret)
+		entry := badger.NewEntry(key, data)
+		entry.ExpiresAt = item.ExpiresAt()
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
 func (r *SecretRepo) Delete(short string) error {
 	return r.db.Update(func(txn *badger.Txn) error {
-		err := txn.Delete([]byte(short))
-		if errors.Is(err, badger.ErrKeyNotFound) {
-			return nil
+		for _, key := range [][]byte{shared.Key(shared.SecretPrefix, short), shared.Key(shared.SecretReceiptPrefix, short)} {
+			if err := txn.Delete(key); err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
 		}
-		return err
+		return nil
 	})
 }
 
+// GetReceipt returns the read receipt of a secret, which may outlive the secret itself.
+func (r *SecretRepo) GetReceipt(short string) (*SecretReceipt, error) {
+	var receipt SecretReceipt
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(shared.Key(shared.SecretReceiptPrefix, short))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &receipt)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
+
+func (r *SecretRepo) DailySalt() ([]byte, error) {
+	return shared.DailySalt(r.db, time.Now())
+}
+
+// recordRead adds the read to the receipt of the secret, whose views left are already decremented.
+// Secrets stored before receipts existed get one on their first read.
+func recordRead(txn *badger.Txn, secret *Secret, read SecretRead) error {
+	receipt := SecretReceipt{DeletionTokenHash: secret.DeletionTokenHash}
+
+	item, err := txn.Get(shared.Key(shared.SecretReceiptPrefix, secret.Short))
+	switch {
+	case err == nil:
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &receipt)
+		}); err != nil {
+			return err
+		}
+	case !errors.Is(err, badger.ErrKeyNotFound):
+		return err
+	}
+
+	receipt.Reads = append(receipt.Reads, read)
+	expiresAt := receiptExpiresAt(secret.ExpiresAt)
+	if secret.ViewsLeft <= 0 {
+		receipt.ConsumedAt = read.At
+		expiresAt = shared.ExpiresAt(config.Config.SecretReceiptTTL)
+	}
+
+	return setReceipt(txn, secret.Short, &receipt, expiresAt)
+}
+
+// receiptExpiresAt is when the receipt of a secret that is never consumed expires, zero to keep it.
+func receiptExpiresAt(secretExpiresAt time.Time) time.Time {
+	if secretExpiresAt.IsZero() || config.Config.SecretReceiptTTL == config.NeverExpires {
+		return time.Time{}
+	}
+	return secretExpiresAt.Add(config.Config.SecretReceiptTTL)
+}
+
+func setReceipt(txn *badger.Txn, short string, receipt *SecretReceipt, expiresAt time.Time) error {
+	data, _ := json.Marshal(receipt)
+	entry := badger.NewEntry(shared.Key(shared.SecretReceiptPrefix, short), data)
+	if !expiresAt.IsZero() {
+		entry = entry.WithTTL(time.Until(expiresAt))
+	}
+	return txn.SetEntry(entry)
+}
+
 func (r *SecretRepo) CountSecrets() (total int, err error) {
 	return total, r.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = true
+		opts.Prefix = []byte(shared.SecretPrefix)
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 
-			if len(item.Key()) != 6 {
-				continue
-			}
-
 			_ = item.Value(func(val []byte) error {
 				var secret Secret
 				if err := json.Unmarshal(val, &secret); err != nil || secret.Data == "" {
@@ -2,9 +2,10 @@ package secret
 
 import (
 	"errors"
-	"log/slog"
 	"time"
 
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
 	"github.com/piheta/seq.re/internal/shared"
 )
 
@@ -16,15 +17,23 @@ func NewSecretService(secretRepo *SecretRepo) *SecretService {
 	return &SecretService{secretRepo: secretRepo}
 }
 
-func (s *SecretService) CreateSecret(encryptedSecret string) (*Secret, error) {
+func (s *SecretService) CreateSecret(encryptedSecret string, maxViews int, expiresIn time.Duration) (*Secret, error) {
+	token, tokenHash := shared.NewDeletionToken()
+
 	secret := Secret{
-		Short:     shared.CreateShort(),
-		Data:      encryptedSecret,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+		Data:              encryptedSecret,
+		MaxViews:          maxViews,
+		ViewsLeft:         maxViews,
+		CreatedAt:         time.Now(),
+		ExpiresAt:         shared.ExpiresAt(expiresIn),
+		DeletionTokenHash: tokenHash,
+		DeletionToken:     token,
 	}
 
-	err := s.secretRepo.Create(&secret)
+	err := shared.AllocateShort(config.Config.SecretShortLength, func(short string) error {
+		secret.Short = short
+		return s.secretRepo.Create(&secret)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -32,28 +41,91 @@ func (s *SecretService) CreateSecret(encryptedSecret string) (*Secret, error) {
 	return &secret, nil
 }
 
-func (s *SecretService) GetSecret(short string) (*Secret, error) {
-	secret, err := s.secretRepo.GetByShort(short)
+// GetSecret reveals the secret to the reader at ip, using up one of its views. Only a hash of the
+// reader's network is kept on the secret's receipt, salted per day and per secret.
+func (s *SecretService) GetSecret(short, ip string) (*Secret, error) {
+	salt, err := s.secretRepo.DailySalt()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.DeleteSecret(short); err != nil {
-		slog.With("error", err).With("short", short).Error("failed to delete secret after retrieval")
-		return nil, errors.New("failed to delete secret")
+	read := SecretRead{
+		At:          time.Now(),
+		NetworkHash: shared.HashVisitor(salt, short, shared.NetworkPrefix(ip)),
 	}
 
-	return secret, nil
+	// Count the view in one transaction so a secret can't be revealed more often than allowed
+	return s.secretRepo.ConsumeView(short, read)
 }
 
 func (s *SecretService) DeleteSecret(short string) error {
 	return s.secretRepo.Delete(short)
 }
 
-func (s *SecretService) CheckSecretExists(short string) (bool, error) {
-	_, err := s.secretRepo.GetByShort(short)
+// RevokeSecret deletes the secret if the deletion token matches the one handed out on creation.
+func (s *SecretService) RevokeSecret(short, token string) error {
+	secret, err := s.secretRepo.GetByShort(short)
+	if err != nil {
+		return err
+	}
+
+	if err := shared.CheckDeletionToken(token, secret.DeletionTokenHash); err != nil {
+		return err
+	}
+
+	return s.secretRepo.Delete(short)
+}
+
+// GetStatus tells the holder of the secret's deletion token whether it is still pending, was consumed or
+// expired unread, along with the reads so far. It works until the secret's receipt expires.
+func (s *SecretService) GetStatus(short, token string) (*SecretStatusResponse, error) {
+	receipt, err := s.secretRepo.GetReceipt(short)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	if err := shared.CheckDeletionToken(token, receipt.DeletionTokenHash); err != nil {
+		return nil, err
+	}
+
+	status := SecretStatusResponse{Reads: make([]SecretReadResponse, 0, len(receipt.Reads))}
+	for _, read := range receipt.Reads {
+		status.Reads = append(status.Reads, SecretReadResponse(read))
+	}
+
+	if !receipt.ConsumedAt.IsZero() {
+		status.Status = SecretConsumed
+		status.ConsumedAt = &receipt.ConsumedAt
+		return &status, nil
+	}
+
+	secret, err := s.secretRepo.GetByShort(short)
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		status.Status = SecretExpired
+	case err != nil:
+		return nil, err
+	default:
+		status.Status = SecretPending
+		status.ViewsLeft = secret.ViewsLeft
+	}
+
+	return &status, nil
+}
+
+func (s *SecretService) CheckSecretExists(short string) (*Secret, error) {
+	return s.secretRepo.GetByShort(short)
+}
+
+// Card describes the secret for link previews, secrets are always encrypted so the card reveals nothing.
+func (s *SecretService) Card(short string) (*shared.Card, error) {
+	if _, err := s.secretRepo.GetByShort(short); err != nil {
+		return nil, err
 	}
-	return true, nil
+	return secretCard(short), nil
+}
+
+func secretCard(short string) *shared.Card {
+	card := shared.ProtectedCard(shared.PublicURL("/s/"+short), true)
+	return &card
 }
@@ -21,7 +21,7 @@ func NewSeqreHandler(version, commit, date string) *SeqreHandler {
 // @Tags seqre
 // @Accept json
 // @Produce json
-// @Success 200 {string}
+// @Success 200 {object} VersionResponse "Server version"
 // @Router /api/version [get]
 func (h *SeqreHandler) GetVersion(w http.ResponseWriter, _ *http.Request) error {
 	version := VersionResponse{
@@ -0,0 +1,234 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/apicore/response"
+	"github.com/piheta/seq.re/config"
+	s "github.com/piheta/seq.re/internal/shared"
+)
+
+// Target is where finished uploads of one kind end up.
+type Target struct {
+	Dir   string                                                               // Upload directory, chunks are assembled there so the file can be moved into place
+	Store func(w http.ResponseWriter, r *http.Request, upload *s.Upload) error // Stores the upload and writes the created response
+}
+
+type UploadHandler struct {
+	uploadService *UploadService
+	targets       map[string]Target
+}
+
+func NewUploadHandler(uploadService *UploadService, targets map[string]Target) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+		targets:       targets,
+	}
+}
+
+// CreateUpload starts a chunked upload.
+// @Summary Start a chunked upload
+// @Description Starts an upload of an image or file that is sent in numbered chunks, so an interrupted transfer can be resumed. Takes the same options as the multipart upload
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Param request body UploadRequest true "Upload size and options of the finished image or file"
+// @Success 201 {object} SessionResponse "Upload ID and chunk layout"
+// @Failure 400 "Invalid request, chunk size or expiry"
+// @Failure 413 "Upload larger than the configured maximum upload size"
+// @Failure 429 "Too many unfinished uploads from this IP"
+// @Router /api/uploads [post]
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) error {
+	var uploadReq UploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&uploadReq); err != nil {
+		return err
+	}
+
+	if err := s.Validate.Struct(&uploadReq); err != nil {
+		return err
+	}
+
+	target, ok := h.targets[uploadReq.Kind]
+	if !ok {
+		return apierr.NewError(400, "validation", "Unknown upload kind")
+	}
+
+	if uploadReq.Size > config.Config.MaxUploadSize {
+		return apierr.NewError(413, "too_large", "File too large")
+	}
+
+	chunkSize := uploadReq.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
+		return apierr.NewError(400, "validation", fmt.Sprintf("chunk_size must be between %d and %d", MinChunkSize, MaxChunkSize))
+	}
+
+	// Options are checked again when the upload is stored, failing early saves sending the chunks
+	if uploadReq.PasswordProtected && !uploadReq.Encrypted {
+		return apierr.NewError(400, "validation", "Password protection requires encryption")
+	}
+	if _, err := s.ParseExpiresIn(uploadReq.ExpiresIn); err != nil {
+		return err
+	}
+	if _, err := s.ViewLimit(uploadReq.MaxViews, uploadReq.OneTime); err != nil {
+		return err
+	}
+
+	fields := map[string]string{
+		"encrypted":          strconv.FormatBool(uploadReq.Encrypted),
+		"password_protected": strconv.FormatBool(uploadReq.PasswordProtected),
+		"onetime":            strconv.FormatBool(uploadReq.OneTime),
+		"max_views":          strconv.Itoa(uploadReq.MaxViews),
+		"expires_in":         uploadReq.ExpiresIn,
+		"filename":           uploadReq.FileName,
+		"keep_metadata":      strconv.FormatBool(uploadReq.KeepMetadata),
+	}
+
+	session, err := h.uploadService.CreateSession(s.GetIP(r), uploadReq.Kind, target.Dir, uploadReq.Size, chunkSize, uploadReq.FileName, uploadReq.ContentType, fields)
+	if err != nil {
+		return uploadError(err)
+	}
+
+	return response.JSON(w, 201, newSessionResponse(session))
+}
+
+// GetUpload reports which chunks of an upload were received.
+// @Summary Get chunked upload progress
+// @Description Lists the chunks received so far, clients resume by sending the missing ones
+// @Tags upload
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Success 200 {object} SessionResponse "Chunk layout and received chunks"
+// @Failure 404 "Upload not found or abandoned"
+// @Router /api/uploads/{id} [get]
+func (h *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.uploadService.GetSession(r.PathValue("id"))
+	if err != nil {
+		return uploadError(err)
+	}
+
+	return response.JSON(w, 200, newSessionResponse(session))
+}
+
+// UploadChunk stores a chunk of an upload.
+// @Summary Upload a chunk
+// @Description Stores chunk number index (from 0) of the upload. Every chunk but the last has the chunk size of the upload
+// @Tags upload
+// @Accept octet-stream
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Param index path int true "Chunk index"
+// @Param X-Chunk-SHA256 header string true "Hex encoded SHA-256 of the chunk"
+// @Success 200 {object} SessionResponse "Chunk layout and received chunks"
+// @Failure 400 "Invalid chunk index, size or checksum"
+// @Failure 404 "Upload not found or abandoned"
+// @Router /api/uploads/{id}/chunks/{index} [put]
+func (h *UploadHandler) UploadChunk(w http.ResponseWriter, r *http.Request) error {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		return apierr.NewError(400, "validation", "Invalid chunk index")
+	}
+
+	checksum := r.Header.Get(ChecksumHeader)
+	if checksum == "" {
+		return apierr.NewError(400, "validation", "Missing "+ChecksumHeader+" header")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxChunkSize+1)
+	session, err := h.uploadService.WriteChunk(r.PathValue("id"), index, r.Body, checksum)
+	if err != nil {
+		return uploadError(err)
+	}
+
+	return response.JSON(w, 200, newSessionResponse(session))
+}
+
+// FinalizeUpload stores a completely received upload as an image or file.
+// @Summary Finalize a chunked upload
+// @Description Assembles the chunks into the image or file, responding like the multipart upload of its kind
+// @Tags upload
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Success 201 {object} shared.CreatedResponse "Image or file URL and expiry time"
+// @Failure 404 "Upload not found or abandoned"
+// @Failure 409 "Chunks are missing"
+// @Router /api/uploads/{id}/finalize [post]
+func (h *UploadHandler) FinalizeUpload(w http.ResponseWriter, r *http.Request) error {
+	session, err := h.uploadService.FinalizeSession(r.PathValue("id"))
+	if err != nil {
+		return uploadError(err)
+	}
+
+	upload, err := s.UploadFromFile(session.Path, session.FileName, session.ContentType, session.Fields)
+	if err != nil {
+		return uploadError(badger.ErrKeyNotFound)
+	}
+	// Nothing is left to remove once the upload was moved into place
+	defer upload.Remove()
+
+	return h.targets[session.Kind].Store(w, r, upload)
+}
+
+// AbortUpload discards an unfinished upload.
+// @Summary Abort a chunked upload
+// @Description Discards the upload and the chunks received so far
+// @Tags upload
+// @Param id path string true "Upload ID"
+// @Success 204 "Upload discarded"
+// @Failure 404 "Upload not found or abandoned"
+// @Router /api/uploads/{id} [delete]
+func (h *UploadHandler) AbortUpload(w http.ResponseWriter, r *http.Request) error {
+	if err := h.uploadService.AbortSession(r.PathValue("id")); err != nil {
+		return uploadError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func newSessionResponse(session *Session) SessionResponse {
+	received := []int{}
+	for i, ok := range session.Received {
+		if ok {
+			received = append(received, i)
+		}
+	}
+
+	return SessionResponse{
+		ID:        session.ID,
+		Size:      session.Size,
+		ChunkSize: session.ChunkSize,
+		Chunks:    session.Chunks(),
+		Received:  received,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// uploadError maps errors of the upload service to API errors.
+func uploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return apierr.NewError(404, "not_found", "Upload not found")
+	case errors.Is(err, ErrTooManyUploads):
+		return apierr.NewError(429, "too_many_uploads", "Too many unfinished uploads, finish or abort one first")
+	case errors.Is(err, ErrIncomplete):
+		return apierr.NewError(409, "incomplete", "Upload is missing chunks")
+	case errors.Is(err, ErrChunkIndex):
+		return apierr.NewError(400, "validation", "Invalid chunk index")
+	case errors.Is(err, ErrChunkSize), errors.As(err, &maxBytesErr):
+		return apierr.NewError(400, "validation", "Chunk has the wrong size")
+	case errors.Is(err, ErrChecksumMismatch):
+		return apierr.NewError(400, "checksum_mismatch", "Chunk does not match its checksum")
+	default:
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package upload
+
+import "time"
+
+// Upload kinds, a finished upload becomes an image or a file.
+const (
+	KindImage = "image"
+	KindFile  = "file"
+)
+
+// Chunk sizes clients may pick, every chunk but the last has the chosen size.
+const (
+	DefaultChunkSize = 8 << 20
+	MinChunkSize     = 64 << 10
+	MaxChunkSize     = 64 << 20
+)
+
+// ChecksumHeader carries the hex encoded SHA-256 of a chunk.
+const ChecksumHeader = "X-Chunk-SHA256"
+
+type UploadRequest struct {
+	Kind              string `json:"kind" validate:"required,oneof=image file"`
+	Size              int64  `json:"size" validate:"required,min=1"`
+	ChunkSize         int64  `json:"chunk_size,omitempty"`   // Server default when empty
+	FileName          string `json:"filename,omitempty"`     // Encrypted with the content for encrypted files
+	ContentType       string `json:"content_type,omitempty"` // Detected from the content when empty
+	Encrypted         bool   `json:"encrypted,omitempty"`
+	PasswordProtected bool   `json:"password_protected,omitempty"`
+	OneTime           bool   `json:"onetime,omitempty"`
+	MaxViews          int    `json:"max_views,omitempty"`
+	ExpiresIn         string `json:"expires_in,omitempty"` // e.g. "1h", "7d", "never"; server default when empty
+	KeepMetadata      bool   `json:"keep_metadata,omitempty"`
+}
+
+// Session is a chunked upload in progress. Chunks are written straight into a temporary file in the
+// upload directory of its kind, which is handed over as a regular upload once every chunk arrived.
+type Session struct {
+	ID          string
+	Kind        string
+	Owner       string // Hash of the client IP with the salt of the day it was created on, limits open uploads per client
+	Path        string
+	Size        int64
+	ChunkSize   int64
+	Received    []bool            // Indexed by chunk
+	FileName    string            // Name of the finished upload
+	ContentType string            // Declared by the client, may be empty
+	Fields      map[string]string // Options of the finished upload, as they are sent with a multipart upload
+	CreatedAt   time.Time
+	ExpiresAt   time.Time // Pushed back by every chunk, the session is abandoned once it passes
+}
+
+// Chunks returns the number of chunks the upload is split into.
+func (s *Session) Chunks() int {
+	return len(s.Received)
+}
+
+type SessionResponse struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	ChunkSize int64     `json:"chunk_size"`
+	Chunks    int       `json:"chunks"`
+	Received  []int     `json:"received"` // Indexes of the chunks stored so far
+	ExpiresAt time.Time `json:"expires_at"`
+}
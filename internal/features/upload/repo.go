@@ -0,0 +1,148 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+var (
+	// ErrIncomplete is returned when finalizing an upload that is still missing chunks.
+	ErrIncomplete = errors.New("upload is missing chunks")
+	// ErrTooManyUploads is returned when a client already has as many unfinished uploads as it may open.
+	ErrTooManyUploads = errors.New("too many unfinished uploads")
+)
+
+type UploadRepo struct {
+	db *badger.DB
+}
+
+func NewUploadRepo(db *badger.DB) *UploadRepo {
+	return &UploadRepo{db: db}
+}
+
+// Create stores a new session unless its owner already has limit unfinished uploads, 0 disables the limit.
+func (r *UploadRepo) Create(session *Session, limit int) error {
+	return shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		if limit > 0 && countOwned(txn, session.Owner) >= limit {
+			return ErrTooManyUploads
+		}
+		return setSession(txn, session)
+	})
+}
+
+// DailySalt returns today's salt for hashing the owners of sessions.
+func (r *UploadRepo) DailySalt() ([]byte, error) {
+	return shared.DailySalt(r.db, time.Now())
+}
+
+func (r *UploadRepo) GetByID(id string) (*Session, error) {
+	var session Session
+
+	err := r.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(shared.Key(shared.UploadPrefix, id))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &session)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// MarkReceived records a stored chunk and pushes back the expiry of the session.
+func (r *UploadRepo) MarkReceived(id string, index int, expiresAt time.Time) (*Session, error) {
+	var session *Session
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		var err error
+		session, err = getSession(txn, id)
+		if err != nil {
+			return err
+		}
+
+		session.Received[index] = true
+		session.ExpiresAt = expiresAt
+		return setSession(txn, session)
+	})
+
+	return session, err
+}
+
+// Claim removes a session that received all of its chunks, so it can be finalized exactly once.
+// Incomplete sessions are kept and fail with ErrIncomplete.
+func (r *UploadRepo) Claim(id string) (*Session, error) {
+	var session *Session
+
+	err := shared.UpdateWithRetry(r.db, func(txn *badger.Txn) error {
+		var err error
+		session, err = getSession(txn, id)
+		if err != nil {
+			return err
+		}
+
+		if slices.Contains(session.Received, false) {
+			return ErrIncomplete
+		}
+		return txn.Delete(shared.Key(shared.UploadPrefix, id))
+	})
+
+	return session, err
+}
+
+func (r *UploadRepo) Delete(id string) error {
+	return r.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(shared.Key(shared.UploadPrefix, id))
+	})
+}
+
+func getSession(txn *badger.Txn, id string) (*Session, error) {
+	item, err := txn.Get(shared.Key(shared.UploadPrefix, id))
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &session)
+	}); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// countOwned counts the unfinished sessions of an owner, abandoned ones are gone once their TTL passed.
+func countOwned(txn *badger.Txn, owner string) int {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(shared.UploadPrefix)
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	count := 0
+	for it.Rewind(); it.Valid(); it.Next() {
+		_ = it.Item().Value(func(val []byte) error {
+			var session Session
+			if err := json.Unmarshal(val, &session); err == nil && session.Owner == owner {
+				count++
+			}
+			return nil
+		})
+	}
+	return count
+}
+
+func setSession(txn *badger.Txn, session *Session) error {
+	data, _ := json.Marshal(session)
+	return txn.SetEntry(badger.NewEntry(shared.Key(shared.UploadPrefix, session.ID), data).WithTTL(time.Until(session.ExpiresAt)))
+}
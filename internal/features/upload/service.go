@@ -0,0 +1,174 @@
+package upload
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+var (
+	ErrChunkIndex       = errors.New("chunk index out of range")
+	ErrChunkSize        = errors.New("chunk has the wrong size")
+	ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+)
+
+type UploadService struct {
+	uploadRepo *UploadRepo
+}
+
+func NewUploadService(uploadRepo *UploadRepo) *UploadService {
+	return &UploadService{uploadRepo: uploadRepo}
+}
+
+// CreateSession starts a chunked upload into dir for the client at ip. The temporary file is allocated up front,
+// so chunks can be written in any order and resent after an interruption. Clients that already have
+// MAX_UPLOAD_SESSIONS unfinished uploads get ErrTooManyUploads before anything is allocated.
+//
+//nolint:revive // The options are passed through to the finished upload
+func (s *UploadService) CreateSession(ip, kind, dir string, size, chunkSize int64, fileName, contentType string, fields map[string]string) (*Session, error) {
+	salt, err := s.uploadRepo.DailySalt()
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := shared.CreateTempUpload(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+
+	now := time.Now()
+	session := Session{
+		ID:          rand.Text(),
+		Kind:        kind,
+		Owner:       shared.HashVisitor(salt, ip),
+		Path:        tmp.Name(),
+		Size:        size,
+		ChunkSize:   chunkSize,
+		Received:    make([]bool, (size+chunkSize-1)/chunkSize),
+		FileName:    fileName,
+		ContentType: contentType,
+		Fields:      fields,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(shared.StaleUploadAge),
+	}
+
+	// The session is counted against the client's limit before its file takes up any space
+	if err := s.uploadRepo.Create(&session, config.Config.MaxUploadSessions); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(session.Path)
+		return nil, err
+	}
+
+	err = tmp.Truncate(size)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = s.uploadRepo.Delete(session.ID)
+		_ = os.Remove(session.Path)
+		return nil, fmt.Errorf("failed to allocate upload file: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (s *UploadService) GetSession(id string) (*Session, error) {
+	return s.uploadRepo.GetByID(id)
+}
+
+// WriteChunk stores a chunk of the upload at its offset, it only counts as received if its content
+// matches the hex encoded SHA-256 checksum. Resending a chunk overwrites it once the new content was verified.
+func (s *UploadService) WriteChunk(id string, index int, chunk io.Reader, checksum string) (*Session, error) {
+	session, err := s.uploadRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 || index >= session.Chunks() {
+		return nil, ErrChunkIndex
+	}
+
+	offset := int64(index) * session.ChunkSize
+	length := min(session.ChunkSize, session.Size-offset)
+
+	f, err := os.OpenFile(session.Path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		// Cleaned up as abandoned, the session is of no use without its file
+		_ = s.uploadRepo.Delete(id)
+		return nil, badger.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	// The chunk is staged next to the upload, so a chunk that fails its checks never overwrites a stored one
+	staged, err := shared.CreateTempUpload(filepath.Dir(session.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage chunk: %w", err)
+	}
+	defer func() {
+		_ = staged.Close()
+		_ = os.Remove(staged.Name())
+	}()
+
+	// A chunk never spills over into its neighbour, anything beyond its length is rejected
+	hash := sha256.New()
+	n, err := io.Copy(staged, io.TeeReader(io.LimitReader(chunk, length), hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage chunk: %w", err)
+	}
+	if extra, _ := chunk.Read(make([]byte, 1)); n != length || extra > 0 {
+		return nil, ErrChunkSize
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != strings.ToLower(checksum) {
+		return nil, ErrChecksumMismatch
+	}
+
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if _, err := io.Copy(io.NewOffsetWriter(f, offset), staged); err != nil {
+		return nil, fmt.Errorf("failed to write chunk: %w", err)
+	}
+
+	return s.uploadRepo.MarkReceived(id, index, time.Now().Add(shared.StaleUploadAge))
+}
+
+// FinalizeSession ends an upload that received all of its chunks. Its file is left for the caller to move
+// into place, incomplete uploads fail with ErrIncomplete and can still be continued.
+func (s *UploadService) FinalizeSession(id string) (*Session, error) {
+	return s.uploadRepo.Claim(id)
+}
+
+// AbortSession ends an upload and removes what was received so far.
+func (s *UploadService) AbortSession(id string) error {
+	session, err := s.uploadRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.uploadRepo.Delete(id); err != nil {
+		return err
+	}
+
+	if err := os.Remove(session.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.With("error", err).With("path", session.Path).Error("failed to delete aborted upload from disk")
+	}
+	return nil
+}
@@ -28,25 +28,36 @@ func (h *WebHandler) ServeIndex(w http.ResponseWriter, _ *http.Request) error {
 }
 
 func (h *WebHandler) ServeURLTab(w http.ResponseWriter, _ *http.Request) error {
-	return h.templateService.RenderIndexTemplate(w, "url-shortener.html", nil)
+	return h.templateService.RenderIndexTemplate(w, "url-shortener.html", h.tabData())
 }
 
 func (h *WebHandler) ServeImageTab(w http.ResponseWriter, _ *http.Request) error {
-	return h.templateService.RenderIndexTemplate(w, "image-sharing.html", nil)
+	return h.templateService.RenderIndexTemplate(w, "image-sharing.html", h.tabData())
+}
+
+func (h *WebHandler) ServeFileTab(w http.ResponseWriter, _ *http.Request) error {
+	return h.templateService.RenderIndexTemplate(w, "file-sharing.html", h.tabData())
 }
 
 func (h *WebHandler) ServeSecretTab(w http.ResponseWriter, _ *http.Request) error {
-	return h.templateService.RenderIndexTemplate(w, "secret-sharing.html", nil)
+	return h.templateService.RenderIndexTemplate(w, "secret-sharing.html", h.tabData())
 }
 
 func (h *WebHandler) ServeCodeTab(w http.ResponseWriter, _ *http.Request) error {
-	return h.templateService.RenderIndexTemplate(w, "code-sharing.html", nil)
+	return h.templateService.RenderIndexTemplate(w, "code-sharing.html", h.tabData())
 }
 
 func (h *WebHandler) ServeIPTab(w http.ResponseWriter, _ *http.Request) error {
 	return h.templateService.RenderIndexTemplate(w, "ip-detection.html", nil)
 }
 
+// tabData holds the template data shared by the sharing tabs
+func (h *WebHandler) tabData() map[string]any {
+	return map[string]any{
+		"ExpiryOptions": shared.ExpiryOptions(),
+	}
+}
+
 func (h *WebHandler) DetectIP(w http.ResponseWriter, r *http.Request) error {
 	ip := shared.GetIP(r)
 
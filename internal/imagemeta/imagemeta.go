@@ -0,0 +1,426 @@
+// Package imagemeta removes EXIF, XMP and IPTC metadata from JPEG, PNG and WebP images. Images are rewritten at
+// the container level, the image data itself is copied as is and never re-encoded.
+package imagemeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrMalformed is returned for images whose container can't be parsed.
+var ErrMalformed = errors.New("malformed image")
+
+var (
+	jpegMagic = []byte{0xFF, 0xD8}
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// Strip copies the image in src to dst without its metadata. Formats other than JPEG, PNG and WebP are copied unchanged.
+func Strip(dst io.Writer, src io.ReadSeeker) error {
+	head := make([]byte, 12)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, jpegMagic):
+		return stripJPEG(dst, bufio.NewReader(src))
+	case bytes.HasPrefix(head, pngMagic):
+		return stripPNG(dst, bufio.NewReader(src))
+	case len(head) == 12 && string(head[:4]) == "RIFF" && string(head[8:]) == "WEBP":
+		return stripWebP(dst, src)
+	default:
+		_, err := io.Copy(dst, src)
+		return err
+	}
+}
+
+// StripBytes returns the image in data without its metadata.
+func StripBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	if err := Strip(&buf, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// JPEG markers, see ITU T.81 B.1.1.3
+const (
+	markerSOS   = 0xDA
+	markerEOI   = 0xD9
+	markerTEM   = 0x01
+	markerRST0  = 0xD0
+	markerRST7  = 0xD7
+	markerAPP1  = 0xE1 // EXIF and XMP
+	markerAPP13 = 0xED // Photoshop resources holding IPTC
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// stripJPEG drops the APP1 and APP13 segments. The EXIF orientation is kept, so photos aren't shown rotated.
+// Anything after the end of image marker is dropped too, some cameras append their own metadata there.
+func stripJPEG(dst io.Writer, r *bufio.Reader) error {
+	w := bufio.NewWriter(dst)
+
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(r, soi); err != nil {
+		return ErrMalformed
+	}
+	_, _ = w.Write(soi)
+
+	marker, err := nextMarker(r)
+	for err == nil {
+		switch {
+		case marker == markerEOI:
+			_, _ = w.Write([]byte{0xFF, markerEOI})
+			return w.Flush()
+		case marker == markerTEM || (marker >= markerRST0 && marker <= markerRST7):
+			_, _ = w.Write([]byte{0xFF, marker})
+			marker, err = nextMarker(r)
+			continue
+		}
+
+		if err = copySegment(w, r, marker); err != nil {
+			break
+		}
+
+		if marker == markerSOS {
+			marker, err = copyScan(w, r)
+			if errors.Is(err, io.EOF) {
+				// Truncated images are kept as far as they go, they still render partially
+				return w.Flush()
+			}
+		} else {
+			marker, err = nextMarker(r)
+		}
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrMalformed
+	}
+	return err
+}
+
+// Orientation returns the EXIF orientation of a JPEG image, 1 (upright) when it has none.
+func Orientation(src io.Reader) uint16 {
+	r := bufio.NewReader(src)
+
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(r, soi); err != nil || !bytes.Equal(soi, jpegMagic) {
+		return 1
+	}
+
+	// The EXIF segment comes before the image data
+	for {
+		marker, err := nextMarker(r)
+		if err != nil || marker == markerSOS || marker == markerEOI {
+			return 1
+		}
+		if marker == markerTEM || (marker >= markerRST0 && marker <= markerRST7) {
+			continue
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return 1
+		}
+
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return 1
+		}
+		if marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader) {
+			if orientation := exifOrientation(payload[len(exifHeader):]); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+		}
+	}
+}
+
+// nextMarker reads the marker of the next segment, skipping fill bytes.
+func nextMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xFF {
+		return 0, ErrMalformed
+	}
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+// copySegment copies a segment with a length, dropping metadata segments.
+func copySegment(w *bufio.Writer, r *bufio.Reader, marker byte) error {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return err
+	}
+	if length < 2 {
+		return ErrMalformed
+	}
+	size := int64(length) - 2
+
+	switch marker {
+	case markerAPP1:
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+		if bytes.HasPrefix(payload, exifHeader) {
+			if orientation := exifOrientation(payload[len(exifHeader):]); orientation > 1 {
+				writeOrientation(w, orientation)
+			}
+		}
+		return nil
+	case markerAPP13:
+		_, err := r.Discard(int(size))
+		return err
+	}
+
+	_, _ = w.Write([]byte{0xFF, marker, byte(length >> 8), byte(length)})
+	_, err := io.CopyN(w, r, size)
+	return err
+}
+
+// copyScan copies entropy coded data up to the next marker and returns that marker.
+func copyScan(w *bufio.Writer, r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != 0xFF {
+			_ = w.WriteByte(b)
+			continue
+		}
+
+		next, err := r.ReadByte()
+		for err == nil && next == 0xFF {
+			next, err = r.ReadByte()
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		// Stuffed zero bytes and restart markers are part of the scan
+		if next == 0x00 || (next >= markerRST0 && next <= markerRST7) {
+			_, _ = w.Write([]byte{0xFF, next})
+			continue
+		}
+		return next, nil
+	}
+}
+
+// exifOrientation returns the orientation tag of the first IFD of an EXIF TIFF structure, 0 when missing.
+func exifOrientation(tiff []byte) uint16 {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int64(order.Uint32(tiff[4:8]))
+	if ifd+2 > int64(len(tiff)) {
+		return 0
+	}
+	count := int64(order.Uint16(tiff[ifd:]))
+	for i := range count {
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			break
+		}
+		// Tag 0x0112 of type SHORT, the value is stored in the entry itself
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return order.Uint16(tiff[entry+8:])
+		}
+	}
+	return 0
+}
+
+// writeOrientation writes an APP1 segment with an EXIF structure holding nothing but the orientation.
+func writeOrientation(w *bufio.Writer, orientation uint16) {
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, // Big endian TIFF header
+		0x00, 0x00, 0x00, 0x08, // First IFD right after the header
+		0x00, 0x01, // One entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, byte(orientation >> 8), byte(orientation), 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, // No next IFD
+	}
+
+	length := 2 + len(exifHeader) + len(tiff)
+	_, _ = w.Write([]byte{0xFF, markerAPP1, byte(length >> 8), byte(length)})
+	_, _ = w.Write(exifHeader)
+	_, _ = w.Write(tiff)
+}
+
+// pngMetadataChunks hold EXIF, XMP (in iTXt), IPTC (as raw profile text) and timestamps.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNG drops the metadata chunks and anything after the IEND chunk.
+func stripPNG(dst io.Writer, r *bufio.Reader) error {
+	w := bufio.NewWriter(dst)
+
+	signature := make([]byte, len(pngMagic))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return ErrMalformed
+	}
+	_, _ = w.Write(signature)
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return ErrMalformed
+			}
+			return err
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		if length > 1<<31-1 {
+			return ErrMalformed
+		}
+		// The chunk data is followed by its CRC
+		size := int64(length) + 4
+		chunkType := string(header[4:])
+
+		var err error
+		if pngMetadataChunks[chunkType] {
+			_, err = io.CopyN(io.Discard, r, size)
+		} else {
+			_, _ = w.Write(header)
+			_, err = io.CopyN(w, r, size)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return ErrMalformed
+			}
+			return err
+		}
+
+		if chunkType == "IEND" {
+			return w.Flush()
+		}
+	}
+}
+
+// webpChunk is a chunk of a WebP RIFF container, offset points at its header.
+type webpChunk struct {
+	fourCC string
+	offset int64
+	size   int64 // Including the padding byte of odd sized chunks
+}
+
+// VP8X flags announcing EXIF and XMP chunks
+const (
+	vp8xFlagEXIF = 0x08
+	vp8xFlagXMP  = 0x04
+)
+
+// vp8xSize is the fixed size of the VP8X chunk: flags, reserved bytes and the canvas size.
+const vp8xSize = 10
+
+// stripWebP drops the EXIF and XMP chunks, clearing their flags in the VP8X chunk. The chunks are listed first
+// because the RIFF header holds the size of everything that follows. Sizes come from the file, so none of them is
+// trusted beyond the size of the file itself.
+func stripWebP(dst io.Writer, src io.ReadSeeker) error {
+	fileSize, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return ErrMalformed
+	}
+	end := 8 + int64(binary.LittleEndian.Uint32(header[4:8]))
+	if end > fileSize {
+		return ErrMalformed
+	}
+
+	var chunks []webpChunk
+	riffSize := int64(4) // "WEBP"
+	chunkHeader := make([]byte, 8)
+	for offset := int64(12); offset < end; {
+		if _, err := src.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(src, chunkHeader); err != nil {
+			return ErrMalformed
+		}
+
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:]))
+		size += size & 1
+		if offset+8+size > end {
+			return ErrMalformed
+		}
+
+		chunk := webpChunk{fourCC: string(chunkHeader[:4]), offset: offset, size: size}
+		if chunk.fourCC == "VP8X" && chunk.size != vp8xSize {
+			return ErrMalformed
+		}
+		if chunk.fourCC != "EXIF" && chunk.fourCC != "XMP " {
+			chunks = append(chunks, chunk)
+			riffSize += 8 + size
+		}
+		offset += 8 + size
+	}
+
+	w := bufio.NewWriter(dst)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(riffSize))
+	_, _ = w.Write(header)
+
+	for _, chunk := range chunks {
+		if _, err := src.Seek(chunk.offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		if chunk.fourCC == "VP8X" {
+			// Only the flags byte changes, the rest of the chunk is copied like any other
+			head := make([]byte, 9)
+			if _, err := io.ReadFull(src, head); err != nil {
+				return ErrMalformed
+			}
+			head[8] &^= vp8xFlagEXIF | vp8xFlagXMP
+			_, _ = w.Write(head)
+			if _, err := io.CopyN(w, src, chunk.size-1); err != nil {
+				return ErrMalformed
+			}
+			continue
+		}
+
+		if _, err := io.CopyN(w, src, 8+chunk.size); err != nil {
+			return ErrMalformed
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,172 @@
+// Package languages is the registry of paste languages shared by the server and the CLI. Language IDs double as
+// highlight.js class names, aliases, file extensions, file names and shebang interpreters all resolve to them.
+package languages
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Language is an entry of the registry.
+type Language struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Extensions   []string `json:"extensions,omitempty"`
+	FileNames    []string `json:"filenames,omitempty"`
+	Interpreters []string `json:"-"` // Shebang interpreters, version suffixes like python3 are stripped
+}
+
+var registry = []Language{
+	{ID: "plaintext", Name: "Plain Text", Aliases: []string{"plain", "text", "txt"}, Extensions: []string{".txt", ".log"}},
+	{ID: "bash", Name: "Bash", Aliases: []string{"sh", "shell", "zsh"}, Extensions: []string{".sh", ".bash", ".zsh"}, FileNames: []string{".bashrc", ".zshrc", ".profile"}, Interpreters: []string{"sh", "bash", "zsh", "dash", "ash", "ksh"}},
+	{ID: "c", Name: "C", Aliases: []string{"h"}, Extensions: []string{".c", ".h"}},
+	{ID: "cpp", Name: "C++", Aliases: []string{"c++", "cc", "cxx", "hpp"}, Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh", ".hxx"}},
+	{ID: "csharp", Name: "C#", Aliases: []string{"c#", "cs"}, Extensions: []string{".cs"}},
+	{ID: "css", Name: "CSS", Extensions: []string{".css"}},
+	{ID: "diff", Name: "Diff", Aliases: []string{"patch"}, Extensions: []string{".diff", ".patch"}},
+	{ID: "dockerfile", Name: "Dockerfile", Aliases: []string{"docker"}, Extensions: []string{".dockerfile"}, FileNames: []string{"Dockerfile", "Containerfile"}},
+	{ID: "go", Name: "Go", Aliases: []string{"golang"}, Extensions: []string{".go"}},
+	{ID: "html", Name: "HTML", Aliases: []string{"htm", "xhtml"}, Extensions: []string{".html", ".htm", ".xhtml"}},
+	{ID: "ini", Name: "INI / TOML", Aliases: []string{"toml", "cfg", "conf"}, Extensions: []string{".ini", ".toml", ".cfg", ".conf"}},
+	{ID: "java", Name: "Java", Extensions: []string{".java"}},
+	{ID: "javascript", Name: "JavaScript", Aliases: []string{"js", "jsx", "node", "nodejs"}, Extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, Interpreters: []string{"node", "nodejs", "deno", "bun"}},
+	{ID: "json", Name: "JSON", Extensions: []string{".json"}},
+	{ID: "kotlin", Name: "Kotlin", Aliases: []string{"kt"}, Extensions: []string{".kt", ".kts"}},
+	{ID: "lua", Name: "Lua", Extensions: []string{".lua"}, Interpreters: []string{"lua", "luajit"}},
+	{ID: "makefile", Name: "Makefile", Aliases: []string{"make", "mk"}, Extensions: []string{".mk", ".mak"}, FileNames: []string{"Makefile", "GNUmakefile", "makefile"}, Interpreters: []string{"make"}},
+	{ID: "markdown", Name: "Markdown", Aliases: []string{"md"}, Extensions: []string{".md", ".markdown"}},
+	{ID: "perl", Name: "Perl", Aliases: []string{"pl"}, Extensions: []string{".pl", ".pm"}, Interpreters: []string{"perl"}},
+	{ID: "php", Name: "PHP", Extensions: []string{".php"}, Interpreters: []string{"php"}},
+	{ID: "python", Name: "Python", Aliases: []string{"py", "python3"}, Extensions: []string{".py", ".pyw"}, Interpreters: []string{"python", "pypy"}},
+	{ID: "r", Name: "R", Extensions: []string{".r"}, Interpreters: []string{"Rscript"}},
+	{ID: "ruby", Name: "Ruby", Aliases: []string{"rb"}, Extensions: []string{".rb"}, FileNames: []string{"Gemfile", "Rakefile"}, Interpreters: []string{"ruby"}},
+	{ID: "rust", Name: "Rust", Aliases: []string{"rs"}, Extensions: []string{".rs"}},
+	{ID: "scss", Name: "SCSS", Extensions: []string{".scss"}},
+	{ID: "sql", Name: "SQL", Extensions: []string{".sql"}},
+	{ID: "swift", Name: "Swift", Extensions: []string{".swift"}},
+	{ID: "typescript", Name: "TypeScript", Aliases: []string{"ts", "tsx"}, Extensions: []string{".ts", ".mts", ".cts", ".tsx"}},
+	{ID: "xml", Name: "XML", Aliases: []string{"svg"}, Extensions: []string{".xml", ".svg", ".xsd", ".plist"}},
+	{ID: "yaml", Name: "YAML", Aliases: []string{"yml"}, Extensions: []string{".yaml", ".yml"}},
+}
+
+var (
+	byName        = map[string]string{}
+	byExtension   = map[string]string{}
+	byFileName    = map[string]string{}
+	byInterpreter = map[string]string{}
+)
+
+func init() {
+	for _, language := range registry {
+		byName[language.ID] = language.ID
+		for _, alias := range language.Aliases {
+			byName[alias] = language.ID
+		}
+		for _, ext := range language.Extensions {
+			byExtension[ext] = language.ID
+		}
+		for _, name := range language.FileNames {
+			byFileName[name] = language.ID
+		}
+		for _, interpreter := range language.Interpreters {
+			byInterpreter[interpreter] = language.ID
+		}
+	}
+}
+
+// All returns the registry in the order of the web dropdown, plain text first and the rest sorted by ID.
+func All() []Language {
+	return slices.Clone(registry)
+}
+
+// Normalize resolves a language ID or alias, case insensitively, to its ID.
+func Normalize(name string) (string, bool) {
+	id, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+	return id, ok
+}
+
+// Extension returns the first file extension of the language, such as ".go", or "" for unknown languages.
+func Extension(id string) string {
+	for _, language := range registry {
+		if language.ID == id && len(language.Extensions) > 0 {
+			return language.Extensions[0]
+		}
+	}
+	return ""
+}
+
+// FromFileName returns the language of a file name or slash separated path by its name or extension, "" if it is
+// unknown.
+func FromFileName(name string) string {
+	base := path.Base(name)
+	if id, ok := byFileName[base]; ok {
+		return id
+	}
+	return byExtension[strings.ToLower(path.Ext(base))]
+}
+
+var (
+	// vim: set ft=python: / vim: filetype=go / vi: syntax=sh
+	vimModeline = regexp.MustCompile(`\b(?:vim?|ex):.*?\b(?:ft|filetype|syntax)=([\w+#-]+)`)
+	// -*- mode: python -*- / -*- python -*-
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*?\bmode:\s*)?([\w+#-]+)\s*(?:;.*)?-\*-`)
+)
+
+// modelineLines is how many lines at either end of the content are searched for modelines, like vim does.
+const modelineLines = 5
+
+// Detect guesses the language from the content alone, by its shebang or an editor modeline. It returns "" when
+// the content names no language.
+func Detect(content string) string {
+	lines := strings.SplitN(content, "\n", modelineLines+1)
+	if len(lines) > modelineLines {
+		lines = lines[:modelineLines]
+	}
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		if id := fromShebang(lines[0]); id != "" {
+			return id
+		}
+	}
+
+	tail := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if len(tail) > modelineLines {
+		tail = tail[len(tail)-modelineLines:]
+	}
+	for _, line := range append(lines, tail...) {
+		for _, modeline := range []*regexp.Regexp{vimModeline, emacsModeline} {
+			if match := modeline.FindStringSubmatch(line); match != nil {
+				if id, ok := Normalize(match[1]); ok {
+					return id
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// fromShebang reads the interpreter of a "#!/usr/bin/env python3" or "#!/bin/sh -e" line.
+func fromShebang(line string) string {
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip the options of env, such as -S
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = path.Base(field)
+				break
+			}
+		}
+	}
+
+	// python3.12 and ruby2.7 are python and ruby
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	return byInterpreter[interpreter]
+}
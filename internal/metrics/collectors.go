@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"github.com/piheta/seq.re/internal/features/file"
 	"github.com/piheta/seq.re/internal/features/img"
 	"github.com/piheta/seq.re/internal/features/link"
 	"github.com/piheta/seq.re/internal/features/paste"
@@ -162,3 +163,44 @@ func (c *SecretCollector) Collect(ch chan<- prometheus.Metric) {
 
 	ch <- prometheus.MustNewConstMetric(c.totalSecrets, prometheus.GaugeValue, float64(total))
 }
+
+type FileCollector struct {
+	fileRepo         *file.FileRepo
+	encryptedFiles   *prometheus.Desc
+	unencryptedFiles *prometheus.Desc
+}
+
+func NewFileCollector(fileRepo *file.FileRepo) *FileCollector {
+	return &FileCollector{
+		fileRepo: fileRepo,
+		encryptedFiles: prometheus.NewDesc(
+			"seqre_files_encrypted_total",
+			"Total number of encrypted files in the database",
+			nil,
+			nil,
+		),
+		unencryptedFiles: prometheus.NewDesc(
+			"seqre_files_unencrypted_total",
+			"Total number of unencrypted files in the database",
+			nil,
+			nil,
+		),
+	}
+}
+
+func (c *FileCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.encryptedFiles
+	ch <- c.unencryptedFiles
+}
+
+func (c *FileCollector) Collect(ch chan<- prometheus.Metric) {
+	encrypted, unencrypted, err := c.fileRepo.CountFiles()
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.encryptedFiles, prometheus.GaugeValue, 0)
+		ch <- prometheus.MustNewConstMetric(c.unencryptedFiles, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.encryptedFiles, prometheus.GaugeValue, float64(encrypted))
+	ch <- prometheus.MustNewConstMetric(c.unencryptedFiles, prometheus.GaugeValue, float64(unencrypted))
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/shared"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -54,8 +56,8 @@ func NewPrometheusMiddleware() func(next http.Handler) http.Handler {
 
 			next.ServeHTTP(recorder, r)
 
-			if isValidRoutePrefix(r.URL.Path) {
-				path := normalizePath(r.URL.Path)
+			if isValidRoutePrefix(r.Method, r.URL.Path) {
+				path := normalizePath(r.Method, r.URL.Path)
 				duration := time.Since(start).Seconds()
 				status := strconv.Itoa(recorder.statusCode)
 				HTTPRequestsTotal.WithLabelValues(r.Method, path, status).Inc()
@@ -65,12 +67,14 @@ func NewPrometheusMiddleware() func(next http.Handler) http.Handler {
 	}
 }
 
-func isValidRoutePrefix(path string) bool {
+func isValidRoutePrefix(method, path string) bool {
 	switch {
 	case strings.HasPrefix(path, "/static"):
 		return false
 	case path == "/":
 		return true
+	case method == http.MethodPut && strings.Count(path, "/") == 1:
+		return true
 	case strings.HasPrefix(path, "/tab/"):
 		return true
 	case strings.HasPrefix(path, "/web/"):
@@ -83,26 +87,76 @@ func isValidRoutePrefix(path string) bool {
 		return true
 	case strings.HasPrefix(path, "/p/"):
 		return true
-	case len(path) == 7 && path[0] == '/':
+	case strings.HasPrefix(path, "/f/"):
+		return true
+	case strings.Count(path, "/") == 1 && shared.IsValidLinkCode(path[1:]):
 		return true
 	default:
 		return false
 	}
 }
 
-func normalizePath(path string) string {
+func normalizePath(method, path string) string {
 	parts := strings.Split(path, "/")
-	for i, part := range parts {
-		if len(part) == 6 && !isKnownResource(part) {
+	if method == http.MethodPut && isPlainUpload(parts) {
+		parts[len(parts)-1] = "{name}"
+		return strings.Join(parts, "/")
+	}
+	for i := 1; i < len(parts); i++ {
+		switch {
+		case i == 4 && parts[1] == "p" && parts[3] == "raw":
+			// File names of paste bundles may contain slashes, /p/{short}/raw/{name...}
+			return strings.Join(append(parts[:i], "{name}"), "/")
+		case parts[i-1] == "uploads" && parts[i] != "":
+			parts[i] = "{id}"
+		case parts[i-1] == "chunks" && parts[i] != "":
+			parts[i] = "{index}"
+		case i == 3 && parts[1] == "i" && parts[i] != "":
+			parts[i] = "{size}"
+		case !isKnownResource(parts[i]) && isShortCode(parts[i-1], parts[i]):
 			parts[i] = "{short}"
 		}
 	}
 	return strings.Join(parts, "/")
 }
 
+// isPlainUpload reports whether the path segments name the file of a plain
+// upload, PUT /{name}, /p/{name} or /f/{name}.
+func isPlainUpload(parts []string) bool {
+	switch len(parts) {
+	case 2:
+		// PUT /p and /f upload without a name
+		return parts[1] != "" && parts[1] != "p" && parts[1] != "f"
+	case 3:
+		return (parts[1] == "p" || parts[1] == "f") && parts[2] != ""
+	default:
+		return false
+	}
+}
+
+// isShortCode reports whether segment is a short code of the resource whose
+// codes follow the parent path segment, e.g. "p" in /p/{short}.
+func isShortCode(parent, segment string) bool {
+	switch parent {
+	case "", "links":
+		// Links can also use vanity aliases
+		return shared.IsValidLinkCode(segment)
+	case "p", "pastes":
+		return shared.IsValidShort(segment, config.Config.PasteShortLength)
+	case "i", "images":
+		return shared.IsValidShort(segment, config.Config.ImageShortLength)
+	case "s", "secrets":
+		return shared.IsValidShort(segment, config.Config.SecretShortLength)
+	case "f", "files":
+		return shared.IsValidShort(segment, config.Config.FileShortLength)
+	default:
+		return false
+	}
+}
+
 func isKnownResource(s string) bool {
 	switch s {
-	case "images", "pastes", "secret":
+	case "images", "pastes", "secret", "files":
 		return true
 	}
 	return false
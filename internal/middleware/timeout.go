@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type responseControllerKey struct{}
+
+// WithResponseController keeps a controller for the underlying connection in the request context.
+// It has to wrap all other middleware, as their response writers don't expose the connection.
+func WithResponseController(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), responseControllerKey{}, http.NewResponseController(w))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Timeout gives a route its own read and write timeout in place of the server wide ones,
+// for routes that move whole files and would otherwise be cut off on slow connections.
+func Timeout(timeout time.Duration, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rc, ok := r.Context().Value(responseControllerKey{}).(*http.ResponseController); ok {
+			deadline := time.Now().Add(timeout)
+			_ = rc.SetReadDeadline(deadline)
+			_ = rc.SetWriteDeadline(deadline)
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
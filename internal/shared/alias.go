@@ -0,0 +1,48 @@
+package shared // nolint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/seq.re/config"
+)
+
+// Length range of vanity aliases for links.
+const (
+	AliasMinLength = 3
+	AliasMaxLength = 64
+)
+
+// reservedAliases are top-level route segments an alias would shadow.
+var reservedAliases = []string{"api", "static", "tab", "web", "i", "s", "p", "oembed"}
+
+// CheckAlias validates a vanity alias and returns a 400 API error describing the problem.
+func CheckAlias(alias string) error {
+	if len(alias) < AliasMinLength || len(alias) > AliasMaxLength {
+		return apierr.NewError(400, "validation", fmt.Sprintf("Alias must be between %d and %d characters", AliasMinLength, AliasMaxLength))
+	}
+
+	for _, c := range alias {
+		if !strings.ContainsRune(shortChars, c) {
+			return apierr.NewError(400, "validation", "Alias may only contain letters, digits, '-' and '_'")
+		}
+	}
+
+	if slices.ContainsFunc(reservedAliases, func(r string) bool { return strings.EqualFold(r, alias) }) {
+		return apierr.NewError(400, "validation", fmt.Sprintf("Alias %q is reserved", alias))
+	}
+
+	return nil
+}
+
+// IsValidAlias reports whether alias is an acceptable vanity alias.
+func IsValidAlias(alias string) bool {
+	return CheckAlias(alias) == nil
+}
+
+// IsValidLinkCode reports whether code is a generated link short code or a vanity alias.
+func IsValidLinkCode(code string) bool {
+	return IsValidShort(code, config.Config.LinkShortLength) || IsValidAlias(code)
+}
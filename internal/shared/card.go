@@ -0,0 +1,78 @@
+package shared // nolint
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/piheta/seq.re/config"
+)
+
+// Card is what chat tools show when a link is unfurled, rendered as OpenGraph tags and served by /oembed.
+type Card struct {
+	Type        string // oEmbed type, "photo" for images with known dimensions and "link" for everything else
+	Title       string
+	Description string
+	URL         string // Canonical URL of the resource
+	Image       string // Preview image, only set for unencrypted images
+	Width       int    // Dimensions of the preview image
+	Height      int
+	Encrypted   bool // Keyed by the URL fragment, which the server never sees
+}
+
+// CardFunc looks up the card of a resource by its short code without using up a view.
+type CardFunc func(short string) (*Card, error)
+
+// Limits of the excerpt shown in paste cards
+const (
+	cardExcerptLines = 5
+	cardExcerptRunes = 300
+)
+
+// PublicURL returns the absolute URL of a path on this server.
+func PublicURL(path string) string {
+	return fmt.Sprintf("%s%s%s", config.Config.RedirectHost, config.Config.RedirectPort, path)
+}
+
+// OEmbedURL returns the oEmbed endpoint describing the card, advertised by the pages that carry it.
+func (c Card) OEmbedURL() string {
+	return PublicURL("/oembed?format=json&url=" + url.QueryEscape(c.URL))
+}
+
+// ProtectedCard describes encrypted and view limited content without revealing anything about it. Unfurl bots
+// only ever see this card, so previews can't leak content or use up a view.
+func ProtectedCard(resourceURL string, encrypted bool) Card {
+	if encrypted {
+		return Card{
+			Type:        "link",
+			Title:       "Encrypted content",
+			Description: "This content is end-to-end encrypted, open the link to decrypt it.",
+			URL:         resourceURL,
+			Encrypted:   true,
+		}
+	}
+	return Card{
+		Type:        "link",
+		Title:       "View limited content",
+		Description: "This content can only be viewed a limited number of times, open the link to reveal it.",
+		URL:         resourceURL,
+	}
+}
+
+// Excerpt returns the first lines of text for a card description, shortened with an ellipsis.
+func Excerpt(text string) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n")), "\n")
+	truncated := len(lines) > cardExcerptLines
+	lines = lines[:min(len(lines), cardExcerptLines)]
+
+	excerpt := strings.TrimRight(strings.Join(lines, "\n"), " \t\n")
+	if utf8.RuneCountInString(excerpt) > cardExcerptRunes {
+		excerpt = string([]rune(excerpt)[:cardExcerptRunes])
+		truncated = true
+	}
+	if truncated {
+		excerpt += "…"
+	}
+	return excerpt
+}
@@ -0,0 +1,22 @@
+package shared // nolint
+
+import (
+	"errors"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+const maxTxnRetries = 5
+
+// UpdateWithRetry runs fn in a read-write transaction, retrying when badger reports a conflict
+// with a concurrent transaction. The retry sees the committed state of the winning transaction.
+func UpdateWithRetry(db *badger.DB, fn func(txn *badger.Txn) error) error {
+	var err error
+	for range maxTxnRetries {
+		err = db.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
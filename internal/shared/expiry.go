@@ -0,0 +1,161 @@
+package shared // nolint
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/seq.re/config"
+)
+
+// ExpiryOption is a choice offered by the expiry selector in the web UI.
+type ExpiryOption struct {
+	Value   string
+	Label   string
+	Default bool
+}
+
+var expiryPresets = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	config.NeverExpires,
+}
+
+// ParseExpiresIn resolves a caller supplied expires_in value against the server's expiry policy.
+// An empty value selects the configured default.
+func ParseExpiresIn(value string) (time.Duration, error) {
+	if value == "" {
+		return config.Config.ExpiryDefault, nil
+	}
+
+	d, err := config.ParseExpiry(value)
+	if err != nil {
+		return 0, apierr.NewError(400, "validation", "Invalid expires_in, use values like 1h, 1d, 30d or never")
+	}
+
+	if !IsExpiryAllowed(d) {
+		return 0, apierr.NewError(400, "validation", "expires_in is not allowed, "+describeExpiryPolicy())
+	}
+
+	return d, nil
+}
+
+// IsExpiryAllowed reports whether the duration satisfies the configured expiry policy.
+// When EXPIRY_ALLOWED is set it is the complete set of choices, otherwise the min/max bounds apply.
+func IsExpiryAllowed(d time.Duration) bool {
+	cfg := config.Config
+
+	if len(cfg.ExpiryAllowed) > 0 {
+		return slices.Contains(cfg.ExpiryAllowed, d)
+	}
+
+	if d == config.NeverExpires {
+		return cfg.ExpiryMax == config.NeverExpires
+	}
+
+	if d < cfg.ExpiryMin {
+		return false
+	}
+
+	return cfg.ExpiryMax == config.NeverExpires || d <= cfg.ExpiryMax
+}
+
+// ExpiresAt converts an expiry duration into an absolute time. Resources that never expire get the zero time.
+func ExpiresAt(d time.Duration) time.Time {
+	if d == config.NeverExpires {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
+// ExpiryOptions returns the expiry choices the web UI should offer, with the server default preselected.
+func ExpiryOptions() []ExpiryOption {
+	cfg := config.Config
+
+	candidates := expiryPresets
+	if len(cfg.ExpiryAllowed) > 0 {
+		candidates = cfg.ExpiryAllowed
+	}
+
+	durations := make([]time.Duration, 0, len(candidates)+1)
+	for _, d := range candidates {
+		if IsExpiryAllowed(d) && !slices.Contains(durations, d) {
+			durations = append(durations, d)
+		}
+	}
+	if !slices.Contains(durations, cfg.ExpiryDefault) {
+		durations = append(durations, cfg.ExpiryDefault)
+	}
+
+	// Shortest first, "never" last
+	slices.SortFunc(durations, func(a, b time.Duration) int {
+		return cmp.Compare(expirySortKey(a), expirySortKey(b))
+	})
+
+	options := make([]ExpiryOption, 0, len(durations))
+	for _, d := range durations {
+		options = append(options, ExpiryOption{
+			Value:   config.FormatExpiry(d),
+			Label:   expiryLabel(d),
+			Default: d == cfg.ExpiryDefault,
+		})
+	}
+	return options
+}
+
+// DescribeExpiry returns a human readable expiry notice for creation results.
+func DescribeExpiry(expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return "This link does not expire."
+	}
+	return "Expires " + expiresAt.UTC().Format("2006-01-02 15:04 MST") + "."
+}
+
+func describeExpiryPolicy() string {
+	cfg := config.Config
+
+	if len(cfg.ExpiryAllowed) > 0 {
+		values := make([]string, 0, len(cfg.ExpiryAllowed))
+		for _, d := range cfg.ExpiryAllowed {
+			values = append(values, config.FormatExpiry(d))
+		}
+		return "must be one of: " + strings.Join(values, ", ")
+	}
+
+	if cfg.ExpiryMax == config.NeverExpires {
+		return fmt.Sprintf("must be at least %s or never", config.FormatExpiry(cfg.ExpiryMin))
+	}
+	return fmt.Sprintf("must be between %s and %s", config.FormatExpiry(cfg.ExpiryMin), config.FormatExpiry(cfg.ExpiryMax))
+}
+
+func expiryLabel(d time.Duration) string {
+	value := config.FormatExpiry(d)
+	if d == config.NeverExpires {
+		return "Never"
+	}
+
+	units := map[byte]string{'d': "day", 'h': "hour", 'm': "minute"}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return value
+	}
+
+	amount := value[:len(value)-1]
+	if amount != "1" {
+		unit += "s"
+	}
+	return amount + " " + unit
+}
+
+func expirySortKey(d time.Duration) time.Duration {
+	if d == config.NeverExpires {
+		return math.MaxInt64
+	}
+	return d
+}
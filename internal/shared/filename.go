@@ -0,0 +1,33 @@
+package shared // nolint
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// ContentDisposition returns a Content-Disposition header of the type, "attachment" or "inline", for the file
+// name. Non-ASCII names are RFC 2231 encoded.
+func ContentDisposition(disposition, fileName string) string {
+	if header := mime.FormatMediaType(disposition, map[string]string{"filename": fileName}); header != "" {
+		return header
+	}
+	return disposition
+}
+
+// SanitizeFileName strips directories and control characters from a client supplied name.
+func SanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
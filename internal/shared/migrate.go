@@ -0,0 +1,127 @@
+package shared // nolint
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// legacyPrefix tells which keyspace a record stored before keyspaces existed belongs to. Links, pastes, images
+// and secrets all lived under their bare short code then and are told apart by the fields they store.
+func legacyPrefix(val []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(val, &fields); err != nil {
+		return ""
+	}
+
+	switch {
+	case fields["URL"] != nil:
+		return LinkPrefix
+	case fields["FilePath"] != nil:
+		return ImagePrefix
+	case fields["Content"] != nil:
+		return PastePrefix
+	case fields["Data"] != nil:
+		return SecretPrefix
+	default:
+		return ""
+	}
+}
+
+// upgradeViewLimit rewrites the single view flag of records stored before view limits. Links, pastes and images
+// marked OneTime allowed one view, secrets always did.
+func upgradeViewLimit(prefix string, val []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(val, &fields); err != nil {
+		return nil, err
+	}
+
+	oneTime := prefix == SecretPrefix || string(fields["OneTime"]) == "true"
+	delete(fields, "OneTime")
+	if oneTime && (fields["MaxViews"] == nil || string(fields["MaxViews"]) == "0") {
+		fields["MaxViews"], fields["ViewsLeft"] = json.RawMessage("1"), json.RawMessage("1")
+	}
+	return json.Marshal(fields)
+}
+
+// MigrateKeys moves records stored under their bare short code into the keyspace of their resource, keeping
+// their expiry and upgrading them to view limits. It has to run before anything reads the database, keys of every keyspace contain a ":" while
+// short codes never do, so already migrated databases are left alone.
+func MigrateKeys(db *badger.DB) error {
+	var legacy [][]byte
+	err := db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if key := it.Item().KeyCopy(nil); !strings.Contains(string(key), ":") {
+				legacy = append(legacy, key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, key := range legacy {
+		moved := false
+		err := UpdateWithRetry(db, func(txn *badger.Txn) error {
+			item, err := txn.Get(key)
+			if err != nil {
+				return err
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			prefix := legacyPrefix(val)
+			if prefix == "" {
+				slog.With("key", string(key)).Warn("unknown record without keyspace, leaving it in place")
+				return nil
+			}
+
+			if val, err = upgradeViewLimit(prefix, val); err != nil {
+				return err
+			}
+
+			// A record allocated after the upgrade wins, the old one stays where it is rather than being lost
+			if err := EnsureUnused(txn, Key(prefix, string(key))); err != nil {
+				return err
+			}
+
+			entry := badger.NewEntry(Key(prefix, string(key)), val)
+			if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+				entry = entry.WithTTL(time.Until(time.Unix(int64(expiresAt), 0)))
+			}
+			if err := txn.SetEntry(entry); err != nil {
+				return err
+			}
+			moved = true
+			return txn.Delete(key)
+		})
+		if errors.Is(err, ErrShortTaken) {
+			slog.With("key", string(key)).Warn("short code of a record without keyspace is taken, leaving it in place")
+			continue
+		}
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		if moved {
+			migrated++
+		}
+	}
+
+	if migrated > 0 {
+		slog.With("count", migrated).Info("moved records into their keyspaces")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package shared // nolint
+
+import "time"
+
+// CreatedResponse is returned by the create endpoints of every shareable resource.
+// ExpiresAt is omitted for resources that never expire. DeletionToken and EditToken are only ever shown here.
+type CreatedResponse struct {
+	URL           string     `json:"url"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	DeletionToken string     `json:"deletion_token"`
+	EditToken     string     `json:"edit_token,omitempty"` // Only set for editable pastes
+}
+
+func NewCreatedResponse(url string, expiresAt time.Time, deletionToken string) CreatedResponse {
+	resp := CreatedResponse{URL: url, DeletionToken: deletionToken}
+	if !expiresAt.IsZero() {
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
+
+// RevealResponse hands out the reveal token of a resource without using up a view. The token is sent along with
+// POST /api/{kind}/{short}/reveal, the way the interstitial page sends its own.
+type RevealResponse struct {
+	RevealToken string `json:"reveal_token"`
+	ViewsLeft   *int   `json:"views_left,omitempty"` // Only set for view limited resources
+}
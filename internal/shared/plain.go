@@ -0,0 +1,109 @@
+package shared // nolint
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/piheta/apicore/apierr"
+)
+
+// plainOptions maps the query parameters and headers of plain uploads to the upload fields multipart forms send,
+// so `curl -T - host/p?expires=1d` and `curl -H "X-Expires: 1d" -T - host/p` are read like expires_in.
+var plainOptions = []struct {
+	field  string
+	query  string
+	header string
+}{
+	{"expires_in", "expires", "X-Expires"},
+	{"max_views", "views", "X-Views"},
+	{"onetime", "onetime", "X-Onetime"},
+	{"language", "language", "X-Language"},
+	{"filename", "filename", "X-Filename"},
+}
+
+// ReceiveBody streams the raw body of a plain upload, as sent by `curl --data-binary @file` or `curl -T file`, into
+// dir. Options come from the query or headers, a file name from the {name} path segment curl -T appends. Bodies
+// larger than maxSize are rejected with 413.
+func ReceiveBody(w http.ResponseWriter, r *http.Request, dir string, maxSize int64) (*Upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+
+	tmp, err := CreateTempUpload(dir)
+	if err != nil {
+		return nil, apierr.NewError(500, "write_error", "Failed to store file")
+	}
+	upload := &Upload{Path: tmp.Name(), Plain: true, Fields: plainFields(r)}
+
+	head := &headBuffer{limit: 512}
+	n, err := io.Copy(tmp, io.TeeReader(r.Body, head))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		upload.discard()
+		return nil, uploadError(err)
+	}
+	if n == 0 {
+		upload.discard()
+		return nil, apierr.NewError(400, "invalid_request", "No content provided")
+	}
+
+	upload.Size = n
+	upload.Sniffed = http.DetectContentType(head.Bytes())
+	// curl --data-binary declares every body as a form, which says nothing about the content
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/x-www-form-urlencoded" {
+		upload.ContentType = r.Header.Get("Content-Type")
+	}
+	// curl -T - appends nothing, but "curl -T - host/" names the upload "-"
+	if name := r.PathValue("name"); name != "-" {
+		upload.FileName = name
+	}
+	return upload, nil
+}
+
+// plainFields reads the options of a plain upload, query parameters take precedence over headers. Flags like
+// ?onetime may be given without a value.
+func plainFields(r *http.Request) map[string]string {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+	for _, option := range plainOptions {
+		value := r.Header.Get(option.header)
+		if query.Has(option.query) {
+			value = query.Get(option.query)
+			if value == "" {
+				value = "true"
+			}
+		}
+		if option.field == "onetime" && value != "" {
+			value = fmt.Sprint(value != "false" && value != "0")
+		}
+		if value != "" {
+			fields[option.field] = value
+		}
+	}
+	return fields
+}
+
+// WritePlainCreated answers a plain upload in plain text. The URL comes first on a line of its own, so
+// `curl ... | head -1` is just the link, followed by the expiry and the command that deletes it again. The
+// deletion token is sent as a header too.
+func WritePlainCreated(w http.ResponseWriter, created CreatedResponse, deleteURL string) error {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(DeletionTokenHeader, created.DeletionToken)
+	w.WriteHeader(http.StatusCreated)
+
+	var expiresAt time.Time
+	if created.ExpiresAt != nil {
+		expiresAt = *created.ExpiresAt
+	}
+
+	var b strings.Builder
+	b.WriteString(created.URL + "\n")
+	b.WriteString(DescribeExpiry(expiresAt) + "\n")
+	fmt.Fprintf(&b, "Delete with: curl -X DELETE -H '%s: %s' %s\n", DeletionTokenHeader, created.DeletionToken, deleteURL)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
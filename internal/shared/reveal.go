@@ -0,0 +1,153 @@
+package shared // nolint
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/apicore/response"
+	"github.com/piheta/seq.re/config"
+)
+
+// RevealTokenHeader carries the reveal token on requests that use up a view, forms send it as reveal_token.
+const RevealTokenHeader = "X-Reveal-Token"
+
+// RevealTokenTTL is how long the interstitial of a view limited resource can be left open before revealing.
+const RevealTokenTTL = 30 * time.Minute
+
+// revealKey signs reveal tokens. Without REVEAL_SECRET it only lives as long as the process, a restart merely asks
+// viewers to reload, but instances behind a load balancer would refuse each other's tokens.
+var revealKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key) // nolint
+	return key
+}()
+
+// InitRevealKey derives the key that signs reveal tokens from REVEAL_SECRET, so tokens survive restarts and are
+// accepted by every instance sharing the secret.
+func InitRevealKey() {
+	if config.Config.RevealSecret == "" {
+		slog.Warn("REVEAL_SECRET is not set, reveal tokens are only accepted by this instance until it restarts")
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.Config.RevealSecret))
+	mac.Write([]byte("seq.re reveal tokens"))
+	revealKey = mac.Sum(nil)
+}
+
+// unfurlBots are user agent fragments of link preview and crawler bots, which must never use up a view.
+var unfurlBots = []string{
+	"slackbot", "slack-imgproxy", "discordbot", "twitterbot", "facebookexternalhit", "facebookcatalog",
+	"linkedinbot", "telegrambot", "whatsapp", "skypeuripreview", "microsoftpreview", "mattermost",
+	"rocket.chat", "zulip", "redditbot", "pinterest", "embedly", "iframely", "vkshare",
+	"googlebot", "bingbot", "applebot", "duckduckbot", "yandexbot", "baiduspider", "bitlybot", "google-pagerenderer",
+}
+
+// IsUnfurlBot reports whether the request comes from a link preview or crawler bot. HEAD requests count as bots
+// too, they are how unfurlers probe a URL before fetching it.
+func IsUnfurlBot(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	userAgent := strings.ToLower(r.UserAgent())
+	for _, bot := range unfurlBots {
+		if strings.Contains(userAgent, bot) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRevealToken returns a token that allows revealing the resource until it expires. kind is the API collection
+// of the resource, such as "pastes".
+func NewRevealToken(kind, short string) string {
+	expires := strconv.FormatInt(time.Now().Add(RevealTokenTTL).Unix(), 10)
+	return expires + "." + signReveal(kind, short, expires)
+}
+
+// CheckReveal guards requests that use up a view. Bots are turned away, the request has to name this server as
+// its origin, and every request needs a reveal token of the resource minted by its interstitial or
+// GET /api/{kind}/{short}/reveal.
+func CheckReveal(r *http.Request, kind, short string) error {
+	if err := checkRevealOrigin(r); err != nil {
+		return err
+	}
+
+	token := r.Header.Get(RevealTokenHeader)
+	if token == "" {
+		token = r.PostFormValue("reveal_token")
+	}
+
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return apierr.NewError(403, "forbidden", "Missing reveal token, reload the page and try again")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix || !hmac.Equal([]byte(signature), []byte(signReveal(kind, short, expires))) {
+		return apierr.NewError(403, "forbidden", "Invalid or expired reveal token, reload the page and try again")
+	}
+
+	return nil
+}
+
+// StartReveal answers GET /api/{kind}/{short}/reveal with a reveal token for the resource. Tokens are only handed
+// out to requests from this server's origin, the same ones that may spend them.
+func StartReveal(w http.ResponseWriter, r *http.Request, kind, short string, maxViews, viewsLeft int) error {
+	if err := checkRevealOrigin(r); err != nil {
+		return err
+	}
+
+	return response.JSON(w, 200, RevealResponse{
+		RevealToken: NewRevealToken(kind, short),
+		ViewsLeft:   RemainingViews(maxViews, viewsLeft),
+	})
+}
+
+// RevealRequired is returned to ?cli=true requests for view limited resources, which no longer use up a view
+// because any bot can append the flag.
+func RevealRequired(kind, short string) error {
+	return apierr.NewError(403, "reveal_required", "View limited content is revealed with GET and then POST /api/"+kind+"/"+short+"/reveal")
+}
+
+func signReveal(kind, short, expires string) string {
+	mac := hmac.New(sha256.New, revealKey)
+	mac.Write([]byte(kind + "/" + short + "/" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func checkRevealOrigin(r *http.Request) error {
+	if IsUnfurlBot(r) {
+		return apierr.NewError(403, "forbidden", "Link previews can't reveal view limited content")
+	}
+	if !isSameOrigin(r) {
+		return apierr.NewError(403, "forbidden", "Content can only be revealed from its own page or the CLI")
+	}
+	return nil
+}
+
+// isSameOrigin reports whether the request names this server as its origin. Browsers send Sec-Fetch-Site or Origin
+// on the interstitial's requests and pages can't forge either, the CLI sends the Origin of the server it talks to.
+// Requests with neither header are refused, unfurlers and scanners that merely follow links never send them.
+func isSameOrigin(r *http.Request) bool {
+	site := r.Header.Get("Sec-Fetch-Site")
+	if site != "" && site != "same-origin" {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return site == "same-origin"
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,45 @@
+package shared // nolint
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// DailySalt returns the random salt for the current UTC day, creating it on first use.
+// Salts expire shortly after their day ends, so hashes made with them can't be linked across days.
+func DailySalt(db *badger.DB, now time.Time) ([]byte, error) {
+	key := []byte(SaltPrefix + now.UTC().Format(time.DateOnly))
+
+	var salt []byte
+	err := UpdateWithRetry(db, func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == nil {
+			salt, err = item.ValueCopy(nil)
+			return err
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		salt = make([]byte, 32)
+		rand.Read(salt) // nolint
+		return txn.SetEntry(badger.NewEntry(key, salt).WithTTL(48 * time.Hour))
+	})
+	return salt, err
+}
+
+// HashVisitor derives an anonymous visitor id from the salt and identifying values such as the IP.
+func HashVisitor(salt []byte, values ...string) string {
+	h := sha256.New()
+	h.Write(salt)
+	for _, v := range values {
+		h.Write([]byte{0})
+		h.Write([]byte(v))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
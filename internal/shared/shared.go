@@ -1,7 +1,6 @@
 package shared // nolint
 
 import (
-	"crypto/rand"
 	"net"
 	"net/http"
 	"strings"
@@ -35,12 +34,22 @@ func GetIP(r *http.Request) string {
 	return host
 }
 
-func CreateShort() string {
-	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
-	b := make([]byte, 6)
-	rand.Read(b) // nolint
-	for i := range b {
-		b[i] = chars[b[i]%byte(len(chars))]
+// NetworkPrefix coarsens an IP address to its /24 (IPv4) or /48 (IPv6) network,
+// so hashes of it tell networks apart without identifying a single host.
+func NetworkPrefix(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
 	}
-	return string(b)
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// WantsHTML reports whether the client opens the URL as a page, as browsers and most unfurl bots do, rather than
+// loading it as a resource such as the source of an image tag.
+func WantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
 }
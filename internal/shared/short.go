@@ -0,0 +1,100 @@
+package shared // nolint
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+)
+
+// Key prefixes that give every resource type its own keyspace.
+const (
+	LinkPrefix   = "l:"
+	PastePrefix  = "p:"
+	ImagePrefix  = "i:"
+	SecretPrefix = "s:"
+	FilePrefix   = "f:"
+
+	LinkStatsPrefix     = "ls:" // Click counters of a link
+	PasteRevisionPrefix = "pr:" // Earlier revisions of an editable paste, keyed by short and revision number
+	LinkVisitorPrefix   = "lv:" // Hashed visitors seen today, used to count unique visitors
+	SecretReceiptPrefix = "sr:" // Read receipt of a secret, outlives the secret itself
+	UploadPrefix        = "u:"  // Chunked upload in progress
+	SaltPrefix          = "salt:"
+)
+
+const shortChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+
+// Attempts at one code length before allocation moves on to a longer code.
+const shortAttemptsPerLength = 3
+
+var (
+	ErrShortTaken     = errors.New("short code already in use")
+	ErrShortExhausted = errors.New("no free short code available")
+)
+
+// CreateShort returns a random short code of the given length.
+func CreateShort(length int) string {
+	b := make([]byte, length)
+	rand.Read(b) // nolint
+	for i := range b {
+		b[i] = shortChars[b[i]%byte(len(shortChars))]
+	}
+	return string(b)
+}
+
+// AllocateShort calls claim with random codes until one is free. claim must return ErrShortTaken
+// when the code exists. The code grows by one character after repeated collisions.
+func AllocateShort(length int, claim func(short string) error) error {
+	for length = startLength(length); length <= config.MaxShortLength; length++ {
+		for range shortAttemptsPerLength {
+			if err := claim(CreateShort(length)); !errors.Is(err, ErrShortTaken) {
+				return err
+			}
+		}
+	}
+	return ErrShortExhausted
+}
+
+// Key returns the database key of a short code within a resource keyspace.
+func Key(prefix, short string) []byte {
+	return []byte(prefix + short)
+}
+
+// EnsureUnused returns ErrShortTaken when key already exists. Calling it in the transaction
+// that writes the key makes the write conflict-safe against concurrent allocations.
+func EnsureUnused(txn *badger.Txn, key []byte) error {
+	_, err := txn.Get(key)
+	switch {
+	case err == nil:
+		return ErrShortTaken
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// IsValidShort reports whether short could have been allocated with the given starting length. Codes of the
+// default length stay valid after the configured length is raised, it only applies to new allocations.
+func IsValidShort(short string, length int) bool {
+	if len(short) < min(startLength(length), config.DefaultShortLength) || len(short) > config.MaxShortLength {
+		return false
+	}
+	for _, c := range short {
+		if !strings.ContainsRune(shortChars, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// startLength guards against an unset config, where lengths are zero.
+func startLength(length int) int {
+	if length < config.MinShortLength {
+		return config.DefaultShortLength
+	}
+	return length
+}
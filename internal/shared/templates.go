@@ -13,7 +13,10 @@ type TemplateService struct {
 	onetimeReveal *template.Template
 	error         *template.Template
 	redirect      *template.Template
+	linkPreview   *template.Template
 	imageDecrypt  *template.Template
+	fileDecrypt   *template.Template
+	imageViewer   *template.Template
 	index         *template.Template
 	partials      *template.Template
 	version       string
@@ -22,13 +25,16 @@ type TemplateService struct {
 
 func NewTemplateService(version, contactEmail string) *TemplateService {
 	return &TemplateService{
-		contentViewer: template.Must(template.ParseFiles("web/templates/content-viewer.html")),
+		contentViewer: parsePage("web/templates/content-viewer.html"),
 		result:        template.Must(template.ParseFiles("web/templates/partials/generic-result.html")),
-		onetime:       template.Must(template.ParseFiles("web/templates/onetime.html")),
+		onetime:       parsePage("web/templates/onetime.html"),
 		onetimeReveal: template.Must(template.ParseFiles("web/templates/partials/onetime-revealed.html")),
 		error:         template.Must(template.ParseFiles("web/templates/error.html")),
-		redirect:      template.Must(template.ParseFiles("web/templates/redirect.html")),
-		imageDecrypt:  template.Must(template.ParseFiles("web/templates/image-decrypt.html")),
+		redirect:      parsePage("web/templates/redirect.html"),
+		linkPreview:   parsePage("web/templates/link-preview.html"),
+		imageDecrypt:  parsePage("web/templates/image-decrypt.html"),
+		fileDecrypt:   parsePage("web/templates/file-decrypt.html"),
+		imageViewer:   parsePage("web/templates/image-viewer.html"),
 		index:         loadIndexTemplate(),
 		partials:      template.Must(template.ParseGlob("web/templates/partials/*.html")),
 		version:       version,
@@ -36,6 +42,11 @@ func NewTemplateService(version, contactEmail string) *TemplateService {
 	}
 }
 
+// parsePage parses a page that shares links with its unfurl metadata.
+func parsePage(path string) *template.Template {
+	return template.Must(template.ParseFiles(path, "web/templates/meta.html"))
+}
+
 func loadIndexTemplate() *template.Template {
 	tmpl := template.Must(template.ParseGlob("web/templates/partials/*.html"))
 	return template.Must(tmpl.ParseFiles("web/templates/index.html"))
@@ -94,10 +105,22 @@ func (ts *TemplateService) RenderRedirect(w io.Writer, data any) error {
 	return ts.redirect.Execute(w, data)
 }
 
+func (ts *TemplateService) RenderLinkPreview(w io.Writer, data any) error {
+	return ts.linkPreview.Execute(w, ts.mergeFooterData(data))
+}
+
 func (ts *TemplateService) RenderImageDecrypt(w io.Writer, data any) error {
 	return ts.imageDecrypt.Execute(w, data)
 }
 
+func (ts *TemplateService) RenderFileDecrypt(w io.Writer, data any) error {
+	return ts.fileDecrypt.Execute(w, data)
+}
+
+func (ts *TemplateService) RenderImageViewer(w io.Writer, data any) error {
+	return ts.imageViewer.Execute(w, ts.mergeFooterData(data))
+}
+
 func (ts *TemplateService) RenderIndexTemplate(w io.Writer, name string, data any) error {
 	return ts.index.ExecuteTemplate(w, name, data)
 }
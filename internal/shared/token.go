@@ -0,0 +1,62 @@
+package shared // nolint
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/apicore/apierr"
+)
+
+// DeletionTokenHeader carries the deletion token on DELETE requests.
+const DeletionTokenHeader = "X-Deletion-Token"
+
+var ErrInvalidDeletionToken = errors.New("invalid deletion token")
+
+// NewDeletionToken returns a random token for the creator and the hash to store in its place.
+func NewDeletionToken() (token, hash string) {
+	b := make([]byte, 24)
+	rand.Read(b) // nolint
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashDeletionToken(token)
+}
+
+// HashDeletionToken returns the stored form of a deletion token.
+func HashDeletionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckDeletionToken compares a presented token against the stored hash in constant time.
+// Records created before deletion tokens existed have no hash and can't be deleted.
+func CheckDeletionToken(token, hash string) error {
+	if token == "" || hash == "" {
+		return ErrInvalidDeletionToken
+	}
+	if subtle.ConstantTimeCompare([]byte(HashDeletionToken(token)), []byte(hash)) != 1 {
+		return ErrInvalidDeletionToken
+	}
+	return nil
+}
+
+// DeletionToken extracts the deletion token from a request.
+func DeletionToken(r *http.Request) string {
+	return r.Header.Get(DeletionTokenHeader)
+}
+
+// TokenError maps errors from token protected service calls, such as Revoke*, to API errors.
+func TokenError(err error, notFoundMessage string) error {
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return apierr.NewError(404, "not_found", notFoundMessage)
+	case errors.Is(err, ErrInvalidDeletionToken):
+		return apierr.NewError(403, "forbidden", "Invalid deletion token")
+	default:
+		return err
+	}
+}
@@ -0,0 +1,250 @@
+package shared // nolint
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/piheta/apicore/apierr"
+)
+
+// TempUploadExt marks files that are still being received, cleanup workers leave them alone until they are stale.
+const TempUploadExt = ".part"
+
+// StaleUploadAge is how long an unfinished upload is kept before cleanup removes it.
+const StaleUploadAge = 24 * time.Hour
+
+// maxFieldSize caps the plain form fields sent along with an upload.
+const maxFieldSize = 64 << 10
+
+// Upload is a file received from a multipart form. Its content is streamed to a temporary file in the
+// upload directory, so it can be moved into place without ever being held in memory.
+type Upload struct {
+	Path        string // Temporary file, moved into place by the service or removed with Remove
+	Size        int64
+	FileName    string // Name of the uploaded part as sent by the client
+	ContentType string // Declared by the client, may be empty
+	Sniffed     string // Detected from the first 512 bytes
+	Fields      map[string]string
+	Plain       bool // Sent as a raw request body by curl, answered in plain text
+}
+
+// Value returns a form field sent with the upload, empty when missing.
+func (u *Upload) Value(key string) string {
+	return u.Fields[key]
+}
+
+// Remove deletes the temporary file, services that moved it into place leave nothing to remove.
+func (u *Upload) Remove() {
+	_ = os.Remove(u.Path)
+}
+
+// WriteTempUpload writes content held in memory to a temporary file in dir and returns its path.
+func WriteTempUpload(dir string, data []byte) (string, error) {
+	tmp, err := CreateTempUpload(dir)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// UploadInProgress reports whether a directory entry is an upload that is still being received.
+// Unfinished uploads that have not been written to for StaleUploadAge are fair game for cleanup.
+func UploadInProgress(entry os.DirEntry) bool {
+	if filepath.Ext(entry.Name()) != TempUploadExt {
+		return false
+	}
+	info, err := entry.Info()
+	return err == nil && time.Since(info.ModTime()) < StaleUploadAge
+}
+
+// CreateTempUpload creates a temporary file in dir for content that is moved into place once complete.
+func CreateTempUpload(dir string) (*os.File, error) {
+	return os.CreateTemp(dir, "upload-*"+TempUploadExt)
+}
+
+// ReceiveUpload streams the "file" part of a multipart request into dir and collects the other fields,
+// which may come before or after the file. Uploads larger than maxSize are rejected with 413.
+func ReceiveUpload(w http.ResponseWriter, r *http.Request, dir string, maxSize int64) (*Upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+maxFieldSize)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, apierr.NewError(400, "invalid_request", "Failed to parse multipart form")
+	}
+
+	upload := &Upload{Fields: make(map[string]string)}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			upload.discard()
+			return nil, uploadError(err)
+		}
+
+		if part.FormName() == "file" && upload.Path == "" {
+			err = upload.receiveFile(part, dir, maxSize)
+		} else {
+			err = upload.receiveField(part)
+		}
+		_ = part.Close()
+		if err != nil {
+			upload.discard()
+			return nil, err
+		}
+	}
+
+	if upload.Path == "" {
+		return nil, apierr.NewError(400, "invalid_request", "No file provided")
+	}
+	return upload, nil
+}
+
+// UploadFromFile describes a completely received temporary file as an upload, detecting its type from its content.
+func UploadFromFile(path string, fileName, contentType string, fields map[string]string) (*Upload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return &Upload{
+		Path:        path,
+		Size:        info.Size(),
+		FileName:    fileName,
+		ContentType: contentType,
+		Sniffed:     http.DetectContentType(head[:n]),
+		Fields:      fields,
+	}, nil
+}
+
+func (u *Upload) receiveFile(part *multipart.Part, dir string, maxSize int64) error {
+	tmp, err := CreateTempUpload(dir)
+	if err != nil {
+		return apierr.NewError(500, "write_error", "Failed to store file")
+	}
+	u.Path = tmp.Name()
+	u.FileName = part.FileName()
+	u.ContentType = part.Header.Get("Content-Type")
+
+	// Keep the head of the file around to detect its type
+	head := &headBuffer{limit: 512}
+	n, err := io.Copy(tmp, io.TeeReader(io.LimitReader(part, maxSize+1), head))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return uploadError(err)
+	}
+	if n > maxSize {
+		return apierr.NewError(413, "too_large", "File too large")
+	}
+
+	u.Size = n
+	u.Sniffed = http.DetectContentType(head.Bytes())
+	return nil
+}
+
+func (u *Upload) receiveField(part *multipart.Part) error {
+	value, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+	if err != nil {
+		return uploadError(err)
+	}
+	if len(value) > maxFieldSize {
+		return apierr.NewError(400, "validation", "Form field too large")
+	}
+	if name := part.FormName(); name != "" {
+		u.Fields[name] = string(value)
+	}
+	return nil
+}
+
+func (u *Upload) discard() {
+	if u.Path != "" {
+		u.Remove()
+	}
+}
+
+func uploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return apierr.NewError(413, "too_large", "File too large")
+	}
+	return apierr.NewError(400, "invalid_request", "Failed to read upload")
+}
+
+// headBuffer keeps the first limit bytes written to it and discards the rest.
+type headBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// StreamDataJSON writes a JSON object whose "data" field is src encoded as base64, followed by the fields of
+// rest. Encrypted blobs are streamed this way so they are never base64 encoded in memory.
+func StreamDataJSON(w http.ResponseWriter, status int, src io.Reader, rest any) error {
+	tail, err := json.Marshal(rest)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, _ = io.WriteString(w, `{"data":"`)
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(encoder, src); err != nil {
+		// The status is already sent, all that is left is to cut the response short
+		slog.With("error", err).Warn("failed to stream response data")
+		return nil
+	}
+	_ = encoder.Close()
+	_, _ = io.WriteString(w, `"`)
+
+	// rest is an object, its fields follow the data field
+	if len(tail) > 2 {
+		_, _ = io.WriteString(w, ",")
+		_, _ = w.Write(tail[1:])
+	} else {
+		_, _ = io.WriteString(w, "}")
+	}
+	return nil
+}
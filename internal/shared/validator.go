@@ -1,6 +1,7 @@
 package shared // nolint
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -8,14 +9,21 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/seq.re/internal/languages"
+	"golang.org/x/net/idna"
 )
 
 var (
 	Validate *validator.Validate
 )
 
+// resolveTimeout bounds host lookups, a slow DNS server mustn't stall link creation or redirects.
+const resolveTimeout = 3 * time.Second
+
 func InitValidator() {
 	Validate = validator.New()
 
@@ -23,6 +31,11 @@ func InitValidator() {
 		slog.With("error", err).Error("Failed to Init notprivateip validation")
 		panic(err)
 	}
+
+	if err := Validate.RegisterValidation("language", ValidateLanguage); err != nil {
+		slog.With("error", err).Error("Failed to Init language validation")
+		panic(err)
+	}
 }
 
 func getStringValue(v reflect.Value) string {
@@ -42,6 +55,12 @@ func ValidateIsNotPrivateIP(fl validator.FieldLevel) bool {
 	return false
 }
 
+// ValidateLanguage accepts IDs and aliases of the languages registry.
+func ValidateLanguage(fl validator.FieldLevel) bool {
+	_, ok := languages.Normalize(getStringValue(fl.Field()))
+	return ok
+}
+
 func validateURL(rawURL string) error {
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
@@ -52,12 +71,20 @@ func validateURL(rawURL string) error {
 		return errors.New("invalid URL scheme: only http and https are allowed")
 	}
 
-	hostname := parsedURL.Hostname()
+	hostname, err := NormalizeHost(parsedURL.Hostname())
+	if err != nil {
+		return err
+	}
 	if hostname == "" {
 		return errors.New("URL must have a valid hostname")
 	}
 
-	if hostname == "localhost" || hostname == "localhost.localdomain" {
+	// Links have no use for credentials, user info (https://good.com@evil.com) only hides where the link really goes
+	if parsedURL.User != nil {
+		return errors.New("URL must not contain user info")
+	}
+
+	if hostname == "localhost" || hostname == "localhost.localdomain" || strings.HasSuffix(hostname, ".localhost") {
 		return errors.New("localhost URLs are not allowed")
 	}
 
@@ -68,6 +95,10 @@ func validateURL(rawURL string) error {
 		return nil
 	}
 
+	if endsInNumber(hostname) {
+		return fmt.Errorf("IP addresses must be written as four decimal numbers: %s", hostname)
+	}
+
 	lastDot := strings.LastIndex(hostname, ".")
 	if lastDot == -1 || lastDot == len(hostname)-1 {
 		return errors.New("invalid domain: must include a TLD (e.g., .com, .no)")
@@ -76,6 +107,100 @@ func validateURL(rawURL string) error {
 	return nil
 }
 
+// CheckResolvedURL resolves the host of a validated link destination, so names that resolve to internal addresses
+// are rejected too. A nil resolver skips the lookup.
+func CheckResolvedURL(ctx context.Context, resolver *net.Resolver, rawURL string) error {
+	hostname := resolvableHost(rawURL)
+	if resolver == nil || hostname == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return apierr.NewError(400, "validation", fmt.Sprintf("Could not resolve %s", hostname))
+	}
+	if ip := internalAddress(addrs); ip != nil {
+		return apierr.NewError(400, "validation", fmt.Sprintf("%s resolves to an internal network address", hostname))
+	}
+	return nil
+}
+
+// CheckDestinationAddress resolves the host of a stored link again before redirecting to it, a domain that was
+// public when the link was created may point to an internal address by now (DNS rebinding). Failed lookups don't
+// stop the redirect, only internal results do. A nil resolver skips the lookup.
+func CheckDestinationAddress(ctx context.Context, resolver *net.Resolver, rawURL string) error {
+	hostname := resolvableHost(rawURL)
+	if resolver == nil || hostname == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil
+	}
+	if ip := internalAddress(addrs); ip != nil {
+		slog.With("host", hostname).With("ip", ip.String()).Warn("link destination resolves to an internal address")
+		return apierr.NewError(403, "private_destination", "This link points to an internal network address")
+	}
+	return nil
+}
+
+// resolvableHost returns the normalized host of a URL, empty when there is no name to look up.
+func resolvableHost(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	hostname, err := NormalizeHost(parsedURL.Hostname())
+	if err != nil || net.ParseIP(hostname) != nil {
+		return ""
+	}
+	return hostname
+}
+
+// NormalizeHost lowercases a host, drops a trailing dot and converts international domain names to punycode, the
+// form DNS and the domain lists use. Look-alike characters such as full-width letters are mapped like browsers map
+// them, so ｌｏｃａｌｈｏｓｔ is localhost.
+func NormalizeHost(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(host) != nil {
+		return host, nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain name %q: %w", host, err)
+	}
+	return ascii, nil
+}
+
+// endsInNumber reports whether browsers treat a host as an IPv4 address. Besides dotted decimal they accept
+// decimal (2130706433), octal (0177.0.0.1), hex (0x7f.1) and shortened (127.1) forms, which net.ParseIP doesn't.
+func endsInNumber(host string) bool {
+	last := host[strings.LastIndex(host, ".")+1:]
+	if hex, ok := strings.CutPrefix(last, "0x"); ok {
+		return strings.Trim(hex, "0123456789abcdef") == ""
+	}
+	return last != "" && strings.Trim(last, "0123456789") == ""
+}
+
+// internalAddress returns the first internal address of a lookup, a host resolving to a public and a private
+// address is as dangerous as one resolving to the private one only.
+func internalAddress(addrs []net.IPAddr) net.IP {
+	for _, addr := range addrs {
+		if isInternalIP(addr.IP) {
+			return addr.IP
+		}
+	}
+	return nil
+}
+
 func isInternalIP(ip net.IP) bool {
 	// Check if it's an IPv4 address (To4 returns non-nil for IPv4)
 	if ip.To4() != nil {
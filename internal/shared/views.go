@@ -0,0 +1,47 @@
+package shared // nolint
+
+import (
+	"fmt"
+
+	"github.com/piheta/apicore/apierr"
+)
+
+// ViewsLeftHeader carries the remaining views on raw responses of view limited resources.
+const ViewsLeftHeader = "X-Views-Left"
+
+// MaxViewsLimit caps max_views, a resource meant to be seen more often than this should not be view limited.
+const MaxViewsLimit = 1000
+
+// ViewLimit resolves the max_views and onetime request fields into the number of views a resource allows.
+// onetime is shorthand for a single view, 0 means unlimited.
+func ViewLimit(maxViews int, onetime bool) (int, error) {
+	if maxViews < 0 || maxViews > MaxViewsLimit {
+		return 0, apierr.NewError(400, "validation", fmt.Sprintf("max_views must be between 1 and %d", MaxViewsLimit))
+	}
+
+	if maxViews == 0 && onetime {
+		return 1, nil
+	}
+
+	return maxViews, nil
+}
+
+// RemainingViews returns the views left for responses, nil when the resource is not view limited.
+func RemainingViews(maxViews, viewsLeft int) *int {
+	if maxViews == 0 {
+		return nil
+	}
+	return &viewsLeft
+}
+
+// ViewsWarning describes the view limit on the result of a create request, empty when there is none.
+func ViewsWarning(maxViews int) string {
+	switch maxViews {
+	case 0:
+		return ""
+	case 1:
+		return "This link will self-destruct after being viewed once."
+	default:
+		return fmt.Sprintf("This link will self-destruct after being viewed %d times.", maxViews)
+	}
+}
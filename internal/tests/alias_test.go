@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestCreateLinkWithAlias(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLinkWithAlias("deploy-guide", "https://example.com/deploy", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create aliased link: %v", err)
+	}
+
+	if created.Short != "deploy-guide" {
+		t.Errorf("expected short deploy-guide, got %s", created.Short)
+	}
+
+	if created.DeletionToken == "" {
+		t.Error("expected deletion token for aliased link")
+	}
+
+	retrieved, err := service.GetLinkByShort("deploy-guide")
+	if err != nil {
+		t.Fatalf("failed to retrieve aliased link: %v", err)
+	}
+
+	if retrieved.URL != "https://example.com/deploy" {
+		t.Errorf("expected URL https://example.com/deploy, got %s", retrieved.URL)
+	}
+}
+
+func TestCreateLinkWithTakenAlias(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	if _, err := service.CreateLinkWithAlias("runbook", "https://example.com/first", false, false, 0, testExpiry, false); err != nil {
+		t.Fatalf("failed to create aliased link: %v", err)
+	}
+
+	_, err := service.CreateLinkWithAlias("runbook", "https://example.com/second", false, false, 0, testExpiry, false)
+	if !errors.Is(err, shared.ErrShortTaken) {
+		t.Fatalf("expected ErrShortTaken, got %v", err)
+	}
+
+	retrieved, err := service.GetLinkByShort("runbook")
+	if err != nil {
+		t.Fatalf("failed to retrieve aliased link: %v", err)
+	}
+
+	if retrieved.URL != "https://example.com/first" {
+		t.Errorf("expected the first link to be kept, got %s", retrieved.URL)
+	}
+}
+
+func TestAliasCannotTakeGeneratedCode(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	generated, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	_, err = service.CreateLinkWithAlias(generated.Short, "https://example.com/other", false, false, 0, testExpiry, false)
+	if !errors.Is(err, shared.ErrShortTaken) {
+		t.Errorf("expected ErrShortTaken, got %v", err)
+	}
+}
+
+func TestCheckAlias(t *testing.T) {
+	tests := []struct {
+		alias string
+		valid bool
+	}{
+		{"deploy-guide", true},
+		{"Runbook_2024", true},
+		{"abc", true},
+		{strings.Repeat("a", 64), true},
+		{"ab", false},
+		{strings.Repeat("a", 65), false},
+		{"with space", false},
+		{"dots.not.allowed", false},
+		{"slash/alias", false},
+		{"api", false},
+		{"static", false},
+		{"tab", false},
+		{"web", false},
+		{"API", false},
+		{"i", false},
+		{"s", false},
+		{"p", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			err := shared.CheckAlias(tt.alias)
+			if tt.valid && err != nil {
+				t.Errorf("expected %q to be valid, got %v", tt.alias, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected %q to be rejected", tt.alias)
+			}
+		})
+	}
+}
+
+func TestIsValidLinkCode(t *testing.T) {
+	tests := []struct {
+		code  string
+		valid bool
+	}{
+		{"abc123", true},
+		{"deploy-guide", true},
+		{strings.Repeat("a", 40), true},
+		{"api", false},
+		{"ab", false},
+		{"bad!code", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := shared.IsValidLinkCode(tt.code); got != tt.valid {
+				t.Errorf("IsValidLinkCode(%q) = %v, want %v", tt.code, got, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/secret"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestDeletionTokenIsNotStored(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := link.NewLinkRepo(db)
+	service := link.NewLinkService(repo, nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	if created.DeletionToken == "" {
+		t.Fatal("expected deletion token to be returned on creation")
+	}
+
+	stored, err := repo.GetByShort(created.Short)
+	if err != nil {
+		t.Fatalf("failed to retrieve link: %v", err)
+	}
+
+	if stored.DeletionToken != "" {
+		t.Error("expected plain deletion token not to be stored")
+	}
+
+	if stored.DeletionTokenHash != shared.HashDeletionToken(created.DeletionToken) {
+		t.Error("expected stored hash to match the returned token")
+	}
+}
+
+func TestDeletionTokensAreUnique(t *testing.T) {
+	tokens := make(map[string]bool)
+	for range 100 {
+		token, _ := shared.NewDeletionToken()
+		if tokens[token] {
+			t.Fatalf("duplicate deletion token %s", token)
+		}
+		tokens[token] = true
+	}
+}
+
+func TestRevokeLink(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	if err := service.RevokeLink(created.Short, "wrong-token"); !errors.Is(err, shared.ErrInvalidDeletionToken) {
+		t.Fatalf("expected ErrInvalidDeletionToken, got %v", err)
+	}
+
+	if err := service.RevokeLink(created.Short, ""); !errors.Is(err, shared.ErrInvalidDeletionToken) {
+		t.Fatalf("expected ErrInvalidDeletionToken for empty token, got %v", err)
+	}
+
+	if _, err := service.CheckLinkExists(created.Short); err != nil {
+		t.Fatalf("expected link to survive a rejected deletion: %v", err)
+	}
+
+	if err := service.RevokeLink(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke link: %v", err)
+	}
+
+	_, err = service.CheckLinkExists(created.Short)
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after revoking, got %v", err)
+	}
+
+	if err := service.RevokeLink(created.Short, created.DeletionToken); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound when revoking twice, got %v", err)
+	}
+}
+
+func TestRevokeRecordWithoutToken(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := paste.NewPasteRepo(db)
+	service := paste.NewPasteService(repo)
+
+	// Records created before deletion tokens existed have no hash
+	if err := repo.Create(&paste.Paste{Short: "legacy", Content: "old"}); err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	if err := service.RevokePaste("legacy", ""); !errors.Is(err, shared.ErrInvalidDeletionToken) {
+		t.Errorf("expected ErrInvalidDeletionToken, got %v", err)
+	}
+}
+
+func TestRevokePaste(t *testing.T) {
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	created, err := service.CreatePaste("wrong channel", "plain", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	if err := service.RevokePaste(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke paste: %v", err)
+	}
+
+	if _, err := service.GetPaste(created.Short); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after revoking, got %v", err)
+	}
+}
+
+func TestRevokeSecret(t *testing.T) {
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("revokeme==", 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	if err := service.RevokeSecret(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke secret: %v", err)
+	}
+
+	if _, err := service.GetSecret(created.Short, testIP); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after revoking, got %v", err)
+	}
+}
+
+func TestRevokeImageRemovesFile(t *testing.T) {
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	created, err := service.CreateImage([]byte("image"), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	if err := service.RevokeImage(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke image: %v", err)
+	}
+
+	if _, err := os.Stat(created.FilePath); !os.IsNotExist(err) {
+		t.Error("expected image file to be deleted from disk")
+	}
+
+	if _, _, err := service.GetImage(created.Short); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after revoking, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/domains"
+	"github.com/piheta/seq.re/internal/features/admin"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestDomainPolicy(t *testing.T) {
+	dir := t.TempDir()
+	blocklist := "# phishing\n\nevil.com # reported twice\n*.Bad.Example.\n/^login-.*\\.net$/\nnot a host\n/[/\n"
+	if err := os.WriteFile(filepath.Join(dir, "blocklist.txt"), []byte(blocklist), 0o600); err != nil {
+		t.Fatalf("failed to write blocklist: %v", err)
+	}
+
+	policy, err := domains.Load(dir)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+
+	tests := map[string]bool{
+		"evil.com":               true,
+		"EVIL.com.":              true,
+		"www.evil.com":           false,
+		"notevil.com":            false,
+		"bad.example":            true,
+		"login.bad.example":      true,
+		"notbad.example":         false,
+		"login-bank.net":         true,
+		"login-bank.net.example": false,
+		"example.com":            false,
+	}
+	for host, blocked := range tests {
+		err := policy.Check(host)
+		var blockedErr *domains.BlockedError
+		if blocked != errors.As(err, &blockedErr) {
+			t.Errorf("%s: expected blocked %v, got %v", host, blocked, err)
+		}
+	}
+
+	// A non-empty allowlist rejects everything else, the blocklist still wins
+	if err := policy.Add(domains.Allow, "*.example.com"); err != nil {
+		t.Fatalf("failed to add entry: %v", err)
+	}
+	if err := policy.Check("docs.example.com"); err != nil {
+		t.Errorf("expected allowed host, got %v", err)
+	}
+	var blockedErr *domains.BlockedError
+	if err := policy.Check("example.org"); !errors.As(err, &blockedErr) || blockedErr.Entry != "" {
+		t.Errorf("expected host missing from the allowlist to be rejected, got %v", err)
+	}
+	if err := policy.Add(domains.Block, "evil.example.com"); err != nil {
+		t.Fatalf("failed to add entry: %v", err)
+	}
+	if err := policy.Check("evil.example.com"); !errors.As(err, &blockedErr) || blockedErr.Entry != "evil.example.com" {
+		t.Errorf("expected blocklist to win over the allowlist, got %v", err)
+	}
+
+	for _, entry := range []string{"", "https://evil.com", "evil.com/path", "/(/", "a.com\n/.*/", "a.com\r/.*/", "/a\n.*/", "*.a.com\r\nb.com"} {
+		if err := policy.Add(domains.Block, entry); !errors.Is(err, domains.ErrInvalidEntry) {
+			t.Errorf("%q: expected ErrInvalidEntry, got %v", entry, err)
+		}
+	}
+
+	// Rejected entries never reach the list file, a line break would have smuggled in a second entry
+	if data, _ := os.ReadFile(filepath.Join(dir, "blocklist.txt")); !strings.HasSuffix(string(data), "\nevil.example.com\n") {
+		t.Errorf("expected only valid entries in the blocklist, got %q", data)
+	}
+
+	// Edits of the files are picked up by the watcher
+	policy.StartWatcher(10 * time.Millisecond)
+	if err := os.Remove(filepath.Join(dir, "allowlist.txt")); err != nil {
+		t.Fatalf("failed to remove allowlist: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for policy.Check("example.org") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the watcher to reload the lists")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDomainPolicyLinks(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+
+	policy, err := domains.Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, policy)
+	handler := link.NewLinkHandler(service, newTemplateService(t))
+
+	existing, err := service.CreateLink("https://phish.example.net/login", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	encrypted, err := service.CreateLink("ciphertext", true, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	adminHandler := admin.NewAdminHandler(policy, "admin-secret")
+	addEntry := func(token, body string) error {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/domains", bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return adminHandler.AddDomainEntry(httptest.NewRecorder(), req)
+	}
+
+	if err := addEntry("", `{"list":"block","entry":"*.example.net"}`); errorStatus(err) != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %v", err)
+	}
+	if err := addEntry("wrong", `{"list":"block","entry":"*.example.net"}`); errorStatus(err) != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong token, got %v", err)
+	}
+	if err := admin.NewAdminHandler(policy, "").AddDomainEntry(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/admin/domains", nil)); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 without ADMIN_TOKEN, got %v", err)
+	}
+	for _, body := range []string{`{"list":"deny","entry":"example.net"}`, `{"list":"block","entry":"https://example.net/"}`} {
+		if err := addEntry("admin-secret", body); errorStatus(err) != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", body, err)
+		}
+	}
+	if err := addEntry("admin-secret", `{"list":"block","entry":"*.example.net"}`); err != nil {
+		t.Fatalf("failed to add entry: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url":"https://login.example.net/"}`))
+	if err := handler.CreateLink(httptest.NewRecorder(), req); errorStatus(err) != http.StatusForbidden || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("expected 403 for a blocked domain, got %v", err)
+	}
+
+	// The policy sees hosts in the form URL validation accepted them in
+	if err := addEntry("admin-secret", `{"list":"block","entry":"bücher.example"}`); err != nil {
+		t.Fatalf("failed to add entry: %v", err)
+	}
+	for _, url := range []string{"https://BÜCHER.example./", "https://xn--bcher-kva.example/", "https://ｂüｃｈｅｒ.example/"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url":"`+url+`"}`))
+		if err := handler.CreateLink(httptest.NewRecorder(), req); errorStatus(err) != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for a blocked domain, got %v", url, err)
+		}
+	}
+
+	// Links created before the domain was blocked stop working
+	req = httptest.NewRequest(http.MethodGet, "/"+existing.Short+"?cli=true", nil)
+	req.SetPathValue("short", existing.Short)
+	if err := handler.RedirectByShort(httptest.NewRecorder(), req); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 when redirecting to a blocked domain, got %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/"+existing.Short, nil)
+	req.SetPathValue("short", existing.Short)
+	rec := httptest.NewRecorder()
+	if err := handler.RedirectByShort(rec, req); err != nil || !strings.Contains(rec.Body.String(), "phish.example.net are blocked") {
+		t.Errorf("expected the error page for browsers, got %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/links/"+existing.Short+"?cli=true", nil)
+	req.SetPathValue("short", existing.Short)
+	if err := handler.GetLinkByShort(httptest.NewRecorder(), req); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 when looking up a blocked link, got %v", err)
+	}
+
+	// The server can't tell where encrypted links lead
+	req = httptest.NewRequest(http.MethodGet, "/"+encrypted.Short+"?cli=true", nil)
+	req.SetPathValue("short", encrypted.Short)
+	if err := handler.RedirectByShort(httptest.NewRecorder(), req); err != nil {
+		t.Errorf("expected encrypted links to redirect, got %v", err)
+	}
+}
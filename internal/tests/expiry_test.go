@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestParseExpiresInDefault(t *testing.T) {
+	config.InitEnv()
+
+	d, err := shared.ParseExpiresIn("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d != 7*24*time.Hour {
+		t.Errorf("expected default expiry of 7 days, got %v", d)
+	}
+}
+
+func TestParseExpiresInValues(t *testing.T) {
+	config.InitEnv()
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"1h", time.Hour},
+		{"90m", 90 * time.Minute},
+		{"1d", 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"30d", 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, err := shared.ParseExpiresIn(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestParseExpiresInRejectsInvalid(t *testing.T) {
+	config.InitEnv()
+
+	// Default policy is 5m to 30d, so never is not allowed either
+	for _, value := range []string{"soon", "-1h", "0d", "1m", "31d", "never"} {
+		t.Run(value, func(t *testing.T) {
+			if _, err := shared.ParseExpiresIn(value); err == nil {
+				t.Errorf("expected %q to be rejected", value)
+			}
+		})
+	}
+}
+
+func TestParseExpiresInNeverWithoutMax(t *testing.T) {
+	_ = os.Setenv("EXPIRY_MAX", "never")
+	defer func() { _ = os.Unsetenv("EXPIRY_MAX") }()
+	config.InitEnv()
+	defer config.InitEnv()
+
+	d, err := shared.ParseExpiresIn("never")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d != config.NeverExpires {
+		t.Errorf("expected never, got %v", d)
+	}
+
+	if _, err := shared.ParseExpiresIn("365d"); err != nil {
+		t.Errorf("expected 365d to be allowed without an upper bound: %v", err)
+	}
+}
+
+func TestParseExpiresInAllowedList(t *testing.T) {
+	_ = os.Setenv("EXPIRY_ALLOWED", "1h,1d,never")
+	defer func() { _ = os.Unsetenv("EXPIRY_ALLOWED") }()
+	config.InitEnv()
+	defer config.InitEnv()
+
+	for _, value := range []string{"1h", "1d", "never"} {
+		if _, err := shared.ParseExpiresIn(value); err != nil {
+			t.Errorf("expected %q to be allowed: %v", value, err)
+		}
+	}
+
+	if _, err := shared.ParseExpiresIn("7d"); err == nil {
+		t.Error("expected 7d to be rejected when not in the allowed list")
+	}
+}
+
+func TestLinkCreationWithCustomExpiry(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := link.NewLinkRepo(db)
+	service := link.NewLinkService(repo, nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, time.Hour, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	diff := created.ExpiresAt.Sub(time.Now().Add(time.Hour)).Abs()
+	if diff > time.Second {
+		t.Errorf("expected expiry ~1 hour from now, got %v", created.ExpiresAt)
+	}
+}
+
+func TestLinkCreationNeverExpires(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := link.NewLinkRepo(db)
+	service := link.NewLinkService(repo, nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, config.NeverExpires, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	if !created.ExpiresAt.IsZero() {
+		t.Errorf("expected no expiry, got %v", created.ExpiresAt)
+	}
+
+	retrieved, err := repo.GetByShort(created.Short)
+	if err != nil {
+		t.Fatalf("failed to retrieve link without expiry: %v", err)
+	}
+
+	if retrieved.URL != created.URL {
+		t.Errorf("expected URL %s, got %s", created.URL, retrieved.URL)
+	}
+}
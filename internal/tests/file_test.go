@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/file"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestFileCreation(t *testing.T) {
+	db := SetupTestDB(t)
+	tempDir := t.TempDir()
+	service := file.NewFileService(file.NewFileRepo(db), tempDir)
+
+	fileData := []byte("%PDF-1.7 fake document")
+
+	created, err := service.CreateFile(fileData, "report.pdf", "application/pdf", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if created.FileName != "report.pdf" {
+		t.Errorf("expected file name report.pdf, got %s", created.FileName)
+	}
+
+	if created.ContentType != "application/pdf" {
+		t.Errorf("expected content type application/pdf, got %s", created.ContentType)
+	}
+
+	if created.Size != int64(len(fileData)) {
+		t.Errorf("expected size %d, got %d", len(fileData), created.Size)
+	}
+
+	// Stored under the short code, the original name never reaches the disk
+	if want := filepath.Join(tempDir, created.Short+".bin"); created.FilePath != want {
+		t.Errorf("expected file path %s, got %s", want, created.FilePath)
+	}
+
+	content, err := os.ReadFile(created.FilePath)
+	if err != nil {
+		t.Fatalf("failed to read stored file: %v", err)
+	}
+	if !bytes.Equal(content, fileData) {
+		t.Errorf("expected file content %q, got %q", fileData, content)
+	}
+}
+
+func TestFileRetrieval(t *testing.T) {
+	db := SetupTestDB(t)
+	service := file.NewFileService(file.NewFileRepo(db), t.TempDir())
+
+	fileData := []byte{0x1f, 0x8b, 0x08, 0x00, 0x00}
+
+	created, err := service.CreateFile(fileData, "logs.tar.gz", "application/gzip", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	for range 2 {
+		retrieved, retrievedFile, err := service.GetFile(created.Short)
+		if err != nil {
+			t.Fatalf("failed to retrieve file: %v", err)
+		}
+
+		if retrievedData := ReadAndClose(t, retrievedFile); !bytes.Equal(retrievedData, fileData) {
+			t.Errorf("expected file data %v, got %v", fileData, retrievedData)
+		}
+
+		if retrieved.FileName != "logs.tar.gz" {
+			t.Errorf("expected file name logs.tar.gz, got %s", retrieved.FileName)
+		}
+	}
+}
+
+func TestEncryptedFileRetrieval(t *testing.T) {
+	db := SetupTestDB(t)
+	service := file.NewFileService(file.NewFileRepo(db), t.TempDir())
+
+	fileData := []byte("encrypted blob")
+
+	created, err := service.CreateFile(fileData, "ZW5jcnlwdGVkIG5hbWU=", "application/octet-stream", true, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	retrieved, retrievedFile, err := service.GetFile(created.Short)
+	if err != nil {
+		t.Fatalf("failed to retrieve file: %v", err)
+	}
+
+	if retrievedData := ReadAndClose(t, retrievedFile); !bytes.Equal(retrievedData, fileData) {
+		t.Errorf("expected stored encrypted data %q, got %q", fileData, retrievedData)
+	}
+
+	if retrieved.FileName != "ZW5jcnlwdGVkIG5hbWU=" {
+		t.Errorf("expected encrypted name to be kept as is, got %s", retrieved.FileName)
+	}
+}
+
+func TestViewLimitedFileDeletion(t *testing.T) {
+	db := SetupTestDB(t)
+	service := file.NewFileService(file.NewFileRepo(db), t.TempDir())
+
+	created, err := service.CreateFile([]byte("core dump"), "core.1234", "application/octet-stream", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if _, _, err := service.GetFile(created.Short); err != nil {
+		t.Fatalf("failed to retrieve file: %v", err)
+	}
+
+	if _, err := os.Stat(created.FilePath); !os.IsNotExist(err) {
+		t.Error("expected file to be deleted from disk after the last view")
+	}
+
+	if _, _, err := service.GetFile(created.Short); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestRevokeFileRemovesFile(t *testing.T) {
+	db := SetupTestDB(t)
+	service := file.NewFileService(file.NewFileRepo(db), t.TempDir())
+
+	created, err := service.CreateFile([]byte("file"), "notes.txt", "text/plain", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if err := service.RevokeFile(created.Short, "wrong-token"); err == nil {
+		t.Error("expected wrong token to be rejected")
+	}
+
+	if err := service.RevokeFile(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke file: %v", err)
+	}
+
+	if _, err := os.Stat(created.FilePath); !os.IsNotExist(err) {
+		t.Error("expected file to be deleted from disk")
+	}
+
+	if _, _, err := service.GetFile(created.Short); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after revoking, got %v", err)
+	}
+}
+
+func TestFileDownloadIsAttachment(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := file.NewFileService(file.NewFileRepo(db), t.TempDir())
+	handler := file.NewFileHandler(service, nil)
+
+	tests := []struct {
+		name        string
+		partName    string
+		partType    string
+		wantName    string
+		wantType    string
+		fileContent string
+	}{
+		{"declared type is kept", "report.pdf", "application/pdf", "report.pdf", "application/pdf", "%PDF-1.7"},
+		{"missing type is sniffed", "page.html", "", "page.html", "text/html; charset=utf-8", "<html><body>hi</body></html>"},
+		{"directories are stripped", "../../etc/passwd", "text/plain", "passwd", "text/plain", "root:x:0:0"},
+		{"windows paths are stripped", `C:\Users\me\dump.core`, "application/octet-stream", "dump.core", "application/octet-stream", "\x7fELF\x00"},
+		{"non-ascii names survive", "résumé.txt", "text/plain", "résumé.txt", "text/plain", "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": "file", "filename": tt.partName}))
+			if tt.partType != "" {
+				header.Set("Content-Type", tt.partType)
+			}
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				t.Fatalf("failed to create part: %v", err)
+			}
+			_, _ = part.Write([]byte(tt.fileContent))
+			_ = writer.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/files", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rec := httptest.NewRecorder()
+			if err := handler.CreateFile(rec, req); err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var created shared.CreatedResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+
+			short := path.Base(created.URL)
+			req = httptest.NewRequest(http.MethodGet, "/f/"+short, nil)
+			req.SetPathValue("short", short)
+			rec = httptest.NewRecorder()
+			if err := handler.GetFileByShort(rec, req); err != nil {
+				t.Fatalf("failed to get file: %v", err)
+			}
+
+			disposition, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+			if err != nil || disposition != "attachment" {
+				t.Fatalf("expected attachment disposition, got %q", rec.Header().Get("Content-Disposition"))
+			}
+			if params["filename"] != tt.wantName {
+				t.Errorf("expected file name %q, got %q", tt.wantName, params["filename"])
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("expected content type %q, got %q", tt.wantType, got)
+			}
+			if rec.Body.String() != tt.fileContent {
+				t.Errorf("expected body %q, got %q", tt.fileContent, rec.Body.String())
+			}
+		})
+	}
+}
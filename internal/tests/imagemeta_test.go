@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/imagemeta"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// Metadata planted in the fixtures: the camera make and GPS IFD of the EXIF block, the GPS position in the XMP
+// packet and the city in the IPTC record. WebP has no place for IPTC.
+var fixtureMetadata = map[string][]string{
+	"gps.jpg":  {"SeqreCam", "\x25\x88\x04\x00", "GPSLatitude", "Oslo"},
+	"gps.png":  {"SeqreCam", "\x25\x88\x04\x00", "GPSLatitude", "Oslo"},
+	"gps.webp": {"SeqreCam", "\x25\x88\x04\x00", "GPSLatitude"},
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func assertNoMetadata(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	for _, marker := range fixtureMetadata[name] {
+		if bytes.Contains(data, []byte(marker)) {
+			t.Errorf("%s: expected %q to be stripped", name, marker)
+		}
+	}
+}
+
+func TestStripMetadataFixtures(t *testing.T) {
+	for name, markers := range fixtureMetadata {
+		original := readFixture(t, name)
+		for _, marker := range markers {
+			if !bytes.Contains(original, []byte(marker)) {
+				t.Fatalf("%s: fixture is missing %q", name, marker)
+			}
+		}
+
+		stripped, err := imagemeta.StripBytes(original)
+		if err != nil {
+			t.Fatalf("%s: failed to strip metadata: %v", name, err)
+		}
+		assertNoMetadata(t, name, stripped)
+
+		if filepath.Ext(name) == ".webp" {
+			continue
+		}
+
+		// The image data itself is untouched
+		before, _, err := image.Decode(bytes.NewReader(original))
+		if err != nil {
+			t.Fatalf("%s: failed to decode fixture: %v", name, err)
+		}
+		after, _, err := image.Decode(bytes.NewReader(stripped))
+		if err != nil {
+			t.Fatalf("%s: failed to decode stripped image: %v", name, err)
+		}
+		if before.Bounds() != after.Bounds() || before.At(5, 3) != after.At(5, 3) {
+			t.Errorf("%s: expected the same image after stripping", name)
+		}
+	}
+}
+
+func TestStripMetadataKeepsJPEGOrientation(t *testing.T) {
+	stripped, err := imagemeta.StripBytes(readFixture(t, "gps.jpg"))
+	if err != nil {
+		t.Fatalf("failed to strip metadata: %v", err)
+	}
+
+	// The fixture is rotated 90° (orientation 6), only that tag survives
+	orientation := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06")
+	if !bytes.Contains(stripped, orientation) {
+		t.Error("expected the orientation to be kept")
+	}
+}
+
+func TestStripMetadataWebPHeader(t *testing.T) {
+	stripped, err := imagemeta.StripBytes(readFixture(t, "gps.webp"))
+	if err != nil {
+		t.Fatalf("failed to strip metadata: %v", err)
+	}
+
+	if size := binary.LittleEndian.Uint32(stripped[4:8]); int(size) != len(stripped)-8 {
+		t.Errorf("expected RIFF size %d, got %d", len(stripped)-8, size)
+	}
+	if string(stripped[12:16]) != "VP8X" || stripped[20]&0x0C != 0 {
+		t.Errorf("expected VP8X chunk without EXIF and XMP flags, got %q flags %#x", stripped[12:16], stripped[20])
+	}
+	if bytes.Contains(stripped, []byte("EXIF")) || bytes.Contains(stripped, []byte("XMP ")) {
+		t.Error("expected EXIF and XMP chunks to be removed")
+	}
+}
+
+func TestStripMetadataWebPTrustsNoSizes(t *testing.T) {
+	// A VP8X chunk claiming almost 4 GiB that ends exactly where the RIFF header says the file ends
+	const chunkSize = 0xFFFFFF00
+	forged := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x00\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(forged[4:8], 12+chunkSize)
+	binary.LittleEndian.PutUint32(forged[16:20], chunkSize)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := imagemeta.StripBytes(forged)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, imagemeta.ErrMalformed) {
+		t.Errorf("expected ErrMalformed, got %v", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("expected the forged sizes to be rejected without allocating, allocated %d bytes", allocated)
+	}
+
+	// A VP8X chunk of any size but 10 is malformed even when it fits the file
+	odd := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0c\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(odd[4:8], uint32(len(odd)-8))
+	if _, err := imagemeta.StripBytes(odd); !errors.Is(err, imagemeta.ErrMalformed) {
+		t.Errorf("expected ErrMalformed for a 12 byte VP8X chunk, got %v", err)
+	}
+}
+
+func FuzzStripMetadata(f *testing.F) {
+	for name := range fixtureMetadata {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			f.Fatalf("failed to read fixture: %v", err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte("RIFF\xff\xff\xff\xffWEBPVP8X\xf4\xff\xff\xff"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stripped, err := imagemeta.StripBytes(data)
+		if err != nil {
+			return
+		}
+		// Stripping only ever removes data, apart from the small EXIF block that keeps a JPEG's orientation
+		if len(stripped) > len(data)+64 {
+			t.Errorf("expected at most %d bytes, got %d", len(data)+64, len(stripped))
+		}
+	})
+}
+
+func TestStripMetadataPassesOtherContent(t *testing.T) {
+	for _, content := range [][]byte{[]byte("GIF89a not stripped"), {}, []byte("\xFF")} {
+		stripped, err := imagemeta.StripBytes(content)
+		if err != nil || !bytes.Equal(stripped, content) {
+			t.Errorf("expected %q to pass unchanged, got %q (%v)", content, stripped, err)
+		}
+	}
+
+	if _, err := imagemeta.StripBytes([]byte("\x89PNG\r\n\x1a\nshort")); err == nil {
+		t.Error("expected truncated PNG to be reported as malformed")
+	}
+}
+
+func TestImageUploadStripsMetadata(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := img.NewImageHandler(service, nil)
+
+	original := readFixture(t, "gps.jpg")
+	upload := func(fields map[string]string) []byte {
+		rec := httptest.NewRecorder()
+		if err := handler.CreateImage(rec, uploadRequest(t, original, fields)); err != nil {
+			t.Fatalf("failed to upload image: %v", err)
+		}
+
+		var created shared.CreatedResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		_, f, err := service.GetImage(path.Base(created.URL))
+		if err != nil {
+			t.Fatalf("failed to retrieve image: %v", err)
+		}
+		return ReadAndClose(t, f)
+	}
+
+	assertNoMetadata(t, "gps.jpg", upload(nil))
+
+	if stored := upload(map[string]string{"keep_metadata": "true"}); !bytes.Equal(stored, original) {
+		t.Error("expected keep_metadata to store the image as received")
+	}
+
+	// Encrypted images are opaque to the server
+	if stored := upload(map[string]string{"encrypted": "true"}); !bytes.Equal(stored, original) {
+		t.Error("expected encrypted upload to be stored as received")
+	}
+}
+
+func TestImageUploadRejectsMalformedImage(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := img.NewImageHandler(service, nil)
+
+	// Cut off inside its segments, the metadata of the image can't be located
+	original := readFixture(t, "gps.jpg")
+	truncated := original[:len(original)/2]
+
+	err := handler.CreateImage(httptest.NewRecorder(), uploadRequest(t, truncated, nil))
+	if status := errorStatus(err); status != 400 {
+		t.Errorf("expected 400 for a malformed image, got %d (%v)", status, err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := handler.CreateImage(rec, uploadRequest(t, truncated, map[string]string{"keep_metadata": "true"})); err != nil {
+		t.Fatalf("expected keep_metadata to store the image as received, got %v", err)
+	}
+}
@@ -20,7 +20,7 @@ func TestImageCreation(t *testing.T) {
 	imageData := []byte("fake image data")
 	contentType := "image/png"
 
-	created, err := service.CreateImage(imageData, contentType, false, false)
+	created, err := service.CreateImage(imageData, contentType, false, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create image: %v", err)
 	}
@@ -41,8 +41,8 @@ func TestImageCreation(t *testing.T) {
 		t.Error("expected Encrypted to be false")
 	}
 
-	if created.OneTime {
-		t.Error("expected OneTime to be false")
+	if created.MaxViews != 0 {
+		t.Errorf("expected no view limit, got %d", created.MaxViews)
 	}
 
 	if created.CreatedAt.IsZero() {
@@ -78,7 +78,7 @@ func TestEncryptedImageCreation(t *testing.T) {
 	imageData := []byte("encrypted image data")
 	contentType := "application/octet-stream"
 
-	created, err := service.CreateImage(imageData, contentType, true, false)
+	created, err := service.CreateImage(imageData, contentType, true, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create encrypted image: %v", err)
 	}
@@ -102,13 +102,13 @@ func TestOneTimeImageCreation(t *testing.T) {
 	imageData := []byte("onetime image data")
 	contentType := "image/jpeg"
 
-	created, err := service.CreateImage(imageData, contentType, false, true)
+	created, err := service.CreateImage(imageData, contentType, false, false, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create onetime image: %v", err)
 	}
 
-	if !created.OneTime {
-		t.Error("expected OneTime to be true")
+	if created.MaxViews != 1 || created.ViewsLeft != 1 {
+		t.Errorf("expected a single view, got %d of %d", created.ViewsLeft, created.MaxViews)
 	}
 
 	if created.Encrypted {
@@ -125,18 +125,18 @@ func TestImageRetrieval(t *testing.T) {
 	imageData := []byte("test image data")
 	contentType := "image/png"
 
-	created, err := service.CreateImage(imageData, contentType, false, false)
+	created, err := service.CreateImage(imageData, contentType, false, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create image: %v", err)
 	}
 
 	// Retrieve the image
-	retrievedImage, retrievedData, err := service.GetImage(created.Short)
+	retrievedImage, retrievedFile, err := service.GetImage(created.Short)
 	if err != nil {
 		t.Fatalf("failed to retrieve image: %v", err)
 	}
 
-	if string(retrievedData) != string(imageData) {
+	if retrievedData := ReadAndClose(t, retrievedFile); string(retrievedData) != string(imageData) {
 		t.Errorf("expected image data %s, got %s", imageData, retrievedData)
 	}
 
@@ -164,7 +164,7 @@ func TestOneTimeImageDeletion(t *testing.T) {
 	imageData := []byte("onetime image")
 	contentType := "image/png"
 
-	created, err := service.CreateImage(imageData, contentType, false, true)
+	created, err := service.CreateImage(imageData, contentType, false, false, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create onetime image: %v", err)
 	}
@@ -201,13 +201,13 @@ func TestEncryptedImagePersistence(t *testing.T) {
 	contentType := "application/octet-stream"
 
 	// Create encrypted image WITHOUT onetime flag
-	created, err := service.CreateImage(imageData, contentType, true, false)
+	created, err := service.CreateImage(imageData, contentType, true, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create encrypted image: %v", err)
 	}
 
 	// Retrieve once
-	retrievedImage, retrievedData, err := service.GetImage(created.Short)
+	retrievedImage, retrievedFile, err := service.GetImage(created.Short)
 	if err != nil {
 		t.Fatalf("failed to retrieve encrypted image: %v", err)
 	}
@@ -216,9 +216,9 @@ func TestEncryptedImagePersistence(t *testing.T) {
 		t.Error("expected encrypted flag to be true")
 	}
 
-	// Verify data is base64 encoded (different from original)
-	if string(retrievedData) == string(imageData) {
-		t.Error("expected base64 encoded data, got raw data")
+	// Encrypted data is stored as uploaded, handlers encode it for the response
+	if retrievedData := ReadAndClose(t, retrievedFile); string(retrievedData) != string(imageData) {
+		t.Errorf("expected stored encrypted data %s, got %s", imageData, retrievedData)
 	}
 
 	// Verify file still exists (encrypted but not onetime)
@@ -268,7 +268,7 @@ func TestMultipleImageCreation(t *testing.T) {
 
 	createdImages := make([]*img.Image, len(images))
 	for i, imgData := range images {
-		created, err := service.CreateImage(imgData.data, imgData.contentType, false, false)
+		created, err := service.CreateImage(imgData.data, imgData.contentType, false, false, 0, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create image %d: %v", i, err)
 		}
@@ -277,12 +277,12 @@ func TestMultipleImageCreation(t *testing.T) {
 
 	// Verify all images can be retrieved
 	for i, created := range createdImages {
-		retrievedImage, retrievedData, err := service.GetImage(created.Short)
+		retrievedImage, retrievedFile, err := service.GetImage(created.Short)
 		if err != nil {
 			t.Fatalf("failed to retrieve image %d: %v", i, err)
 		}
 
-		if string(retrievedData) != string(images[i].data) {
+		if retrievedData := ReadAndClose(t, retrievedFile); string(retrievedData) != string(images[i].data) {
 			t.Errorf("image %d: expected data %s, got %s", i, images[i].data, retrievedData)
 		}
 
@@ -301,7 +301,7 @@ func TestImageExpiry(t *testing.T) {
 	imageData := []byte("expiring image")
 	contentType := "image/png"
 
-	created, err := service.CreateImage(imageData, contentType, false, false)
+	created, err := service.CreateImage(imageData, contentType, false, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create image: %v", err)
 	}
@@ -319,7 +319,6 @@ func TestImageExpiry(t *testing.T) {
 		FilePath:    filepath.Join(tempDir, "testshort.png"),
 		ContentType: "image/png",
 		Encrypted:   false,
-		OneTime:     false,
 		CreatedAt:   time.Now(),
 		ExpiresAt:   time.Now().Add(1 * time.Second),
 	}
@@ -365,7 +364,7 @@ func TestImageShortCodeUniqueness(t *testing.T) {
 	shortCodes := make(map[string]bool)
 	for i := range 100 {
 		imageData := []byte("image" + string(rune(i)))
-		created, err := service.CreateImage(imageData, "image/png", false, false)
+		created, err := service.CreateImage(imageData, "image/png", false, false, 0, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create image %d: %v", i, err)
 		}
@@ -400,7 +399,7 @@ func TestFileExtensionMapping(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.contentType, func(t *testing.T) {
-			created, err := service.CreateImage([]byte("test"), tt.contentType, false, false)
+			created, err := service.CreateImage([]byte("test"), tt.contentType, false, false, 0, testExpiry)
 			if err != nil {
 				t.Fatalf("failed to create image: %v", err)
 			}
@@ -423,7 +422,7 @@ func TestEncryptedAndOneTime(t *testing.T) {
 	contentType := "application/octet-stream"
 
 	// Create image that is both encrypted and onetime
-	created, err := service.CreateImage(imageData, contentType, true, true)
+	created, err := service.CreateImage(imageData, contentType, true, false, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create image: %v", err)
 	}
@@ -432,8 +431,8 @@ func TestEncryptedAndOneTime(t *testing.T) {
 		t.Error("expected Encrypted to be true")
 	}
 
-	if !created.OneTime {
-		t.Error("expected OneTime to be true")
+	if created.MaxViews != 1 || created.ViewsLeft != 1 {
+		t.Errorf("expected a single view, got %d of %d", created.ViewsLeft, created.MaxViews)
 	}
 
 	// Retrieve once - should delete because onetime flag triggers deletion
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/languages"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestLanguageLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"go", "go"},
+		{"Golang", "go"},
+		{"py", "python"},
+		{" YML ", "yaml"},
+		{"c++", "cpp"},
+		{"plain", "plaintext"},
+		{"docker", "dockerfile"},
+		{"cobol", ""},
+	}
+	for _, tt := range tests {
+		if got, _ := languages.Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q): expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	files := map[string]string{
+		"main.go":             "go",
+		"src/App.TSX":         "typescript",
+		"build/Dockerfile":    "dockerfile",
+		"Makefile":            "makefile",
+		"pyproject.toml":      "ini",
+		"notes.txt":           "plaintext",
+		"LICENSE":             "",
+		"archive.tar.unknown": "",
+	}
+	for name, want := range files {
+		if got := languages.FromFileName(name); got != want {
+			t.Errorf("FromFileName(%q): expected %q, got %q", name, want, got)
+		}
+	}
+
+	for _, language := range languages.All() {
+		if got, ok := languages.Normalize(language.ID); !ok || got != language.ID {
+			t.Errorf("%s doesn't resolve to itself", language.ID)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"sh shebang", "#!/bin/sh\necho hi\n", "bash"},
+		{"env shebang", "#!/usr/bin/env python3\nprint('hi')\n", "python"},
+		{"versioned interpreter", "#!/usr/local/bin/python3.12\n", "python"},
+		{"env options", "#!/usr/bin/env -S node --experimental-modules\n", "javascript"},
+		{"unknown interpreter", "#!/usr/bin/awk -f\n{ print }\n", ""},
+		{"vim modeline", "package main\n\n// vim: set ft=go ts=4:\n", "go"},
+		{"vim modeline at the end", "a\nb\nc\nd\ne\nf\ng\n# vim: filetype=yaml\n", "yaml"},
+		{"emacs modeline", "# -*- mode: ruby; coding: utf-8 -*-\nputs 1\n", "ruby"},
+		{"emacs short modeline", "/* -*- c++ -*- */\n", "cpp"},
+		{"modeline in the middle", "1\n2\n3\n4\n5\n6\n# vim: ft=go\n7\n8\n9\n10\n11\n12\n", ""},
+		{"unknown modeline", "# vim: ft=cobol\n", ""},
+		{"no hints", "just some text\n", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := languages.Detect(tt.content); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCreatePasteLanguage(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	create := func(body string) (*paste.Paste, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/pastes", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		if err := handler.CreatePaste(rec, req); err != nil {
+			return nil, err
+		}
+		var created shared.CreatedResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		return service.CheckPasteExists(created.URL[strings.LastIndex(created.URL, "/")+1:])
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"alias", `{"content":"print(1)","language":"py"}`, "python"},
+		{"formerly rejected", `{"content":"<a/>","language":"xml"}`, "xml"},
+		{"shebang", `{"content":"#!/usr/bin/env bash\necho hi"}`, "bash"},
+		{"file name", `{"content":"FROM alpine","filename":"Dockerfile"}`, "dockerfile"},
+		{"given language wins", `{"content":"#!/bin/sh","language":"plain"}`, "plaintext"},
+		{"nothing to detect", `{"content":"hello"}`, ""},
+		{"encrypted", `{"content":"#!/bin/sh","encrypted":true}`, ""},
+	}
+	for _, tt := range tests {
+		stored, err := create(tt.body)
+		if err != nil || stored.Language != tt.want {
+			t.Errorf("%s: expected %q, got %+v (%v)", tt.name, tt.want, stored, err)
+		}
+	}
+
+	bundle, err := create(`{"files":[{"name":"run","content":"#!/usr/bin/ruby\n"},{"name":"a.rs","content":"fn main() {}"},{"name":"b","language":"yml","content":"a: 1"}]}`)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	for i, want := range []string{"ruby", "rust", "yaml"} {
+		if bundle.Files[i].Language != want {
+			t.Errorf("%s: expected %q, got %q", bundle.Files[i].Name, want, bundle.Files[i].Language)
+		}
+	}
+
+	if _, err := create(`{"content":"x","language":"cobol"}`); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown language, got %v", err)
+	}
+}
+
+func TestListLanguages(t *testing.T) {
+	handler := paste.NewPasteHandler(nil, nil)
+
+	rec := httptest.NewRecorder()
+	if err := handler.ListLanguages(rec, httptest.NewRequest(http.MethodGet, "/api/languages", nil)); err != nil {
+		t.Fatalf("failed to list languages: %v", err)
+	}
+
+	var listed []languages.Language
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if len(listed) != len(languages.All()) || listed[0].ID != "plaintext" {
+		t.Errorf("unexpected languages %+v", listed)
+	}
+	for _, language := range listed {
+		if language.ID == "dockerfile" && (language.Name != "Dockerfile" || language.FileNames[0] != "Dockerfile") {
+			t.Errorf("unexpected dockerfile entry %+v", language)
+		}
+	}
+}
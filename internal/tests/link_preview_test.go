@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestLinkPreview(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+	handler := link.NewLinkHandler(service, newTemplateService(t))
+
+	get := func(path, short string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.SetPathValue("short", short)
+		req.Header.Set("User-Agent", firefoxUA)
+		rec := httptest.NewRecorder()
+		if err := handler.RedirectByShort(rec, req); err != nil {
+			t.Fatalf("%s: failed to get link: %v", path, err)
+		}
+		return rec
+	}
+
+	risky, err := service.CreateLink("http://203.0.113.7:8443/setup.exe", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	for _, path := range []string{"/" + risky.Short + "+", "/" + risky.Short + "?preview=1"} {
+		_, short, _ := strings.Cut(path, "/")
+		short, _, _ = strings.Cut(short, "?")
+		rec := get(path, short)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, "http://203.0.113.7:8443/setup.exe") {
+			t.Fatalf("%s: expected the preview page, got %d", path, rec.Code)
+		}
+		for _, warning := range []string{"not encrypted (http)", "an IP address", "unusual port 8443", "a .exe file"} {
+			if !strings.Contains(body, warning) {
+				t.Errorf("%s: expected the warning %q", path, warning)
+			}
+		}
+	}
+
+	// Look-alike Unicode hosts and a trailing dot get the same warnings as their ASCII forms
+	for url, warning := range map[string]string{
+		"https://\u0430pple.com/login": "international characters (xn--)",
+		"https://bit.ly./abc":          "another link shortener (bit.ly)",
+	} {
+		created, err := service.CreateLink(url, false, false, 0, testExpiry, false)
+		if err != nil {
+			t.Fatalf("%s: failed to create link: %v", url, err)
+		}
+		if body := get("/"+created.Short+"+", created.Short+"+").Body.String(); !strings.Contains(body, warning) {
+			t.Errorf("%s: expected the warning %q", url, warning)
+		}
+	}
+
+	safe, err := service.CreateLink("https://example.com/docs", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if body := get("/"+safe.Short+"+", safe.Short+"+").Body.String(); strings.Contains(body, "⚠️") || !strings.Contains(body, "Continue to example.com") {
+		t.Error("expected a preview without warnings")
+	}
+	if rec := get("/"+safe.Short, safe.Short); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("expected links to redirect without preview, got %d", rec.Code)
+	}
+
+	// Looking at the destination isn't a click
+	for created, want := range map[*link.Link]int{risky: 0, safe: 1} {
+		stats, err := service.GetStats(created.Short, created.DeletionToken)
+		if err != nil {
+			t.Fatalf("failed to get stats: %v", err)
+		}
+		if stats.Clicks != want {
+			t.Errorf("%s: expected %d clicks, got %d", created.URL, want, stats.Clicks)
+		}
+	}
+
+	// Links created with preview always show it to browsers, the CLI still gets the redirect
+	req := httptest.NewRequest(http.MethodPost, "/api/links", bytes.NewBufferString(`{"url":"https://example.com/forced","preview":true}`))
+	rec := httptest.NewRecorder()
+	if err := handler.CreateLink(rec, req); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	var created shared.CreatedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	short := created.URL[strings.LastIndex(created.URL, "/")+1:]
+	if rec := get("/"+short, short); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "https://example.com/forced") {
+		t.Errorf("expected the forced preview, got %d", rec.Code)
+	}
+	if rec := get("/"+short+"?cli=true", short); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("expected the CLI to be redirected, got %d", rec.Code)
+	}
+
+	// Encrypted destinations are decrypted and shown by the browser
+	encrypted, err := service.CreateLink("ciphertext", true, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if body := get("/"+encrypted.Short+"+", encrypted.Short+"+").Body.String(); !strings.Contains(body, "const preview = true") || !strings.Contains(body, "<title>Link Preview</title>") {
+		t.Error("expected the redirect page in preview mode")
+	}
+	if body := get("/"+encrypted.Short, encrypted.Short).Body.String(); !strings.Contains(body, "const preview = false") {
+		t.Error("expected the redirect page to redirect")
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+const (
+	firefoxUA = "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"
+	chromeUA  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36"
+)
+
+func TestRecordClick(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	clicks := []struct {
+		ip, referrer, userAgent string
+	}{
+		{"203.0.113.7", "https://news.example.org/item?id=1", firefoxUA},
+		{"203.0.113.7", "https://news.example.org/item?id=2", firefoxUA},
+		{"198.51.100.4", "", chromeUA},
+		{"198.51.100.4", "", "curl/8.5.0"},
+	}
+	for _, c := range clicks {
+		if err := service.RecordClick(created, c.ip, c.referrer, c.userAgent); err != nil {
+			t.Fatalf("failed to record click: %v", err)
+		}
+	}
+
+	stats, err := service.GetStats(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+
+	if stats.Clicks != 4 {
+		t.Errorf("expected 4 clicks, got %d", stats.Clicks)
+	}
+
+	// Same IP with another user agent is another visitor
+	if stats.UniqueVisitors != 3 {
+		t.Errorf("expected 3 unique visitors, got %d", stats.UniqueVisitors)
+	}
+
+	if stats.Referrers["news.example.org"] != 2 || stats.Referrers["direct"] != 2 {
+		t.Errorf("unexpected referrers: %v", stats.Referrers)
+	}
+
+	if stats.UserAgents["firefox"] != 2 || stats.UserAgents["chrome"] != 1 || stats.UserAgents["curl"] != 1 {
+		t.Errorf("unexpected user agents: %v", stats.UserAgents)
+	}
+
+	if stats.LastClickAt == nil || time.Since(*stats.LastClickAt) > time.Minute {
+		t.Errorf("expected recent last click, got %v", stats.LastClickAt)
+	}
+}
+
+func TestLinkStatsRequireToken(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	_, err = service.GetStats(created.Short, "wrong-token")
+	if !errors.Is(err, shared.ErrInvalidDeletionToken) {
+		t.Errorf("expected ErrInvalidDeletionToken, got %v", err)
+	}
+
+	_, err = service.GetStats("nonexistent", created.DeletionToken)
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	stats, err := service.GetStats(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+
+	if stats.Clicks != 0 || stats.LastClickAt != nil {
+		t.Errorf("expected empty stats, got %+v", stats)
+	}
+}
+
+func TestLinkStatsStoreNoRawIP(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	const ip = "192.0.2.55"
+	if err := service.RecordClick(created, ip, "", firefoxUA); err != nil {
+		t.Fatalf("failed to record click: %v", err)
+	}
+
+	err = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if strings.Contains(string(item.Key()), ip) {
+				t.Errorf("raw IP found in key %q", item.Key())
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if strings.Contains(string(val), ip) {
+				t.Errorf("raw IP found in value of %q", item.Key())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan database: %v", err)
+	}
+}
+
+func TestLinkStatsDeletedWithLink(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	if err := service.RecordClick(created, "203.0.113.7", "", firefoxUA); err != nil {
+		t.Fatalf("failed to record click: %v", err)
+	}
+
+	if err := service.RevokeLink(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke link: %v", err)
+	}
+
+	err = db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(shared.Key(shared.LinkStatsPrefix, created.Short))
+		return err
+	})
+	if !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected stats to be deleted with the link, got %v", err)
+	}
+}
@@ -12,10 +12,10 @@ import (
 func TestLinkCreation(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com"
-	created, err := service.CreateLink(url, false, false)
+	created, err := service.CreateLink(url, false, false, 0, testExpiry, false)
 
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
@@ -37,8 +37,8 @@ func TestLinkCreation(t *testing.T) {
 		t.Error("expected Encrypted to be false")
 	}
 
-	if created.OneTime {
-		t.Error("expected OneTime to be false")
+	if created.MaxViews != 0 {
+		t.Errorf("expected no view limit, got %d", created.MaxViews)
 	}
 
 	if created.CreatedAt.IsZero() {
@@ -60,10 +60,10 @@ func TestLinkCreation(t *testing.T) {
 func TestLinkRetrieval(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com"
-	created, err := service.CreateLink(url, false, false)
+	created, err := service.CreateLink(url, false, false, 0, testExpiry, false)
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
@@ -90,7 +90,7 @@ func TestLinkRetrieval(t *testing.T) {
 func TestLinkNotFound(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	// Try to retrieve non-existent link
 	retrieved, err := service.GetLinkByShort("nonexistent")
@@ -111,10 +111,10 @@ func TestLinkNotFound(t *testing.T) {
 func TestLinkExpiry(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com"
-	created, err := service.CreateLink(url, false, false)
+	created, err := service.CreateLink(url, false, false, 0, testExpiry, false)
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
@@ -134,7 +134,6 @@ func TestLinkExpiry(t *testing.T) {
 		Short:     "testshort",
 		URL:       "https://short-lived.com",
 		Encrypted: false,
-		OneTime:   false,
 		CreatedAt: time.Now(),
 		ExpiresAt: time.Now().Add(1 * time.Second),
 	}
@@ -175,7 +174,7 @@ func TestLinkExpiry(t *testing.T) {
 func TestMultipleLinkCreation(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	urls := []string{
 		"https://example1.com",
@@ -185,7 +184,7 @@ func TestMultipleLinkCreation(t *testing.T) {
 
 	links := make([]*link.Link, len(urls))
 	for i, url := range urls {
-		created, err := service.CreateLink(url, false, false)
+		created, err := service.CreateLink(url, false, false, 0, testExpiry, false)
 		if err != nil {
 			t.Fatalf("failed to create link %d: %v", i, err)
 		}
@@ -208,12 +207,12 @@ func TestMultipleLinkCreation(t *testing.T) {
 func TestShortCodeUniqueness(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	// Create 100 links and verify all have unique short codes
 	shortCodes := make(map[string]bool)
 	for i := range 100 {
-		created, err := service.CreateLink("https://example.com/"+string(rune(i)), false, false)
+		created, err := service.CreateLink("https://example.com/"+string(rune(i)), false, false, 0, testExpiry, false)
 		if err != nil {
 			t.Fatalf("failed to create link %d: %v", i, err)
 		}
@@ -232,11 +231,11 @@ func TestShortCodeUniqueness(t *testing.T) {
 func TestLinkEncryptedPersistence(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com/secret"
 	// Create encrypted link WITHOUT onetime flag
-	created, err := service.CreateLink(url, true, false)
+	created, err := service.CreateLink(url, true, false, 0, testExpiry, false)
 	if err != nil {
 		t.Fatalf("failed to create encrypted link: %v", err)
 	}
@@ -245,8 +244,8 @@ func TestLinkEncryptedPersistence(t *testing.T) {
 		t.Error("expected Encrypted to be true")
 	}
 
-	if created.OneTime {
-		t.Error("expected OneTime to be false")
+	if created.MaxViews != 0 {
+		t.Errorf("expected no view limit, got %d", created.MaxViews)
 	}
 
 	// First retrieval should succeed
@@ -281,16 +280,16 @@ func TestLinkEncryptedPersistence(t *testing.T) {
 func TestLinkOneTime(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com/onetime"
-	created, err := service.CreateLink(url, false, true)
+	created, err := service.CreateLink(url, false, false, 1, testExpiry, false)
 	if err != nil {
 		t.Fatalf("failed to create onetime link: %v", err)
 	}
 
-	if !created.OneTime {
-		t.Error("expected OneTime to be true")
+	if created.MaxViews != 1 || created.ViewsLeft != 1 {
+		t.Errorf("expected a single view, got %d of %d", created.ViewsLeft, created.MaxViews)
 	}
 
 	// First retrieval should succeed
@@ -325,10 +324,10 @@ func TestLinkOneTime(t *testing.T) {
 func TestLinkEncryptedAndOneTime(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com/super-secret"
-	created, err := service.CreateLink(url, true, true)
+	created, err := service.CreateLink(url, true, false, 1, testExpiry, false)
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
@@ -337,8 +336,8 @@ func TestLinkEncryptedAndOneTime(t *testing.T) {
 		t.Error("expected Encrypted to be true")
 	}
 
-	if !created.OneTime {
-		t.Error("expected OneTime to be true")
+	if created.MaxViews != 1 || created.ViewsLeft != 1 {
+		t.Errorf("expected a single view, got %d of %d", created.ViewsLeft, created.MaxViews)
 	}
 
 	// First retrieval should succeed
@@ -365,10 +364,10 @@ func TestLinkEncryptedAndOneTime(t *testing.T) {
 func TestLinkDeletion(t *testing.T) {
 	db := SetupTestDB(t)
 	repo := link.NewLinkRepo(db)
-	service := link.NewLinkService(repo)
+	service := link.NewLinkService(repo, nil, nil)
 
 	url := "https://example.com/to-delete"
-	created, err := service.CreateLink(url, false, false)
+	created, err := service.CreateLink(url, false, false, 0, testExpiry, false)
 	if err != nil {
 		t.Fatalf("failed to create link: %v", err)
 	}
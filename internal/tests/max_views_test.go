@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/secret"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestSecretTwoViews(t *testing.T) {
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("twoviews==", 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	for want := 1; want >= 0; want-- {
+		retrieved, err := service.GetSecret(created.Short, testIP)
+		if err != nil {
+			t.Fatalf("failed to retrieve secret with %d views left: %v", want+1, err)
+		}
+		if retrieved.ViewsLeft != want {
+			t.Errorf("expected %d views left, got %d", want, retrieved.ViewsLeft)
+		}
+	}
+
+	if _, err := service.GetSecret(created.Short, testIP); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after the last view, got %v", err)
+	}
+}
+
+func TestSecretParallelRevealsSucceedTwice(t *testing.T) {
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("parallel==", 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, err := service.GetSecret(created.Short, testIP)
+		return err
+	})
+
+	if successes != 2 {
+		t.Errorf("expected exactly two reveals to succeed, got %d", successes)
+	}
+}
+
+func TestPasteParallelRevealsRespectMaxViews(t *testing.T) {
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	created, err := service.CreatePaste("parallel", "plain", "", false, false, 5, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, err := service.GetPaste(created.Short)
+		return err
+	})
+
+	if successes != 5 {
+		t.Errorf("expected exactly five reveals to succeed, got %d", successes)
+	}
+}
+
+func TestLinkViewKeepsExpiry(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := link.NewLinkRepo(db)
+	service := link.NewLinkService(repo, nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 3, time.Hour, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	expiresAt := func() uint64 {
+		var ts uint64
+		err := db.View(func(txn *badger.Txn) error {
+			item, err := txn.Get(shared.Key(shared.LinkPrefix, created.Short))
+			if err != nil {
+				return err
+			}
+			ts = item.ExpiresAt()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to read link: %v", err)
+		}
+		return ts
+	}
+
+	before := expiresAt()
+	retrieved, err := service.GetLinkByShort(created.Short)
+	if err != nil {
+		t.Fatalf("failed to retrieve link: %v", err)
+	}
+
+	if retrieved.ViewsLeft != 2 {
+		t.Errorf("expected 2 views left, got %d", retrieved.ViewsLeft)
+	}
+
+	if after := expiresAt(); after != before {
+		t.Errorf("expected expiry %d to be kept, got %d", before, after)
+	}
+}
+
+func TestImageFileRemovedWithLastView(t *testing.T) {
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	created, err := service.CreateImage([]byte("two views"), "image/png", false, false, 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	if _, _, err := service.GetImage(created.Short); err != nil {
+		t.Fatalf("failed to retrieve image: %v", err)
+	}
+	if _, err := os.Stat(created.FilePath); err != nil {
+		t.Errorf("expected file to be kept while views are left: %v", err)
+	}
+
+	if _, _, err := service.GetImage(created.Short); err != nil {
+		t.Fatalf("failed to retrieve image: %v", err)
+	}
+	if _, err := os.Stat(created.FilePath); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed with the last view, got %v", err)
+	}
+}
+
+func TestLegacyOneTimePasteIsSingleView(t *testing.T) {
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	// Stored before view limits and keyspaces existed, upgraded on startup
+	storeLegacy(t, db, "legacy", `{"Short":"legacy","Content":"old","OneTime":true}`)
+	if err := shared.MigrateKeys(db); err != nil {
+		t.Fatalf("failed to migrate keys: %v", err)
+	}
+
+	if _, err := service.GetPaste("legacy"); err != nil {
+		t.Fatalf("failed to retrieve legacy paste: %v", err)
+	}
+
+	if _, err := service.GetPaste("legacy"); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected legacy one-time paste to be consumed, got %v", err)
+	}
+}
+
+func TestViewLimit(t *testing.T) {
+	tests := []struct {
+		maxViews int
+		onetime  bool
+		want     int
+		wantErr  bool
+	}{
+		{0, false, 0, false},
+		{0, true, 1, false},
+		{2, false, 2, false},
+		{3, true, 3, false},
+		{shared.MaxViewsLimit, false, shared.MaxViewsLimit, false},
+		{shared.MaxViewsLimit + 1, false, 0, true},
+		{-1, false, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := shared.ViewLimit(tt.maxViews, tt.onetime)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ViewLimit(%d, %v) error = %v, wantErr %v", tt.maxViews, tt.onetime, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ViewLimit(%d, %v) = %d, want %d", tt.maxViews, tt.onetime, got, tt.want)
+		}
+	}
+}
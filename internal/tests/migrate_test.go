@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/secret"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// storeLegacy writes a record the way versions before keyspaces did, under its bare short code.
+func storeLegacy(t *testing.T, db *badger.DB, short, value string) {
+	t.Helper()
+
+	err := db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(short), []byte(value)).WithTTL(time.Hour))
+	})
+	if err != nil {
+		t.Fatalf("failed to store legacy record: %v", err)
+	}
+}
+
+func TestMigrateKeysOpensPreUpgradeDatabase(t *testing.T) {
+	db := SetupTestDB(t)
+	dir := t.TempDir()
+
+	imagePath := filepath.Join(dir, "oldimg.png")
+	if err := os.WriteFile(imagePath, []byte("png"), 0o600); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+	orphanPath := filepath.Join(dir, "orphan.png")
+	if err := os.WriteFile(orphanPath, []byte("png"), 0o600); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+
+	storeLegacy(t, db, "oldlnk", `{"Short":"oldlnk","URL":"https://example.com","Encrypted":false,"OneTime":false}`)
+	storeLegacy(t, db, "oldpst", `{"Short":"oldpst","Content":"hello","Language":"go","Encrypted":false,"OneTime":false}`)
+	storeLegacy(t, db, "oldimg", `{"Short":"oldimg","FilePath":"`+imagePath+`","ContentType":"image/png","Encrypted":false,"OneTime":false}`)
+	storeLegacy(t, db, "oldsec", `{"Short":"oldsec","Data":"c2VjcmV0"}`)
+
+	if err := shared.MigrateKeys(db); err != nil {
+		t.Fatalf("failed to migrate keys: %v", err)
+	}
+
+	if l, err := link.NewLinkRepo(db).GetByShort("oldlnk"); err != nil || l.URL != "https://example.com" || l.MaxViews != 0 {
+		t.Errorf("expected the link to be readable, got %+v (%v)", l, err)
+	}
+	if p, err := paste.NewPasteRepo(db).GetByShort("oldpst"); err != nil || p.Content != "hello" {
+		t.Errorf("expected the paste to be readable, got %+v (%v)", p, err)
+	}
+	if s, err := secret.NewSecretRepo(db).GetByShort("oldsec"); err != nil || s.Data != "c2VjcmV0" || s.MaxViews != 1 || s.ViewsLeft != 1 {
+		t.Errorf("expected the secret to be readable once, got %+v (%v)", s, err)
+	}
+
+	imageRepo := img.NewImageRepo(db)
+	if i, err := imageRepo.GetByShort("oldimg"); err != nil || i.FilePath != imagePath {
+		t.Errorf("expected the image to be readable, got %+v (%v)", i, err)
+	}
+
+	// The bare keys are gone and the expiry carried over
+	err := db.View(func(txn *badger.Txn) error {
+		if _, err := txn.Get([]byte("oldlnk")); !errors.Is(err, badger.ErrKeyNotFound) {
+			t.Errorf("expected the bare key to be removed, got %v", err)
+		}
+		item, err := txn.Get(shared.Key(shared.LinkPrefix, "oldlnk"))
+		if err != nil {
+			return err
+		}
+		if item.ExpiresAt() == 0 {
+			t.Error("expected the migrated link to keep its expiry")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read migrated keys: %v", err)
+	}
+
+	// Running again finds nothing left to move
+	if err := shared.MigrateKeys(db); err != nil {
+		t.Fatalf("failed to migrate keys again: %v", err)
+	}
+
+	// Cleanup keeps the migrated image and only removes the file without a record
+	service := img.NewImageService(imageRepo, dir)
+	service.StartCleanupWorker(10 * time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(orphanPath); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the cleanup worker to remove the orphaned file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(imagePath); err != nil {
+		t.Errorf("expected the migrated image to survive cleanup, got %v", err)
+	}
+}
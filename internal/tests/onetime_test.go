@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/secret"
+)
+
+const parallelReveals = 50
+
+// revealConcurrently calls reveal from many goroutines at once and returns how many calls succeeded.
+// Every failed call must report the resource as gone.
+func revealConcurrently(t *testing.T, reveal func() error) int {
+	t.Helper()
+
+	var successes atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for range parallelReveals {
+		wg.Go(func() {
+			<-start
+			err := reveal()
+			if err == nil {
+				successes.Add(1)
+				return
+			}
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				t.Errorf("expected ErrKeyNotFound for losing reveal, got %v", err)
+			}
+		})
+	}
+
+	close(start)
+	wg.Wait()
+
+	return int(successes.Load())
+}
+
+func TestSecretParallelRevealsSucceedOnce(t *testing.T) {
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("parallel==", 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, err := service.GetSecret(created.Short, testIP)
+		return err
+	})
+
+	if successes != 1 {
+		t.Errorf("expected exactly one reveal to succeed, got %d", successes)
+	}
+}
+
+func TestOneTimeLinkParallelRevealsSucceedOnce(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 1, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, err := service.GetLinkByShort(created.Short)
+		return err
+	})
+
+	if successes != 1 {
+		t.Errorf("expected exactly one reveal to succeed, got %d", successes)
+	}
+}
+
+func TestOneTimePasteParallelRevealsSucceedOnce(t *testing.T) {
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	created, err := service.CreatePaste("parallel", "plain", "", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, err := service.GetPaste(created.Short)
+		return err
+	})
+
+	if successes != 1 {
+		t.Errorf("expected exactly one reveal to succeed, got %d", successes)
+	}
+}
+
+func TestOneTimeImageParallelRevealsSucceedOnce(t *testing.T) {
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	created, err := service.CreateImage([]byte("parallel image"), "image/png", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, _, err := service.GetImage(created.Short)
+		return err
+	})
+
+	if successes != 1 {
+		t.Errorf("expected exactly one reveal to succeed, got %d", successes)
+	}
+}
+
+func TestReusableLinkParallelRevealsAllSucceed(t *testing.T) {
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	successes := revealConcurrently(t, func() error {
+		_, err := service.GetLinkByShort(created.Short)
+		return err
+	})
+
+	if successes != parallelReveals {
+		t.Errorf("expected all %d reveals to succeed, got %d", parallelReveals, successes)
+	}
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/piheta/seq.re/cmd/cli/crypto"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+)
+
+func TestEncryptWithPassword(t *testing.T) {
+	plaintext := []byte("https://example.com/runbook")
+
+	encrypted, err := crypto.EncryptWithPassword(plaintext, "correct horse")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	decrypted, err := crypto.DecryptWithPassword(encrypted, "correct horse")
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+
+	if string(decrypted) != string(plaintext) {
+		t.Errorf("expected %q, got %q", plaintext, decrypted)
+	}
+
+	if _, err := crypto.DecryptWithPassword(encrypted, "wrong horse"); err == nil {
+		t.Error("expected decryption with the wrong password to fail")
+	}
+}
+
+func TestEncryptWithPasswordStoresKDFParameters(t *testing.T) {
+	first, err := crypto.EncryptWithPassword([]byte("data"), "password")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	second, err := crypto.EncryptWithPassword([]byte("data"), "password")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	a, _ := base64.StdEncoding.DecodeString(first)
+	b, _ := base64.StdEncoding.DecodeString(second)
+
+	// Every share gets its own salt
+	if string(a[:16]) == string(b[:16]) {
+		t.Error("expected a random salt per encryption")
+	}
+
+	if iterations := binary.BigEndian.Uint32(a[16:20]); iterations < 100000 {
+		t.Errorf("expected at least 100000 PBKDF2 iterations, got %d", iterations)
+	}
+}
+
+func TestDecryptWithPasswordRejectsHugeIterationCount(t *testing.T) {
+	encrypted, err := crypto.EncryptWithPassword([]byte("data"), "password")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	data, _ := base64.StdEncoding.DecodeString(encrypted)
+	binary.BigEndian.PutUint32(data[16:20], 1<<31)
+
+	if _, err := crypto.DecryptWithPassword(base64.StdEncoding.EncodeToString(data), "password"); err == nil {
+		t.Error("expected an absurd iteration count to be rejected")
+	}
+}
+
+func TestPasswordProtectedFlagIsStored(t *testing.T) {
+	db := SetupTestDB(t)
+	linkService := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+	pasteService := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	createdLink, err := linkService.CreateLink("ciphertext", true, true, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	retrievedLink, err := linkService.GetLinkByShort(createdLink.Short)
+	if err != nil {
+		t.Fatalf("failed to retrieve link: %v", err)
+	}
+
+	if !retrievedLink.Encrypted || !retrievedLink.PasswordProtected {
+		t.Errorf("expected encrypted password protected link, got %+v", retrievedLink)
+	}
+
+	createdPaste, err := pasteService.CreatePaste("ciphertext", "go", "", true, true, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	retrievedPaste, err := pasteService.GetPaste(createdPaste.Short)
+	if err != nil {
+		t.Fatalf("failed to retrieve paste: %v", err)
+	}
+
+	if !retrievedPaste.PasswordProtected {
+		t.Error("expected password protected paste")
+	}
+}
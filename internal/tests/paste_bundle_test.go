@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+var testBundle = []paste.PasteFile{
+	{Name: "main.go", Language: "go", Content: "package main\n"},
+	{Name: "config/app.yaml", Language: "yaml", Content: "port: 8080\n"},
+}
+
+func TestCreateBundleValidation(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	handler := paste.NewPasteHandler(paste.NewPasteService(paste.NewPasteRepo(db)), nil)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"files", `{"files":[{"name":"a.go","language":"go","content":"package a"},{"name":"dir/b.txt","content":"b"}]}`, http.StatusCreated},
+		{"encrypted bundle", `{"content":"ciphertext==","encrypted":true,"bundle":true}`, http.StatusCreated},
+		{"duplicate name", `{"files":[{"name":"a.go","content":"1"},{"name":"a.go","content":"2"}]}`, http.StatusBadRequest},
+		{"parent directory", `{"files":[{"name":"../a.go","content":"1"}]}`, http.StatusBadRequest},
+		{"absolute path", `{"files":[{"name":"/etc/passwd","content":"1"}]}`, http.StatusBadRequest},
+		{"backslash", `{"files":[{"name":"dir\\a.go","content":"1"}]}`, http.StatusBadRequest},
+		{"control character", `{"files":[{"name":"a\u0007.go","content":"1"}]}`, http.StatusBadRequest},
+		{"missing name", `{"files":[{"content":"1"}]}`, http.StatusBadRequest},
+		{"unknown language", `{"files":[{"name":"a.go","language":"cobol","content":"1"}]}`, http.StatusBadRequest},
+		{"no files", `{"files":[]}`, http.StatusBadRequest},
+		{"plain files with content", `{"content":"x","files":[{"name":"a.go","content":"1"}]}`, http.StatusBadRequest},
+		{"encrypted files", `{"encrypted":true,"files":[{"name":"a.go","content":"1"}]}`, http.StatusBadRequest},
+		{"bundle language", `{"language":"go","files":[{"name":"a.go","content":"1"}]}`, http.StatusBadRequest},
+		{"too large", `{"files":[{"name":"a","content":"` + strings.Repeat("x", 600000) + `"},{"name":"b","content":"` + strings.Repeat("x", 600000) + `"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/pastes", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		err := handler.CreatePaste(rec, req)
+		if tt.want == http.StatusCreated && (err != nil || rec.Code != http.StatusCreated) {
+			t.Errorf("%s: expected the bundle to be created, got %d (%v)", tt.name, rec.Code, err)
+		}
+		if tt.want != http.StatusCreated && errorStatus(err) != tt.want {
+			t.Errorf("%s: expected %d, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestPasteBundleRoutes(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	created, err := service.CreateBundle(testBundle, "", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/p/"+created.Short+"?cli=true", nil)
+	req.SetPathValue("short", created.Short)
+	rec := httptest.NewRecorder()
+	if err := handler.GetPasteByShort(rec, req); err != nil {
+		t.Fatalf("failed to get bundle: %v", err)
+	}
+	var resp paste.PasteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !resp.Bundle || len(resp.Files) != 2 || resp.Files[1] != testBundle[1] {
+		t.Errorf("unexpected bundle %+v", resp)
+	}
+
+	getFile := func(short, name string) (string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/p/"+short+"/raw/"+name, nil)
+		req.SetPathValue("short", short)
+		req.SetPathValue("name", name)
+		rec := httptest.NewRecorder()
+		err := handler.GetPasteFile(rec, req)
+		return rec.Body.String(), err
+	}
+
+	for _, file := range testBundle {
+		if body, err := getFile(created.Short, file.Name); err != nil || body != file.Content {
+			t.Errorf("%s: expected %q, got %q (%v)", file.Name, file.Content, body, err)
+		}
+	}
+	if _, err := getFile(created.Short, "missing.go"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing file, got %v", err)
+	}
+
+	// Encrypted bundles keep their file names to themselves, and single pastes have no files
+	encrypted, err := service.CreateBundle(nil, "ciphertext==", true, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	if _, err := getFile(encrypted.Short, "main.go"); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for an encrypted bundle, got %v", err)
+	}
+	single, err := service.CreatePaste("package main\n", "go", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	if _, err := getFile(single.Short, "main.go"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a single paste, got %v", err)
+	}
+
+	card, err := service.Card(created.Short)
+	if err != nil {
+		t.Fatalf("failed to get card: %v", err)
+	}
+	if card.Title != "Paste (2 files)" || card.Description != "main.go\nconfig/app.yaml" {
+		t.Errorf("unexpected card %+v", card)
+	}
+}
+
+func TestUpdatePasteBundle(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	created, err := service.CreateBundle(testBundle, "", false, false, 0, testExpiry, true)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+
+	if _, err := service.UpdatePaste(created.Short, created.EditToken, "package main\n", nil, false); !errors.Is(err, paste.ErrBundleMismatch) {
+		t.Errorf("expected ErrBundleMismatch for content, got %v", err)
+	}
+
+	changed := []paste.PasteFile{testBundle[0], {Name: "config/app.yaml", Language: "yaml", Content: "port: 9090\n"}}
+	updated, err := service.UpdatePaste(created.Short, created.EditToken, "", changed, true)
+	if err != nil {
+		t.Fatalf("failed to update bundle: %v", err)
+	}
+
+	first, err := service.GetRevision(updated, 1)
+	if err != nil {
+		t.Fatalf("failed to get revision: %v", err)
+	}
+	second, err := service.GetRevision(updated, 2)
+	if err != nil {
+		t.Fatalf("failed to get revision: %v", err)
+	}
+	var got []string
+	for _, line := range paste.Diff(first.Text(), second.Text()) {
+		got = append(got, string(line.Op)+line.Text)
+	}
+	want := " ==> main.go <==| package main| | ==> config/app.yaml <==|-port: 8080|+port: 9090"
+	if strings.Join(got, "|") != want {
+		t.Errorf("expected %q, got %q", want, strings.Join(got, "|"))
+	}
+
+	fork, err := service.ForkPaste(created.Short, 1, false, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to fork bundle: %v", err)
+	}
+	if !fork.Bundle || len(fork.Files) != 2 || fork.Files[1] != testBundle[1] {
+		t.Errorf("expected the fork to keep the first revision of the files, got %+v", fork)
+	}
+
+	single, err := service.CreateEditablePaste("fixed", "", "", false, false, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	if _, err := service.UpdatePaste(single.Short, single.EditToken, "", changed, true); !errors.Is(err, paste.ErrBundleMismatch) {
+		t.Errorf("expected ErrBundleMismatch for files, got %v", err)
+	}
+}
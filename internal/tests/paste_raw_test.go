@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// getRaw requests /p/{short}/raw or /p/{short}/download with the given headers
+func getRaw(handler func(http.ResponseWriter, *http.Request) error, short, target string, headers map[string]string) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.SetPathValue("short", short)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	return rec, handler(rec, req)
+}
+
+func TestPasteRawAndDownload(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	script, err := service.CreateEditablePaste("echo hello\n", "bash", "install.sh", false, false, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	raw, err := getRaw(handler.GetPasteRaw, script.Short, "/p/"+script.Short+"/raw", nil)
+	if err != nil {
+		t.Fatalf("failed to get raw paste: %v", err)
+	}
+	if raw.Body.String() != "echo hello\n" || raw.Header().Get("Content-Type") != "text/plain; charset=utf-8" || raw.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("unexpected raw response %q %v", raw.Body.String(), raw.Header())
+	}
+	if disposition, params, _ := mime.ParseMediaType(raw.Header().Get("Content-Disposition")); disposition != "inline" || params["filename"] != "install.sh" {
+		t.Errorf("expected an inline install.sh, got %q", raw.Header().Get("Content-Disposition"))
+	}
+
+	etag := raw.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	cached, err := getRaw(handler.GetPasteRaw, script.Short, "/p/"+script.Short+"/raw", map[string]string{"If-None-Match": etag})
+	if err != nil || cached.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d (%v)", cached.Code, err)
+	}
+
+	// A new revision changes the ETag, the old one stays downloadable
+	if _, err := service.UpdatePaste(script.Short, script.EditToken, "echo bye\n", nil, false); err != nil {
+		t.Fatalf("failed to update paste: %v", err)
+	}
+	changed, err := getRaw(handler.GetPasteRaw, script.Short, "/p/"+script.Short+"/raw", map[string]string{"If-None-Match": etag})
+	if err != nil || changed.Code != http.StatusOK || changed.Body.String() != "echo bye\n" {
+		t.Errorf("expected the new revision, got %d %q (%v)", changed.Code, changed.Body.String(), err)
+	}
+	download, err := getRaw(handler.DownloadPaste, script.Short, "/p/"+script.Short+"/download?rev=1", nil)
+	if err != nil || download.Body.String() != "echo hello\n" || download.Header().Get("ETag") != etag {
+		t.Errorf("expected the first revision, got %q (%v)", download.Body.String(), err)
+	}
+	if disposition, params, _ := mime.ParseMediaType(download.Header().Get("Content-Disposition")); disposition != "attachment" || params["filename"] != "install.sh" {
+		t.Errorf("expected an install.sh attachment, got %q", download.Header().Get("Content-Disposition"))
+	}
+
+	unnamed, err := service.CreatePaste("package main\n", "go", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	download, err = getRaw(handler.DownloadPaste, unnamed.Short, "/p/"+unnamed.Short+"/download", nil)
+	if _, params, _ := mime.ParseMediaType(download.Header().Get("Content-Disposition")); err != nil || params["filename"] != unnamed.Short+".go" {
+		t.Errorf("expected the short code with the extension of the language, got %q (%v)", download.Header().Get("Content-Disposition"), err)
+	}
+}
+
+func TestPasteRawRefusesProtectedPastes(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	encrypted, err := service.CreatePaste("ciphertext==", "", "", true, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	limited, err := service.CreatePaste("launch codes", "", "", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	if _, err := getRaw(handler.GetPasteRaw, encrypted.Short, "/p/"+encrypted.Short+"/raw", nil); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for an encrypted paste, got %v", err)
+	}
+	if _, err := getRaw(handler.DownloadPaste, limited.Short, "/p/"+limited.Short+"/download", nil); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for a view limited paste, got %v", err)
+	}
+	if stored, err := service.CheckPasteExists(limited.Short); err != nil || stored.ViewsLeft != 1 {
+		t.Errorf("expected the view to be left, got %+v (%v)", stored, err)
+	}
+	if _, err := getRaw(handler.GetPasteRaw, "abc123", "/p/abc123/raw", nil); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing paste, got %v", err)
+	}
+}
+
+func TestDownloadPasteBundle(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	created, err := service.CreateBundle(testBundle, "", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+
+	raw, err := getRaw(handler.GetPasteRaw, created.Short, "/p/"+created.Short+"/raw", nil)
+	if err != nil || raw.Body.String() != "==> main.go <==\npackage main\n\n==> config/app.yaml <==\nport: 8080\n" {
+		t.Errorf("expected the files one after another, got %q (%v)", raw.Body.String(), err)
+	}
+
+	download, err := getRaw(handler.DownloadPaste, created.Short, "/p/"+created.Short+"/download", nil)
+	if err != nil {
+		t.Fatalf("failed to download bundle: %v", err)
+	}
+	if download.Header().Get("Content-Type") != "application/zip" {
+		t.Errorf("expected a zip archive, got %s", download.Header().Get("Content-Type"))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(download.Body.Bytes()), int64(download.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	for i, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(f)
+		_ = f.Close()
+		if file.Name != testBundle[i].Name || string(content) != testBundle[i].Content {
+			t.Errorf("unexpected file %s %q", file.Name, content)
+		}
+	}
+}
+
+func TestCreatePasteFileName(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	create := func(body string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/pastes", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		return rec, handler.CreatePaste(rec, req)
+	}
+
+	rec, err := create(`{"content":"echo hi","filename":"../../etc/install.sh"}`)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	var created shared.CreatedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	short := created.URL[strings.LastIndex(created.URL, "/")+1:]
+	if stored, err := service.CheckPasteExists(short); err != nil || stored.FileName != "install.sh" {
+		t.Errorf("expected the directories to be stripped, got %+v (%v)", stored, err)
+	}
+
+	for _, body := range []string{
+		`{"content":"ciphertext==","encrypted":true,"filename":"secret-plans.txt"}`,
+		`{"files":[{"name":"a.go","content":"1"}],"filename":"a.zip"}`,
+		`{"content":"x","filename":"` + strings.Repeat("a", 256) + `"}`,
+	} {
+		if _, err := create(body); errorStatus(err) != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %v", body[:40], err)
+		}
+	}
+}
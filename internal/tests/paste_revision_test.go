@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string // Lines of the diff, prefixed with their op
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", " a| b"},
+		{"insert", "a\nc", "a\nb\nc", " a|+b| c"},
+		{"delete", "a\nb\nc", "a\nc", " a|-b| c"},
+		{"replace", "a\nb\nc", "a\nx\nc", " a|-b|+x| c"},
+		{"from empty", "", "a\nb", "+a|+b"},
+		{"to empty", "a\nb", "", "-a|-b"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n", " a| b"},
+		{"moved", "a\nb\nc\nd", "b\nc\na\nd", "-a| b| c|+a| d"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, line := range paste.Diff(tt.from, tt.to) {
+			got = append(got, string(line.Op)+line.Text)
+		}
+		if strings.Join(got, "|") != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestDiffRebuildsBothRevisions(t *testing.T) {
+	var from, to []string
+	for i := range 300 {
+		line := strings.Repeat("x", i%7)
+		if i%5 != 0 {
+			from = append(from, line)
+		}
+		if i%3 != 0 {
+			to = append(to, line+"y")
+		} else {
+			to = append(to, line)
+		}
+	}
+
+	var gotFrom, gotTo []string
+	for _, line := range paste.Diff(strings.Join(from, "\n"), strings.Join(to, "\n")) {
+		if line.Op != paste.DiffInsert {
+			gotFrom = append(gotFrom, line.Text)
+		}
+		if line.Op != paste.DiffDelete {
+			gotTo = append(gotTo, line.Text)
+		}
+	}
+	if strings.Join(gotFrom, "\n") != strings.Join(from, "\n") || strings.Join(gotTo, "\n") != strings.Join(to, "\n") {
+		t.Error("expected the diff to rebuild both revisions")
+	}
+}
+
+func TestUpdatePasteKeepsRevisions(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	repo := paste.NewPasteRepo(db)
+	service := paste.NewPasteService(repo)
+
+	created, err := service.CreateEditablePaste("first", "go", "", false, false, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	if created.EditToken == "" {
+		t.Fatal("expected an edit token")
+	}
+
+	for _, content := range []string{"second", "third"} {
+		if _, err := service.UpdatePaste(created.Short, created.EditToken, content, nil, false); err != nil {
+			t.Fatalf("failed to update paste: %v", err)
+		}
+	}
+
+	stored, err := service.CheckPasteExists(created.Short)
+	if err != nil {
+		t.Fatalf("failed to get paste: %v", err)
+	}
+	if stored.CurrentRevision() != 3 || stored.Content != "third" {
+		t.Fatalf("expected revision 3 with the latest content, got %d %q", stored.CurrentRevision(), stored.Content)
+	}
+	for number, want := range map[int]string{1: "first", 2: "second", 3: "third"} {
+		if revision, err := service.GetRevision(stored, number); err != nil || revision.Content != want {
+			t.Errorf("revision %d: expected %q, got %+v (%v)", number, want, revision, err)
+		}
+	}
+	for _, number := range []int{0, 4} {
+		if _, err := service.GetRevision(stored, number); !errors.Is(err, paste.ErrUnknownRevision) {
+			t.Errorf("revision %d: expected ErrUnknownRevision, got %v", number, err)
+		}
+	}
+
+	if _, err := service.UpdatePaste(created.Short, created.DeletionToken, "hijacked", nil, false); !errors.Is(err, paste.ErrInvalidEditToken) {
+		t.Errorf("expected ErrInvalidEditToken for the deletion token, got %v", err)
+	}
+
+	plain, err := service.CreatePaste("fixed", "", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	if _, err := service.UpdatePaste(plain.Short, "", "changed", nil, false); !errors.Is(err, paste.ErrInvalidEditToken) {
+		t.Errorf("expected pastes without edit token to stay fixed, got %v", err)
+	}
+
+	// Revisions go with the paste
+	if err := service.RevokePaste(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to delete paste: %v", err)
+	}
+	if _, err := repo.GetRevision(created.Short, 1); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected revisions to be deleted, got %v", err)
+	}
+}
+
+func TestForkPaste(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+
+	original, err := service.CreateEditablePaste("ciphertext-1", "rust", "", true, false, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	if _, err := service.UpdatePaste(original.Short, original.EditToken, "ciphertext-2", nil, false); err != nil {
+		t.Fatalf("failed to update paste: %v", err)
+	}
+
+	fork, err := service.ForkPaste(original.Short, 1, true, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to fork paste: %v", err)
+	}
+	if fork.Short == original.Short || fork.Content != "ciphertext-1" || fork.Language != "rust" || !fork.Encrypted || fork.ForkedFrom != original.Short {
+		t.Errorf("unexpected fork %+v", fork)
+	}
+	if fork.EditToken == "" || fork.CurrentRevision() != 1 {
+		t.Errorf("expected an editable fork starting at revision 1, got %+v", fork)
+	}
+
+	limited, err := service.CreatePaste("secret", "", "", false, false, 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	if _, err := service.ForkPaste(limited.Short, 0, false, testExpiry); !errors.Is(err, paste.ErrViewLimited) {
+		t.Errorf("expected ErrViewLimited, got %v", err)
+	}
+	if stored, err := service.CheckPasteExists(limited.Short); err != nil || stored.ViewsLeft != 2 {
+		t.Errorf("expected both views to be left, got %+v (%v)", stored, err)
+	}
+}
+
+func TestPasteRevisionRoutes(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	created, err := service.CreateEditablePaste("a\nb\n", "", "", false, false, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	update := func(token string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPut, "/api/pastes/"+created.Short, strings.NewReader(`{"content":"a\nc\n"}`))
+		req.SetPathValue("short", created.Short)
+		req.Header.Set(paste.EditTokenHeader, token)
+		rec := httptest.NewRecorder()
+		return rec, handler.UpdatePaste(rec, req)
+	}
+
+	if _, err := update("wrong"); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for a wrong edit token, got %v", err)
+	}
+	rec, err := update(created.EditToken)
+	if err != nil {
+		t.Fatalf("failed to update paste: %v", err)
+	}
+	var revision paste.RevisionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &revision); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if revision.Revision != 2 || !strings.HasSuffix(revision.URL, "/p/"+created.Short+"?rev=2") {
+		t.Errorf("unexpected revision %+v", revision)
+	}
+
+	get := func(query string) (string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/p/"+created.Short+"?cli=true"+query, nil)
+		req.SetPathValue("short", created.Short)
+		rec := httptest.NewRecorder()
+		err := handler.GetPasteByShort(rec, req)
+		return rec.Body.String(), err
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "a\nc\n"},
+		{"&rev=1", "a\nb\n"},
+		{"&diff=1", " a\n-b\n+c\n"},
+	}
+	for _, tt := range tests {
+		if body, err := get(tt.query); err != nil || body != tt.want {
+			t.Errorf("%q: expected %q, got %q (%v)", tt.query, tt.want, body, err)
+		}
+	}
+	if _, err := get("&rev=3"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown revision, got %v", err)
+	}
+	if _, err := get("&rev=first"); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid revision, got %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pastes/"+created.Short+"/fork?rev=1", nil)
+	req.SetPathValue("short", created.Short)
+	rec = httptest.NewRecorder()
+	if err := handler.ForkPaste(rec, req); err != nil {
+		t.Fatalf("failed to fork paste: %v", err)
+	}
+	var fork shared.CreatedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &fork); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if fork.DeletionToken == "" || fork.EditToken != "" {
+		t.Errorf("expected a fork that isn't editable, got %+v", fork)
+	}
+}
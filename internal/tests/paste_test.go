@@ -19,7 +19,7 @@ func TestPasteCreation(t *testing.T) {
 	content := "package main\n\nfunc main() {\n\tprintln(\"Hello, World!\")\n}"
 	language := "go"
 
-	created, err := service.CreatePaste(content, language, false, false)
+	created, err := service.CreatePaste(content, language, "", false, false, 0, testExpiry)
 
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
@@ -68,7 +68,7 @@ func TestPasteCreationWithoutLanguage(t *testing.T) {
 
 	content := "Just some plain text without a language"
 
-	created, err := service.CreatePaste(content, "", false, false)
+	created, err := service.CreatePaste(content, "", "", false, false, 0, testExpiry)
 
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
@@ -91,7 +91,7 @@ func TestPasteRetrieval(t *testing.T) {
 	content := "console.log('Hello, World!');"
 	language := "javascript"
 
-	created, err := service.CreatePaste(content, language, false, false)
+	created, err := service.CreatePaste(content, language, "", false, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
 	}
@@ -125,13 +125,13 @@ func TestPasteOneTime(t *testing.T) {
 	service := paste.NewPasteService(repo)
 
 	content := "This is a one-time paste"
-	created, err := service.CreatePaste(content, "plain", false, true)
+	created, err := service.CreatePaste(content, "plain", "", false, false, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
 	}
 
-	if !created.OneTime {
-		t.Error("expected OneTime to be true")
+	if created.MaxViews != 1 || created.ViewsLeft != 1 {
+		t.Errorf("expected a single view, got %d of %d", created.ViewsLeft, created.MaxViews)
 	}
 
 	// First retrieval should succeed
@@ -173,7 +173,7 @@ func TestPasteEncryptedPersistence(t *testing.T) {
 	base64Content := base64.StdEncoding.EncodeToString([]byte(plainContent))
 
 	// Create encrypted paste WITHOUT onetime flag
-	created, err := service.CreatePaste(base64Content, "", true, false)
+	created, err := service.CreatePaste(base64Content, "", "", true, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
 	}
@@ -182,8 +182,8 @@ func TestPasteEncryptedPersistence(t *testing.T) {
 		t.Error("expected Encrypted to be true")
 	}
 
-	if created.OneTime {
-		t.Error("expected OneTime to be false")
+	if created.MaxViews != 0 {
+		t.Errorf("expected no view limit, got %d", created.MaxViews)
 	}
 
 	// First retrieval should succeed
@@ -248,7 +248,7 @@ func TestPasteExpiry(t *testing.T) {
 	service := paste.NewPasteService(repo)
 
 	content := "expiring paste"
-	created, err := service.CreatePaste(content, "", false, false)
+	created, err := service.CreatePaste(content, "", "", false, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
 	}
@@ -269,7 +269,6 @@ func TestPasteExpiry(t *testing.T) {
 		Content:   "short lived content",
 		Language:  "plain",
 		Encrypted: false,
-		OneTime:   false,
 		CreatedAt: time.Now(),
 		ExpiresAt: time.Now().Add(1 * time.Second),
 	}
@@ -315,7 +314,7 @@ func TestMultiplePasteCreation(t *testing.T) {
 
 	pastes := make([]*paste.Paste, len(pasteData))
 	for i, data := range pasteData {
-		created, err := service.CreatePaste(data.content, data.language, false, false)
+		created, err := service.CreatePaste(data.content, data.language, "", false, false, 0, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create paste %d: %v", i, err)
 		}
@@ -347,7 +346,7 @@ func TestPasteShortCodeUniqueness(t *testing.T) {
 	// Create 100 pastes and verify all have unique short codes
 	shortCodes := make(map[string]bool)
 	for i := range 100 {
-		created, err := service.CreatePaste("content"+string(rune(i)), "", false, false)
+		created, err := service.CreatePaste("content"+string(rune(i)), "", "", false, false, 0, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create paste %d: %v", i, err)
 		}
@@ -386,7 +385,7 @@ func TestPasteContentValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			created, err := service.CreatePaste(tt.content, tt.language, false, false)
+			created, err := service.CreatePaste(tt.content, tt.language, "", false, false, 0, testExpiry)
 			if err != nil {
 				t.Fatalf("failed to create paste: %v", err)
 			}
@@ -413,7 +412,7 @@ func TestPasteDeletion(t *testing.T) {
 	service := paste.NewPasteService(repo)
 
 	content := "test content"
-	created, err := service.CreatePaste(content, "", false, false)
+	created, err := service.CreatePaste(content, "", "", false, false, 0, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
 	}
@@ -455,7 +454,7 @@ func TestPasteTimestamps(t *testing.T) {
 	service := paste.NewPasteService(repo)
 
 	beforeCreate := time.Now()
-	created, err := service.CreatePaste("timestamp test", "plain", false, false)
+	created, err := service.CreatePaste("timestamp test", "plain", "", false, false, 0, testExpiry)
 	afterCreate := time.Now()
 
 	if err != nil {
@@ -493,7 +492,7 @@ func TestPasteLanguageOptions(t *testing.T) {
 	}
 
 	for _, lang := range languages {
-		created, err := service.CreatePaste("test content", lang, false, false)
+		created, err := service.CreatePaste("test content", lang, "", false, false, 0, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create paste with language %s: %v", lang, err)
 		}
@@ -522,7 +521,7 @@ func TestPasteEncryptedAndOneTime(t *testing.T) {
 	plainContent := "super secret content"
 	base64Content := base64.StdEncoding.EncodeToString([]byte(plainContent))
 
-	created, err := service.CreatePaste(base64Content, "", true, true)
+	created, err := service.CreatePaste(base64Content, "", "", true, false, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create paste: %v", err)
 	}
@@ -531,8 +530,8 @@ func TestPasteEncryptedAndOneTime(t *testing.T) {
 		t.Error("expected Encrypted to be true")
 	}
 
-	if !created.OneTime {
-		t.Error("expected OneTime to be true")
+	if created.MaxViews != 1 || created.ViewsLeft != 1 {
+		t.Errorf("expected a single view, got %d of %d", created.ViewsLeft, created.MaxViews)
 	}
 
 	// Retrieve should return base64 content and delete
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/file"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/plain"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// plainRequest builds a raw body upload the way curl sends them, --data-binary declares every body as a form.
+func plainRequest(method, target, name string, body []byte, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if name != "" {
+		req.SetPathValue("name", name)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return req
+}
+
+func TestPlainUpload(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	pasteService := paste.NewPasteService(paste.NewPasteRepo(db))
+	fileService := file.NewFileService(file.NewFileRepo(db), t.TempDir())
+	handler := plain.NewPlainHandler(fileService.UploadDir(),
+		plain.Target{MaxSize: paste.MaxContentSize, Store: paste.NewPasteHandler(pasteService, nil).StoreUpload},
+		plain.Target{MaxSize: config.Config.MaxUploadSize, Store: file.NewFileHandler(fileService, nil).StoreUpload},
+	)
+
+	upload := func(h func(http.ResponseWriter, *http.Request) error, req *http.Request) (short string, rec *httptest.ResponseRecorder, err error) {
+		rec = httptest.NewRecorder()
+		if err := h(rec, req); err != nil {
+			return "", rec, err
+		}
+		first, _, _ := strings.Cut(rec.Body.String(), "\n")
+		return first[strings.LastIndex(first, "/")+1:], rec, nil
+	}
+
+	// Text becomes a paste, named after the file curl -T appends and detected by its shebang
+	short, rec, err := upload(handler.Upload, plainRequest(http.MethodPut, "/install.sh?expires=1h", "install.sh", []byte("#!/bin/sh\necho hi\n"), nil))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if rec.Code != http.StatusCreated || !strings.HasPrefix(rec.Body.String(), shared.PublicURL("/p/"+short)+"\n") || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+	token := rec.Header().Get(shared.DeletionTokenHeader)
+	if token == "" || !strings.Contains(rec.Body.String(), token) {
+		t.Errorf("expected the deletion token in the header and body, got %q", rec.Body.String())
+	}
+	stored, err := pasteService.CheckPasteExists(short)
+	if err != nil {
+		t.Fatalf("failed to get paste: %v", err)
+	}
+	if stored.FileName != "install.sh" || stored.Language != "bash" || stored.ExpiresAt.Sub(stored.CreatedAt).Round(time.Minute) != time.Hour {
+		t.Errorf("unexpected paste %+v", stored)
+	}
+	if err := pasteService.RevokePaste(short, token); err != nil {
+		t.Errorf("expected the token to delete the paste, got %v", err)
+	}
+
+	// Options also come from headers, query parameters win
+	short, _, err = upload(handler.UploadPaste, plainRequest(http.MethodPut, "/p?language=py&onetime", "", []byte("print(1)"), map[string]string{"X-Language": "go", "X-Views": "3"}))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if stored, err := pasteService.CheckPasteExists(short); err != nil || stored.Language != "python" || stored.MaxViews != 3 {
+		t.Errorf("unexpected paste %+v (%v)", stored, err)
+	}
+
+	// Binary bodies become files, also when the text would be too large for a paste
+	binary := []byte{0x89, 'P', 'N', 'G', 0, 1, 2, 3}
+	for _, body := range [][]byte{binary, bytes.Repeat([]byte("x"), paste.MaxContentSize+1)} {
+		short, _, err = upload(handler.Upload, plainRequest(http.MethodPost, "/", "", body, nil))
+		if err != nil {
+			t.Fatalf("failed to upload: %v", err)
+		}
+		if stored, err := fileService.CheckFileExists(short); err != nil || stored.Size != int64(len(body)) {
+			t.Errorf("expected a file of %d bytes, got %+v (%v)", len(body), stored, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		h    func(http.ResponseWriter, *http.Request) error
+		req  *http.Request
+		want int
+	}{
+		{"binary paste", handler.UploadPaste, plainRequest(http.MethodPut, "/p", "", binary, nil), http.StatusBadRequest},
+		{"empty body", handler.Upload, plainRequest(http.MethodPost, "/", "", nil, nil), http.StatusBadRequest},
+		{"unknown language", handler.UploadPaste, plainRequest(http.MethodPut, "/p?language=cobol", "", []byte("x"), nil), http.StatusBadRequest},
+		{"invalid views", handler.Upload, plainRequest(http.MethodPost, "/", "", []byte("x"), map[string]string{"X-Views": "many"}), http.StatusBadRequest},
+		{"invalid expiry", handler.UploadFile, plainRequest(http.MethodPut, "/f?expires=soon", "", []byte("x"), nil), http.StatusBadRequest},
+		{"too large paste", handler.UploadPaste, plainRequest(http.MethodPut, "/p", "", bytes.Repeat([]byte("x"), paste.MaxContentSize+1), nil), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if _, _, err := upload(tt.h, tt.req); errorStatus(err) != tt.want {
+			t.Errorf("%s: expected %d, got %v", tt.name, tt.want, err)
+		}
+	}
+}
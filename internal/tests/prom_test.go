@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// recordedRequests serves a request through the Prometheus middleware and returns how many requests
+// were counted under the given path label.
+func recordedRequests(t *testing.T, method, target, path string) float64 {
+	t.Helper()
+
+	counter := middleware.HTTPRequestsTotal.WithLabelValues(method, path, "200")
+	before := testutil.ToFloat64(counter)
+
+	handler := middleware.NewPrometheusMiddleware()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, nil))
+
+	return testutil.ToFloat64(counter) - before
+}
+
+func TestMetricsCollapsePasteFileNames(t *testing.T) {
+	config.InitEnv()
+
+	for _, target := range []string{"/p/abc123/raw/main.go", "/p/abc123/raw/cmd/server/main.go"} {
+		if n := recordedRequests(t, http.MethodGet, target, "/p/{short}/raw/{name}"); n != 1 {
+			t.Errorf("expected %s to be counted as /p/{short}/raw/{name}, got %v", target, n)
+		}
+	}
+
+	if n := recordedRequests(t, http.MethodGet, "/p/abc123/raw", "/p/{short}/raw"); n != 1 {
+		t.Errorf("expected the raw paste to keep its path, got %v", n)
+	}
+}
+
+func TestMetricsCollapsePlainUploadNames(t *testing.T) {
+	config.InitEnv()
+
+	for target, path := range map[string]string{
+		"/notes.txt":  "/{name}",
+		"/abc123":     "/{name}",
+		"/p/main.go":  "/p/{name}",
+		"/f/dump.tar": "/f/{name}",
+	} {
+		if n := recordedRequests(t, http.MethodPut, target, path); n != 1 {
+			t.Errorf("expected PUT %s to be counted as %s, got %v", target, path, n)
+		}
+	}
+
+	if n := recordedRequests(t, http.MethodPut, "/p", "/p"); n != 1 {
+		t.Errorf("expected an unnamed upload to keep its path, got %v", n)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/features/qr"
+	"github.com/piheta/seq.re/internal/qrcode"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestQREncode(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+	}{
+		{"", 21},
+		{"https://seq.re/abc123", 25},
+		{"https://seq.re/abc123#" + strings.Repeat("k", 22), 33},
+		{strings.Repeat("x", 2331), 177}, // Byte mode capacity of version 40 at level M
+	}
+	for _, tt := range tests {
+		code, err := qrcode.Encode(tt.text)
+		if err != nil {
+			t.Fatalf("failed to encode %d bytes: %v", len(tt.text), err)
+		}
+		if code.Size != tt.size {
+			t.Errorf("%d bytes: expected size %d, got %d", len(tt.text), tt.size, code.Size)
+		}
+
+		// Finder patterns in three corners, dark rings around a light ring around a dark center
+		for _, corner := range [][2]int{{3, 3}, {code.Size - 4, 3}, {3, code.Size - 4}} {
+			for d := -3; d <= 3; d++ {
+				ring := max(d, -d)
+				if code.Dark(corner[0]+d, corner[1]) != (ring != 2) || code.Dark(corner[0], corner[1]+d) != (ring != 2) {
+					t.Fatalf("%d bytes: broken finder pattern at %v", len(tt.text), corner)
+				}
+			}
+		}
+
+		// Format information says level M in both copies
+		var first, second int
+		for i, pos := range [][2]int{{8, 0}, {8, 1}, {8, 2}, {8, 3}, {8, 4}, {8, 5}, {8, 7}, {8, 8}, {7, 8}, {5, 8}, {4, 8}, {3, 8}, {2, 8}, {1, 8}, {0, 8}} {
+			if code.Dark(pos[0], pos[1]) {
+				first |= 1 << i
+			}
+		}
+		for i := range 15 {
+			x, y := 8, code.Size-15+i
+			if i < 8 {
+				x, y = code.Size-1-i, 8
+			}
+			if code.Dark(x, y) {
+				second |= 1 << i
+			}
+		}
+		if first != second || (first^0x5412)>>13 != 0 {
+			t.Errorf("%d bytes: unexpected format information %015b and %015b", len(tt.text), first, second)
+		}
+	}
+
+	if _, err := qrcode.Encode(strings.Repeat("x", 2332)); !errors.Is(err, qrcode.ErrTooLong) {
+		t.Errorf("expected ErrTooLong, got %v", err)
+	}
+}
+
+func TestQRCodeHandler(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	pasteService := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := qr.NewQRHandler(map[string]shared.CardFunc{"p": pasteService.Card})
+
+	plain, err := pasteService.CreatePaste("hello", "", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	encrypted, err := pasteService.CreatePaste("ciphertext==", "", "", true, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	get := func(kind, short, query string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, "/qr/"+kind+"/"+short+query, nil)
+		req.SetPathValue("kind", kind)
+		req.SetPathValue("short", short)
+		rec := httptest.NewRecorder()
+		return rec, handler.GetResourceQRCode(rec, req)
+	}
+
+	rec, err := get("p", plain.Short, "")
+	if err != nil {
+		t.Fatalf("failed to get QR code: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a PNG, got %s (%v)", rec.Header().Get("Content-Type"), err)
+	}
+	code, _ := qrcode.Encode(shared.PublicURL("/p/" + plain.Short))
+	if side := (code.Size + 8) * 8; img.Bounds().Dx() != side || img.Bounds().Dy() != side {
+		t.Errorf("expected %dpx with the quiet zone, got %v", side, img.Bounds())
+	}
+
+	rec, err = get("p", plain.Short, "?format=svg")
+	if err != nil || rec.Header().Get("Content-Type") != "image/svg+xml" || !bytes.Equal(rec.Body.Bytes(), code.SVG()) {
+		t.Errorf("expected the SVG of the paste URL, got %s (%v)", rec.Header().Get("Content-Type"), err)
+	}
+
+	tests := []struct {
+		name  string
+		kind  string
+		short string
+		query string
+		want  int
+	}{
+		{"encrypted", "p", encrypted.Short, "", http.StatusBadRequest},
+		{"unknown format", "p", plain.Short, "?format=gif", http.StatusBadRequest},
+		{"missing", "p", "missing", "", http.StatusNotFound},
+		{"unknown kind", "x", plain.Short, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := get(tt.kind, tt.short, tt.query); errorStatus(err) != tt.want {
+			t.Errorf("%s: expected %d, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestCheckReveal(t *testing.T) {
+	token := shared.NewRevealToken("pastes", "abc123")
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		form    string
+		wantErr bool
+	}{
+		{"cli with token", http.MethodPost, map[string]string{shared.RevealTokenHeader: token, "Origin": "https://seq.re"}, "", false},
+		{"same origin page", http.MethodPost, map[string]string{shared.RevealTokenHeader: token, "Origin": "https://seq.re", "Sec-Fetch-Site": "same-origin"}, "", false},
+		{"same origin fetch without origin", http.MethodPost, map[string]string{shared.RevealTokenHeader: token, "Sec-Fetch-Site": "same-origin"}, "", false},
+		{"form token", http.MethodPost, map[string]string{"Origin": "https://seq.re"}, "reveal_token=" + url.QueryEscape(token), false},
+		{"missing token", http.MethodPost, map[string]string{"Origin": "https://seq.re"}, "", true},
+		{"no origin", http.MethodPost, map[string]string{shared.RevealTokenHeader: token}, "", true},
+		{"token of another paste", http.MethodPost, map[string]string{shared.RevealTokenHeader: shared.NewRevealToken("pastes", "xyz789")}, "", true},
+		{"token of another kind", http.MethodPost, map[string]string{shared.RevealTokenHeader: shared.NewRevealToken("files", "abc123")}, "", true},
+		{"expired token", http.MethodPost, map[string]string{shared.RevealTokenHeader: "100." + strings.SplitN(token, ".", 2)[1]}, "", true},
+		{"unfurl bot", http.MethodPost, map[string]string{shared.RevealTokenHeader: token, "User-Agent": slackbotUA}, "", true},
+		{"head request", http.MethodHead, map[string]string{shared.RevealTokenHeader: token}, "", true},
+		{"cross site", http.MethodPost, map[string]string{shared.RevealTokenHeader: token, "Sec-Fetch-Site": "cross-site"}, "", true},
+		{"foreign origin", http.MethodPost, map[string]string{shared.RevealTokenHeader: token, "Origin": "https://evil.example"}, "", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "https://seq.re/api/pastes/abc123/reveal", strings.NewReader(tt.form))
+		if tt.form != "" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+
+		err := shared.CheckReveal(req, "pastes", "abc123")
+		if tt.wantErr && errorStatus(err) != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %v", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: expected the reveal to be allowed, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestPasteRevealFlow(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, nil)
+
+	created, err := service.CreatePaste("launch codes", "", "", false, false, 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	// Anyone can append cli=true, so it no longer uses up a view
+	req := httptest.NewRequest(http.MethodGet, "/p/"+created.Short+"?cli=true", nil)
+	req.SetPathValue("short", created.Short)
+	if err := handler.GetPasteByShort(httptest.NewRecorder(), req); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for cli=true, got %v", err)
+	}
+
+	// Tokens are only minted for requests naming this server as their origin
+	req = httptest.NewRequest(http.MethodGet, "/api/pastes/"+created.Short+"/reveal", nil)
+	req.SetPathValue("short", created.Short)
+	if err := handler.StartPasteReveal(httptest.NewRecorder(), req); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 without an origin, got %v", err)
+	}
+
+	req.Header.Set("Origin", "http://example.com")
+	rec := httptest.NewRecorder()
+	if err := handler.StartPasteReveal(rec, req); err != nil {
+		t.Fatalf("failed to start reveal: %v", err)
+	}
+	var start shared.RevealResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &start); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if start.ViewsLeft == nil || *start.ViewsLeft != 2 {
+		t.Errorf("expected 2 views left, got %v", start.ViewsLeft)
+	}
+
+	reveal := func(userAgent string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/pastes/"+created.Short+"/reveal", nil)
+		req.SetPathValue("short", created.Short)
+		req.Header.Set(shared.RevealTokenHeader, start.RevealToken)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("User-Agent", userAgent)
+		rec := httptest.NewRecorder()
+		return rec, handler.RevealPaste(rec, req)
+	}
+
+	if _, err := reveal(slackbotUA); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 for a bot, got %v", err)
+	}
+	if stored, err := service.CheckPasteExists(created.Short); err != nil || stored.ViewsLeft != 2 {
+		t.Fatalf("expected both views to be left, got %+v (%v)", stored, err)
+	}
+
+	rec, err = reveal("seqre-cli")
+	if err != nil {
+		t.Fatalf("failed to reveal paste: %v", err)
+	}
+	if rec.Body.String() != "launch codes" || rec.Header().Get(shared.ViewsLeftHeader) != "1" {
+		t.Errorf("expected the paste with 1 view left, got %q (%s)", rec.Body.String(), rec.Header().Get(shared.ViewsLeftHeader))
+	}
+}
+
+func TestOnetimePageRequiresRevealToken(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, newTemplateService(t))
+
+	created, err := service.CreatePaste("launch codes", "", "", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/p/"+created.Short, nil)
+	req.SetPathValue("short", created.Short)
+	rec := httptest.NewRecorder()
+	if err := handler.GetPasteByShort(rec, req); err != nil {
+		t.Fatalf("failed to get paste: %v", err)
+	}
+	match := regexp.MustCompile(`const revealToken = '([^']+)'`).FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		t.Fatal("expected the interstitial to carry a reveal token")
+	}
+
+	post := func(token string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/pastes/"+created.Short+"/onetime", nil)
+		req.SetPathValue("short", created.Short)
+		req.Header.Set("Sec-Fetch-Site", "same-origin")
+		if token != "" {
+			req.Header.Set(shared.RevealTokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		if err := handler.RevealOneTimePaste(rec, req); err != nil {
+			t.Fatalf("failed to reveal paste: %v", err)
+		}
+		return rec.Body.String()
+	}
+
+	if strings.Contains(post(""), "launch codes") {
+		t.Fatal("expected the paste to stay hidden without a token")
+	}
+	if stored, err := service.CheckPasteExists(created.Short); err != nil || stored.ViewsLeft != 1 {
+		t.Fatalf("expected the view to be left, got %+v (%v)", stored, err)
+	}
+
+	if !strings.Contains(post(match[1]), "launch codes") {
+		t.Error("expected the paste to be revealed")
+	}
+}
+
+func TestRedirectIgnoresUnfurlBots(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+	handler := link.NewLinkHandler(service, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	for _, userAgent := range []string{slackbotUA, "Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", firefoxUA} {
+		req := httptest.NewRequest(http.MethodGet, "/"+created.Short, nil)
+		req.SetPathValue("short", created.Short)
+		req.Header.Set("User-Agent", userAgent)
+		if err := handler.RedirectByShort(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("failed to redirect: %v", err)
+		}
+	}
+
+	stats, err := service.GetStats(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.Clicks != 1 {
+		t.Errorf("expected only the browser click to count, got %d", stats.Clicks)
+	}
+}
+
+func TestRevealTokensSurviveRestartWithSecret(t *testing.T) {
+	config.InitEnv()
+	defer func() { config.Config.RevealSecret = "" }()
+
+	check := func(token string) error {
+		req := httptest.NewRequest(http.MethodPost, "/api/pastes/abc123/reveal", nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set(shared.RevealTokenHeader, token)
+		return shared.CheckReveal(req, "pastes", "abc123")
+	}
+
+	config.Config.RevealSecret = "first secret"
+	shared.InitRevealKey()
+	token := shared.NewRevealToken("pastes", "abc123")
+
+	// A restart derives the same key from the same secret
+	shared.InitRevealKey()
+	if err := check(token); err != nil {
+		t.Errorf("expected the token to survive a restart, got %v", err)
+	}
+
+	config.Config.RevealSecret = "second secret"
+	shared.InitRevealKey()
+	if err := check(token); errorStatus(err) != http.StatusForbidden {
+		t.Errorf("expected 403 once the secret changed, got %v", err)
+	}
+}
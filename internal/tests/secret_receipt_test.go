@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/secret"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestSecretStatusPendingThenConsumed(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("receipt==", 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	status, err := service.GetStatus(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.Status != secret.SecretPending || status.ViewsLeft != 2 || len(status.Reads) != 0 {
+		t.Errorf("expected unread pending secret, got %+v", status)
+	}
+
+	if _, err := service.GetSecret(created.Short, "198.51.100.20"); err != nil {
+		t.Fatalf("failed to retrieve secret: %v", err)
+	}
+
+	status, err = service.GetStatus(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.Status != secret.SecretPending || status.ViewsLeft != 1 || len(status.Reads) != 1 {
+		t.Errorf("expected pending secret with one read, got %+v", status)
+	}
+
+	if _, err := service.GetSecret(created.Short, "198.51.100.99"); err != nil {
+		t.Fatalf("failed to retrieve secret: %v", err)
+	}
+
+	status, err = service.GetStatus(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get status of consumed secret: %v", err)
+	}
+	if status.Status != secret.SecretConsumed || status.ViewsLeft != 0 {
+		t.Errorf("expected consumed secret, got %+v", status)
+	}
+	if status.ConsumedAt == nil || time.Since(*status.ConsumedAt) > time.Minute {
+		t.Errorf("expected recent consumed_at, got %v", status.ConsumedAt)
+	}
+	if len(status.Reads) != 2 {
+		t.Fatalf("expected 2 reads, got %d", len(status.Reads))
+	}
+
+	// Both readers share a /24, so they can't be told apart
+	if status.Reads[0].NetworkHash != status.Reads[1].NetworkHash {
+		t.Errorf("expected reads from the same network to share a hash, got %v", status.Reads)
+	}
+}
+
+func TestSecretStatusTellsNetworksApart(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("receipt==", 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	for _, ip := range []string{"198.51.100.20", "2001:db8:1::7"} {
+		if _, err := service.GetSecret(created.Short, ip); err != nil {
+			t.Fatalf("failed to retrieve secret: %v", err)
+		}
+	}
+
+	status, err := service.GetStatus(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if len(status.Reads) != 2 || status.Reads[0].NetworkHash == status.Reads[1].NetworkHash {
+		t.Errorf("expected reads from different networks to differ, got %v", status.Reads)
+	}
+}
+
+func TestSecretStatusExpired(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("receipt==", 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	// Expire the secret the way its TTL would, leaving the receipt behind
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(shared.Key(shared.SecretPrefix, created.Short))
+	})
+	if err != nil {
+		t.Fatalf("failed to expire secret: %v", err)
+	}
+
+	status, err := service.GetStatus(created.Short, created.DeletionToken)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	if status.Status != secret.SecretExpired || status.ConsumedAt != nil {
+		t.Errorf("expected expired secret, got %+v", status)
+	}
+}
+
+func TestSecretStatusRequiresToken(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("receipt==", 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	if _, err := service.GetStatus(created.Short, "wrong-token"); !errors.Is(err, shared.ErrInvalidDeletionToken) {
+		t.Errorf("expected ErrInvalidDeletionToken, got %v", err)
+	}
+
+	if _, err := service.GetStatus("nonexistent", created.DeletionToken); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	// Revoking removes the receipt along with the secret
+	if err := service.RevokeSecret(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to revoke secret: %v", err)
+	}
+	if _, err := service.GetStatus(created.Short, created.DeletionToken); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound after revoking, got %v", err)
+	}
+}
+
+func TestSecretReceiptOutlivesSecret(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("receipt==", 1, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	receiptExpiresAt := func() time.Time {
+		var expiresAt uint64
+		err := db.View(func(txn *badger.Txn) error {
+			item, err := txn.Get(shared.Key(shared.SecretReceiptPrefix, created.Short))
+			if err != nil {
+				return err
+			}
+			expiresAt = item.ExpiresAt()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to read receipt: %v", err)
+		}
+		return time.Unix(int64(expiresAt), 0)
+	}
+
+	// Unread, the receipt is kept for the TTL after the secret expires
+	want := created.ExpiresAt.Add(config.Config.SecretReceiptTTL)
+	if got := receiptExpiresAt(); got.Sub(want).Abs() > time.Minute {
+		t.Errorf("expected receipt to expire around %v, got %v", want, got)
+	}
+
+	if _, err := service.GetSecret(created.Short, testIP); err != nil {
+		t.Fatalf("failed to retrieve secret: %v", err)
+	}
+
+	// Consumed, the TTL starts over from the last read
+	want = time.Now().Add(config.Config.SecretReceiptTTL)
+	if got := receiptExpiresAt(); got.Sub(want).Abs() > time.Minute {
+		t.Errorf("expected receipt to expire around %v, got %v", want, got)
+	}
+}
+
+func TestSecretReceiptStoresNoRawIP(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := secret.NewSecretService(secret.NewSecretRepo(db))
+
+	created, err := service.CreateSecret("receipt==", 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	const ip = "192.0.2.55"
+	if _, err := service.GetSecret(created.Short, ip); err != nil {
+		t.Fatalf("failed to retrieve secret: %v", err)
+	}
+
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(shared.Key(shared.SecretReceiptPrefix, created.Short))
+		if err != nil {
+			return err
+		}
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(val), ip) || strings.Contains(string(val), "192.0.2.0") {
+			t.Errorf("raw IP or network found in receipt: %s", val)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read receipt: %v", err)
+	}
+}
+
+func TestNetworkPrefix(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"203.0.113.7", "203.0.113.0/24"},
+		{"203.0.113.250", "203.0.113.0/24"},
+		{"::ffff:203.0.113.7", "203.0.113.0/24"},
+		{"2001:db8:1:2::7", "2001:db8:1::/48"},
+		{"not an ip", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := shared.NetworkPrefix(tt.ip); got != tt.want {
+			t.Errorf("NetworkPrefix(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -15,7 +15,7 @@ func TestSecretCreation(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	encryptedData := "base64encodedencrypteddata=="
-	created, err := service.CreateSecret(encryptedData)
+	created, err := service.CreateSecret(encryptedData, 1, testExpiry)
 
 	if err != nil {
 		t.Fatalf("failed to create secret: %v", err)
@@ -55,13 +55,13 @@ func TestSecretRetrieval(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	encryptedData := "base64encodedencrypteddata=="
-	created, err := service.CreateSecret(encryptedData)
+	created, err := service.CreateSecret(encryptedData, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create secret: %v", err)
 	}
 
 	// Retrieve the secret
-	retrieved, err := service.GetSecret(created.Short)
+	retrieved, err := service.GetSecret(created.Short, testIP)
 	if err != nil {
 		t.Fatalf("failed to retrieve secret: %v", err)
 	}
@@ -85,13 +85,13 @@ func TestSecretOneTimeView(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	encryptedData := "onetimesecret=="
-	created, err := service.CreateSecret(encryptedData)
+	created, err := service.CreateSecret(encryptedData, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create secret: %v", err)
 	}
 
 	// First retrieval should succeed
-	retrieved, err := service.GetSecret(created.Short)
+	retrieved, err := service.GetSecret(created.Short, testIP)
 	if err != nil {
 		t.Fatalf("failed to retrieve secret on first attempt: %v", err)
 	}
@@ -105,7 +105,7 @@ func TestSecretOneTimeView(t *testing.T) {
 	}
 
 	// Second retrieval should fail (secret deleted after first view)
-	retrieved, err = service.GetSecret(created.Short)
+	retrieved, err = service.GetSecret(created.Short, testIP)
 	if err == nil {
 		t.Fatal("expected error on second retrieval, got nil")
 	}
@@ -125,7 +125,7 @@ func TestSecretNotFound(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	// Try to retrieve non-existent secret
-	retrieved, err := service.GetSecret("nonexistent")
+	retrieved, err := service.GetSecret("nonexistent", testIP)
 
 	if err == nil {
 		t.Fatal("expected error for non-existent secret, got nil")
@@ -146,13 +146,13 @@ func TestSecretExpiry(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	encryptedData := "expiringdata=="
-	created, err := service.CreateSecret(encryptedData)
+	created, err := service.CreateSecret(encryptedData, 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create secret: %v", err)
 	}
 
 	// Verify secret exists immediately
-	retrieved, err := service.GetSecret(created.Short)
+	retrieved, err := service.GetSecret(created.Short, testIP)
 	if err != nil {
 		t.Fatalf("failed to retrieve secret: %v", err)
 	}
@@ -175,7 +175,7 @@ func TestSecretExpiry(t *testing.T) {
 	}
 
 	// Verify it exists
-	retrieved, err = service.GetSecret("testshort")
+	retrieved, err = service.GetSecret("testshort", testIP)
 	if err != nil {
 		t.Fatalf("failed to retrieve short-lived secret: %v", err)
 	}
@@ -230,7 +230,7 @@ func TestMultipleSecretCreation(t *testing.T) {
 
 	secrets := make([]*secret.Secret, len(secretData))
 	for i, data := range secretData {
-		created, err := service.CreateSecret(data)
+		created, err := service.CreateSecret(data, 1, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create secret %d: %v", i, err)
 		}
@@ -239,7 +239,7 @@ func TestMultipleSecretCreation(t *testing.T) {
 
 	// Verify all secrets can be retrieved
 	for i, s := range secrets {
-		retrieved, err := service.GetSecret(s.Short)
+		retrieved, err := service.GetSecret(s.Short, testIP)
 		if err != nil {
 			t.Fatalf("failed to retrieve secret %d: %v", i, err)
 		}
@@ -249,7 +249,7 @@ func TestMultipleSecretCreation(t *testing.T) {
 		}
 
 		// Verify each is deleted after retrieval
-		_, err = service.GetSecret(s.Short)
+		_, err = service.GetSecret(s.Short, testIP)
 		if err == nil {
 			t.Errorf("secret %d: expected error after deletion, got nil", i)
 		}
@@ -264,7 +264,7 @@ func TestSecretShortCodeUniqueness(t *testing.T) {
 	// Create 100 secrets and verify all have unique short codes
 	shortCodes := make(map[string]bool)
 	for i := range 100 {
-		created, err := service.CreateSecret("data" + string(rune(i)))
+		created, err := service.CreateSecret("data"+string(rune(i)), 1, testExpiry)
 		if err != nil {
 			t.Fatalf("failed to create secret %d: %v", i, err)
 		}
@@ -300,12 +300,12 @@ func TestSecretDataValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			created, err := service.CreateSecret(tt.data)
+			created, err := service.CreateSecret(tt.data, 1, testExpiry)
 			if err != nil {
 				t.Fatalf("failed to create secret: %v", err)
 			}
 
-			retrieved, err := service.GetSecret(created.Short)
+			retrieved, err := service.GetSecret(created.Short, testIP)
 			if err != nil {
 				t.Fatalf("failed to retrieve secret: %v", err)
 			}
@@ -323,7 +323,7 @@ func TestSecretDeletionFailure(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	// Create a secret
-	created, err := service.CreateSecret("testdata==")
+	created, err := service.CreateSecret("testdata==", 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create secret: %v", err)
 	}
@@ -335,7 +335,7 @@ func TestSecretDeletionFailure(t *testing.T) {
 	}
 
 	// Try to retrieve - should fail with key not found
-	retrieved, err := service.GetSecret(created.Short)
+	retrieved, err := service.GetSecret(created.Short, testIP)
 	if err == nil {
 		t.Fatal("expected error when retrieving deleted secret, got nil")
 	}
@@ -355,7 +355,7 @@ func TestSecretTimestamps(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	beforeCreate := time.Now()
-	created, err := service.CreateSecret("timestamptest==")
+	created, err := service.CreateSecret("timestamptest==", 1, testExpiry)
 	afterCreate := time.Now()
 
 	if err != nil {
@@ -386,7 +386,7 @@ func TestSecretConcurrentAccess(t *testing.T) {
 	service := secret.NewSecretService(repo)
 
 	// Create a secret
-	created, err := service.CreateSecret("concurrenttest==")
+	created, err := service.CreateSecret("concurrenttest==", 1, testExpiry)
 	if err != nil {
 		t.Fatalf("failed to create secret: %v", err)
 	}
@@ -397,12 +397,12 @@ func TestSecretConcurrentAccess(t *testing.T) {
 	var err1, err2 error
 
 	go func() {
-		retrieved1, err1 = service.GetSecret(created.Short)
+		retrieved1, err1 = service.GetSecret(created.Short, testIP)
 		done <- true
 	}()
 
 	go func() {
-		retrieved2, err2 = service.GetSecret(created.Short)
+		retrieved2, err2 = service.GetSecret(created.Short, testIP)
 		done <- true
 	}()
 
@@ -419,16 +419,12 @@ func TestSecretConcurrentAccess(t *testing.T) {
 		successCount++
 	}
 
-	// Note: Due to lack of locking in the current implementation,
-	// both goroutines can read the secret before either deletes it.
-	// This is a known race condition but acceptable for this test.
-	// In production, this would be extremely rare due to timing.
-	if successCount == 0 {
-		t.Error("both retrievals failed, expected at least one to succeed")
+	if successCount != 1 {
+		t.Errorf("expected exactly one retrieval to succeed, got %d", successCount)
 	}
 
 	// Verify secret is deleted after both attempts
-	_, err = service.GetSecret(created.Short)
+	_, err = service.GetSecret(created.Short, testIP)
 	if err == nil {
 		t.Error("expected secret to be deleted after retrievals")
 	}
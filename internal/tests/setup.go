@@ -1,12 +1,20 @@
 package tests
 
 import (
+	"io"
 	"os"
 	"testing"
+	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
 )
 
+// testExpiry is the expiry used by tests that don't exercise expiry selection
+const testExpiry = 7 * 24 * time.Hour
+
+// testIP is the client address used by tests that don't care where a request came from
+const testIP = "203.0.113.7"
+
 // SetupTestDB creates a temporary Badger database for testing
 func SetupTestDB(t *testing.T) *badger.DB {
 	t.Helper()
@@ -32,3 +40,18 @@ func SetupTestDB(t *testing.T) *badger.DB {
 
 	return db
 }
+
+// ReadAndClose reads a file opened by a service and closes it
+func ReadAndClose(t *testing.T, f *os.File) []byte {
+	t.Helper()
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	return data
+}
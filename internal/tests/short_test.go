@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/link"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func TestCreateDuplicateShortFails(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := link.NewLinkRepo(db)
+
+	first := link.Link{Short: "taken1", URL: "https://first.example.com", CreatedAt: time.Now()}
+	if err := repo.Create(&first); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	second := link.Link{Short: "taken1", URL: "https://second.example.com", CreatedAt: time.Now()}
+	err := repo.Create(&second)
+	if !errors.Is(err, shared.ErrShortTaken) {
+		t.Fatalf("expected ErrShortTaken, got %v", err)
+	}
+
+	// The original link must not have been overwritten
+	retrieved, err := repo.GetByShort("taken1")
+	if err != nil {
+		t.Fatalf("failed to retrieve link: %v", err)
+	}
+	if retrieved.URL != first.URL {
+		t.Errorf("expected URL %s, got %s", first.URL, retrieved.URL)
+	}
+}
+
+func TestShortCodesAreNamespacedPerResource(t *testing.T) {
+	db := SetupTestDB(t)
+	linkRepo := link.NewLinkRepo(db)
+	pasteRepo := paste.NewPasteRepo(db)
+
+	if err := linkRepo.Create(&link.Link{Short: "shared", URL: "https://example.com", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	if err := pasteRepo.Create(&paste.Paste{Short: "shared", Content: "hello", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("expected paste to use its own keyspace, got %v", err)
+	}
+
+	retrievedLink, err := linkRepo.GetByShort("shared")
+	if err != nil || retrievedLink.URL != "https://example.com" {
+		t.Errorf("expected link to be intact, got %v, %v", retrievedLink, err)
+	}
+
+	retrievedPaste, err := pasteRepo.GetByShort("shared")
+	if err != nil || retrievedPaste.Content != "hello" {
+		t.Errorf("expected paste to be intact, got %v, %v", retrievedPaste, err)
+	}
+}
+
+func TestAllocateShortRetriesOnCollision(t *testing.T) {
+	attempts := 0
+	var allocated string
+
+	err := shared.AllocateShort(6, func(short string) error {
+		attempts++
+		if attempts < 3 {
+			return shared.ErrShortTaken
+		}
+		allocated = short
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if len(allocated) != 6 {
+		t.Errorf("expected short code length 6, got %d", len(allocated))
+	}
+}
+
+func TestAllocateShortGrowsWhenCrowded(t *testing.T) {
+	var allocated string
+
+	// Every 6 character code is taken
+	err := shared.AllocateShort(6, func(short string) error {
+		if len(short) == 6 {
+			return shared.ErrShortTaken
+		}
+		allocated = short
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(allocated) != 7 {
+		t.Errorf("expected short code to grow to 7 characters, got %d", len(allocated))
+	}
+}
+
+func TestAllocateShortExhausted(t *testing.T) {
+	err := shared.AllocateShort(config.MaxShortLength, func(string) error {
+		return shared.ErrShortTaken
+	})
+	if !errors.Is(err, shared.ErrShortExhausted) {
+		t.Errorf("expected ErrShortExhausted, got %v", err)
+	}
+}
+
+func TestAllocateShortPassesThroughErrors(t *testing.T) {
+	dbErr := errors.New("database unavailable")
+
+	err := shared.AllocateShort(6, func(string) error {
+		return dbErr
+	})
+	if !errors.Is(err, dbErr) {
+		t.Errorf("expected database error, got %v", err)
+	}
+}
+
+func TestConfiguredShortLength(t *testing.T) {
+	_ = os.Setenv("LINK_SHORT_LENGTH", "8")
+	defer func() { _ = os.Unsetenv("LINK_SHORT_LENGTH") }()
+	config.InitEnv()
+	defer config.InitEnv()
+
+	db := SetupTestDB(t)
+	service := link.NewLinkService(link.NewLinkRepo(db), nil, nil)
+
+	created, err := service.CreateLink("https://example.com", false, false, 0, testExpiry, false)
+	if err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+
+	if len(created.Short) != 8 {
+		t.Errorf("expected short code length 8, got %d", len(created.Short))
+	}
+
+	if config.Config.PasteShortLength != config.DefaultShortLength {
+		t.Errorf("expected paste length to stay at default, got %d", config.Config.PasteShortLength)
+	}
+}
+
+func TestInvalidShortLengthFallsBackToDefault(t *testing.T) {
+	_ = os.Setenv("LINK_SHORT_LENGTH", "2")
+	defer func() { _ = os.Unsetenv("LINK_SHORT_LENGTH") }()
+	config.InitEnv()
+	defer config.InitEnv()
+
+	if config.Config.LinkShortLength != config.DefaultShortLength {
+		t.Errorf("expected default length, got %d", config.Config.LinkShortLength)
+	}
+}
+
+func TestIsValidShort(t *testing.T) {
+	tests := []struct {
+		short  string
+		length int
+		valid  bool
+	}{
+		{"abc123", 6, true},
+		{"aB-_9z", 6, true},
+		{"abc1234", 6, true}, // grown code
+		{"abc12", 6, false},
+		{"abc12!", 6, false},
+		{"abc/12", 6, false},
+		{"abcd", 4, true},
+		{"abc123", 8, true}, // issued before the length was raised
+		{"abcd", 8, false},
+		{"abcdefghijklmnopq", 6, false}, // longer than MaxShortLength
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.short, func(t *testing.T) {
+			if got := shared.IsValidShort(tt.short, tt.length); got != tt.valid {
+				t.Errorf("IsValidShort(%q, %d) = %v, want %v", tt.short, tt.length, got, tt.valid)
+			}
+		})
+	}
+}
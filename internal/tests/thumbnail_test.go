@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/imagemeta"
+)
+
+// gradient returns a width x height image that is red on the left and blue on the right.
+func gradient(width, height int) image.Image {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			m.Set(x, y, color.RGBA{uint8(255 - x*255/width), 0, uint8(x * 255 / width), 255})
+		}
+	}
+	return m
+}
+
+func encodePNG(t *testing.T, m image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decodeVariant(t *testing.T, f *os.File) image.Image {
+	t.Helper()
+
+	decoded, _, err := image.Decode(bytes.NewReader(ReadAndClose(t, f)))
+	if err != nil {
+		t.Fatalf("failed to decode variant: %v", err)
+	}
+	return decoded
+}
+
+func TestImageVariantScalesDown(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	created, err := service.CreateImage(encodePNG(t, gradient(800, 400)), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	variant, f, err := service.GetVariant(created.Short, "thumb")
+	if err != nil {
+		t.Fatalf("failed to get thumbnail: %v", err)
+	}
+	thumb := decodeVariant(t, f)
+
+	if thumb.Bounds().Dx() != 320 || thumb.Bounds().Dy() != 160 {
+		t.Errorf("expected a 320x160 thumbnail, got %v", thumb.Bounds())
+	}
+	if variant.ContentType != "image/png" || filepath.Dir(variant.FilePath) != service.UploadDir() {
+		t.Errorf("expected png cached next to the original, got %s at %s", variant.ContentType, variant.FilePath)
+	}
+	if r, _, b, _ := thumb.At(0, 80).RGBA(); r < b {
+		t.Error("expected the left edge to stay red")
+	}
+
+	// The cached variant is served from then on
+	cached, f, err := service.GetVariant(created.Short, "thumb")
+	if err != nil {
+		t.Fatalf("failed to get cached thumbnail: %v", err)
+	}
+	_ = f.Close()
+	if cached.FilePath != variant.FilePath {
+		t.Errorf("expected cached thumbnail %s, got %s", variant.FilePath, cached.FilePath)
+	}
+}
+
+func TestImageVariantConcurrentFirstRequests(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	created, err := service.CreateImage(encodePNG(t, gradient(800, 400)), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	for i := range paths {
+		wg.Go(func() {
+			variant, f, err := service.GetVariant(created.Short, "thumb")
+			if err != nil {
+				t.Errorf("failed to get thumbnail: %v", err)
+				return
+			}
+			_ = f.Close()
+			paths[i] = variant.FilePath
+		})
+	}
+	wg.Wait()
+
+	for _, p := range paths {
+		if p != paths[0] {
+			t.Errorf("expected every request to get %s, got %s", paths[0], p)
+		}
+	}
+
+	// The requests share one variant, no temporary files of duplicate creations are left behind
+	entries, _ := os.ReadDir(service.UploadDir())
+	if len(entries) != 2 {
+		t.Errorf("expected the original and one variant, got %d files", len(entries))
+	}
+}
+
+func TestImageVariantAppliesOrientation(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, gradient(800, 400), nil); err != nil {
+		t.Fatalf("failed to encode jpeg: %v", err)
+	}
+	// Tag the photo as rotated 90° clockwise, the way phones store portrait shots
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")
+	photo := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, byte(len(exif) + 2)}, exif...)
+	photo = append(photo, buf.Bytes()[2:]...)
+	if imagemeta.Orientation(bytes.NewReader(photo)) != 6 {
+		t.Fatal("expected the test photo to carry orientation 6")
+	}
+
+	created, err := service.CreateImage(photo, "image/jpeg", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	variant, f, err := service.GetVariant(created.Short, "thumb")
+	if err != nil {
+		t.Fatalf("failed to get thumbnail: %v", err)
+	}
+	thumb := decodeVariant(t, f)
+
+	if variant.ContentType != "image/jpeg" {
+		t.Errorf("expected jpeg thumbnail, got %s", variant.ContentType)
+	}
+	if thumb.Bounds().Dx() != 160 || thumb.Bounds().Dy() != 320 {
+		t.Fatalf("expected an upright 160x320 thumbnail, got %v", thumb.Bounds())
+	}
+	// Rotated clockwise, the red left edge ends up on top
+	if r, _, b, _ := thumb.At(80, 2).RGBA(); r < b {
+		t.Error("expected the top edge to be red")
+	}
+}
+
+func TestImageVariantServesSmallImagesWhole(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	content := encodePNG(t, gradient(100, 50))
+	created, err := service.CreateImage(content, "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	variant, f, err := service.GetVariant(created.Short, "thumb")
+	if err != nil {
+		t.Fatalf("failed to get thumbnail: %v", err)
+	}
+	if data := ReadAndClose(t, f); !bytes.Equal(data, content) || variant.FilePath != created.FilePath {
+		t.Error("expected the original to be served")
+	}
+}
+
+func TestImageVariantUnavailable(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	content := encodePNG(t, gradient(800, 400))
+	plain, err := service.CreateImage(content, "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	limited, err := service.CreateImage(content, "image/png", false, false, 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	encrypted, err := service.CreateImage(content, "application/octet-stream", true, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	if _, _, err := service.GetVariant(plain.Short, "huge"); !errors.Is(err, img.ErrUnknownSize) {
+		t.Errorf("expected ErrUnknownSize, got %v", err)
+	}
+	for _, stored := range []*img.Image{limited, encrypted} {
+		if _, _, err := service.GetVariant(stored.Short, "thumb"); !errors.Is(err, img.ErrNoVariants) {
+			t.Errorf("expected ErrNoVariants, got %v", err)
+		}
+	}
+
+	// Asking for a thumbnail does not use up a view
+	limitedImage, err := service.CheckImageExists(limited.Short)
+	if err != nil || limitedImage.ViewsLeft != 2 {
+		t.Errorf("expected 2 views left, got %+v (%v)", limitedImage, err)
+	}
+}
+
+func TestImageVariantRoutes(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := img.NewImageHandler(service, nil)
+
+	created, err := service.CreateImage(encodePNG(t, gradient(800, 400)), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	tests := []struct {
+		target string
+		size   string // Path value, empty for the query parameter
+	}{
+		{"/i/" + created.Short + "/thumb?cli=true", "thumb"},
+		{"/i/" + created.Short + "?size=thumb&cli=true", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.SetPathValue("short", created.Short)
+		req.SetPathValue("size", tt.size)
+		rec := httptest.NewRecorder()
+		if err := handler.GetImageByShort(rec, req); err != nil {
+			t.Fatalf("%s: failed to get image: %v", tt.target, err)
+		}
+
+		thumb, _, err := image.Decode(rec.Body)
+		if err != nil || thumb.Bounds().Dx() != 320 {
+			t.Errorf("%s: expected a 320 pixel wide thumbnail, got %v", tt.target, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/i/"+created.Short+"/huge?cli=true", nil)
+	req.SetPathValue("short", created.Short)
+	req.SetPathValue("size", "huge")
+	if err := handler.GetImageByShort(httptest.NewRecorder(), req); errorStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown size, got %v", err)
+	}
+}
+
+func TestDeleteImageRemovesVariants(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+
+	created, err := service.CreateImage(encodePNG(t, gradient(800, 400)), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	for _, size := range []string{"thumb", "preview"} {
+		_, f, err := service.GetVariant(created.Short, size)
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", size, err)
+		}
+		_ = f.Close()
+	}
+
+	if err := service.RevokeImage(created.Short, created.DeletionToken); err != nil {
+		t.Fatalf("failed to delete image: %v", err)
+	}
+
+	entries, _ := os.ReadDir(service.UploadDir())
+	if len(entries) != 0 {
+		t.Errorf("expected the image and its variants to be removed, %d files left", len(entries))
+	}
+}
@@ -0,0 +1,234 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/piheta/apicore/apierr"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+// uploadRequest builds a multipart upload with the file part first and the fields after it, the way browsers
+// and the CLI order them when the fields are appended last.
+func uploadRequest(t *testing.T, content []byte, fields map[string]string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatalf("failed to create part: %v", err)
+	}
+	_, _ = part.Write(content)
+	for key, value := range fields {
+		_ = writer.WriteField(key, value)
+	}
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/images", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// errorStatus returns the HTTP status an API error is reported with, 0 for other errors.
+func errorStatus(err error) int {
+	var apiErr *apierr.APIError
+	if !errors.As(err, &apiErr) {
+		return 0
+	}
+
+	var body struct {
+		Status int `json:"status"`
+	}
+	encoded, _ := json.Marshal(apiErr)
+	_ = json.Unmarshal(encoded, &body)
+	return body.Status
+}
+
+func TestReceiveUploadStreamsToDisk(t *testing.T) {
+	dir := t.TempDir()
+	content := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 4096)...)
+
+	req := uploadRequest(t, content, map[string]string{"encrypted": "false", "expires_in": "1h"})
+	upload, err := shared.ReceiveUpload(httptest.NewRecorder(), req, dir, 1<<20)
+	if err != nil {
+		t.Fatalf("failed to receive upload: %v", err)
+	}
+	defer upload.Remove()
+
+	if filepath.Dir(upload.Path) != dir || filepath.Ext(upload.Path) != shared.TempUploadExt {
+		t.Errorf("expected temporary upload in %s, got %s", dir, upload.Path)
+	}
+	if upload.Size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d", len(content), upload.Size)
+	}
+	if upload.Sniffed != "image/png" {
+		t.Errorf("expected sniffed type image/png, got %s", upload.Sniffed)
+	}
+	if upload.FileName != "upload.bin" {
+		t.Errorf("expected part name upload.bin, got %s", upload.FileName)
+	}
+
+	// Fields sent after the file are still picked up
+	if upload.Value("expires_in") != "1h" || upload.Value("encrypted") != "false" {
+		t.Errorf("expected fields after the file, got %v", upload.Fields)
+	}
+
+	stored, err := os.ReadFile(upload.Path)
+	if err != nil || !bytes.Equal(stored, content) {
+		t.Errorf("expected upload content on disk, got %d bytes (%v)", len(stored), err)
+	}
+}
+
+func TestReceiveUploadRejectsLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	req := uploadRequest(t, bytes.Repeat([]byte("x"), 2048), nil)
+	_, err := shared.ReceiveUpload(httptest.NewRecorder(), req, dir, 1024)
+
+	if status := errorStatus(err); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %v", err)
+	}
+
+	// The partial upload is not left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("expected no leftover files, got %d", len(entries))
+	}
+}
+
+func TestUploadInProgress(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"fresh" + shared.TempUploadExt, "stale" + shared.TempUploadExt, "abcdef.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	stale := time.Now().Add(-shared.StaleUploadAge - time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "stale"+shared.TempUploadExt), stale, stale); err != nil {
+		t.Fatalf("failed to age upload: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	for _, entry := range entries {
+		want := entry.Name() == "fresh"+shared.TempUploadExt
+		if got := shared.UploadInProgress(entry); got != want {
+			t.Errorf("UploadInProgress(%s) = %v, want %v", entry.Name(), got, want)
+		}
+	}
+}
+
+func TestImageDownloadSupportsRangeAndConditionalRequests(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := img.NewImageHandler(service, nil)
+
+	content := []byte("0123456789abcdefghij")
+	created, err := service.CreateImage(content, "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/i/"+created.Short, nil)
+		req.SetPathValue("short", created.Short)
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		if err := handler.GetImageByShort(rec, req); err != nil {
+			t.Fatalf("failed to get image: %v", err)
+		}
+		return rec
+	}
+
+	rec := get("Range", "bytes=5-9")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "56789" {
+		t.Errorf("expected 206 with bytes 5-9, got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("expected content type image/png, got %s", got)
+	}
+
+	rec = get("If-Modified-Since", created.CreatedAt.Add(time.Minute).UTC().Format(http.TimeFormat))
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304, got %d", rec.Code)
+	}
+}
+
+func TestEncryptedImageStreamsJSON(t *testing.T) {
+	config.InitEnv()
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := img.NewImageHandler(service, nil)
+
+	content := []byte(strings.Repeat("encrypted", 1000))
+	created, err := service.CreateImage(content, "application/octet-stream", true, true, 2, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/images/"+created.Short+"/reveal", nil)
+	req.SetPathValue("short", created.Short)
+	req.Header.Set(shared.RevealTokenHeader, shared.NewRevealToken("images", created.Short))
+	req.Header.Set("Origin", "http://example.com")
+	rec := httptest.NewRecorder()
+	if err := handler.RevealImage(rec, req); err != nil {
+		t.Fatalf("failed to get image: %v", err)
+	}
+
+	var resp img.ImageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse streamed JSON: %v", err)
+	}
+	if resp.Data != base64.StdEncoding.EncodeToString(content) {
+		t.Error("expected data to be the base64 encoded image")
+	}
+	if !resp.PasswordProtected || resp.ViewsLeft == nil || *resp.ViewsLeft != 1 {
+		t.Errorf("expected password protected image with 1 view left, got %+v", resp)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"512KB", 512 << 10, false},
+		{"100MB", 100 << 20, false},
+		{"2gb", 2 << 30, false},
+		{" 10 MB ", 10 << 20, false},
+		{"0", 0, true},
+		{"-1MB", 0, true},
+		{"MB", 0, true},
+		{"1TB", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := config.ParseSize(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/features/oembed"
+	"github.com/piheta/seq.re/internal/features/paste"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+const slackbotUA = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+
+// newTemplateService parses the templates, which are looked up relative to the repository root
+func newTemplateService(t *testing.T) *shared.TemplateService {
+	t.Helper()
+
+	t.Chdir("../..")
+	return shared.NewTemplateService("test", "")
+}
+
+func getOEmbed(t *testing.T, handler *oembed.OEmbedHandler, target string) (*oembed.OEmbedResponse, error) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/oembed?url="+url.QueryEscape(target), nil)
+	rec := httptest.NewRecorder()
+	if err := handler.GetOEmbed(rec, req); err != nil {
+		return nil, err
+	}
+
+	var resp oembed.OEmbedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	return &resp, nil
+}
+
+func TestPasteUnfurlDoesNotConsumeViews(t *testing.T) {
+	config.InitEnv()
+	config.Config.RedirectHost = "https://seq.re"
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, newTemplateService(t))
+
+	created, err := service.CreatePaste("launch codes", "", "", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/p/"+created.Short, nil)
+		req.SetPathValue("short", created.Short)
+		req.Header.Set("User-Agent", slackbotUA)
+		rec := httptest.NewRecorder()
+		if err := handler.GetPasteByShort(rec, req); err != nil {
+			t.Fatalf("failed to get paste: %v", err)
+		}
+
+		body := rec.Body.String()
+		if strings.Contains(body, "launch codes") {
+			t.Fatal("expected the one-time paste not to be revealed")
+		}
+		if !strings.Contains(body, `<meta property="og:title" content="View limited content">`) {
+			t.Error("expected the generic card")
+		}
+	}
+
+	stored, err := service.CheckPasteExists(created.Short)
+	if err != nil || stored.ViewsLeft != 1 {
+		t.Errorf("expected the view to be left, got %+v (%v)", stored, err)
+	}
+}
+
+func TestPasteCardShowsFirstLines(t *testing.T) {
+	config.InitEnv()
+	config.Config.RedirectHost = "https://seq.re"
+	db := SetupTestDB(t)
+	service := paste.NewPasteService(paste.NewPasteRepo(db))
+	handler := paste.NewPasteHandler(service, newTemplateService(t))
+
+	content := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n// line 6\n// line 7\n"
+	created, err := service.CreatePaste(content, "go", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+
+	card, err := service.Card(created.Short)
+	if err != nil {
+		t.Fatalf("failed to get card: %v", err)
+	}
+	if card.Title != "Paste (go)" || card.Description != "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}…" {
+		t.Errorf("unexpected card %+v", card)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/p/"+created.Short, nil)
+	req.SetPathValue("short", created.Short)
+	rec := httptest.NewRecorder()
+	if err := handler.GetPasteByShort(rec, req); err != nil {
+		t.Fatalf("failed to get paste: %v", err)
+	}
+	for _, tag := range []string{
+		`<meta property="og:title" content="Paste (go)">`,
+		`<meta property="og:url" content="https://seq.re/p/` + created.Short + `">`,
+		`<link rel="alternate" type="application/json+oembed" href="https://seq.re/oembed?format=json&amp;url=https%3A%2F%2Fseq.re%2Fp%2F` + created.Short + `"`,
+	} {
+		if !strings.Contains(rec.Body.String(), tag) {
+			t.Errorf("expected %s in the page", tag)
+		}
+	}
+}
+
+func TestImageLandingPage(t *testing.T) {
+	config.InitEnv()
+	config.Config.RedirectHost = "https://seq.re"
+	db := SetupTestDB(t)
+	service := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := img.NewImageHandler(service, newTemplateService(t))
+
+	created, err := service.CreateImage(encodePNG(t, gradient(1600, 800)), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	get := func(accept string, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/i/"+created.Short+query, nil)
+		req.SetPathValue("short", created.Short)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		if err := handler.GetImageByShort(rec, req); err != nil {
+			t.Fatalf("failed to get image: %v", err)
+		}
+		return rec
+	}
+
+	page := get("text/html,application/xhtml+xml", "")
+	if !strings.Contains(page.Body.String(), `<meta property="og:image" content="https://seq.re/i/`+created.Short+`/preview">`) {
+		t.Error("expected the preview variant as card image")
+	}
+	if !strings.Contains(page.Body.String(), `<meta property="og:image:width" content="1280">`) {
+		t.Error("expected the preview dimensions")
+	}
+
+	// Image tags and raw links get the image itself
+	for _, rec := range []*httptest.ResponseRecorder{get("image/*", ""), get("text/html", "?raw=true")} {
+		if rec.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("expected the image, got %s", rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestOEmbed(t *testing.T) {
+	config.InitEnv()
+	config.Config.RedirectHost = "https://seq.re"
+	db := SetupTestDB(t)
+	pasteService := paste.NewPasteService(paste.NewPasteRepo(db))
+	imageService := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	handler := oembed.NewOEmbedHandler(map[string]oembed.CardFunc{
+		"p": pasteService.Card,
+		"i": imageService.Card,
+	})
+
+	plain, err := pasteService.CreatePaste("hello", "", "", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	encrypted, err := pasteService.CreatePaste("ciphertext==", "", "", true, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create paste: %v", err)
+	}
+	photo, err := imageService.CreateImage(encodePNG(t, gradient(400, 200)), "image/png", false, false, 0, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	limited, err := imageService.CreateImage(encodePNG(t, gradient(400, 200)), "image/png", false, false, 1, testExpiry)
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want oembed.OEmbedResponse
+	}{
+		{"https://seq.re/p/" + plain.Short, oembed.OEmbedResponse{Type: "link", Title: "Paste"}},
+		{"https://seq.re/p/" + encrypted.Short, oembed.OEmbedResponse{Type: "link", Title: "Encrypted content"}},
+		{"https://seq.re/i/" + photo.Short, oembed.OEmbedResponse{Type: "photo", Title: "Image", URL: "https://seq.re/i/" + photo.Short + "/preview", Width: 400, Height: 200}},
+		{"https://seq.re/i/" + limited.Short, oembed.OEmbedResponse{Type: "link", Title: "View limited content"}},
+	}
+	for _, tt := range tests {
+		resp, err := getOEmbed(t, handler, tt.url)
+		if err != nil {
+			t.Fatalf("%s: failed to get oEmbed: %v", tt.url, err)
+		}
+		if resp.Version != "1.0" || resp.ProviderName != "seq.re" {
+			t.Errorf("%s: unexpected provider %+v", tt.url, resp)
+		}
+		resp.Version, resp.ProviderName, resp.ProviderURL = "", "", ""
+		if *resp != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.url, tt.want, *resp)
+		}
+	}
+
+	if stored, err := imageService.CheckImageExists(limited.Short); err != nil || stored.ViewsLeft != 1 {
+		t.Errorf("expected the view to be left, got %+v (%v)", stored, err)
+	}
+
+	for _, target := range []string{"https://example.com/p/" + plain.Short, "https://seq.re/p/missing", "https://seq.re/x/" + plain.Short} {
+		if _, err := getOEmbed(t, handler, target); errorStatus(err) != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %v", target, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/oembed?format=xml&url="+url.QueryEscape("https://seq.re/p/"+plain.Short), nil)
+	if err := handler.GetOEmbed(httptest.NewRecorder(), req); errorStatus(err) != http.StatusNotImplemented {
+		t.Errorf("expected 501 for xml, got %v", err)
+	}
+}
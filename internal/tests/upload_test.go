@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/piheta/seq.re/config"
+	"github.com/piheta/seq.re/internal/features/img"
+	"github.com/piheta/seq.re/internal/features/upload"
+	"github.com/piheta/seq.re/internal/shared"
+)
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChunkedUploadInAnyOrder(t *testing.T) {
+	db := SetupTestDB(t)
+	dir := t.TempDir()
+	service := upload.NewUploadService(upload.NewUploadRepo(db))
+
+	content := []byte("first-second-third")
+	session, err := service.CreateSession(testIP, upload.KindFile, dir, int64(len(content)), 6, "parts.txt", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if session.Chunks() != 3 {
+		t.Fatalf("expected 3 chunks, got %d", session.Chunks())
+	}
+
+	for _, index := range []int{2, 0, 1} {
+		chunk := content[index*6 : min((index+1)*6, len(content))]
+		if _, err := service.WriteChunk(session.ID, index, bytes.NewReader(chunk), checksum(chunk)); err != nil {
+			t.Fatalf("failed to write chunk %d: %v", index, err)
+		}
+	}
+
+	finalized, err := service.FinalizeSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to finalize upload: %v", err)
+	}
+
+	assembled, err := os.ReadFile(finalized.Path)
+	if err != nil || !bytes.Equal(assembled, content) {
+		t.Errorf("expected assembled content %q, got %q (%v)", content, assembled, err)
+	}
+
+	// Finalizing consumes the session
+	if _, err := service.FinalizeSession(session.ID); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound on second finalize, got %v", err)
+	}
+}
+
+func TestChunkedUploadRejectsBadChunks(t *testing.T) {
+	db := SetupTestDB(t)
+	service := upload.NewUploadService(upload.NewUploadRepo(db))
+
+	content := []byte("0123456789")
+	session, err := service.CreateSession(testIP, upload.KindImage, t.TempDir(), int64(len(content)), 5, "", "", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	chunk := content[:5]
+	if _, err := service.WriteChunk(session.ID, 0, bytes.NewReader(chunk), checksum([]byte("other"))); !errors.Is(err, upload.ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if _, err := service.WriteChunk(session.ID, 0, bytes.NewReader(content[:4]), checksum(content[:4])); !errors.Is(err, upload.ErrChunkSize) {
+		t.Errorf("expected ErrChunkSize for a short chunk, got %v", err)
+	}
+	if _, err := service.WriteChunk(session.ID, 1, bytes.NewReader(content), checksum(content)); !errors.Is(err, upload.ErrChunkSize) {
+		t.Errorf("expected ErrChunkSize for a long chunk, got %v", err)
+	}
+	if _, err := service.WriteChunk(session.ID, 2, bytes.NewReader(chunk), checksum(chunk)); !errors.Is(err, upload.ErrChunkIndex) {
+		t.Errorf("expected ErrChunkIndex, got %v", err)
+	}
+
+	// Nothing counts as received, so the upload can't be finalized yet
+	if _, err := service.FinalizeSession(session.ID); !errors.Is(err, upload.ErrIncomplete) {
+		t.Errorf("expected ErrIncomplete, got %v", err)
+	}
+
+	// Resending the chunks resumes the upload
+	for index := range 2 {
+		chunk := content[index*5 : (index+1)*5]
+		if _, err := service.WriteChunk(session.ID, index, bytes.NewReader(chunk), checksum(chunk)); err != nil {
+			t.Fatalf("failed to write chunk %d: %v", index, err)
+		}
+	}
+	if _, err := service.FinalizeSession(session.ID); err != nil {
+		t.Errorf("expected complete upload to finalize, got %v", err)
+	}
+}
+
+func TestChunkedUploadKeepsChunkOnBadResend(t *testing.T) {
+	db := SetupTestDB(t)
+	dir := t.TempDir()
+	service := upload.NewUploadService(upload.NewUploadRepo(db))
+
+	content := []byte("0123456789")
+	session, err := service.CreateSession(testIP, upload.KindFile, dir, int64(len(content)), 5, "", "", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	for index := range 2 {
+		chunk := content[index*5 : (index+1)*5]
+		if _, err := service.WriteChunk(session.ID, index, bytes.NewReader(chunk), checksum(chunk)); err != nil {
+			t.Fatalf("failed to write chunk %d: %v", index, err)
+		}
+	}
+
+	// A corrupted retry of a received chunk is rejected without touching the stored one
+	if _, err := service.WriteChunk(session.ID, 0, bytes.NewReader([]byte("XXXXX")), checksum(content[:5])); !errors.Is(err, upload.ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	finalized, err := service.FinalizeSession(session.ID)
+	if err != nil {
+		t.Fatalf("failed to finalize upload: %v", err)
+	}
+
+	assembled, err := os.ReadFile(finalized.Path)
+	if err != nil || !bytes.Equal(assembled, content) {
+		t.Errorf("expected assembled content %q, got %q (%v)", content, assembled, err)
+	}
+
+	// The staged chunk is cleaned up, only the upload itself is left
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected a single file in the upload directory, got %d", len(entries))
+	}
+}
+
+func TestUploadSessionsLimitedPerIP(t *testing.T) {
+	config.Config.MaxUploadSessions = 2
+	t.Cleanup(func() { config.Config.MaxUploadSessions = 0 })
+
+	db := SetupTestDB(t)
+	dir := t.TempDir()
+	service := upload.NewUploadService(upload.NewUploadRepo(db))
+
+	first, err := service.CreateSession(testIP, upload.KindFile, dir, 10, 5, "", "", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if _, err := service.CreateSession(testIP, upload.KindFile, dir, 10, 5, "", "", nil); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	// The limit is reached before the file of another upload is allocated
+	if _, err := service.CreateSession(testIP, upload.KindFile, dir, 10, 5, "", "", nil); !errors.Is(err, upload.ErrTooManyUploads) {
+		t.Errorf("expected ErrTooManyUploads, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected only the two open uploads on disk, got %d files", len(entries))
+	}
+
+	// Other clients have their own limit
+	if _, err := service.CreateSession("198.51.100.4", upload.KindFile, dir, 10, 5, "", "", nil); err != nil {
+		t.Errorf("expected another IP to start an upload, got %v", err)
+	}
+
+	// Finishing an upload frees its slot
+	if err := service.AbortSession(first.ID); err != nil {
+		t.Fatalf("failed to abort upload: %v", err)
+	}
+	if _, err := service.CreateSession(testIP, upload.KindFile, dir, 10, 5, "", "", nil); err != nil {
+		t.Errorf("expected a new upload after aborting one, got %v", err)
+	}
+}
+
+func TestAbortUploadRemovesFile(t *testing.T) {
+	db := SetupTestDB(t)
+	service := upload.NewUploadService(upload.NewUploadRepo(db))
+
+	session, err := service.CreateSession(testIP, upload.KindFile, t.TempDir(), 10, 5, "", "", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if err := service.AbortSession(session.ID); err != nil {
+		t.Fatalf("failed to abort upload: %v", err)
+	}
+	if _, err := os.Stat(session.Path); !os.IsNotExist(err) {
+		t.Errorf("expected upload file to be removed, got %v", err)
+	}
+	if _, err := service.GetSession(session.ID); !errors.Is(err, badger.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestChunkedImageUpload(t *testing.T) {
+	config.InitEnv()
+	shared.InitValidator()
+	db := SetupTestDB(t)
+	imageService := img.NewImageService(img.NewImageRepo(db), t.TempDir())
+	imageHandler := img.NewImageHandler(imageService, nil)
+	handler := upload.NewUploadHandler(upload.NewUploadService(upload.NewUploadRepo(db)), map[string]upload.Target{
+		upload.KindImage: {Dir: imageService.UploadDir(), Store: imageHandler.StoreUpload},
+	})
+
+	content := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte("png"), upload.MinChunkSize)...)
+
+	// The content only looks like a PNG, keeping its metadata stores it without parsing
+	body, _ := json.Marshal(upload.UploadRequest{Kind: upload.KindImage, Size: int64(len(content)), ChunkSize: upload.MinChunkSize, MaxViews: 2, KeepMetadata: true})
+	rec := httptest.NewRecorder()
+	if err := handler.CreateUpload(rec, httptest.NewRequest(http.MethodPost, "/api/uploads", bytes.NewReader(body))); err != nil {
+		t.Fatalf("failed to create upload: %v", err)
+	}
+
+	var session upload.SessionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+		t.Fatalf("failed to parse session: %v", err)
+	}
+	if session.Chunks != 4 || len(session.Received) != 0 {
+		t.Fatalf("expected 4 pending chunks, got %+v", session)
+	}
+
+	for index := range session.Chunks {
+		chunk := content[index*upload.MinChunkSize : min((index+1)*upload.MinChunkSize, len(content))]
+		req := httptest.NewRequest(http.MethodPut, "/api/uploads/"+session.ID+"/chunks/"+strconv.Itoa(index), bytes.NewReader(chunk))
+		req.SetPathValue("id", session.ID)
+		req.SetPathValue("index", strconv.Itoa(index))
+		req.Header.Set(upload.ChecksumHeader, checksum(chunk))
+		if err := handler.UploadChunk(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("failed to upload chunk %d: %v", index, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads/"+session.ID+"/finalize", nil)
+	req.SetPathValue("id", session.ID)
+	rec = httptest.NewRecorder()
+	if err := handler.FinalizeUpload(rec, req); err != nil {
+		t.Fatalf("failed to finalize upload: %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var created shared.CreatedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+
+	image, imageFile, err := imageService.GetImage(path.Base(created.URL))
+	if err != nil {
+		t.Fatalf("failed to retrieve image: %v", err)
+	}
+	if data := ReadAndClose(t, imageFile); !bytes.Equal(data, content) {
+		t.Errorf("expected stored image to match the upload")
+	}
+	if image.ContentType != "image/png" || image.MaxViews != 2 {
+		t.Errorf("expected view limited png, got %s with %d views", image.ContentType, image.MaxViews)
+	}
+
+	// Only the stored image is left in the upload directory
+	entries, _ := os.ReadDir(imageService.UploadDir())
+	if len(entries) != 1 {
+		t.Errorf("expected a single file in the upload directory, got %d", len(entries))
+	}
+}
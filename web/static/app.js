@@ -25,6 +25,28 @@ function copyFromInput(inputId, buttonId) {
     copyToClipboard(input.value, buttonId);
 }
 
+// Show or hide the QR code of a result URL. It is rendered here from the input value, which holds the key fragment
+// of encrypted results that the server never sees.
+function toggleQRCode(inputId, containerId) {
+    const input = document.getElementById(inputId);
+    const container = document.getElementById(containerId);
+    if (!input || !container) return;
+
+    if (!container.classList.contains('hidden')) {
+        container.classList.add('hidden');
+        return;
+    }
+
+    container.innerHTML = qrSVG(input.value);
+    const svg = container.querySelector('svg');
+    svg.setAttribute('width', '192');
+    svg.setAttribute('height', '192');
+    svg.setAttribute('role', 'img');
+    svg.setAttribute('aria-label', 'QR code of the link');
+    svg.classList.add('mx-auto', 'rounded-md');
+    container.classList.remove('hidden');
+}
+
 // Copy decrypted text from a global variable (for viewers)
 function copyDecryptedText(varName, buttonId) {
     const text = window[varName];
@@ -45,6 +67,61 @@ function copyDecryptedText(varName, buttonId) {
     });
 }
 
+// Render the files of a paste bundle one below the other, each under its name with a copy button. rawURL maps a
+// file name to its raw URL, encrypted bundles have none as the server never sees their file names.
+function renderBundle(container, files, rawURL) {
+    const linkClass = 'hover:text-dr-text-heading dark:hover:text-dr-text-heading-dark';
+    container.replaceChildren();
+
+    files.forEach((file, i) => {
+        const header = document.createElement('div');
+        header.className = 'flex flex-wrap items-center gap-4 text-sm text-dr-text-gray dark:text-dr-text-gray-light';
+
+        const name = document.createElement('span');
+        name.className = 'font-mono text-dr-text-heading dark:text-dr-text-heading-dark';
+        name.textContent = file.name;
+        header.append(name);
+
+        if (file.language) {
+            const language = document.createElement('span');
+            language.textContent = file.language;
+            header.append(language);
+        }
+
+        if (rawURL) {
+            const raw = document.createElement('a');
+            raw.href = rawURL(file.name);
+            raw.className = linkClass;
+            raw.textContent = 'Raw';
+            header.append(raw);
+        }
+
+        const copy = document.createElement('button');
+        copy.id = 'copy-file-' + i;
+        copy.className = linkClass;
+        copy.textContent = 'Copy';
+        copy.onclick = () => copyToClipboard(file.content, copy.id);
+        header.append(copy);
+
+        const code = document.createElement('code');
+        code.className = (file.language ? 'language-' + file.language + ' ' : '') + '!bg-gray-900';
+        code.textContent = file.content;
+        const pre = document.createElement('pre');
+        pre.className = '!bg-gray-900 !m-0';
+        pre.append(code);
+        const block = document.createElement('div');
+        block.className = 'bg-gray-900 rounded-lg overflow-hidden';
+        block.append(pre);
+
+        const section = document.createElement('div');
+        section.className = 'space-y-2';
+        section.append(header, block);
+        container.append(section);
+
+        hljs.highlightElement(code);
+    });
+}
+
 // Common form submission helper for encrypted forms
 async function submitEncryptedForm(options) {
     const {
@@ -162,3 +239,20 @@ if (document.readyState === 'loading') {
 } else {
     initDarkMode();
 }
+
+// Show the password field of a form while the password mode is selected
+function togglePasswordInput(inputId, mode) {
+    const input = document.getElementById(inputId);
+    if (!input) return;
+
+    input.classList.toggle('hidden', mode !== 'password');
+    input.required = mode === 'password';
+}
+
+// Views allowed by a form's view limit option, 0 when the checkbox is cleared
+function readMaxViews(checkboxId, inputId) {
+    const checkbox = document.getElementById(checkboxId);
+    if (checkbox && !checkbox.checked) return 0;
+
+    return parseInt(document.getElementById(inputId).value, 10) || 1;
+}
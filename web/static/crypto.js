@@ -102,3 +102,97 @@ async function decryptFile(encryptedData, key) {
         ciphertext
     );
 }
+
+// Password mode: the key is derived from a passphrase with PBKDF2-SHA256.
+// Salt and iteration count travel with the ciphertext:
+// salt (16 bytes) | iterations (uint32, big endian) | IV (12 bytes) | ciphertext
+const PASSWORD_SALT_SIZE = 16;
+const PASSWORD_ITERATIONS = 600000;
+const PASSWORD_HEADER_SIZE = PASSWORD_SALT_SIZE + 4;
+const PASSWORD_MAX_ITERATIONS = 10000000;
+
+// Derive an AES-128 key from a password
+async function deriveKey(password, salt, iterations) {
+    const material = await crypto.subtle.importKey(
+        "raw",
+        new TextEncoder().encode(password),
+        "PBKDF2",
+        false,
+        ["deriveKey"]
+    );
+
+    return await crypto.subtle.deriveKey(
+        { name: "PBKDF2", salt: salt, iterations: iterations, hash: "SHA-256" },
+        material,
+        { name: "AES-GCM", length: 128 },
+        false,
+        ["encrypt", "decrypt"]
+    );
+}
+
+// Encrypt file data with a password, returns the KDF header followed by IV and ciphertext
+async function encryptFileWithPassword(fileData, password) {
+    const salt = crypto.getRandomValues(new Uint8Array(PASSWORD_SALT_SIZE));
+    const key = await deriveKey(password, salt, PASSWORD_ITERATIONS);
+    const encrypted = await encryptFile(fileData, key);
+
+    const combined = new Uint8Array(PASSWORD_HEADER_SIZE + encrypted.length);
+    combined.set(salt, 0);
+    new DataView(combined.buffer).setUint32(PASSWORD_SALT_SIZE, PASSWORD_ITERATIONS);
+    combined.set(encrypted, PASSWORD_HEADER_SIZE);
+
+    return combined;
+}
+
+// Decrypt file data that was encrypted with a password
+async function decryptFileWithPassword(encryptedData, password) {
+    const salt = encryptedData.slice(0, PASSWORD_SALT_SIZE);
+    const iterations = new DataView(encryptedData.buffer, encryptedData.byteOffset).getUint32(PASSWORD_SALT_SIZE);
+    if (iterations < 1 || iterations > PASSWORD_MAX_ITERATIONS) {
+        throw new Error('Unsupported iteration count');
+    }
+    const key = await deriveKey(password, salt, iterations);
+
+    return await decryptFile(encryptedData.slice(PASSWORD_HEADER_SIZE), key);
+}
+
+// Encrypt text with a password, returns base64
+async function encryptWithPassword(data, password) {
+    const combined = await encryptFileWithPassword(new TextEncoder().encode(data), password);
+    return btoa(String.fromCharCode(...combined));
+}
+
+// Decrypt base64 text that was encrypted with a password
+async function decryptWithPassword(base64Data, password) {
+    const combined = Uint8Array.from(atob(base64Data), c => c.charCodeAt(0));
+    const decrypted = await decryptFileWithPassword(combined, password);
+    return new TextDecoder().decode(decrypted);
+}
+
+// Show a password form in place of the content and call onPassword with each
+// submitted password until it resolves without throwing
+function promptForPassword(container, onPassword) {
+    container.innerHTML = `
+        <form class="space-y-3 text-left">
+            <label for="content-password" class="block text-sm text-dr-text-body dark:text-dr-text-body-dark">This content is password protected</label>
+            <input id="content-password" type="password" required autofocus autocomplete="current-password"
+                class="w-full px-4 py-2 rounded-md focus:outline-none border-2 border-dr-border-secondary dark:border-dr-border-secondary-dark bg-dr-bg dark:bg-dr-bg-dark text-dr-text-heading dark:text-dr-text-heading-dark focus:border-dr-blue dark:focus:border-dr-blue-light" />
+            <button type="submit" class="px-4 py-2 bg-dr-blue dark:bg-dr-blue-dark hover:bg-dr-bg-blue-hover dark:hover:bg-dr-bg-blue-hover-dark text-white rounded-md transition-colors">Unlock</button>
+            <p id="content-password-error" class="hidden text-sm text-dr-orange dark:text-dr-orange-dark">Wrong password</p>
+        </form>`;
+
+    const form = container.querySelector('form');
+    form.addEventListener('submit', async (event) => {
+        event.preventDefault();
+        const button = form.querySelector('button');
+        button.disabled = true;
+        try {
+            await onPassword(form.querySelector('input').value);
+        } catch (err) {
+            console.error('Decryption with password failed:', err);
+            form.querySelector('#content-password-error').classList.remove('hidden');
+        } finally {
+            button.disabled = false;
+        }
+    });
+}
@@ -0,0 +1,321 @@
+// QR code encoder, a port of internal/qrcode. Codes are generated here rather than by /qr/{short} because the key
+// of encrypted content lives in the URL fragment, which must never reach the server.
+
+const QR_ECC_CODEWORDS_PER_BLOCK = [-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28];
+const QR_ECC_BLOCKS = [-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49];
+const QR_QUIET_ZONE = 4;
+
+function qrRawDataModules(version) {
+    let result = (16 * version + 128) * version + 64;
+    if (version >= 2) {
+        const numAlign = Math.floor(version / 7) + 2;
+        result -= (25 * numAlign - 10) * numAlign - 55;
+        if (version >= 7) {
+            result -= 36;
+        }
+    }
+    return result;
+}
+
+function qrDataCodewords(version) {
+    return Math.floor(qrRawDataModules(version) / 8) - QR_ECC_CODEWORDS_PER_BLOCK[version] * QR_ECC_BLOCKS[version];
+}
+
+function qrAlignmentPositions(version) {
+    if (version === 1) {
+        return [];
+    }
+    const numAlign = Math.floor(version / 7) + 2;
+    const step = Math.floor((version * 8 + numAlign * 3 + 5) / (numAlign * 4 - 4)) * 2;
+    const positions = [6];
+    for (let pos = version * 4 + 17 - 7; positions.length < numAlign; pos -= step) {
+        positions.splice(1, 0, pos);
+    }
+    return positions;
+}
+
+function qrMultiply(x, y) {
+    let z = 0;
+    for (let i = 7; i >= 0; i--) {
+        z = (z << 1) ^ ((z >>> 7) * 0x11D);
+        z ^= ((y >>> i) & 1) * x;
+    }
+    return z;
+}
+
+function qrReedSolomonDivisor(degree) {
+    const result = new Array(degree).fill(0);
+    result[degree - 1] = 1;
+    let root = 1;
+    for (let i = 0; i < degree; i++) {
+        for (let j = 0; j < result.length; j++) {
+            result[j] = qrMultiply(result[j], root);
+            if (j + 1 < result.length) {
+                result[j] ^= result[j + 1];
+            }
+        }
+        root = qrMultiply(root, 0x02);
+    }
+    return result;
+}
+
+function qrReedSolomonRemainder(data, divisor) {
+    const result = new Array(divisor.length).fill(0);
+    for (const b of data) {
+        const factor = b ^ result.shift();
+        result.push(0);
+        divisor.forEach((coefficient, i) => { result[i] ^= qrMultiply(coefficient, factor); });
+    }
+    return result;
+}
+
+function qrAddEccAndInterleave(data, version) {
+    const numBlocks = QR_ECC_BLOCKS[version];
+    const eccLen = QR_ECC_CODEWORDS_PER_BLOCK[version];
+    const raw = Math.floor(qrRawDataModules(version) / 8);
+    const numShort = numBlocks - raw % numBlocks;
+    const shortLen = Math.floor(raw / numBlocks);
+
+    const divisor = qrReedSolomonDivisor(eccLen);
+    const blocks = [];
+    for (let i = 0, k = 0; i < numBlocks; i++) {
+        const block = data.slice(k, k + shortLen - eccLen + (i < numShort ? 0 : 1));
+        k += block.length;
+        const ecc = qrReedSolomonRemainder(block, divisor);
+        if (i < numShort) {
+            block.push(0);
+        }
+        blocks.push(block.concat(ecc));
+    }
+
+    const result = [];
+    for (let i = 0; i <= shortLen; i++) {
+        blocks.forEach((block, j) => {
+            if (i !== shortLen - eccLen || j >= numShort) {
+                result.push(block[i]);
+            }
+        });
+    }
+    return result;
+}
+
+// qrEncode encodes text in byte mode with medium error correction, returning rows of booleans (true is dark).
+function qrEncode(text) {
+    const data = Array.from(new TextEncoder().encode(text));
+
+    let version = 1;
+    while (version <= 40 && 4 + (version <= 9 ? 8 : 16) + 8 * data.length > qrDataCodewords(version) * 8) {
+        version++;
+    }
+    if (version > 40) {
+        throw new Error('Text too long for a QR code');
+    }
+
+    const bits = [];
+    const append = (value, length) => {
+        for (let i = length - 1; i >= 0; i--) {
+            bits.push((value >>> i) & 1);
+        }
+    };
+    append(0b0100, 4);
+    append(data.length, version <= 9 ? 8 : 16);
+    data.forEach(b => append(b, 8));
+    const capacity = qrDataCodewords(version) * 8;
+    append(0, Math.min(4, capacity - bits.length));
+    append(0, (8 - bits.length % 8) % 8);
+    for (let pad = 0xEC; bits.length < capacity; pad ^= 0xEC ^ 0x11) {
+        append(pad, 8);
+    }
+    const codewords = [];
+    for (let i = 0; i < bits.length; i += 8) {
+        codewords.push(parseInt(bits.slice(i, i + 8).join(''), 2));
+    }
+
+    const size = version * 4 + 17;
+    const modules = Array.from({ length: size }, () => new Array(size).fill(false));
+    const isFunction = Array.from({ length: size }, () => new Array(size).fill(false));
+    const setFunction = (x, y, dark) => {
+        modules[y][x] = dark;
+        isFunction[y][x] = true;
+    };
+
+    const drawFormatBits = mask => {
+        const data = mask; // Level M is 0
+        let rem = data;
+        for (let i = 0; i < 10; i++) {
+            rem = (rem << 1) ^ ((rem >>> 9) * 0x537);
+        }
+        const format = ((data << 10) | rem) ^ 0x5412;
+        const bit = i => ((format >>> i) & 1) !== 0;
+        for (let i = 0; i < 6; i++) {
+            setFunction(8, i, bit(i));
+        }
+        setFunction(8, 7, bit(6));
+        setFunction(8, 8, bit(7));
+        setFunction(7, 8, bit(8));
+        for (let i = 9; i < 15; i++) {
+            setFunction(14 - i, 8, bit(i));
+        }
+        for (let i = 0; i < 8; i++) {
+            setFunction(size - 1 - i, 8, bit(i));
+        }
+        for (let i = 8; i < 15; i++) {
+            setFunction(8, size - 15 + i, bit(i));
+        }
+        setFunction(8, size - 8, true);
+    };
+
+    // Function patterns
+    for (let i = 0; i < size; i++) {
+        setFunction(6, i, i % 2 === 0);
+        setFunction(i, 6, i % 2 === 0);
+    }
+    for (const [cx, cy] of [[3, 3], [size - 4, 3], [3, size - 4]]) {
+        for (let dy = -4; dy <= 4; dy++) {
+            for (let dx = -4; dx <= 4; dx++) {
+                const x = cx + dx, y = cy + dy;
+                if (x >= 0 && x < size && y >= 0 && y < size) {
+                    const dist = Math.max(Math.abs(dx), Math.abs(dy));
+                    setFunction(x, y, dist !== 2 && dist !== 4);
+                }
+            }
+        }
+    }
+    const positions = qrAlignmentPositions(version);
+    const last = positions.length - 1;
+    positions.forEach((y, i) => positions.forEach((x, j) => {
+        if ((i === 0 && j === 0) || (i === 0 && j === last) || (i === last && j === 0)) {
+            return;
+        }
+        for (let dy = -2; dy <= 2; dy++) {
+            for (let dx = -2; dx <= 2; dx++) {
+                setFunction(x + dx, y + dy, Math.max(Math.abs(dx), Math.abs(dy)) !== 1);
+            }
+        }
+    }));
+    drawFormatBits(0);
+    if (version >= 7) {
+        let rem = version;
+        for (let i = 0; i < 12; i++) {
+            rem = (rem << 1) ^ ((rem >>> 11) * 0x1F25);
+        }
+        const versionBits = (version << 12) | rem;
+        for (let i = 0; i < 18; i++) {
+            const dark = ((versionBits >>> i) & 1) !== 0;
+            const a = size - 11 + i % 3, b = Math.floor(i / 3);
+            setFunction(a, b, dark);
+            setFunction(b, a, dark);
+        }
+    }
+
+    // Data in the zigzag order of two module wide columns, from the bottom right
+    const all = qrAddEccAndInterleave(codewords, version);
+    let i = 0;
+    for (let right = size - 1; right >= 1; right -= 2) {
+        if (right === 6) {
+            right = 5;
+        }
+        for (let vert = 0; vert < size; vert++) {
+            for (let j = 0; j < 2; j++) {
+                const x = right - j;
+                const y = ((right + 1) & 2) === 0 ? size - 1 - vert : vert;
+                if (!isFunction[y][x] && i < all.length * 8) {
+                    modules[y][x] = ((all[i >>> 3] >>> (7 - (i & 7))) & 1) !== 0;
+                    i++;
+                }
+            }
+        }
+    }
+
+    const masks = [
+        (x, y) => (x + y) % 2 === 0,
+        (x, y) => y % 2 === 0,
+        (x, y) => x % 3 === 0,
+        (x, y) => (x + y) % 3 === 0,
+        (x, y) => (Math.floor(x / 3) + Math.floor(y / 2)) % 2 === 0,
+        (x, y) => x * y % 2 + x * y % 3 === 0,
+        (x, y) => (x * y % 2 + x * y % 3) % 2 === 0,
+        (x, y) => ((x + y) % 2 + x * y % 3) % 2 === 0,
+    ];
+    const applyMask = mask => {
+        for (let y = 0; y < size; y++) {
+            for (let x = 0; x < size; x++) {
+                if (masks[mask](x, y) && !isFunction[y][x]) {
+                    modules[y][x] = !modules[y][x];
+                }
+            }
+        }
+    };
+
+    let best = 0, bestPenalty = -1;
+    for (let mask = 0; mask < 8; mask++) {
+        applyMask(mask);
+        drawFormatBits(mask);
+        const penalty = qrPenalty(modules);
+        if (bestPenalty < 0 || penalty < bestPenalty) {
+            best = mask;
+            bestPenalty = penalty;
+        }
+        applyMask(mask);
+    }
+    applyMask(best);
+    drawFormatBits(best);
+    return modules;
+}
+
+const QR_FINDER_BEFORE = [0, 0, 0, 0, 1, 0, 1, 1, 1, 0, 1];
+const QR_FINDER_AFTER = [1, 0, 1, 1, 1, 0, 1, 0, 0, 0, 0];
+
+function qrPenalty(modules) {
+    const size = modules.length;
+    let penalty = 0, dark = 0;
+    const matches = (line, start, pattern) => pattern.every((d, i) => line[start + i] === (d === 1));
+    for (let i = 0; i < size; i++) {
+        const row = modules[i];
+        const col = modules.map(r => r[i]);
+        dark += row.filter(Boolean).length;
+        for (const line of [row, col]) {
+            let run = 1;
+            for (let j = 1; j <= size; j++) {
+                if (j < size && line[j] === line[j - 1]) {
+                    run++;
+                    continue;
+                }
+                if (run >= 5) {
+                    penalty += run - 2;
+                }
+                run = 1;
+            }
+            for (let j = 0; j + QR_FINDER_BEFORE.length <= size; j++) {
+                if (matches(line, j, QR_FINDER_BEFORE) || matches(line, j, QR_FINDER_AFTER)) {
+                    penalty += 40;
+                }
+            }
+        }
+    }
+    for (let y = 0; y < size - 1; y++) {
+        for (let x = 0; x < size - 1; x++) {
+            const color = modules[y][x];
+            if (color === modules[y][x + 1] && color === modules[y + 1][x] && color === modules[y + 1][x + 1]) {
+                penalty += 3;
+            }
+        }
+    }
+    const total = size * size;
+    const k = Math.ceil(Math.abs(dark * 20 - total * 10) / total) - 1;
+    return penalty + Math.max(k, 0) * 10;
+}
+
+// qrSVG renders text as an SVG QR code, the same markup /qr/{short}?format=svg serves.
+function qrSVG(text) {
+    const modules = qrEncode(text);
+    const side = modules.length + 2 * QR_QUIET_ZONE;
+    let path = '';
+    modules.forEach((row, y) => row.forEach((dark, x) => {
+        if (dark) {
+            path += `M${x + QR_QUIET_ZONE},${y + QR_QUIET_ZONE}h1v1h-1z`;
+        }
+    }));
+    return `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 ${side} ${side}" shape-rendering="crispEdges">` +
+        `<rect width="${side}" height="${side}" fill="#fff"/><path d="${path}" fill="#000"/></svg>`;
+}